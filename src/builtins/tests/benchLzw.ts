@@ -0,0 +1,112 @@
+import { LZWReader, LZWWriter, Order } from '../../compress/lzw'
+import { EOF } from '../../io'
+import { Buffer as GoBuffer } from '../tshelpers/buffer'
+
+// Size of each uncompressed corpus
+const corpusSize = 16 * 1024 * 1024
+
+// Number of timed decodes per corpus
+const iterations = 5
+
+// makeCorpus returns a mix of repetitive, text-like runs and random noise,
+// which roughly matches the redundancy of GIF image data
+const makeCorpus = (size: number): Uint8Array => {
+    let corpus = new Uint8Array(size)
+    let seed = 1
+
+    // Simple LCG so the corpus is identical across runs
+    const rand = () => {
+        seed = (Math.imul(seed, 1103515245) + 12345) >>> 0
+        return seed >>> 16
+    }
+
+    for(let i = 0; i < size; i++) {
+        if(i > 64 && rand() % 4 != 0) {
+            corpus[i] = corpus[i - 1 - (rand() % 64)]
+        } else {
+            corpus[i] = rand() & 0xff
+        }
+    }
+
+    return corpus
+}
+
+const compress = (data: Uint8Array, order: Order): Uint8Array => {
+    let out = new GrowingWriter()
+    let w = new LZWWriter(out, order, 8)
+
+    let [, err] = w.Write(data)
+    if(err) {
+        throw err
+    }
+
+    err = w.Close()
+    if(err) {
+        throw err
+    }
+
+    return out.bytes()
+}
+
+// GrowingWriter is an io.Writer that appends into an amortized buffer,
+// as the tshelpers Buffer copies itself on every Write
+class GrowingWriter {
+    private buf = new Uint8Array(1024)
+    private n = 0
+
+    Write(p: Uint8Array): [number, Error | null] {
+        if(this.n + p.length > this.buf.length) {
+            let grown = new Uint8Array(Math.max(this.buf.length * 2, this.n + p.length))
+            grown.set(this.buf.subarray(0, this.n))
+            this.buf = grown
+        }
+
+        this.buf.set(p, this.n)
+        this.n += p.length
+        return [p.length, null]
+    }
+
+    bytes(): Uint8Array {
+        return this.buf.subarray(0, this.n)
+    }
+}
+
+const benchDecode = (name: string, compressed: Uint8Array, order: Order, want: number) => {
+    let reader = new LZWReader(new GoBuffer(compressed), order, 8)
+    let buf = new Uint8Array(32 * 1024)
+    let best = Infinity
+
+    for(let i = 0; i < iterations; i++) {
+        reader.Reset(new GoBuffer(compressed), order, 8)
+
+        let start = performance.now()
+        let total = 0
+        while(true) {
+            let [n, err] = reader.Read(buf)
+            total += n
+
+            if(err) {
+                if(err != EOF) {
+                    throw err
+                }
+                break
+            }
+        }
+        let elapsed = performance.now() - start
+
+        if(total != want) {
+            throw new Error(`${name}: decoded ${total} bytes, want ${want}`)
+        }
+
+        best = Math.min(best, elapsed)
+    }
+
+    let mbs = (want / (1024 * 1024)) / (best / 1000)
+    console.log(`${name}: ${want} bytes in ${best.toFixed(1)}ms (${mbs.toFixed(1)} MB/s)`)
+}
+
+let corpus = makeCorpus(corpusSize)
+
+for(let [name, order] of [["BenchmarkDecoderLSB", Order.LSB], ["BenchmarkDecoderMSB", Order.MSB]] as const) {
+    benchDecode(name, compress(corpus, order), order, corpus.length)
+}
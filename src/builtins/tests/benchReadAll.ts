@@ -0,0 +1,96 @@
+import { LZWReader, LZWWriter, Order } from '../../compress/lzw'
+import { AsyncReadAll, EOF, ReadAll, Reader, ToAsyncReader } from '../../io'
+import { mergeUint8Arrays } from '../tshelpers/arrays'
+import { Buffer as GoBuffer } from '../tshelpers/buffer'
+
+// Sizes of the inputs read by each benchmark
+const sizes = [1 << 20, 16 << 20, 128 << 20]
+
+// Size of the LZW corpus, decoded straight into ReadAll
+const lzwCorpusSize = 64 << 20
+
+// Number of timed runs per input
+const iterations = 3
+
+// chunkReader returns data in reads of at most chunk bytes, like a file or socket would
+class chunkReader implements Reader {
+    private data: Uint8Array
+    private chunk: number
+
+    constructor(data: Uint8Array, chunk: number) {
+        this.data = data
+        this.chunk = chunk
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if(this.data.length == 0) {
+            return [0, EOF]
+        }
+
+        let n = Math.min(p.length, this.chunk, this.data.length)
+        p.set(this.data.subarray(0, n))
+        this.data = this.data.subarray(n)
+        return [n, null]
+    }
+}
+
+// makeCorpus returns size bytes of repetitive, text-like data
+const makeCorpus = (size: number): Uint8Array => {
+    let corpus = new Uint8Array(size)
+    let text = new TextEncoder().encode("TOBEORNOTTOBEORTOBEORNOT, that is the question. ")
+    for(let i = 0; i < size; i++) {
+        corpus[i] = text[(i * 7 + (i >>> 10)) % text.length]
+    }
+    return corpus
+}
+
+// bench runs read iterations times, and reports the best time to read want bytes
+const bench = async (name: string, want: number, read: () => Promise<[Uint8Array, Error | null]> | [Uint8Array, Error | null]) => {
+    let best = Infinity
+
+    for(let i = 0; i < iterations; i++) {
+        let start = performance.now()
+        let [b, err] = await read()
+        let elapsed = performance.now() - start
+
+        if(err) {
+            throw err
+        }
+        if(b.length != want) {
+            throw new Error(`${name}: read ${b.length} bytes, want ${want}`)
+        }
+
+        best = Math.min(best, elapsed)
+    }
+
+    let mbs = (want / (1024 * 1024)) / (best / 1000)
+    console.log(`${name}: ${want} bytes in ${best.toFixed(1)}ms (${mbs.toFixed(1)} MB/s)`)
+}
+
+const main = async () => {
+    for(let size of sizes) {
+        let data = new Uint8Array(size)
+        let mb = size >> 20
+
+        await bench(`BenchmarkReadAll/${mb}MB/512B`, size, () => ReadAll(new chunkReader(data, 512)))
+        await bench(`BenchmarkReadAll/${mb}MB/32KB`, size, () => ReadAll(new chunkReader(data, 32 * 1024)))
+        await bench(`BenchmarkAsyncReadAll/${mb}MB/32KB`, size, () => AsyncReadAll(ToAsyncReader(new chunkReader(data, 32 * 1024))))
+    }
+
+    // Decompressing a large LZW stream, the case ReadAll is most used for
+    let corpus = makeCorpus(lzwCorpusSize)
+    let chunks: Uint8Array[] = []
+    let w = new LZWWriter({
+        Write: (p) => {
+            chunks.push(p.slice())
+            return [p.length, null]
+        },
+    }, Order.LSB, 8)
+    w.Write(corpus)
+    w.Close()
+    let c = mergeUint8Arrays(chunks)
+
+    await bench(`BenchmarkReadAllLZW/${lzwCorpusSize >> 20}MB`, corpus.length, () => ReadAll(new LZWReader(new GoBuffer(c), Order.LSB, 8)))
+}
+
+main()
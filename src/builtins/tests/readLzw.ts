@@ -24,4 +24,5 @@ const readLzwFile = (path: string) => {
     console.log("Output:", n, "written to buffer of length", abf.length)
 }
 
-readLzwFile('test.ibl')
\ No newline at end of file
+// Usage: npm run testReadLzw -- [file], see src/cmd/gostd.ts for a full tool
+readLzwFile(process.argv[2] ?? 'test.ibl')
@@ -23,11 +23,42 @@ export function mergeUint8Arrays(arrays: Uint8Array[]): Uint8Array {
     const totalSize = arrays.reduce((acc, e) => acc + e.length, 0);
     const merged = new Uint8Array(totalSize);
   
-    arrays.forEach((array, i, arrays) => {
-      const offset = arrays.slice(0, i).reduce((acc, e) => acc + e.length, 0);
+    let offset = 0;
+    for (const array of arrays) {
       merged.set(array, offset);
-    });
+      offset += array.length;
+    }
   
     return merged;
 }  
 
+
+/**
+ * Returns the capacity that Go's append grows a slice with capacity oldCap to,
+ * when it needs room for newLen elements: double for small slices, and about
+ * 1.25x for large ones, so that appending n elements takes amortized O(n) time.
+ * Taken from growslice in https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/runtime/slice.go,
+ * without the rounding up to a malloc size class
+ *
+ * @param newLen The length the slice needs to hold
+ * @param oldCap The current capacity of the slice
+ * @returns The new capacity, at least newLen
+ */
+export function nextSliceCap(newLen: number, oldCap: number): number {
+    let newcap = oldCap;
+    const doublecap = newcap + newcap;
+    if (newLen > doublecap) {
+        return newLen;
+    }
+    const threshold = 256;
+    if (oldCap < threshold) {
+        return doublecap;
+    }
+    while (newcap < newLen) {
+        // Transition from growing 2x for small slices
+        // to growing 1.25x for large slices. This formula
+        // gives a smooth-ish transition between the two.
+        newcap += Math.floor((newcap + 3 * threshold) / 4);
+    }
+    return newcap;
+}
@@ -1,14 +1,32 @@
+import * as errors from "../../errors"
 import * as io from "../../io"
+import * as utf8 from "../../unicode/utf8"
+import { mergeUint8Arrays } from "./arrays"
+
+// The readOp constants describe the last action performed on
+// the buffer, so that UnreadRune and UnreadByte can check for
+// invalid usage. opReadRuneX constants are chosen such that
+// converted to int they correspond to the rune size that was read.
+const opRead = -1 // Any other read operation.
+const opInvalid = 0 // Non-read operation.
+const opReadRune1 = 1 // Read rune of size 1.
+
+const errUnreadByte = errors.New("bytes.Buffer: UnreadByte: previous operation was not a successful read")
+const errUnreadRune = errors.New("bytes.Buffer: UnreadRune: previous operation was not a successful ReadRune")
+const errNegativeOffset = errors.New("bytes.Buffer.ReadAt: negative offset")
 
 /**
  * An implementation of Go's io.ByteReader interface
  */
-export class Buffer implements io.ByteReader, io.Reader, io.ReaderAt, io.ReaderFrom, io.ByteWriter, io.Writer, io.WriterAt, io.WriterTo {
+export class Buffer implements io.ByteScanner, io.RuneScanner, io.Reader, io.ReaderAt, io.ReaderFrom, io.ByteWriter, io.Writer, io.WriterAt, io.WriterTo, io.StringWriter {
     private buf: Uint8Array
     private _readAllOnCopy: boolean
+    private data: Uint8Array // contents since the last write, including bytes already read, for ReadAt
+    private lastRead = opInvalid // last read operation, so that Unread* can work correctly.
 
     constructor(buf: Uint8Array, readAllOnCopy: boolean = true) {
         this.buf = buf
+        this.data = buf
         this._readAllOnCopy = readAllOnCopy
     }
 
@@ -23,19 +41,70 @@ export class Buffer implements io.ByteReader, io.Reader, io.ReaderAt, io.ReaderF
     // Equivalent to r.ReadByte() in io.ByteReader
     ReadByte(): [number, Error | null] {
         if (this.buf.length == 0) {
-            return [0, new Error(io.Errors.EOF)]
+            return [0, io.EOF]
         }
 
         let b = this.buf[0]
         this.buf = this.buf.subarray(1)
+        this.lastRead = opRead
         return [b, null]
     }
 
+    // ReadRune reads and returns the next UTF-8-encoded Unicode code point from the buffer.
+    // If no bytes are available, the error returned is io.EOF.
+    // If the bytes are an erroneous UTF-8 encoding, it consumes one byte and returns U+FFFD, 1.
+    ReadRune(): [number, number, Error | null] {
+        if (this.buf.length == 0) {
+            this.lastRead = opInvalid
+            return [0, 0, io.EOF]
+        }
+
+        let [r, size] = utf8.DecodeRune(this.buf)
+        this.buf = this.buf.subarray(size)
+        this.lastRead = opReadRune1 - 1 + size
+        return [r, size, null]
+    }
+
+    // UnreadRune unreads the last rune returned by ReadRune.
+    // If the most recent read or write operation on the buffer was
+    // not a successful ReadRune, UnreadRune returns an error.
+    UnreadRune(): Error | null {
+        if (this.lastRead <= opInvalid) {
+            return errUnreadRune
+        }
+        this.unread(this.lastRead)
+        this.lastRead = opInvalid
+        return null
+    }
+
+    // UnreadByte unreads the last byte returned by the most recent successful
+    // read operation that read at least one byte. If a write has happened since
+    // the last read, if the last read returned an error, or if the read read zero
+    // bytes, UnreadByte returns an error.
+    UnreadByte(): Error | null {
+        if (this.lastRead == opInvalid) {
+            return errUnreadByte
+        }
+        this.unread(1)
+        this.lastRead = opInvalid
+        return null
+    }
+
+    // unread moves the start of the buffer back by n bytes. Reads only narrow
+    // the view of the underlying ArrayBuffer, so the bytes are still there.
+    private unread(n: number) {
+        this.buf = new Uint8Array(this.buf.buffer, this.buf.byteOffset - n, this.buf.length + n)
+    }
+
     // Read reads data into p implementing the io.Reader interface
     Read(p: Uint8Array): [number, Error | null] {
+        this.lastRead = opInvalid
         let n = this.buf.length
         if (n == 0) {
-            return [0, new Error(io.Errors.EOF)]
+            if (p.length == 0) {
+                return [0, null]
+            }
+            return [0, io.EOF]
         }
 
         if (n > p.length) {
@@ -44,31 +113,39 @@ export class Buffer implements io.ByteReader, io.Reader, io.ReaderAt, io.ReaderF
 
         p.set(this.buf.subarray(0, n))
         this.buf = this.buf.subarray(n)
+        if (n > 0) {
+            this.lastRead = opRead
+        }
         return [n, null]
     }
 
-    // ReadAt reads len(p) bytes into p starting at offset off in the underlying input source
+    // ReadAt reads len(p) bytes into p starting at offset off in the buffer's
+    // contents since the last write, including bytes already read, like
+    // bytes.Reader.ReadAt. It does not change the read position
     ReadAt(p: Uint8Array, off: number): [number, Error | null] {
-        let n = this.buf.length
-        if (n == 0) {
-            return [0, new Error(io.Errors.EOF)]
+        if (off < 0) {
+            return [0, errNegativeOffset]
         }
-
-        if (n > p.length) {
-            n = p.length
+        if (off >= this.data.length) {
+            return [0, io.EOF]
         }
 
-        p.set(this.buf.subarray(0, n), off)
-        this.buf = this.buf.subarray(n)
+        let n = Math.min(p.length, this.data.length - off)
+        p.set(this.data.subarray(off, off + n))
+        if (n < p.length) {
+            return [n, io.EOF]
+        }
         return [n, null]
     }
 
-    // ReadFrom reads data from r until EOF or error
+    // ReadFrom reads data from r until EOF or error, appending it to the buffer
     ReadFrom(r: io.Reader): [number, Error | null] {
+        this.lastRead = opInvalid
         if(this._readAllOnCopy) {
             let [buf, err] = io.ReadAll(r)
 
-            this.buf = buf
+            this.buf = mergeUint8Arrays([this.buf, buf])
+            this.data = this.buf
 
             return [buf.length, err]
         }
@@ -80,24 +157,48 @@ export class Buffer implements io.ByteReader, io.Reader, io.ReaderAt, io.ReaderF
 
     // WriteByte writes a single byte to the buffer
     WriteByte(b: number): Error | null {
+        this.lastRead = opInvalid
         this.buf = Uint8Array.from([...this.buf, b])
+        this.data = this.buf
         return null
     }
 
     // Write writes len(p) bytes from p to the buffer
     Write(p: Uint8Array): [number, Error | null] {
+        this.lastRead = opInvalid
         this.buf = Uint8Array.from([...this.buf, ...p])
+        this.data = this.buf
         return [p.length, null]
     }
 
+    // WriteString appends the UTF-8 encoding of s to the buffer
+    WriteString(s: string): [number, Error | null] {
+        return this.Write(new TextEncoder().encode(s))
+    }
+
+    // WriteRune appends the UTF-8 encoding of Unicode code point r to the buffer,
+    // returning its length. Invalid code points are written as U+FFFD
+    WriteRune(r: number): [number, Error | null] {
+        if (r >= 0 && r < utf8.RuneSelf) {
+            this.WriteByte(r)
+            return [1, null]
+        }
+        let p = new Uint8Array(utf8.UTFMax)
+        let n = utf8.EncodeRune(p, r)
+        return this.Write(p.subarray(0, n))
+    }
+
     // WriteAt writes len(p) bytes from p to the buffer starting at offset off
     WriteAt(p: Uint8Array, off: number): [number, Error | null] {
+        this.lastRead = opInvalid
         this.buf = Uint8Array.from([...this.buf.subarray(0, off), ...p, ...this.buf.subarray(off)])
+        this.data = this.buf
         return [p.length, null]
     }
 
     // WriteTo writes data to w until there's no more data to write or when an error occurs
     WriteTo(w: io.Writer): [number, Error | null] {
+        this.lastRead = opInvalid
         let [n, err] = w.Write(this.buf)
         this.buf = this.buf.subarray(n)
         return [n, err]
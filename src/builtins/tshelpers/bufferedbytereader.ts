@@ -0,0 +1,119 @@
+import * as io from "../../io"
+
+/**
+ * BufferedByteReader adds ReadByte to an io.Reader that lacks it. This replaces
+ * the bufio.Reader that Go wraps such sources in
+ */
+export class BufferedByteReader implements io.ByteReader, io.Reader {
+    private src: io.Reader
+    private buf: Uint8Array = new Uint8Array(io.DefaultBufferSize)
+    private r: number = 0 // read index into buf
+    private w: number = 0 // write index into buf
+    private err: Error | null = null // sticky error from src, returned once buf is drained
+
+    constructor(src: io.Reader) {
+        this.src = src
+    }
+
+    // reset discards any buffered data and switches to reading from src
+    reset(src: io.Reader) {
+        this.src = src
+        this.r = 0
+        this.w = 0
+        this.err = null
+    }
+
+    // ReadByte reads the next byte, refilling the buffer from src when it is empty
+    ReadByte(): [number, Error | null] {
+        while (this.r == this.w) {
+            if (this.err) {
+                return [0, this.readErr()]
+            }
+            this.fill()
+        }
+
+        let c = this.buf[this.r]
+        this.r++
+        return [c, null]
+    }
+
+    // Read reads data into p, following bufio.Reader.Read. The bytes are taken from
+    // at most one Read on src, so n may be less than len(p)
+    Read(p: Uint8Array): [number, Error | null] {
+        if (p.length == 0) {
+            if (this.r < this.w) {
+                return [0, null]
+            }
+            return [0, this.readErr()]
+        }
+
+        if (this.r == this.w) {
+            if (this.err) {
+                return [0, this.readErr()]
+            }
+
+            if (p.length >= this.buf.length) {
+                // Large read, empty buffer.
+                // Read directly into p to avoid copy.
+                let [n, err] = this.src.Read(p)
+                if (n < 0) {
+                    throw new Error("bufio: reader returned negative count from Read")
+                }
+                this.err = err
+                return [n, this.readErr()]
+            }
+
+            // One read.
+            this.r = 0
+            this.w = 0
+            let [n, err] = this.src.Read(this.buf)
+            if (n < 0) {
+                throw new Error("bufio: reader returned negative count from Read")
+            }
+            this.err = err
+            if (n == 0) {
+                return [0, this.readErr()]
+            }
+            this.w += n
+        }
+
+        // copy as much as we can
+        let n = Math.min(p.length, this.w - this.r)
+        p.set(this.buf.subarray(this.r, this.r + n))
+        this.r += n
+        return [n, null]
+    }
+
+    // readErr returns the sticky error from src and clears it
+    private readErr(): Error | null {
+        let err = this.err
+        this.err = null
+        return err
+    }
+
+    // fill reads a new chunk into the (empty) buffer.
+    private fill() {
+        this.r = 0
+        this.w = 0
+
+        // Read new data: try a limited number of times.
+        for (let i = io.MaxConsecutiveEmptyReads; i > 0; i--) {
+            let [n, err] = this.src.Read(this.buf)
+            if (n < 0) {
+                throw new Error("bufio: reader returned negative count from Read")
+            }
+            this.w += n
+
+            if (err) {
+                this.err = err
+                return
+            }
+
+            if (n > 0) {
+                return
+            }
+        }
+
+        this.err = io.ErrNoProgress
+    }
+}
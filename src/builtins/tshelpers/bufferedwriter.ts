@@ -0,0 +1,89 @@
+import * as io from "../../io"
+
+/**
+ * BufferedWriter batches small writes to an io.Writer. This replaces the
+ * bufio.Writer that Go wraps such destinations in.
+ *
+ * After a write error no more data is accepted, and all subsequent writes and
+ * Flush return the error.
+ */
+export class BufferedWriter implements io.Writer, io.ByteWriter {
+    private dst: io.Writer
+    private buf: Uint8Array = new Uint8Array(io.DefaultBufferSize)
+    private n: number = 0 // number of buffered bytes
+    private err: Error | null = null // sticky error from dst
+
+    constructor(dst: io.Writer) {
+        this.dst = dst
+    }
+
+    // Flush writes any buffered data to dst
+    Flush(): Error | null {
+        if (this.err) {
+            return this.err
+        }
+        if (this.n == 0) {
+            return null
+        }
+
+        let [n, err] = this.dst.Write(this.buf.subarray(0, this.n))
+        if (n < this.n && err == null) {
+            err = io.ErrShortWrite
+        }
+        if (err) {
+            if (n > 0 && n < this.n) {
+                this.buf.copyWithin(0, n, this.n)
+            }
+            this.n -= n
+            this.err = err
+            return err
+        }
+
+        this.n = 0
+        return null
+    }
+
+    // Write writes the contents of p into the buffer. It returns the number of
+    // bytes written. If n < len(p), it also returns an error explaining why the
+    // write is short.
+    Write(p: Uint8Array): [number, Error | null] {
+        let nn = 0
+        while (p.length > this.buf.length - this.n && this.err == null) {
+            let n: number
+            if (this.n == 0) {
+                // Large write, empty buffer.
+                // Write directly from p to avoid copy.
+                [n, this.err] = this.dst.Write(p)
+            } else {
+                n = this.buf.length - this.n
+                this.buf.set(p.subarray(0, n), this.n)
+                this.n += n
+                this.Flush()
+            }
+            nn += n
+            p = p.subarray(n)
+        }
+        if (this.err) {
+            return [nn, this.err]
+        }
+
+        this.buf.set(p, this.n)
+        this.n += p.length
+        nn += p.length
+        return [nn, null]
+    }
+
+    // WriteByte writes a single byte.
+    WriteByte(c: number): Error | null {
+        if (this.err) {
+            return this.err
+        }
+        if (this.n == this.buf.length && this.Flush()) {
+            return this.err
+        }
+
+        this.buf[this.n] = c
+        this.n++
+        return null
+    }
+}
@@ -0,0 +1,22 @@
+/**
+ * hasMethods reports whether v has a method with each of the given names.
+ *
+ * TS interfaces don't exist at runtime, so this stands in for Go's type
+ * assertions to an interface. Like those, it only looks at the method set:
+ * own and inherited methods both count, while a property that isn't a
+ * function does not.
+ *
+ * @param v The value to check
+ * @param names The names of the methods v must have
+ */
+export function hasMethods(v: unknown, ...names: string[]): boolean {
+    if (v == null || (typeof v != "object" && typeof v != "function")) {
+        return false
+    }
+    for (let name of names) {
+        if (typeof (v as Record<string, unknown>)[name] != "function") {
+            return false
+        }
+    }
+    return true
+}
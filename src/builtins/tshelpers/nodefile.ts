@@ -0,0 +1,113 @@
+import * as fs from "node:fs"
+import * as io from "../../io"
+
+// Number of milliseconds to wait for a non-blocking descriptor before retrying
+// a read or write that failed with EAGAIN
+const retryDelay = 5
+
+// retryWait is the cell Atomics.wait sleeps on. Nothing ever notifies it
+const retryWait = new Int32Array(new SharedArrayBuffer(4))
+
+// sleep blocks the thread for ms milliseconds. The fs calls are synchronous, so
+// there is no event loop to yield to while the descriptor isn't ready
+function sleep(ms: number) {
+    Atomics.wait(retryWait, 0, 0, ms)
+}
+
+/**
+ * NodeFile is an io.Reader and io.Writer over a node:fs file descriptor, using
+ * the synchronous fs calls. It is what Go's *os.File is to the CLI.
+ *
+ * Exceptions thrown by node:fs are returned as errors.
+ */
+export class NodeFile implements io.Reader, io.Writer, io.Closer {
+    fd: number
+    name: string
+    private closed: boolean = false
+
+    constructor(fd: number, name: string) {
+        this.fd = fd
+        this.name = name
+    }
+
+    /**
+     * Open opens the named file for reading.
+     */
+    static Open(name: string): [NodeFile | null, Error | null] {
+        try {
+            return [new NodeFile(fs.openSync(name, "r"), name), null]
+        } catch (e) {
+            return [null, e as Error]
+        }
+    }
+
+    /**
+     * Create creates or truncates the named file for writing.
+     */
+    static Create(name: string): [NodeFile | null, Error | null] {
+        try {
+            return [new NodeFile(fs.openSync(name, "w"), name), null]
+        } catch (e) {
+            return [null, e as Error]
+        }
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (p.length == 0) {
+            return [0, null]
+        }
+
+        while (true) /* for */ {
+            try {
+                let n = fs.readSync(this.fd, p, 0, p.length, null)
+                if (n == 0) {
+                    return [0, io.EOF]
+                }
+                return [n, null]
+            } catch (e) {
+                // stdin may be a non-blocking pipe, in which case there is nothing to do but
+                // wait for data and retry
+                if ((e as NodeJS.ErrnoException).code == "EAGAIN") {
+                    sleep(retryDelay)
+                    continue
+                }
+                // A closed pipe or terminal reads as EOF
+                if ((e as NodeJS.ErrnoException).code == "EOF") {
+                    return [0, io.EOF]
+                }
+                return [0, e as Error]
+            }
+        }
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        let n = 0
+        while (n < p.length) {
+            try {
+                n += fs.writeSync(this.fd, p, n, p.length - n)
+            } catch (e) {
+                if ((e as NodeJS.ErrnoException).code == "EAGAIN") {
+                    sleep(retryDelay)
+                    continue
+                }
+                return [n, e as Error]
+            }
+        }
+        return [n, null]
+    }
+
+    // Close closes the file descriptor. Calling Close more than once returns an error
+    Close(): Error | null {
+        if (this.closed) {
+            return new Error(`close ${this.name}: file already closed`)
+        }
+        this.closed = true
+
+        try {
+            fs.closeSync(this.fd)
+        } catch (e) {
+            return e as Error
+        }
+        return null
+    }
+}
@@ -0,0 +1,34 @@
+// Conversions between strings and byte slices, for tests
+//
+// Go converts between string and []byte without an encoding. JS strings are
+// UTF-16, so there are two helpers for each direction: bytes and str use
+// UTF-8, like Go source text, and latin1Bytes and latin1Str map each char
+// to one byte, for binary data written as "\xNN" escapes.
+
+/**
+ * bytes returns the UTF-8 encoding of s, like []byte(s) in Go
+ */
+export function bytes(s: string): Uint8Array {
+    return new TextEncoder().encode(s)
+}
+
+/**
+ * str decodes b as UTF-8, like string(b) in Go
+ */
+export function str(b: Uint8Array): string {
+    return new TextDecoder().decode(b)
+}
+
+/**
+ * latin1Bytes converts a byte string, with one char per byte, to a Uint8Array
+ */
+export function latin1Bytes(s: string): Uint8Array {
+    return Uint8Array.from(s, (c) => c.charCodeAt(0))
+}
+
+/**
+ * latin1Str converts a Uint8Array to a byte string, with one char per byte
+ */
+export function latin1Str(b: Uint8Array): string {
+    return Array.from(b, (c) => String.fromCharCode(c)).join("")
+}
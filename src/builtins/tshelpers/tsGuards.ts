@@ -1,3 +0,0 @@
-export function is<T>(object: any, prop: string): object is T {
-    return prop in object;
-}
\ No newline at end of file
@@ -0,0 +1,208 @@
+#!/usr/bin/env node
+// gostd is a command line tool for the codecs in this repository.
+//
+// Usage:
+//
+//     gostd lzw decompress [flags] [file ...]
+//     gostd lzw compress [flags] [file ...]
+//
+// The files are read in order, or stdin if there are none or a file is "-". Each
+// file is a separate stream, and the results are concatenated to stdout, or to the
+// file given with --output.
+//
+// Flags:
+//
+//     --order lsb|msb     bit ordering of the codes (default lsb, as in GIF; TIFF and PDF use msb)
+//     --lit-width n       number of bits in a literal code, from 2 to 8 (default 8)
+//     --early-change      decompress streams that widen codes one code early, as in TIFF and PDF
+//     -o, --output file   write to file instead of stdout
+//
+// Decoding errors report the position of the offending code, so that suspect
+// streams can be inspected.
+import { parseArgs } from "node:util"
+import { NodeFile } from "../builtins/tshelpers/nodefile"
+import { LZWError, LZWErrorKind, LZWReader, LZWWriter, Order } from "../compress/lzw"
+import { Copy, Reader, Writer } from "../io"
+
+const usage = `usage: gostd lzw decompress [flags] [file ...]
+       gostd lzw compress [flags] [file ...]
+
+flags:
+  --order lsb|msb     bit ordering of the codes (default lsb)
+  --lit-width n       number of bits in a literal code, from 2 to 8 (default 8)
+  --early-change      use early change code widths when decompressing (TIFF, PDF)
+  -o, --output file   write to file instead of stdout
+`
+
+// usageError is returned for bad command lines, which also print the usage
+class usageError extends Error {}
+
+interface lzwOptions {
+    order: Order
+    litWidth: number
+    earlyChange: boolean
+}
+
+// parseLZWArgs parses the flags of the lzw subcommands
+function parseLZWArgs(args: string[]): [{ opts: lzwOptions, output: string | undefined, files: string[] } | null, Error | null] {
+    let parsed
+    try {
+        parsed = parseArgs({
+            args,
+            allowPositionals: true,
+            options: {
+                "order": { type: "string", default: "lsb" },
+                "lit-width": { type: "string", default: "8" },
+                "early-change": { type: "boolean", default: false },
+                "output": { type: "string", short: "o" },
+            },
+        })
+    } catch (e) {
+        return [null, new usageError((e as Error).message)]
+    }
+
+    let v = parsed.values
+    let order: Order
+    switch (v.order!.toLowerCase()) {
+        case "lsb":
+            order = Order.LSB
+            break
+        case "msb":
+            order = Order.MSB
+            break
+        default:
+            return [null, new usageError(`invalid --order ${JSON.stringify(v.order)}, must be lsb or msb`)]
+    }
+
+    let litWidth = Number(v["lit-width"])
+    if (!Number.isInteger(litWidth) || litWidth < 2 || litWidth > 8) {
+        return [null, new usageError(`invalid --lit-width ${JSON.stringify(v["lit-width"])}, must be from 2 to 8`)]
+    }
+
+    return [{
+        opts: { order, litWidth, earlyChange: v["early-change"]! },
+        output: v.output,
+        files: parsed.positionals,
+    }, null]
+}
+
+// describe adds the position of LZW decoding errors to their message
+function describe(err: Error): string {
+    if (!(err instanceof LZWError)) {
+        return err.message
+    }
+
+    let msg = `${err.message} at byte ${err.offset} (bit ${err.bit}, code #${err.code}, width ${err.width})`
+    if (err.kind == LZWErrorKind.InvalidCode) {
+        msg += `, next free code ${err.hi + 1}`
+    }
+    return msg
+}
+
+// copyFile copies the decompressed or compressed contents of the named file to dst
+function copyFile(dst: Writer, name: string, decompress: boolean, opts: lzwOptions): Error | null {
+    let src: NodeFile
+    if (name == "-") {
+        src = new NodeFile(0, "stdin")
+    } else {
+        let [f, err] = NodeFile.Open(name)
+        if (err) {
+            return err
+        }
+        src = f!
+    }
+
+    let err: Error | null
+    if (decompress) {
+        let r: Reader = new LZWReader(src, opts.order, opts.litWidth, opts.earlyChange);
+        [, err] = Copy(dst, r)
+    } else {
+        let w = new LZWWriter(dst, opts.order, opts.litWidth);
+        [, err] = Copy(w, src)
+        let cerr = w.Close()
+        if (err == null) {
+            err = cerr
+        }
+    }
+
+    if (src.fd != 0) {
+        src.Close()
+    }
+    if (err) {
+        return new Error(`${src.name}: ${describe(err)}`)
+    }
+    return null
+}
+
+function lzw(args: string[]): Error | null {
+    let [cmd, ...rest] = args
+    if (cmd != "decompress" && cmd != "compress") {
+        return new usageError(cmd ? `unknown lzw command ${JSON.stringify(cmd)}` : "missing lzw command")
+    }
+
+    let [parsed, err] = parseLZWArgs(rest)
+    if (err) {
+        return err
+    }
+    let { opts, output, files } = parsed!
+    if (opts.earlyChange && cmd == "compress") {
+        return new usageError("--early-change is only supported by lzw decompress")
+    }
+    if (files.length == 0) {
+        files = ["-"]
+    }
+
+    let dst: NodeFile
+    if (output == undefined || output == "-") {
+        dst = new NodeFile(1, "stdout")
+    } else {
+        let [f, err] = NodeFile.Create(output)
+        if (err) {
+            return err
+        }
+        dst = f!
+    }
+
+    for (let name of files) {
+        err = copyFile(dst, name, cmd == "decompress", opts)
+        if (err) {
+            break
+        }
+    }
+
+    if (dst.fd != 1) {
+        let cerr = dst.Close()
+        if (err == null) {
+            err = cerr
+        }
+    }
+    return err
+}
+
+function main(args: string[]): number {
+    let err: Error | null
+    switch (args[0]) {
+        case "lzw":
+            err = lzw(args.slice(1))
+            break
+        case "-h":
+        case "--help":
+        case "help":
+            process.stdout.write(usage)
+            return 0
+        default:
+            err = new usageError(args[0] ? `unknown command ${JSON.stringify(args[0])}` : "missing command")
+    }
+
+    if (err) {
+        process.stderr.write(`gostd: ${err.message}\n`)
+        if (err instanceof usageError) {
+            process.stderr.write(usage)
+            return 2
+        }
+        return 1
+    }
+    return 0
+}
+
+process.exitCode = main(process.argv.slice(2))
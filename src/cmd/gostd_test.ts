@@ -0,0 +1,149 @@
+// Tests for the gostd command
+//
+// Not present in the Go code: these run the command in a child process, the way
+// it is used from a shell
+import * as assert from "node:assert/strict"
+import { spawnSync } from "node:child_process"
+import * as fs from "node:fs"
+import * as os from "node:os"
+import * as nodepath from "node:path"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { LZWReader, LZWWriter, Order } from "../compress/lzw"
+import { ReadAll } from "../io"
+
+// gostd is the path of the command next to this test, so that it runs from the
+// compiled .js files as well as the sources
+const gostd = nodepath.join(nodepath.dirname(process.argv[1]), "gostd" + nodepath.extname(process.argv[1]))
+
+interface result {
+    code: number | null
+    stdout: Uint8Array
+    stderr: string
+}
+
+// run runs gostd with args, giving it input on stdin
+function run(args: string[], input: Uint8Array = new Uint8Array(0)): result {
+    let r = spawnSync(process.execPath, [...process.execArgv, gostd, ...args], { input })
+    return { code: r.status, stdout: new Uint8Array(r.stdout), stderr: r.stderr.toString() }
+}
+
+// withTempDir runs f with a new temporary directory, and removes it afterwards
+function withTempDir(f: (dir: string) => void) {
+    let dir = fs.mkdtempSync(nodepath.join(os.tmpdir(), "gostd-test-"))
+    try {
+        f(dir)
+    } finally {
+        fs.rmSync(dir, { recursive: true })
+    }
+}
+
+function compress(b: Uint8Array, order: Order, litWidth: number): Uint8Array {
+    let buf = new GoBuffer(new Uint8Array(0))
+    let w = new LZWWriter(buf, order, litWidth)
+    w.Write(b)
+    w.Close()
+    return buf.underlyingArray
+}
+
+function decompress(b: Uint8Array, order: Order, litWidth: number): Uint8Array {
+    let [data, err] = ReadAll(new LZWReader(new GoBuffer(b), order, litWidth))
+    assert.equal(err, null)
+    return data
+}
+
+const golden = new Uint8Array(fs.readFileSync("src/compress/testdata/e.txt"))
+
+test("TestCompressStdin", () => {
+    for (let [flag, order] of [["lsb", Order.LSB], ["msb", Order.MSB]] as const) {
+        let r = run(["lzw", "compress", "--order", flag, "--lit-width", "7"], golden)
+        assert.equal(r.code, 0, r.stderr)
+        assert.deepEqual(decompress(r.stdout, order, 7), golden, `--order ${flag}`)
+
+        r = run(["lzw", "decompress", "--order", flag, "--lit-width", "7"], compress(golden, order, 7))
+        assert.equal(r.code, 0, r.stderr)
+        assert.deepEqual(r.stdout, golden, `--order ${flag}`)
+    }
+})
+
+test("TestFiles", () => {
+    withTempDir((dir) => {
+        let a = nodepath.join(dir, "a.lzw")
+        let b = nodepath.join(dir, "b.lzw")
+        let out = nodepath.join(dir, "out")
+        fs.writeFileSync(a, compress(golden, Order.LSB, 8))
+        fs.writeFileSync(b, compress(new TextEncoder().encode("hello"), Order.LSB, 8))
+
+        // Each file is a separate stream, and stdin is read for "-"
+        let r = run(["lzw", "decompress", a, "-", b, "-o", out], compress(new TextEncoder().encode(", "), Order.LSB, 8))
+        assert.equal(r.code, 0, r.stderr)
+        assert.equal(r.stdout.length, 0)
+        assert.equal(fs.readFileSync(out, "latin1"), fs.readFileSync("src/compress/testdata/e.txt", "latin1") + ", hello")
+
+        r = run(["lzw", "compress", "--output", out, "src/compress/testdata/e.txt"])
+        assert.equal(r.code, 0, r.stderr)
+        assert.deepEqual(decompress(new Uint8Array(fs.readFileSync(out)), Order.LSB, 8), golden)
+    })
+})
+
+test("TestEarlyChange", () => {
+    // An early change stream of 3838 zero literals and code 4095, see
+    // TestNoLongerSavingPriorExpansionsEarlyChange
+    let input = new Uint8Array(5405 + 4)
+    input.set([0x03, 0xff, 0xc4, 0x04], 5405)
+
+    let r = run(["lzw", "decompress", "--order", "msb", "--early-change"], input)
+    assert.equal(r.code, 0, r.stderr)
+    assert.deepEqual(r.stdout, new Uint8Array(3840))
+
+    // Read with the usual code widths, the stream goes out of step
+    r = run(["lzw", "decompress", "--order", "msb"], input)
+    assert.equal(r.code, 1)
+    assert.match(r.stderr, /^gostd: stdin: unexpected EOF at byte \d+ \(bit \d+, code #\d+, width 12\)\n$/)
+})
+
+test("TestDecompressErrors", () => {
+    withTempDir((dir) => {
+        let truncated = nodepath.join(dir, "truncated.lzw")
+        let c = compress(golden, Order.LSB, 8)
+        fs.writeFileSync(truncated, c.subarray(0, c.length / 2))
+
+        let r = run(["lzw", "decompress", truncated])
+        assert.equal(r.code, 1)
+        assert.ok(r.stderr.startsWith(`gostd: ${truncated}: unexpected EOF at byte `), r.stderr)
+        // The data decoded before the error is still written
+        assert.ok(r.stdout.length > 0)
+        assert.deepEqual(r.stdout, golden.subarray(0, r.stdout.length))
+
+        r = run(["lzw", "decompress", nodepath.join(dir, "nonesuch")])
+        assert.equal(r.code, 1)
+        assert.match(r.stderr, /^gostd: ENOENT: no such file or directory/)
+    })
+})
+
+test("TestUsage", () => {
+    let usageErrors: [string[], string][] = [
+        [[], "missing command"],
+        [["gzip"], `unknown command "gzip"`],
+        [["lzw"], "missing lzw command"],
+        [["lzw", "inflate"], `unknown lzw command "inflate"`],
+        [["lzw", "decompress", "--order", "big"], `invalid --order "big", must be lsb or msb`],
+        [["lzw", "decompress", "--lit-width", "9"], `invalid --lit-width "9", must be from 2 to 8`],
+        [["lzw", "decompress", "--lit-width", "x"], `invalid --lit-width "x", must be from 2 to 8`],
+        [["lzw", "compress", "--early-change"], "--early-change is only supported by lzw decompress"],
+    ]
+    for (let [args, want] of usageErrors) {
+        let r = run(args)
+        assert.equal(r.code, 2, `gostd ${args.join(" ")}`)
+        assert.ok(r.stderr.startsWith(`gostd: ${want}\nusage: gostd lzw`), `gostd ${args.join(" ")}: ${r.stderr}`)
+        assert.equal(r.stdout.length, 0)
+    }
+
+    let r = run(["lzw", "decompress", "--level", "9"])
+    assert.equal(r.code, 2)
+    assert.ok(r.stderr.includes("--level"), r.stderr)
+
+    r = run(["help"])
+    assert.equal(r.code, 0)
+    assert.ok(new TextDecoder().decode(r.stdout).startsWith("usage: gostd lzw decompress"))
+})
@@ -1,12 +1,17 @@
-// Decodes a LZW-encoded buffer (LSB first)
-// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/reader.go;l=254
-import { uint8Copy } from "../builtins/tshelpers/arrays"
-import { ByteReader, Errors as IOErrors, Reader } from "../io"
+// Decodes and encodes LZW-encoded buffers (LSB or MSB first)
+// Reader taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/reader.go;l=254
+// Writer taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/writer.go
+import { BufferedByteReader } from "../builtins/tshelpers/bufferedbytereader"
+import * as errors from "../errors"
+import { ByteReader, Closer, DefaultBufferSize, Errors as IOErrors, isByteReader, Reader, Writer } from "../io"
 
 const maxWidth = 12
 const decoderInvalidCode = 0xffff
 const flushBuffer = 1 << maxWidth
 
+// errClosed is the error returned by any operation on a closed reader or writer
+const errClosed = errors.New("lzw: reader/writer is closed")
+
 /** 
  * Order specifies the bit ordering in an LZW data stream.
 */
@@ -15,8 +20,67 @@ export enum Order {
     MSB
 }
 
-export class LZWReader implements Reader {
-    r: ByteReader
+/**
+ * LZWErrorKind is the kind of failure reported by an LZWError
+ */
+export enum LZWErrorKind {
+    InvalidCode = "invalid code",
+    UnexpectedEOF = "unexpected EOF",
+    Closed = "closed"
+}
+
+/**
+ * LZWError is the error LZWReader returns when decoding fails or the reader is closed.
+ * It records where in the source the failure happened and the decoder state at that point.
+ *
+ * It wraps the error Go's LZW reader returns in its place, and has the same message, so
+ * errors.Is(err, io.ErrUnexpectedEOF) reports true for an unexpected EOF.
+ */
+export class LZWError extends Error {
+    // kind is the kind of failure
+    kind: LZWErrorKind
+
+    // offset is the number of bytes read from the source when the error occurred
+    offset: number
+
+    // bit is the position, in bits from the start of the source, of the offending code.
+    // For an unexpected EOF this is where the incomplete code starts
+    bit: number
+
+    // code is the index of the offending code in the code stream, counting from 0
+    code: number
+
+    // width and hi are the code width and the hi code at the time of the error
+    width: number
+    hi: number
+
+    // err is the wrapped error
+    err: Error
+
+    constructor(kind: LZWErrorKind, err: Error, offset: number, bit: number, code: number, width: number, hi: number) {
+        super(err.message)
+        this.name = "LZWError"
+        this.kind = kind
+        this.err = err
+        this.offset = offset
+        this.bit = bit
+        this.code = code
+        this.width = width
+        this.hi = hi
+    }
+
+    Unwrap(): Error {
+        return this.err
+    }
+}
+
+export class LZWReader implements Reader, Closer {
+    r!: ByteReader // set in init
+
+    // Not present in the Go code
+    //
+    // Buffer used when the source does not implement io.ByteReader, kept across Reset
+    private br: BufferedByteReader | undefined
     bits: number = 0 // uint32
     nBits: number = 0// uint
     width: number = 0 // uint
@@ -46,8 +110,8 @@ export class LZWReader implements Reader {
 	//   prefix[c] is the code for all but the last byte.
 	//   This code can either be a literal code or another code in [lo, c).
 	// The c == hi case is a special case.
-    suffix: number[] = [] // [1 << maxWidth]uint8
-    prefix: number[] = [] // [1 << maxWidth]uint16
+    suffix: Uint8Array // [1 << maxWidth]uint8
+    prefix: Uint16Array // [1 << maxWidth]uint16
 
 	// output is the temporary output buffer.
 	// Literal codes are accumulated from the start of the buffer.
@@ -56,20 +120,91 @@ export class LZWReader implements Reader {
 	// to the start of the buffer.
 	// It is flushed when it contains >= 1<<maxWidth bytes,
 	// so that there is always room to decode an entire code.
-    output: number[] = [] // [1 << maxWidth]byte
+    output: Uint8Array // [2 * 1 << maxWidth]byte
     o = 0 // write index into output
-    toRead: number[] = [] // bytes to return from Read
+    toRead: Uint8Array // bytes to return from Read, a view into output
 
     // Not present in the go code
     order: Order = Order.LSB // uint
 
-    constructor(src: ByteReader, order: Order, litWidth: number) {        
+    // Not present in the go code
+    //
+    // offset is the number of bytes read from r and codes is the number of
+    // codes read from the stream, for the position reported in an LZWError
+    offset: number = 0
+    codes: number = 0
+
+    // Not present in the go code
+    //
+    // earlyChange makes the code width increase one code earlier, when hi+1
+    // reaches overflow instead of hi. This is the variant used by TIFF and by
+    // PDF streams with /EarlyChange 1, see golang.org/x/image/tiff/lzw
+    earlyChange: boolean = false
+
+    /**
+     * Creates a new LZWReader reading compressed data from src.
+     *
+     * If src does not also implement io.ByteReader, the LZWReader may read more data than necessary from src.
+     *
+     * @param src The reader to read compressed bytes from, ReadByte is used when present
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8. It must equal the litWidth used during compression.
+     * @param earlyChange Whether the stream uses "early change" code widths, as in TIFF (Order.MSB, litWidth 8) and PDF streams with /EarlyChange 1
+     */
+    constructor(src: Reader | ByteReader, order: Order, litWidth: number, earlyChange: boolean = false) {        
+        // Set up the initial slices [js specific]
+        this.suffix = new Uint8Array(1 << maxWidth)
+        this.prefix = new Uint16Array(1 << maxWidth)
+        this.output = new Uint8Array(2 * (1 << maxWidth))
+        this.toRead = this.output.subarray(0, 0)
+
+        this.init(src, order, litWidth, earlyChange)
+    }
+
+    // Reset clears the Reader's state and allows it to be reused again
+    // as a new Reader.
+    //
+    // The suffix, prefix and output buffers are kept, so a reset reader
+    // does not reallocate them.
+    Reset(src: Reader | ByteReader, order: Order, litWidth: number, earlyChange: boolean = false) {
+        this.init(src, order, litWidth, earlyChange)
+    }
+
+    // Not present in the Go code
+    //
+    // resume clears the stream error if it is want, an error returned by a
+    // source that will have more input later, and reports whether it did.
+    // The source may have failed in the middle of a code: the bits already
+    // read are kept, so decoding continues where it stopped. This is used by
+    // LZWDecoder and AsyncLZWReader, other errors stay sticky.
+    resume(want: Error): boolean {
+        if (this.err !== want) {
+            return false
+        }
+        this.err = undefined
+        return true
+    }
+
+    // init sets up the reader state for a new stream, without clearing the
+    // suffix, prefix and output buffers.
+    private init(src: Reader | ByteReader, order: Order, litWidth: number, earlyChange: boolean) {
         if(litWidth < 2 || 8 < litWidth) {
             throw new Error("lzw: litWidth out of range")
         }
 
         this.order = order
-        this.r = src
+        this.earlyChange = earlyChange
+        if (isByteReader(src)) {
+            this.r = src
+        } else if (this.br) {
+            this.br.reset(src)
+            this.r = this.br
+        } else {
+            this.br = new BufferedByteReader(src)
+            this.r = this.br
+        }
+        this.bits = 0
+        this.nBits = 0
         this.litWidth = litWidth
         this.width = 1 + litWidth
         this.clear = 1 << litWidth
@@ -77,11 +212,17 @@ export class LZWReader implements Reader {
         this.hi = this.clear + 1
         this.overflow = 1 << this.width
         this.last = decoderInvalidCode   
-        
-        // Set up the initial slices [js specific]
-        this.suffix = Array.from({ length: 1 << maxWidth }, () => 0)
-        this.prefix = Array.from({ length: 1 << maxWidth }, () => 0)
-        this.output = Array.from({ length: 2 * (1 << maxWidth) }, () => 0)
+        this.err = undefined
+        this.offset = 0
+        this.codes = 0
+        this.o = 0
+        this.toRead = this.output.subarray(0, 0)
+
+        // Early change streams can use the last code without ever saving its
+        // expansion, so clear what a previous stream left there to decode it
+        // as a new reader does
+        this.suffix[(1 << maxWidth) - 1] = 0
+        this.prefix[(1 << maxWidth) - 1] = 0
     }
 
     // Not present in the Go code
@@ -90,14 +231,39 @@ export class LZWReader implements Reader {
     //
     // This is slightly more idiomatic than defining a function in the class
     private read(): [number /* uint16 */, Error | null] {
+        let res: [number, Error | null]
         switch (this.order) {
             case Order.LSB:
-                return this.readLSB()
+                res = this.readLSB()
+                break
             case Order.MSB:
-                return this.readMSB()
+                res = this.readMSB()
+                break
             default:
                 return [0, new Error("lzw: invalid order")]
         }
+
+        if (res[1] == null) {
+            this.codes++
+        }
+        return res
+    }
+
+    // Not present in the Go code
+    //
+    // newError returns an LZWError for the code that was just read, or for the
+    // code that is being read if kind is LZWErrorKind.UnexpectedEOF
+    private newError(kind: LZWErrorKind, err: Error): LZWError {
+        // Bits consumed from the source so far
+        let bit = this.offset * 8 - this.nBits
+        let code = this.codes
+
+        if (kind == LZWErrorKind.InvalidCode) {
+            bit -= this.width
+            code--
+        }
+
+        return new LZWError(kind, err, this.offset, bit, code, this.width, this.hi)
     }
 
     // readLSB returns the next code for "Least Significant Bits first" data.
@@ -108,6 +274,7 @@ export class LZWReader implements Reader {
             if (err) {
                 return [0, err]
             }
+            this.offset++
 
             this.bits |= (x << this.nBits)
             this.nBits += 8
@@ -127,13 +294,14 @@ export class LZWReader implements Reader {
             if (err) {
                 return [0, err]
             }
+            this.offset++
 
             this.bits |= (x) << (24 - this.nBits)
 
             this.nBits += 8
         }
 
-        let code = this.bits >> (32 - this.width)
+        let code = this.bits >>> (32 - this.width) // unsigned shift as bits is a uint32
         this.bits <<= this.width
         this.nBits -= this.width
         return [code, null]
@@ -143,8 +311,10 @@ export class LZWReader implements Reader {
     Read(b: Uint8Array): [number, Error | null] {
         while(true) /* for */ {
             if(this.toRead.length > 0) {
-                let n = uint8Copy(b, this.toRead) // n := copy(b, r.toRead)
-                this.toRead = this.toRead.slice(n) // r.toRead = r.toRead[n:]
+                // n := copy(b, r.toRead)
+                let n = Math.min(b.length, this.toRead.length)
+                b.set(this.toRead.subarray(0, n))
+                this.toRead = this.toRead.subarray(n) // r.toRead = r.toRead[n:]
                 return [n, null]
             }
 
@@ -166,8 +336,8 @@ export class LZWReader implements Reader {
 
             if(err) {
                 // Check for EOF
-                if(err.message == IOErrors.EOF) {
-                    err = new Error(IOErrors.UnexpectedEOF)
+                if(err == IOErrors.EOF) {
+                    err = this.newError(LZWErrorKind.UnexpectedEOF, IOErrors.UnexpectedEOF)
                 }
                 this.err = err
                 break
@@ -191,7 +361,7 @@ export class LZWReader implements Reader {
                 this.last = decoderInvalidCode
                 continue    
             } else if(code == this.eof) {
-                this.err = new Error(IOErrors.EOF)
+                this.err = IOErrors.EOF
                 break loop
             } else if(code <= this.hi) {
                 let [c, i] = [code, this.output.length - 1] // c, i := code, len(r.output)-1
@@ -214,13 +384,9 @@ export class LZWReader implements Reader {
                     c = this.prefix[c]
                 }
                 this.output[i] = c
-                // copy(r.output[r.o:], r.output[i:]) in JS
-                let srcArray = this.output.slice(i)
-                for(let i = 0; i < srcArray.length; i++) {
-                    this.output[this.o + i] = srcArray[i]
-                }
-                this.o += srcArray.length
-                // End: copy
+                // copy(r.output[r.o:], r.output[i:])
+                this.output.set(this.output.subarray(i), this.o)
+                this.o += this.output.length - i
 
                 if (this.last != decoderInvalidCode) {
                     // Save what the hi code expands to.
@@ -228,7 +394,7 @@ export class LZWReader implements Reader {
                     this.prefix[this.hi] = this.last
                 }    
             } else {
-                this.err = new Error("lzw: invalid code")
+                this.err = this.newError(LZWErrorKind.InvalidCode, errors.New("lzw: invalid code"))
                 break loop
             }
         
@@ -236,17 +402,20 @@ export class LZWReader implements Reader {
             this.last = code
             this.hi++
 
-            if(this.hi >= this.overflow) {
-                if(this.hi > this.overflow) {
-                    throw new Error("Unreachable") // panic("unreachable")
-                }
-
+            // In early change mode, the width is bumped one code before hi overflows
+            let next = this.earlyChange ? this.hi + 1 : this.hi
+            if(next >= this.overflow) {
                 if(this.width == maxWidth) {
                     this.last = decoderInvalidCode
                     // Undo the d.hi++ a few lines above, so that (1) we maintain
                     // the invariant that d.hi < d.overflow, and (2) d.hi does not
                     // eventually overflow a uint16.
-                    this.hi--
+                    //
+                    // In early change mode hi is held at the last code instead of
+                    // one below it, as golang.org/x/image/tiff/lzw accepts that code.
+                    this.hi = this.overflow - 1
+                } else if(next > this.overflow) {
+                    throw new Error("Unreachable") // panic("unreachable")
                 } else {
                     this.width++
                     this.overflow = (1 << this.width)
@@ -259,14 +428,390 @@ export class LZWReader implements Reader {
         }
 
         // Flush pending output
-        this.toRead = this.output.slice(0, this.o)
+        this.toRead = this.output.subarray(0, this.o)
         this.o = 0        
     }
 
     // Close closes the Reader and returns an error for any future read operation.
     // It does not close the underlying io.Reader.
-    close(): [Error | null] {
-        this.err = new Error("lzw: reader/writer is closed")
-        return [null]
+    Close(): Error | null {
+        this.err = this.newError(LZWErrorKind.Closed, errClosed)
+        return null
     }
-}
\ No newline at end of file
+}
+
+// A code is a 12 bit value, stored as a uint32 when encoding to avoid
+// type conversions when shifting bits.
+const maxCode = (1 << 12) - 1
+const invalidCode = 0xffffffff // 1<<32 - 1
+
+// There are 1<<12 possible codes, which is an upper bound on the number of
+// valid hash table entries at any given point in time. tableSize is 4x that.
+const tableSize = 4 * (1 << 12)
+const tableMask = tableSize - 1
+
+// A hash table entry is a uint32. Zero is an invalid entry since the
+// lower 12 bits of a valid entry must be a non-literal code.
+const invalidEntry = 0
+
+// errOutOfCodes is an internal error that means that the writer has run out
+// of unused codes and a clear code needs to be sent next.
+const errOutOfCodes = errors.New("lzw: out of codes")
+
+/**
+ * LZWWriter is an LZW compressor. It writes the compressed form of the data
+ * to an underlying writer.
+ * 
+ * It is the caller's responsibility to call Close on the LZWWriter when
+ * finished writing.
+ */
+export class LZWWriter implements Writer, Closer {
+    // w is the writer that compressed bytes are written to.
+    w!: Writer // set in init
+
+    // litWidth is the width in bits of literal codes.
+    litWidth: number = 0 // uint
+
+    // order, bits, nBits and width are the state for
+	// converting a code stream into a byte stream.
+    order: Order = Order.LSB
+    nBits: number = 0 // uint
+    width: number = 0 // uint
+    bits: number = 0 // uint32
+
+    // hi is the code implied by the next code emission.
+	// overflow is the code at which hi overflows the code width.
+    hi: number = 0 // uint32
+    overflow: number = 0 // uint32
+
+    // savedCode is the accumulated code at the end of the most recent Write
+	// call. It is equal to invalidCode if there was no such call.
+    savedCode: number = invalidCode // uint32
+
+    // err is the first error encountered during writing. Closing the writer
+	// will make any future Write calls return errClosed
+    err: Error | null = null
+
+    // table is the hash table from 20-bit keys to 12-bit values. Each table
+	// entry contains key<<12|val and collisions resolve by linear probing.
+	// The keys consist of a 12-bit code prefix and an 8-bit byte suffix.
+	// The values are a 12-bit code.
+    table: Uint32Array = new Uint32Array(tableSize) // [tableSize]uint32
+
+    // Not present in the go code
+    //
+    // Pending output bytes, flushed to w when full and on Close. This replaces
+    // the bufio.Writer that Go wraps the underlying writer in
+    private buf: Uint8Array = new Uint8Array(DefaultBufferSize)
+    private n: number = 0 // write index into buf
+
+    /**
+     * Creates a new LZWWriter. Writes to the returned LZWWriter are compressed and written to dst.
+     * 
+     * @param dst The writer to write compressed bytes to
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8. Input bytes must be less than 1<<litWidth.
+     */
+    constructor(dst: Writer, order: Order, litWidth: number) {
+        this.init(dst, order, litWidth)
+    }
+
+    // Reset clears the LZWWriter's state and allows it to be reused again
+    // as a new LZWWriter.
+    Reset(dst: Writer, order: Order, litWidth: number) {
+        this.init(dst, order, litWidth)
+    }
+
+    // init sets up the writer state for a new stream, reusing the hash table
+    // and write buffer.
+    private init(dst: Writer, order: Order, litWidth: number) {
+        if(order != Order.LSB && order != Order.MSB) {
+            throw new Error("lzw: unknown order")
+        }
+
+        if(litWidth < 2 || 8 < litWidth) {
+            throw new Error("lzw: litWidth out of range")
+        }
+
+        this.w = dst
+        this.order = order
+        this.nBits = 0
+        this.bits = 0
+        this.width = 1 + litWidth
+        this.litWidth = litWidth
+        this.hi = (1 << litWidth) + 1
+        this.overflow = 1 << (litWidth + 1)
+        this.savedCode = invalidCode
+        this.err = null
+        this.table.fill(invalidEntry)
+        this.n = 0
+    }
+
+    // Not present in the Go code
+    //
+    // writeLSB or writeMSB
+    private write(c: number /* uint32 */): Error | null {
+        switch (this.order) {
+            case Order.LSB:
+                return this.writeLSB(c)
+            case Order.MSB:
+                return this.writeMSB(c)
+            default:
+                return new Error("lzw: unknown order")
+        }
+    }
+
+    // writeLSB writes the code c for "Least Significant Bits first" data.
+    private writeLSB(c: number /* uint32 */): Error | null {
+        this.bits |= c << this.nBits
+        this.nBits += this.width
+        while (this.nBits >= 8) {
+            let err = this.writeByte(this.bits & 0xff)
+
+            if (err) {
+                return err
+            }
+
+            this.bits >>>= 8
+            this.nBits -= 8
+        }
+
+        return null
+    }
+
+    // writeMSB writes the code c for "Most Significant Bits first" data.
+    private writeMSB(c: number /* uint32 */): Error | null {
+        this.bits |= c << (32 - this.width - this.nBits)
+        this.nBits += this.width
+        while (this.nBits >= 8) {
+            let err = this.writeByte(this.bits >>> 24)
+
+            if (err) {
+                return err
+            }
+
+            this.bits <<= 8
+            this.nBits -= 8
+        }
+
+        return null
+    }
+
+    // Not present in the Go code
+    //
+    // writeByte buffers a single byte, flushing the buffer to w when it is full.
+    // Equivalent to w.w.WriteByte() on a bufio.Writer
+    private writeByte(b: number): Error | null {
+        if (this.n == this.buf.length) {
+            let err = this.flush()
+
+            if (err) {
+                return err
+            }
+        }
+
+        this.buf[this.n] = b
+        this.n++
+        return null
+    }
+
+    // Not present in the Go code
+    //
+    // flush writes any buffered bytes to w.
+    // Equivalent to w.w.Flush() on a bufio.Writer
+    private flush(): Error | null {
+        if (this.n == 0) {
+            return null
+        }
+
+        let [n, err] = this.w.Write(this.buf.subarray(0, this.n))
+
+        if (n < this.n && err == null) {
+            err = IOErrors.ShortWrite
+        }
+
+        if (err) {
+            return err
+        }
+
+        this.n = 0
+        return null
+    }
+
+    // incHi increments e.hi and checks for both overflow and running out of
+    // unused codes. In the latter case, incHi sends a clear code, resets the
+    // writer state and returns errOutOfCodes.
+    private incHi(): Error | null {
+        this.hi++
+
+        if (this.hi == this.overflow) {
+            this.width++
+            this.overflow <<= 1
+        }
+
+        if (this.hi == maxCode) {
+            let clear = 1 << this.litWidth
+            let err = this.write(clear)
+
+            if (err) {
+                return err
+            }
+
+            this.width = this.litWidth + 1
+            this.hi = clear + 1
+            this.overflow = clear << 1
+            this.table.fill(invalidEntry)
+            return errOutOfCodes
+        }
+
+        return null
+    }
+
+    // Write writes a compressed representation of p to w's underlying writer.
+    Write(p: Uint8Array): [number, Error | null] {
+        if (this.err) {
+            return [0, this.err]
+        }
+
+        if (p.length == 0) {
+            return [0, null]
+        }
+
+        let maxLit = (1 << this.litWidth) - 1
+        if (maxLit != 0xff) {
+            for (let i = 0; i < p.length; i++) {
+                if (p[i] > maxLit) {
+                    this.err = new Error("lzw: input byte too large for the litWidth")
+                    return [0, this.err]
+                }
+            }
+        }
+
+        let n = p.length
+        let code = this.savedCode
+
+        if (code == invalidCode) {
+            // This is the first write; send a clear code.
+            // https://www.w3.org/Graphics/GIF/spec-gif89a.txt Appendix F
+            // "Variable-Length-Code LZW Compression" says that "Encoders should
+            // output a Clear code as the first code of each image data stream".
+            //
+            // LZW compression isn't only used by GIF, but it's cheap to follow
+            // that directive unconditionally.
+            let clear = 1 << this.litWidth
+            let err = this.write(clear)
+
+            if (err) {
+                return [0, err]
+            }
+
+            // After the starting clear code, the next code sent (for non-empty
+            // input) is always a literal code.
+            code = p[0]
+            p = p.subarray(1) // code, p = uint32(p[0]), p[1:]
+        }
+
+        loop: for (let i = 0; i < p.length; i++) {
+            let literal = p[i]
+            let key = (code << 8) | literal
+
+            // If there is a hash table hit for this key then we continue the loop
+            // and do not emit a code yet.
+            let hash = ((key >>> 12) ^ key) & tableMask
+            for (let h = hash, t = this.table[hash]; t != invalidEntry;) {
+                if (key == (t >>> 12)) {
+                    code = t & maxCode
+                    continue loop
+                }
+                h = (h + 1) & tableMask
+                t = this.table[h]
+            }
+
+            // Otherwise, write the current code, and literal becomes the start of
+            // the next emitted code.
+            this.err = this.write(code)
+            if (this.err) {
+                return [0, this.err]
+            }
+            code = literal
+
+            // Increment e.hi, the next implied code. If we run out of codes, reset
+            // the writer state (including clearing the hash table) and continue.
+            let err1 = this.incHi()
+            if (err1) {
+                if (err1 == errOutOfCodes) {
+                    continue
+                }
+                this.err = err1
+                return [0, this.err]
+            }
+
+            // Otherwise, insert key -> e.hi into the map that e.table represents.
+            while (true) /* for */ {
+                if (this.table[hash] == invalidEntry) {
+                    this.table[hash] = (key << 12) | this.hi
+                    break
+                }
+                hash = (hash + 1) & tableMask
+            }
+        }
+
+        this.savedCode = code
+        return [n, null]
+    }
+
+    // Close closes the LZWWriter, flushing any pending output. It does not close
+    // w's underlying writer.
+    Close(): Error | null {
+        if (this.err) {
+            if (this.err == errClosed) {
+                return null
+            }
+            return this.err
+        }
+
+        // Make any future calls to Write return errClosed.
+        this.err = errClosed
+
+        // Write the savedCode if valid.
+        if (this.savedCode != invalidCode) {
+            let err = this.write(this.savedCode)
+            if (err) {
+                return err
+            }
+
+            err = this.incHi()
+            if (err && err != errOutOfCodes) {
+                return err
+            }
+        } else {
+            // Write the starting clear code, as w.Write did not.
+            let clear = 1 << this.litWidth
+            let err = this.write(clear)
+            if (err) {
+                return err
+            }
+        }
+
+        // Write the eof code.
+        let eof = (1 << this.litWidth) + 1
+        let err = this.write(eof)
+        if (err) {
+            return err
+        }
+
+        // Write the final bits.
+        if (this.nBits > 0) {
+            if (this.order == Order.MSB) {
+                this.bits >>>= 24
+            }
+
+            err = this.writeByte(this.bits & 0xff)
+            if (err) {
+                return err
+            }
+        }
+
+        return this.flush()
+    }
+}
@@ -0,0 +1,357 @@
+// Tests for compress/lzw
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/reader_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/writer_test.go
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { latin1Bytes, latin1Str } from "../builtins/tshelpers/strings"
+import * as errors from "../errors"
+import { Copy, Discard, Errors as IOErrors, ReadAll, ReadFull, Reader } from "../io"
+import * as iotest from "../testing/iotest"
+import { LZWReader, LZWWriter, Order } from "./lzw"
+import { filenames, lzwTests } from "./testdata/lzw"
+
+// matches reports whether err is the error want describes, see lzwTest.err
+function matches(err: Error, want: Error | string | null): boolean {
+    if (typeof want == "string") {
+        return err.message == want
+    }
+    return errors.Is(err, want)
+}
+
+// parseDesc splits a test description into its order and literal width
+function parseDesc(desc: string): [Order, number] {
+    let d = desc.split(";")
+    let order: Order
+    switch (d[1]) {
+        case "LSB":
+            order = Order.LSB
+            break
+        case "MSB":
+            order = Order.MSB
+            break
+        default:
+            throw new Error(`${desc}: bad order ${JSON.stringify(d[1])}`)
+    }
+    return [order, Number(d[2])]
+}
+
+test("TestReader", () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let rc = new LZWReader(new GoBuffer(latin1Bytes(tt.compressed)), order, litWidth)
+        let b = new GoBuffer(new Uint8Array(0))
+        let [n, err] = Copy(b, rc)
+        let s = latin1Str(b.underlyingArray)
+        rc.Close()
+        if (err) {
+            assert.ok(matches(err, tt.err), `${tt.desc}: io.Copy: got ${err}, want ${tt.err}`)
+            if (errors.Is(err, IOErrors.UnexpectedEOF)) {
+                // Even if the input is truncated, we should still return the
+                // partial decoded result.
+                assert.ok(n != 0 && tt.raw.startsWith(s), `got ${n} bytes (${JSON.stringify(s)}), want a non-empty prefix of ${JSON.stringify(tt.raw)}`)
+            } else {
+                assert.ok(tt.raw.startsWith(s), `${tt.desc}: got ${JSON.stringify(s)}, want a prefix of ${JSON.stringify(tt.raw)}`)
+            }
+            continue
+        }
+        assert.equal(tt.err, null, `${tt.desc}: io.Copy: got no error`)
+        assert.equal(s, tt.raw, `${tt.desc}: got ${n}-byte output`)
+    }
+})
+
+test("TestReaderReset", () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let rc = new LZWReader(new GoBuffer(latin1Bytes(tt.compressed)), order, litWidth)
+        let b = new GoBuffer(new Uint8Array(0))
+        let [, err] = Copy(b, rc)
+        let b1 = b.underlyingArray
+        if (err) {
+            assert.ok(matches(err, tt.err), `${tt.desc}: io.Copy: got ${err}, want ${tt.err}`)
+            rc.Close()
+            continue
+        }
+
+        b = new GoBuffer(new Uint8Array(0))
+        rc.Reset(new GoBuffer(latin1Bytes(tt.compressed)), order, litWidth);
+        [, err] = Copy(b, rc)
+        assert.equal(err, null, `${tt.desc}: io.Copy`)
+        assert.deepEqual(b.underlyingArray, b1, "bytes read were not the same")
+        rc.Close()
+    }
+})
+
+// Not present in the Go code
+test("TestReaderContract", () => {
+    // The compressed input arrives in one byte, half or data-with-EOF reads, which
+    // is where LZWReader has to resume decoding mid-code
+    let wrappers: [string, (r: Reader) => Reader][] = [
+        ["plain", (r) => r],
+        ["OneByteReader", iotest.OneByteReader],
+        ["HalfReader", iotest.HalfReader],
+        ["DataErrReader", iotest.DataErrReader],
+    ]
+    let text = latin1Bytes("TOBEORNOTTOBEORTOBEORNOT".repeat(200))
+    for (let order of [Order.LSB, Order.MSB]) {
+        let b = new GoBuffer(new Uint8Array(0))
+        let w = new LZWWriter(b, order, 8)
+        w.Write(text)
+        w.Close()
+        let compressed = b.underlyingArray
+
+        for (let [name, wrap] of wrappers) {
+            let err = iotest.TestReader(new LZWReader(wrap(new GoBuffer(compressed.slice())), order, 8), text)
+            assert.equal(err, null, `${Order[order]} ${name}: ${err?.message}`)
+        }
+    }
+
+    for (let tt of lzwTests) {
+        if (tt.err != null) {
+            continue
+        }
+        let [order, litWidth] = parseDesc(tt.desc)
+        for (let [name, wrap] of wrappers) {
+            let err = iotest.TestReader(new LZWReader(wrap(new GoBuffer(latin1Bytes(tt.compressed))), order, litWidth), latin1Bytes(tt.raw))
+            assert.equal(err, null, `${tt.desc} ${name}: ${err?.message}`)
+        }
+    }
+})
+
+// devZero is a Reader that returns an endless stream of zero bytes
+const devZero: Reader = {
+    Read(p: Uint8Array): [number, Error | null] {
+        p.fill(0)
+        return [p.length, null]
+    }
+}
+
+test("TestHiCodeDoesNotOverflow", () => {
+    let d = new LZWReader(devZero, Order.LSB, 8)
+    let buf = new Uint8Array(1024)
+    let oldHi = 0
+    for (let i = 0; i < 100; i++) {
+        let [, err] = ReadFull(d, buf)
+        assert.equal(err, null, `i=${i}`)
+        // The hi code should never decrease.
+        assert.ok(d.hi >= oldHi, `i=${i}: hi=${d.hi} decreased from previous value ${oldHi}`)
+        oldHi = d.hi
+    }
+})
+
+// TestNoLongerSavingPriorExpansions tests the decoder state when codes other
+// than clear codes continue to be seen after decoder.hi and decoder.width
+// reach their maximum values (4095 and 12), i.e. after we no longer save prior
+// expansions. In particular, it tests seeing the highest possible code, 4095.
+test("TestNoLongerSavingPriorExpansions", () => {
+    // Iterations is used to calculate how many input bits are needed to get
+    // the decoder.hi and decoder.width values up to their maximum.
+    let iterations = [
+        // The final term is 257, not 256, as NewReader initializes d.hi to
+        // d.clear+1 and the clear code is 256.
+        { width: 9, n: 512 - 257 },
+        { width: 10, n: 1024 - 512 },
+        { width: 11, n: 2048 - 1024 },
+        { width: 12, n: 4096 - 2048 },
+    ]
+    let nCodes = 0, nBits = 0
+    for (let e of iterations) {
+        nCodes += e.n
+        nBits += e.n * e.width
+    }
+    assert.equal(nCodes, 3839, "nCodes")
+    assert.equal(nBits, 43255, "nBits")
+
+    // Construct our input of 43255 zero bits (which gets d.hi and d.width up
+    // to 4095 and 12), followed by 0xfff (4095) as 12 bits, followed by 0x101
+    // (EOF) as 12 bits. See the Go test for the bit layout.
+    let input = new Uint8Array(5406 + 4)
+    input.set([0x80, 0xff, 0x0f, 0x08], 5406)
+
+    let r = new LZWReader(new GoBuffer(input), Order.LSB, 8)
+    let [nDecoded, err] = Copy(Discard, r)
+    assert.equal(err, null, "Copy")
+    // nDecoded should be 3841: 3839 literal codes and then 2 decoded bytes
+    // from 1 non-literal code. The EOF code contributes 0 decoded bytes.
+    assert.equal(nDecoded, nCodes + 2, "nDecoded")
+})
+
+// Not present in the Go code
+test("TestNoLongerSavingPriorExpansionsEarlyChange", () => {
+    // Like TestNoLongerSavingPriorExpansions, but with the code width bumped one
+    // code early, as in TIFF. golang.org/x/image/tiff/lzw accepts the highest
+    // code, 4095, once the table is full, and so must LZWReader.
+    let iterations = [
+        { width: 9, n: 511 - 257 },
+        { width: 10, n: 1023 - 511 },
+        { width: 11, n: 2047 - 1023 },
+        { width: 12, n: 4095 - 2047 },
+    ]
+    let nCodes = 0, nBits = 0
+    for (let e of iterations) {
+        nCodes += e.n
+        nBits += e.n * e.width
+    }
+    assert.equal(nCodes, 3838, "nCodes")
+    assert.equal(nBits, 43246, "nBits")
+
+    // 43246 zero bits are 5405 zero bytes and 6 zero bits. In MSB order, those 6
+    // bits are followed by 0xfff (4095) as 12 bits, 0x101 (EOF) as 12 bits and
+    // 2 bits of padding:
+    //
+    //	000000 111111111111 000100000001 00
+    //	= 0x03 0xff 0xc4 0x04
+    let input = new Uint8Array(5405 + 4)
+    input.set([0x03, 0xff, 0xc4, 0x04], 5405)
+
+    let r = new LZWReader(new GoBuffer(input), Order.MSB, 8, true)
+    let [nDecoded, err] = Copy(Discard, r)
+    assert.equal(err, null, "Copy")
+    // 3838 literal codes and then 2 decoded bytes from code 4095, as with
+    // golang.org/x/image/tiff/lzw.
+    assert.equal(nDecoded, nCodes + 2, "nDecoded")
+
+    // Without early change, 3839 literal 'A' codes fill the table up to and
+    // including entry 4095, which an early change stream never writes. A reset
+    // reader must still decode code 4095 like a new one does.
+    let prev = new GoBuffer(new Uint8Array(0))
+    let acc = 0, nAcc = 0
+    let emit = (code: number, width: number) => {
+        acc = (acc << width) | code
+        nAcc += width
+        while (nAcc >= 8) {
+            nAcc -= 8
+            prev.WriteByte((acc >>> nAcc) & 0xff)
+        }
+        acc &= (1 << nAcc) - 1
+    }
+    for (let [width, n] of [[9, 512 - 257], [10, 512], [11, 1024], [12, 2048]]) {
+        for (let i = 0; i < n; i++) {
+            emit(0x41, width)
+        }
+    }
+    emit(0x101, 12)
+    emit(0, 8 - nAcc)
+
+    r = new LZWReader(prev, Order.MSB, 8)
+    ;[nDecoded, err] = Copy(Discard, r)
+    assert.equal(err, null, "Copy")
+    assert.equal(nDecoded, 3839, "nDecoded")
+
+    r.Reset(new GoBuffer(input), Order.MSB, 8, true)
+    let got = new GoBuffer(new Uint8Array(0))
+    ;[, err] = Copy(got, r)
+    assert.equal(err, null, "Copy after Reset")
+    assert.deepEqual(got.underlyingArray, new Uint8Array(nCodes + 2))
+})
+
+// testFile tests that compressing and then decompressing the given file with
+// the given options yields equivalent bytes to the original file.
+//
+// Not present in the Go code: Go streams the data through an io.Pipe, here the
+// compressed data is buffered.
+function testFile(fn: string, order: Order, litWidth: number) {
+    let golden = new Uint8Array(fs.readFileSync(fn))
+    let desc = `${fn} (order=${order} litWidth=${litWidth})`
+
+    let compressed = new GoBuffer(new Uint8Array(0))
+    let lzww = new LZWWriter(compressed, order, litWidth)
+    for (let i = 0; i < golden.length; i += 4096) {
+        let [, err] = lzww.Write(golden.subarray(i, i + 4096))
+        assert.equal(err, null, desc)
+    }
+    assert.equal(lzww.Close(), null, desc)
+
+    let lzwr = new LZWReader(new GoBuffer(compressed.underlyingArray), order, litWidth)
+    let [b1, err] = ReadAll(lzwr)
+    assert.equal(err, null, desc)
+    assert.equal(b1.length, golden.length, `${desc}: length mismatch`)
+    for (let i = 0; i < golden.length; i++) {
+        if (b1[i] != golden[i]) {
+            assert.fail(`${desc}: mismatch at ${i}, 0x${b1[i].toString(16)} != 0x${golden[i].toString(16)}`)
+        }
+    }
+    lzwr.Close()
+}
+
+test("TestWriter", () => {
+    for (let filename of filenames) {
+        for (let order of [Order.LSB, Order.MSB]) {
+            // The test data "2.71828 etcetera" is ASCII text requiring at least 6 bits.
+            for (let litWidth = 6; litWidth <= 8; litWidth++) {
+                if (filename.endsWith("gettysburg.txt") && litWidth == 6) {
+                    continue
+                }
+                testFile(filename, order, litWidth)
+            }
+        }
+    }
+})
+
+test("TestWriterReset", () => {
+    for (let order of [Order.LSB, Order.MSB]) {
+        for (let litWidth = 6; litWidth <= 8; litWidth++) {
+            let data: Uint8Array
+            if (litWidth == 6) {
+                data = new Uint8Array([1, 2, 3])
+            } else {
+                data = latin1Bytes("lorem ipsum dolor sit amet")
+            }
+            let buf = new GoBuffer(new Uint8Array(0))
+            let w = new LZWWriter(buf, order, litWidth)
+            assert.deepEqual(w.Write(data), [data.length, null], "write")
+            assert.equal(w.Close(), null, "close")
+            let b1 = buf.underlyingArray
+
+            buf = new GoBuffer(new Uint8Array(0))
+            w.Reset(buf, order, litWidth)
+            assert.deepEqual(w.Write(data), [data.length, null], "write")
+            assert.equal(w.Close(), null, "close")
+            let b2 = buf.underlyingArray
+
+            assert.deepEqual(b2, b1, `order=${order} litWidth=${litWidth}: bytes written were not same`)
+        }
+    }
+})
+
+test("TestWriterReturnValues", () => {
+    let w = new LZWWriter(Discard, Order.LSB, 8)
+    let [n, err] = w.Write(latin1Bytes("asdf"))
+    assert.equal(n, 4)
+    assert.equal(err, null)
+})
+
+test("TestSmallLitWidth", () => {
+    let w = new LZWWriter(Discard, Order.LSB, 2)
+    let [, err] = w.Write(new Uint8Array([0x03]))
+    assert.equal(err, null, "write a byte < 1<<2");
+    [, err] = w.Write(new Uint8Array([0x04]))
+    assert.notEqual(err, null, "write a byte >= 1<<2: got null error, want non-null")
+})
+
+test("TestStartsWithClearCode", () => {
+    // A literal width of 7 bits means that the code width starts at 8 bits,
+    // which makes it easier to visually inspect the output (provided that the
+    // output is short so codes don't get longer). Each byte is a code:
+    //  - ASCII bytes are literal codes,
+    //  - 0x80 is the clear code,
+    //  - 0x81 is the end code.
+    //  - 0x82 and above are copy codes (unused in this test case).
+    for (let empty of [false, true]) {
+        let buf = new GoBuffer(new Uint8Array(0))
+        let w = new LZWWriter(buf, Order.LSB, 7)
+        if (!empty) {
+            w.Write(latin1Bytes("Hi"))
+        }
+        w.Close()
+        let got = latin1Str(buf.underlyingArray)
+
+        let want = "\x80\x81"
+        if (!empty) {
+            want = "\x80Hi\x81"
+        }
+
+        assert.equal(got, want, `empty=${empty}`)
+    }
+})
@@ -0,0 +1,42 @@
+// Node.js stream adapter for incremental LZW decoding
+import { Transform, TransformCallback, TransformOptions } from "node:stream"
+import { Writer } from "../io"
+import { Order } from "./lzw"
+import { LZWDecoder } from "./lzwstream"
+
+/**
+ * LZWDecompressTransform is a Node.js stream.Transform that decompresses LZW data,
+ * for use with stream.pipeline the same way as zlib's streams.
+ */
+export class LZWDecompressTransform extends Transform {
+    private decoder: LZWDecoder
+
+    /**
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8.
+     * @param earlyChange Whether the stream uses "early change" code widths, see LZWReader
+     * @param opts Options passed on to stream.Transform
+     */
+    constructor(order: Order, litWidth: number, earlyChange: boolean = false, opts?: TransformOptions) {
+        super(opts)
+
+        let dst: Writer = {
+            Write: (p: Uint8Array): [number, Error | null] => {
+                // The decoder reuses its buffer, so push a copy
+                this.push(Buffer.from(p))
+                return [p.length, null]
+            }
+        }
+
+        this.decoder = new LZWDecoder(dst, order, litWidth, earlyChange)
+    }
+
+    _transform(chunk: Buffer, _encoding: BufferEncoding, callback: TransformCallback) {
+        let [, err] = this.decoder.Write(chunk)
+        callback(err)
+    }
+
+    _flush(callback: TransformCallback) {
+        callback(this.decoder.Close())
+    }
+}
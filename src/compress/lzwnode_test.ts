@@ -0,0 +1,95 @@
+// Tests for LZWDecompressTransform
+//
+// Not present in the Go code: these run the compress/lzw reader test vectors
+// through stream.pipeline
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { Readable, Writable } from "node:stream"
+import { pipeline } from "node:stream/promises"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { latin1Bytes, latin1Str } from "../builtins/tshelpers/strings"
+import * as errors from "../errors"
+import { LZWError, LZWErrorKind, LZWWriter, Order } from "./lzw"
+import { LZWDecompressTransform } from "./lzwnode"
+import { filenames, lzwTests } from "./testdata/lzw"
+
+// parseDesc splits a test description into its order and literal width
+function parseDesc(desc: string): [Order, number] {
+    let d = desc.split(";")
+    return [d[1] == "MSB" ? Order.MSB : Order.LSB, Number(d[2])]
+}
+
+// transform pipes b through an LZWDecompressTransform in chunks of size bytes,
+// and returns the output and the error the pipeline failed with
+async function transform(b: Uint8Array, size: number, order: Order, litWidth: number): Promise<[Uint8Array, Error | null]> {
+    let chunks: Buffer[] = []
+    for (let i = 0; i < b.length; i += size) {
+        chunks.push(Buffer.from(b.subarray(i, i + size)))
+    }
+
+    let out: Buffer[] = []
+    let sink = new Writable({
+        write(chunk: Buffer, _encoding, callback) {
+            out.push(chunk)
+            callback()
+        }
+    })
+
+    let err: Error | null = null
+    try {
+        await pipeline(Readable.from(chunks), new LZWDecompressTransform(order, litWidth), sink)
+    } catch (e) {
+        err = e as Error
+    }
+    return [new Uint8Array(Buffer.concat(out)), err]
+}
+
+test("TestTransform", async () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let compressed = latin1Bytes(tt.compressed)
+        // Chunks of 1 byte split every code wider than 8 bits
+        for (let size of [1, 3, compressed.length || 1]) {
+            let [got, err] = await transform(compressed, size, order, litWidth)
+            let s = latin1Str(got)
+            if (err) {
+                assert.ok(err instanceof LZWError, `${tt.desc} size=${size}: got ${err}, want an LZWError`)
+                if (typeof tt.err == "string") {
+                    assert.equal(err.message, tt.err, `${tt.desc} size=${size}`)
+                } else {
+                    assert.ok(errors.Is(err, tt.err), `${tt.desc} size=${size}: got ${err}, want ${tt.err}`)
+                }
+                assert.ok(tt.raw.startsWith(s), `${tt.desc} size=${size}: got ${JSON.stringify(s)}, want a prefix of ${JSON.stringify(tt.raw)}`)
+                continue
+            }
+            assert.equal(tt.err, null, `${tt.desc} size=${size}: got no error`)
+            assert.equal(s, tt.raw, `${tt.desc} size=${size}`)
+        }
+    }
+})
+
+test("TestTransformLarge", async () => {
+    // The output is more than one decode buffer, and the chunks end mid-code
+    let golden = new Uint8Array(fs.readFileSync(filenames[1]))
+    for (let order of [Order.LSB, Order.MSB]) {
+        let compressed = new GoBuffer(new Uint8Array(0))
+        let w = new LZWWriter(compressed, order, 8)
+        w.Write(golden)
+        w.Close()
+
+        let [got, err] = await transform(compressed.underlyingArray, 999, order, 8)
+        assert.equal(err, null, `order=${order}`)
+        assert.deepEqual(got, golden, `order=${order}`)
+    }
+})
+
+test("TestTransformTruncated", async () => {
+    // The unexpected EOF is reported when the input ends, from _flush
+    let [got, err] = await transform(latin1Bytes("\x61\xc4\x00"), 1, Order.LSB, 8)
+    assert.ok(err instanceof LZWError, `got ${err}, want an LZWError`)
+    assert.equal(err.kind, LZWErrorKind.UnexpectedEOF)
+    assert.equal(err.offset, 3)
+    assert.equal(err.bit, 18)
+    assert.equal(latin1Str(got), "ab")
+})
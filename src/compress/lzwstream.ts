@@ -0,0 +1,269 @@
+// Incremental (push-based) LZW decoding
+//
+// LZWReader pulls its input, so it needs the whole compressed stream to be available up front.
+// LZWDecoder instead accepts compressed chunks as they arrive and suspends when it runs out of
+// input, which makes it usable from streams. AsyncLZWReader pulls its input from an AsyncReader.
+// Decoding is done by an LZWReader, so the output is identical.
+//
+// This file only depends on web platform APIs, the Node.js stream.Transform adapter is in lzwnode.ts
+import * as errors from "../errors"
+import { AsyncReader, ByteReader, Closer, DefaultBufferSize, Errors as IOErrors, MaxConsecutiveEmptyReads, Reader, Writer } from "../io"
+import { LZWReader, Order } from "./lzw"
+
+// errNeedInput is returned by chunkSource when it has run out of buffered input.
+// It is compared by identity, and never returned to callers
+const errNeedInput = errors.New("lzw: need more input")
+
+// Size of the buffer decoded output is staged through before being written to dst
+const decodeBufferSize = 32 * 1024
+
+// chunkSource is a ByteReader over the compressed chunk currently being written
+class chunkSource implements ByteReader {
+    chunk: Uint8Array = new Uint8Array(0)
+    i: number = 0 // read index into chunk
+    ended: boolean = false // set once no more chunks will be written
+    err: Error | null = null // returned instead of EOF once ended, if set
+
+    ReadByte(): [number, Error | null] {
+        if (this.i >= this.chunk.length) {
+            if (this.ended) {
+                return [0, this.err ?? IOErrors.EOF]
+            }
+            return [0, errNeedInput]
+        }
+
+        let b = this.chunk[this.i]
+        this.i++
+        return [b, null]
+    }
+}
+
+/**
+ * LZWDecoder is an incremental LZW decompressor. Compressed data written to it is decoded
+ * as far as possible and the decompressed bytes are written to an underlying writer.
+ *
+ * Close must be called once all compressed data has been written. It returns an
+ * unexpected EOF error if the stream ended before its EOF code.
+ */
+export class LZWDecoder implements Writer, Closer {
+    // dst is the writer that decompressed bytes are written to.
+    dst: Writer
+
+    // r does the actual decoding, reading from src.
+    private r: LZWReader
+    private src: chunkSource = new chunkSource()
+    private buf: Uint8Array = new Uint8Array(decodeBufferSize)
+
+    // done is set once the EOF code has been decoded. Any data after it is ignored.
+    done: boolean = false
+
+    // err is the first error encountered while decoding, if any
+    err: Error | null = null
+
+    /**
+     * Creates a new LZWDecoder writing decompressed bytes to dst.
+     *
+     * @param dst The writer to write decompressed bytes to
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8.
+     * @param earlyChange Whether the stream uses "early change" code widths, see LZWReader
+     */
+    constructor(dst: Writer, order: Order, litWidth: number, earlyChange: boolean = false) {
+        this.dst = dst
+        this.r = new LZWReader(this.src, order, litWidth, earlyChange)
+    }
+
+    // Write decodes p, writing all the output that can be produced from the data seen so far
+    // to dst. It returns an error if the stream is corrupt or dst fails.
+    Write(p: Uint8Array): [number, Error | null] {
+        if (this.err) {
+            return [0, this.err]
+        }
+
+        if (this.done) {
+            return [p.length, null]
+        }
+
+        this.src.chunk = p
+        this.src.i = 0
+
+        let err = this.drain()
+
+        // Don't retain p once the write is done
+        this.src.chunk = new Uint8Array(0)
+        this.src.i = 0
+
+        if (err) {
+            return [0, err]
+        }
+
+        return [p.length, null]
+    }
+
+    // drain reads from r until it needs more input, writing the output to dst.
+    private drain(): Error | null {
+        while (true) /* for */ {
+            let [n, err] = this.r.Read(this.buf)
+
+            if (n > 0) {
+                let [nw, ew] = this.dst.Write(this.buf.subarray(0, n))
+
+                if (ew == null && nw != n) {
+                    ew = IOErrors.ShortWrite
+                }
+
+                if (ew) {
+                    this.err = ew
+                    return ew
+                }
+            }
+
+            if (err) {
+                // Suspend until more input is written. The reader may have
+                // stopped in the middle of a code, and keeps the bits it read
+                if (this.r.resume(errNeedInput)) {
+                    return null
+                }
+
+                if (err == IOErrors.EOF) {
+                    this.done = true
+                    return null
+                }
+
+                this.err = err
+                return err
+            }
+        }
+    }
+
+    // Close signals the end of the compressed data. It does not close dst.
+    Close(): Error | null {
+        if (this.err) {
+            return this.err
+        }
+
+        if (!this.done) {
+            // Let the reader see the end of its source, so that it reports the
+            // unexpected EOF with its position
+            this.src.ended = true
+            let err = this.drain()
+            if (err) {
+                return err
+            }
+        }
+
+        return null
+    }
+}
+
+/**
+ * AsyncLZWReader is the asynchronous counterpart of LZWReader: it reads compressed data from
+ * an AsyncReader, such as a PipeReader, and Read returns a promise of the decompressed bytes.
+ *
+ * Compressed data is read in chunks, so the AsyncLZWReader may read more data than necessary
+ * from src.
+ */
+export class AsyncLZWReader implements AsyncReader, Closer {
+    // src is the reader compressed bytes are read from
+    private src: Reader | AsyncReader
+    private buf: Uint8Array = new Uint8Array(DefaultBufferSize)
+
+    // r does the actual decoding, reading from chunk.
+    private r: LZWReader
+    private chunk: chunkSource = new chunkSource()
+
+    /**
+     * Creates a new AsyncLZWReader reading compressed data from src.
+     *
+     * @param src The reader to read compressed bytes from, a sync Reader also works
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8.
+     * @param earlyChange Whether the stream uses "early change" code widths, see LZWReader
+     */
+    constructor(src: Reader | AsyncReader, order: Order, litWidth: number, earlyChange: boolean = false) {
+        this.src = src
+        this.r = new LZWReader(this.chunk, order, litWidth, earlyChange)
+    }
+
+    // Read reads uncompressed bytes into b, reading more compressed data from src when the
+    // decoder runs out of it. Like LZWReader.Read, it returns as soon as some bytes are
+    // available.
+    async Read(b: Uint8Array): Promise<[number, Error | null]> {
+        while (true) /* for */ {
+            let [n, err] = this.r.Read(b)
+            if (err !== errNeedInput) {
+                return [n, err]
+            }
+
+            // The reader may have stopped in the middle of a code, and keeps the
+            // bits it read until more input has been read
+            this.r.resume(errNeedInput)
+
+            let nr = 0, er: Error | null = null
+            for (let i = 0; i < MaxConsecutiveEmptyReads && nr == 0 && er == null; i++) {
+                [nr, er] = await this.src.Read(this.buf)
+            }
+            if (nr == 0 && er == null) {
+                er = IOErrors.NoProgress
+            }
+
+            this.chunk.chunk = this.buf.subarray(0, nr)
+            this.chunk.i = 0
+            if (er) {
+                // Let the reader see the end of its source once the chunk is used up,
+                // so that it reports an unexpected EOF with its position
+                this.chunk.ended = true
+                if (er != IOErrors.EOF) {
+                    this.chunk.err = er
+                }
+            }
+        }
+    }
+
+    // Close closes the AsyncLZWReader and returns an error for any future read operation.
+    // It does not close the underlying reader.
+    Close(): Error | null {
+        return this.r.Close()
+    }
+}
+
+/**
+ * LZWDecompressionStream is a WHATWG TransformStream that decompresses LZW data, for use
+ * with ReadableStream.pipeThrough in the same way as the web's DecompressionStream.
+ */
+export class LZWDecompressionStream extends TransformStream<Uint8Array, Uint8Array> {
+    /**
+     * @param order The bit ordering of the data stream
+     * @param litWidth The number of bits to use for literal codes, must be in the range [2,8] and is typically 8.
+     * @param earlyChange Whether the stream uses "early change" code widths, see LZWReader
+     */
+    constructor(order: Order, litWidth: number, earlyChange: boolean = false) {
+        let decoder: LZWDecoder
+
+        super({
+            start(controller) {
+                let dst: Writer = {
+                    Write(p: Uint8Array): [number, Error | null] {
+                        // The decoder reuses its buffer, so enqueue a copy
+                        controller.enqueue(p.slice())
+                        return [p.length, null]
+                    }
+                }
+
+                decoder = new LZWDecoder(dst, order, litWidth, earlyChange)
+            },
+            transform(chunk) {
+                let [, err] = decoder.Write(chunk)
+                if (err) {
+                    throw err
+                }
+            },
+            flush() {
+                let err = decoder.Close()
+                if (err) {
+                    throw err
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,289 @@
+// Tests for LZWDecoder, AsyncLZWReader and LZWDecompressionStream
+//
+// Not present in the Go code: these run the compress/lzw reader test vectors
+// through the incremental decoders instead of a Reader
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { latin1Bytes, latin1Str } from "../builtins/tshelpers/strings"
+import * as errors from "../errors"
+import { AsyncCopy, AsyncReader, AsyncReadAll, Errors as IOErrors, Pipe, Writer } from "../io"
+import { LZWError, LZWErrorKind, LZWWriter, Order } from "./lzw"
+import { AsyncLZWReader, LZWDecoder, LZWDecompressionStream } from "./lzwstream"
+import { filenames, lzwTests } from "./testdata/lzw"
+
+// matches reports whether err is the error want describes, see lzwTest.err
+function matches(err: Error, want: Error | string | null): boolean {
+    if (typeof want == "string") {
+        return err.message == want
+    }
+    return errors.Is(err, want)
+}
+
+// parseDesc splits a test description into its order and literal width
+function parseDesc(desc: string): [Order, number] {
+    let d = desc.split(";")
+    return [d[1] == "MSB" ? Order.MSB : Order.LSB, Number(d[2])]
+}
+
+// oneByteReader is an AsyncReader that returns b one byte at a time, on a
+// later turn of the event loop
+function oneByteReader(b: Uint8Array): AsyncReader {
+    return {
+        Read: async (p) => {
+            await new Promise((resolve) => setImmediate(resolve))
+            if (b.length == 0) {
+                return [0, IOErrors.EOF]
+            }
+            if (p.length == 0) {
+                return [0, null]
+            }
+            p[0] = b[0]
+            b = b.subarray(1)
+            return [1, null]
+        },
+    }
+}
+
+test("TestAsyncReader", async () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let rc = new AsyncLZWReader(oneByteReader(latin1Bytes(tt.compressed)), order, litWidth)
+        let b = new GoBuffer(new Uint8Array(0))
+        let [n, err] = await AsyncCopy(b, rc)
+        let s = latin1Str(b.underlyingArray)
+        rc.Close()
+        if (err) {
+            assert.ok(matches(err, tt.err), `${tt.desc}: AsyncCopy: got ${err}, want ${tt.err}`)
+            if (errors.Is(err, IOErrors.UnexpectedEOF)) {
+                assert.ok(n != 0 && tt.raw.startsWith(s), `${tt.desc}: got ${JSON.stringify(s)}, want a non-empty prefix of ${JSON.stringify(tt.raw)}`)
+            } else {
+                assert.ok(tt.raw.startsWith(s), `${tt.desc}: got ${JSON.stringify(s)}, want a prefix of ${JSON.stringify(tt.raw)}`)
+            }
+            continue
+        }
+        assert.equal(tt.err, null, `${tt.desc}: AsyncCopy: got no error`)
+        assert.equal(s, tt.raw, `${tt.desc}: got ${n}-byte output`)
+    }
+})
+
+test("TestAsyncReaderErrorPosition", async () => {
+    // The position of an unexpected EOF is the same as LZWReader reports
+    let r = new AsyncLZWReader(oneByteReader(latin1Bytes("\x61\xc4\x00")), Order.LSB, 8)
+    let [, err] = await AsyncReadAll(r)
+    assert.ok(err instanceof LZWError, `got ${err}, want an LZWError`)
+    assert.equal(err.kind, LZWErrorKind.UnexpectedEOF)
+    assert.equal(err.offset, 3)
+    assert.equal(err.bit, 18)
+})
+
+test("TestAsyncReaderSourceError", async () => {
+    let srcErr = new Error("source failed")
+    let sent = false
+    let src: AsyncReader = {
+        Read: async (p) => {
+            if (sent) {
+                return [0, srcErr]
+            }
+            sent = true
+            p.set(latin1Bytes("\x61\xc4")) // "a" and the start of the next code
+            return [2, null]
+        },
+    }
+    let [got, err] = await AsyncReadAll(new AsyncLZWReader(src, Order.LSB, 8))
+    assert.equal(err, srcErr)
+    assert.equal(latin1Str(got), "a")
+})
+
+test("TestAsyncReaderNoProgress", async () => {
+    let src: AsyncReader = { Read: async () => [0, null] }
+    let [, err] = await AsyncReadAll(new AsyncLZWReader(src, Order.LSB, 8))
+    assert.equal(err, IOErrors.NoProgress)
+})
+
+test("TestAsyncReaderPipe", async () => {
+    let golden = new Uint8Array(fs.readFileSync(filenames[0]))
+    let compressed = new GoBuffer(new Uint8Array(0))
+    let w = new LZWWriter(compressed, Order.MSB, 8)
+    w.Write(golden)
+    w.Close()
+
+    let [pr, pw] = Pipe()
+    let writes = (async () => {
+        let c = compressed.underlyingArray
+        for (let i = 0; i < c.length; i += 100) {
+            await pw.Write(c.subarray(i, i + 100))
+        }
+        pw.Close()
+    })()
+    let [got, err] = await AsyncReadAll(new AsyncLZWReader(pr, Order.MSB, 8))
+    await writes
+    assert.equal(err, null)
+    assert.deepEqual(got, golden)
+})
+
+// chunks splits b into chunks of size bytes. A size of 1 splits codes wider
+// than 8 bits across chunks
+function chunks(b: Uint8Array, size: number): Uint8Array[] {
+    let c: Uint8Array[] = []
+    for (let i = 0; i < b.length; i += size) {
+        c.push(b.subarray(i, i + size))
+    }
+    return c
+}
+
+// decode writes chunks to an LZWDecoder and closes it, returning the output and
+// the first error
+function decode(chunks: Uint8Array[], order: Order, litWidth: number): [Uint8Array, Error | null] {
+    let b = new GoBuffer(new Uint8Array(0))
+    let d = new LZWDecoder(b, order, litWidth)
+    for (let c of chunks) {
+        let [n, err] = d.Write(c)
+        if (err) {
+            return [b.underlyingArray, err]
+        }
+        assert.equal(n, c.length)
+    }
+    return [b.underlyingArray, d.Close()]
+}
+
+test("TestDecoder", () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let compressed = latin1Bytes(tt.compressed)
+        for (let size of [1, 2, 3, compressed.length || 1]) {
+            let [got, err] = decode(chunks(compressed, size), order, litWidth)
+            let s = latin1Str(got)
+            if (err) {
+                assert.ok(matches(err, tt.err), `${tt.desc} size=${size}: got ${err}, want ${tt.err}`)
+                assert.ok(tt.raw.startsWith(s), `${tt.desc} size=${size}: got ${JSON.stringify(s)}, want a prefix of ${JSON.stringify(tt.raw)}`)
+                continue
+            }
+            assert.equal(tt.err, null, `${tt.desc} size=${size}: got no error`)
+            assert.equal(s, tt.raw, `${tt.desc} size=${size}`)
+        }
+    }
+})
+
+test("TestDecoderSplit", () => {
+    // Split the compressed data in two at every position, so that every code
+    // is cut somewhere
+    let golden = new Uint8Array(fs.readFileSync(filenames[0]))
+    for (let order of [Order.LSB, Order.MSB]) {
+        let compressed = new GoBuffer(new Uint8Array(0))
+        let w = new LZWWriter(compressed, order, 8)
+        w.Write(golden)
+        w.Close()
+        let c = compressed.underlyingArray
+        for (let i = 0; i <= c.length; i++) {
+            let [got, err] = decode([c.subarray(0, i), c.subarray(i)], order, 8)
+            assert.equal(err, null, `order=${order} i=${i}`)
+            assert.deepEqual(got, golden, `order=${order} i=${i}`)
+        }
+    }
+})
+
+test("TestDecoderErrors", () => {
+    // An invalid code is reported by the Write that completes it, with its
+    // position in the whole stream. Here 'a' is followed by code 259, split
+    // across the writes, when the next free code is 258
+    let d = new LZWDecoder(new GoBuffer(new Uint8Array(0)), Order.LSB, 8)
+    let [n, err] = d.Write(latin1Bytes("\x61\x06"))
+    assert.equal(err, null)
+    assert.equal(n, 2);
+    [n, err] = d.Write(latin1Bytes("\x02"))
+    assert.equal(n, 0)
+    assert.ok(err instanceof LZWError, `got ${err}, want an LZWError`)
+    assert.equal(err.kind, LZWErrorKind.InvalidCode)
+    assert.equal(err.bit, 9)
+    // The error is sticky
+    assert.equal(d.Write(latin1Bytes("\x00"))[1], err)
+    assert.equal(d.Close(), err)
+
+    // A truncated stream is only an error once it is closed
+    d = new LZWDecoder(new GoBuffer(new Uint8Array(0)), Order.LSB, 8);
+    [n, err] = d.Write(latin1Bytes("\x61\xc4\x00"))
+    assert.equal(err, null)
+    assert.equal(n, 3)
+    err = d.Close()
+    assert.ok(err instanceof LZWError, `got ${err}, want an LZWError`)
+    assert.equal(err.kind, LZWErrorKind.UnexpectedEOF)
+    assert.equal(err.offset, 3)
+    assert.equal(err.bit, 18)
+    assert.ok(errors.Is(err, IOErrors.UnexpectedEOF))
+
+    // Data after the EOF code is ignored
+    let b = new GoBuffer(new Uint8Array(0))
+    d = new LZWDecoder(b, Order.LSB, 8)
+    d.Write(latin1Bytes("\x61\x02\x02"));
+    [n, err] = d.Write(latin1Bytes("garbage"))
+    assert.equal(err, null)
+    assert.equal(n, 7)
+    assert.equal(d.Close(), null)
+    assert.equal(latin1Str(b.underlyingArray), "a")
+})
+
+test("TestDecoderWriterError", () => {
+    let dstErr = new Error("dst failed")
+    let failing: Writer = { Write: () => [0, dstErr] }
+    let d = new LZWDecoder(failing, Order.LSB, 8)
+    assert.deepEqual(d.Write(latin1Bytes("\x61\x02\x02")), [0, dstErr])
+    assert.equal(d.Close(), dstErr)
+
+    let short: Writer = { Write: (p) => [p.length - 1, null] }
+    d = new LZWDecoder(short, Order.LSB, 8)
+    assert.deepEqual(d.Write(latin1Bytes("\x61\x02\x02")), [0, IOErrors.ShortWrite])
+})
+
+// decompressionStream pipes chunks through an LZWDecompressionStream and returns
+// the output, or the error the stream failed with
+async function decompressionStream(chunks: Uint8Array[], order: Order, litWidth: number): Promise<[Uint8Array, Error | null]> {
+    let src = new ReadableStream<Uint8Array>({
+        start(controller) {
+            for (let c of chunks) {
+                controller.enqueue(c)
+            }
+            controller.close()
+        }
+    })
+
+    let out = new GoBuffer(new Uint8Array(0))
+    try {
+        for await (let c of src.pipeThrough(new LZWDecompressionStream(order, litWidth))) {
+            out.Write(c)
+        }
+    } catch (e) {
+        return [out.underlyingArray, e as Error]
+    }
+    return [out.underlyingArray, null]
+}
+
+test("TestDecompressionStream", async () => {
+    for (let tt of lzwTests) {
+        let [order, litWidth] = parseDesc(tt.desc)
+        let compressed = latin1Bytes(tt.compressed)
+        for (let size of [1, 3]) {
+            let [got, err] = await decompressionStream(chunks(compressed, size), order, litWidth)
+            let s = latin1Str(got)
+            if (err) {
+                assert.ok(err instanceof LZWError, `${tt.desc} size=${size}: got ${err}, want an LZWError`)
+                assert.ok(matches(err, tt.err), `${tt.desc} size=${size}: got ${err}, want ${tt.err}`)
+                assert.ok(tt.raw.startsWith(s), `${tt.desc} size=${size}: got ${JSON.stringify(s)}, want a prefix of ${JSON.stringify(tt.raw)}`)
+                continue
+            }
+            assert.equal(tt.err, null, `${tt.desc} size=${size}: got no error`)
+            assert.equal(s, tt.raw, `${tt.desc} size=${size}`)
+        }
+    }
+
+    // A large stream, whose output is more than one decode buffer
+    let golden = new Uint8Array(fs.readFileSync(filenames[1]))
+    let compressed = new GoBuffer(new Uint8Array(0))
+    let w = new LZWWriter(compressed, Order.LSB, 8)
+    w.Write(golden)
+    w.Close()
+    let [got, err] = await decompressionStream(chunks(compressed.underlyingArray, 1000), Order.LSB, 8)
+    assert.equal(err, null)
+    assert.deepEqual(got, golden)
+})
@@ -0,0 +1,140 @@
+// Test vectors for compress/lzw
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/compress/lzw/reader_test.go
+//
+// The strings are byte strings: each character is one byte.
+import { Errors as IOErrors } from "../../io"
+
+export interface lzwTest {
+    // desc is "name;order;litWidth"
+    desc: string
+    raw: string
+    compressed: string
+    // err is the error the reader is expected to return, if any. An Error is
+    // matched with errors.Is, and a string with the error's message
+    err: Error | string | null
+}
+
+export const lzwTests: lzwTest[] = [
+    {
+        desc: "empty;LSB;8",
+        raw: "",
+        compressed: "\x01\x01",
+        err: null,
+    },
+    {
+        desc: "empty;MSB;8",
+        raw: "",
+        compressed: "\x80\x80",
+        err: null,
+    },
+    {
+        desc: "tobe;LSB;7",
+        raw: "TOBEORNOTTOBEORTOBEORNOT",
+        compressed: "\x54\x4f\x42\x45\x4f\x52\x4e\x4f\x54\x82\x84\x86\x8b\x85\x87\x89\x81",
+        err: null,
+    },
+    {
+        desc: "tobe;LSB;8",
+        raw: "TOBEORNOTTOBEORTOBEORNOT",
+        compressed: "\x54\x9e\x08\x29\xf2\x44\x8a\x93\x27\x54\x04\x12\x34\xb8\xb0\xe0\xc1\x84\x01\x01",
+        err: null,
+    },
+    {
+        desc: "tobe;MSB;7",
+        raw: "TOBEORNOTTOBEORTOBEORNOT",
+        compressed: "\x54\x4f\x42\x45\x4f\x52\x4e\x4f\x54\x82\x84\x86\x8b\x85\x87\x89\x81",
+        err: null,
+    },
+    {
+        desc: "tobe;MSB;8",
+        raw: "TOBEORNOTTOBEORTOBEORNOT",
+        compressed: "\x2a\x13\xc8\x44\x52\x79\x48\x9c\x4f\x2a\x40\xa0\x90\x68\x5c\x16\x0f\x09\x80\x80",
+        err: null,
+    },
+    {
+        desc: "tobe-truncated;LSB;8",
+        raw: "TOBEORNOTTOBEORTOBEORNOT",
+        compressed: "\x54\x9e\x08\x29\xf2\x44\x8a\x93\x27\x54\x04",
+        err: IOErrors.UnexpectedEOF,
+    },
+    // This example comes from https://en.wikipedia.org/wiki/Graphics_Interchange_Format.
+    {
+        desc: "gif;LSB;8",
+        raw: "\x28\xff\xff\xff\x28\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff",
+        compressed: "\x00\x51\xfc\x1b\x28\x70\xa0\xc1\x83\x01\x01",
+        err: null,
+    },
+    // This example comes from http://compgroups.net/comp.lang.ruby/Decompressing-LZW-compression-from-PDF-file
+    {
+        desc: "pdf;MSB;8",
+        raw: "-----A---B",
+        compressed: "\x80\x0b\x60\x50\x22\x0c\x0c\x85\x01",
+        err: null,
+    },
+
+    // Not present in the Go code
+    //
+    // The cases below were generated by packing codes by hand, and the expected
+    // results are what Go's reader returns for them.
+
+    // A clear code followed by the first unassigned code, which has no previous code to extend.
+    {
+        desc: "invalid-first-code;LSB;8",
+        raw: "",
+        compressed: "\x00\x05\x06\x04",
+        err: "lzw: invalid code",
+    },
+    {
+        desc: "invalid-first-code;MSB;8",
+        raw: "",
+        compressed: "\x80\x40\xa0\x20",
+        err: "lzw: invalid code",
+    },
+    // Two literals followed by code 300, which is above hi.
+    {
+        desc: "invalid-code-above-hi;LSB;8",
+        raw: "ab",
+        compressed: "\x61\xc4\xb0\x0c\x08",
+        err: "lzw: invalid code",
+    },
+    {
+        desc: "invalid-code-above-hi;MSB;8",
+        raw: "ab",
+        compressed: "\x30\x98\xa5\x90\x10",
+        err: "lzw: invalid code",
+    },
+    // A clear code resets hi, so a code that was valid before it no longer is.
+    {
+        desc: "invalid-code-after-clear;LSB;7",
+        raw: "xy",
+        compressed: "\x78\x79\x80\x82\x81",
+        err: "lzw: invalid code",
+    },
+    {
+        desc: "invalid-code-after-clear;MSB;7",
+        raw: "xy",
+        compressed: "\x78\x79\x80\x82\x81",
+        err: "lzw: invalid code",
+    },
+    // The KwKwK case: codes equal to hi, which refer to the entry being built.
+    {
+        desc: "kwkwk;LSB;8",
+        raw: "aaaaaa",
+        compressed: "\x61\x04\x0e\x0c\x08",
+        err: null,
+    },
+    {
+        desc: "missing-eof;LSB;8",
+        raw: "ab",
+        compressed: "\x61\xc4\x00",
+        err: IOErrors.UnexpectedEOF,
+    },
+]
+
+// Files used to test that compressing and then decompressing gives back the
+// original bytes, relative to the repository root.
+export const filenames = [
+    "src/compress/testdata/gettysburg.txt",
+    "src/compress/testdata/e.txt",
+    "src/compress/testdata/pi.txt",
+]
@@ -0,0 +1,650 @@
+// Decodes and encodes the Unix compress (.Z) format
+//
+// This is not part of the Go standard library. The format is LZW with a 3 byte header,
+// "Least Significant Bits first" codes from 9 up to 16 bits wide and no EOF code.
+// The reader follows the decompress() routine of ncompress 4.2.4, which is also what
+// gzip's unlzw.c implements
+import { BufferedByteReader } from "../builtins/tshelpers/bufferedbytereader"
+import * as errors from "../errors"
+import { ByteReader, Closer, DefaultBufferSize, Errors as IOErrors, isByteReader, Reader, Writer } from "../io"
+
+// The magic bytes at the start of every .Z stream
+export const magic = [0x1f, 0x9d]
+
+// blockModeFlag is set in the header flags when the stream may contain CLEAR codes
+const blockModeFlag = 0x80
+
+// maxBitsMask extracts the maximum code width from the header flags
+const maxBitsMask = 0x1f
+
+// Codes start out initWidth wide and grow up to the maxbits given in the header
+const initWidth = 9
+const maxWidth = 16
+
+// In block mode, code 256 resets the dictionary and the first free code is 257.
+// Without block mode, 256 is an ordinary code.
+const clearCode = 256
+const firstCode = 257
+
+const decoderInvalidCode = 0xffffffff
+const flushBuffer = 1 << maxWidth
+
+// errClosed is the error returned by any operation on a closed reader or writer
+const errClosed = errors.New("unixz: reader/writer is closed")
+
+/**
+ * UnixZReader decompresses a Unix compress (.Z) stream.
+ *
+ * The header is read when the reader is created. An invalid header is reported
+ * by the first call to Read.
+ */
+export class UnixZReader implements Reader, Closer {
+    r!: ByteReader // set in init
+    bits: number = 0 // uint32
+    nBits: number = 0 // uint
+    width: number = 0 // uint
+
+    // Set from the header flags
+    maxBits: number = 0 // uint
+    blockMode: boolean = false
+
+    // Buffer used when the source does not implement io.ByteReader, kept across Reset
+    private br: BufferedByteReader | undefined
+
+	// freeEnt is the next code to be added to the dictionary.
+	// maxFreeEnt is 1 << maxBits, at which point the dictionary is full.
+	//
+	// last is the most recently seen code, or decoderInvalidCode at the
+	// start of the stream. Unlike GIF-style LZW, a CLEAR code does not
+	// reset it.
+	//
+	// finchar is the first byte of the most recent expansion.
+    freeEnt: number = 0 // uint
+    maxFreeEnt: number = 0 // uint
+    last: number = 0 // uint
+    finchar: number = 0 // uint8
+
+	// Codes are written in groups of 8, which take up exactly width bytes.
+	// When the width changes or a CLEAR code is seen, the original
+	// implementation skips to the end of the current group. group counts the
+	// codes read from the current group.
+    group: number = 0 // uint
+
+    err: Error | undefined // Error in the stream, if any
+
+	// Each code c in [firstCode, freeEnt) expands to two or more bytes:
+	//   suffix[c] is the last of these bytes.
+	//   prefix[c] is the code for all but the last byte.
+    suffix: Uint8Array // [1 << maxWidth]uint8
+    prefix: Uint16Array // [1 << maxWidth]uint16
+
+	// output is the temporary output buffer, used as in compress/lzw.
+	// It is flushed when it contains >= 1<<maxWidth bytes,
+	// so that there is always room to decode an entire code.
+    output: Uint8Array // [2 * 1 << maxWidth]byte
+    o = 0 // write index into output
+    toRead: Uint8Array // bytes to return from Read, a view into output
+
+    /**
+     * Creates a new UnixZReader reading a .Z stream from src.
+     *
+     * If src does not also implement io.ByteReader, the UnixZReader may read more data than necessary from src.
+     *
+     * @param src The reader to read compressed bytes from, ReadByte is used when present
+     */
+    constructor(src: Reader | ByteReader) {
+        // Set up the initial slices [js specific]
+        this.suffix = new Uint8Array(1 << maxWidth)
+        this.prefix = new Uint16Array(1 << maxWidth)
+        this.output = new Uint8Array(2 * (1 << maxWidth))
+        this.toRead = this.output.subarray(0, 0)
+
+        this.init(src)
+    }
+
+    // Reset clears the UnixZReader's state and allows it to be reused again
+    // as a new UnixZReader, reading the header of src.
+    //
+    // The suffix, prefix and output buffers are kept, so a reset reader
+    // does not reallocate them.
+    Reset(src: Reader | ByteReader) {
+        this.init(src)
+    }
+
+    // init sets up the reader state for a new stream and reads its header
+    private init(src: Reader | ByteReader) {
+        if (isByteReader(src)) {
+            this.r = src
+        } else if (this.br) {
+            this.br.reset(src)
+            this.r = this.br
+        } else {
+            this.br = new BufferedByteReader(src)
+            this.r = this.br
+        }
+        this.bits = 0
+        this.nBits = 0
+        this.width = initWidth
+        this.group = 0
+        this.last = decoderInvalidCode
+        this.finchar = 0
+        this.err = undefined
+        this.o = 0
+        this.toRead = this.output.subarray(0, 0)
+
+        this.readHeader()
+    }
+
+    // readHeader reads the magic bytes and the flags byte
+    private readHeader() {
+        let header = [0, 0, 0]
+        for (let i = 0; i < header.length; i++) {
+            let [c, err] = this.r.ReadByte()
+
+            if (err) {
+                if (err == IOErrors.EOF) {
+                    err = IOErrors.UnexpectedEOF
+                }
+                this.err = err
+                return
+            }
+
+            header[i] = c
+        }
+
+        if (header[0] != magic[0] || header[1] != magic[1]) {
+            this.err = new Error("unixz: invalid header")
+            return
+        }
+
+        this.maxBits = header[2] & maxBitsMask
+        this.blockMode = (header[2] & blockModeFlag) != 0
+
+        if (this.maxBits < initWidth || maxWidth < this.maxBits) {
+            this.err = new Error(`unixz: unsupported maxbits ${this.maxBits}`)
+            return
+        }
+
+        this.maxFreeEnt = 1 << this.maxBits
+        this.freeEnt = this.blockMode ? firstCode : clearCode
+    }
+
+    // read returns the next code, counting it towards the current group.
+    private read(): [number /* uint16 */, Error | null] {
+        while (this.nBits < this.width) {
+            let [x, err] = this.r.ReadByte()
+
+            if (err) {
+                return [0, err]
+            }
+
+            this.bits |= (x << this.nBits)
+            this.nBits += 8
+        }
+
+        let code = this.bits & ((1 << this.width) - 1)
+        this.bits >>>= this.width
+        this.nBits -= this.width
+        this.group = (this.group + 1) & 7
+        return [code, null]
+    }
+
+    // skipGroup discards the rest of the current group of 8 codes.
+    private skipGroup(): Error | null {
+        while (this.group != 0) {
+            let [, err] = this.read()
+
+            if (err) {
+                return err
+            }
+        }
+
+        return null
+    }
+
+    // Read implements io.Reader, reading uncompressed bytes from its underlying Reader.
+    Read(b: Uint8Array): [number, Error | null] {
+        while(true) /* for */ {
+            if(this.toRead.length > 0) {
+                // n := copy(b, r.toRead)
+                let n = Math.min(b.length, this.toRead.length)
+                b.set(this.toRead.subarray(0, n))
+                this.toRead = this.toRead.subarray(n) // r.toRead = r.toRead[n:]
+                return [n, null]
+            }
+
+            if(this.err) {
+                return [0, this.err]
+            }
+
+            this.decode()
+        }
+    }
+
+    // decode decompresses bytes from r and leaves them in r.toRead.
+    private decode() {
+        // Loop over the code stream, converting codes into decompressed bytes.
+        loop: while(true) /* for */ {
+            // Grow the code width once the dictionary outgrows it, skipping
+            // to the end of the current group first.
+            if(this.width < this.maxBits && this.freeEnt > (1 << this.width) - 1) {
+                let err = this.skipGroup()
+                if(err) {
+                    this.err = err
+                    break
+                }
+
+                this.width++
+            }
+
+            let [code, err] = this.read()
+
+            if(err) {
+                this.err = err
+                break
+            }
+
+            if(this.last == decoderInvalidCode) {
+                // The first code of the stream must be a literal.
+                if(code >= clearCode) {
+                    this.err = new Error("unixz: corrupt input")
+                    break loop
+                }
+
+                this.output[this.o] = code
+                this.o++
+                this.finchar = code
+                this.last = code
+                continue
+            }
+
+            if(code == clearCode && this.blockMode) {
+                // The CLEAR code is followed by padding up to the end of its group,
+                // at the width it was read with.
+                let err = this.skipGroup()
+                if(err) {
+                    this.err = err
+                    break
+                }
+
+                this.freeEnt = firstCode - 1
+                this.width = initWidth
+                continue
+            }
+
+            let incode = code
+            let i = this.output.length - 1
+
+            if(code >= this.freeEnt) {
+                if(code > this.freeEnt) {
+                    this.err = new Error("unixz: corrupt input")
+                    break loop
+                }
+
+                // code == freeEnt is a special case which expands to the last expansion
+                // followed by the head of the last expansion.
+                this.output[i] = this.finchar
+                i--
+                code = this.last
+            }
+
+            // Copy the suffix chain into output.
+            while(code >= clearCode) {
+                this.output[i] = this.suffix[code]
+                i--
+                code = this.prefix[code]
+            }
+            this.finchar = code
+            this.output[i] = code
+
+            // copy(r.output[r.o:], r.output[i:])
+            this.output.set(this.output.subarray(i), this.o)
+            this.o += this.output.length - i
+
+            if(this.freeEnt < this.maxFreeEnt) {
+                // Save what the next free code expands to.
+                this.prefix[this.freeEnt] = this.last
+                this.suffix[this.freeEnt] = this.finchar
+                this.freeEnt++
+            }
+
+            this.last = incode
+
+            if(this.o >= flushBuffer) {
+                break
+            }
+        }
+
+        // Flush pending output
+        this.toRead = this.output.subarray(0, this.o)
+        this.o = 0
+    }
+
+    // Close closes the UnixZReader and returns an error for any future read operation.
+    // It does not close the underlying io.Reader.
+    Close(): Error | null {
+        this.err = errClosed
+        return null
+    }
+}
+
+// There are 1<<maxWidth possible codes, which is an upper bound on the number of
+// valid hash table entries at any given point in time. tableSize is 4x that.
+const tableSize = 4 * (1 << maxWidth)
+const tableMask = tableSize - 1
+const tableShift = 32 - 18 // log2(tableSize) == 18
+
+/**
+ * UnixZWriter compresses data into the Unix compress (.Z) format, in block mode.
+ *
+ * A CLEAR code is emitted whenever the dictionary fills up. The output is readable
+ * by compress, gzip and UnixZReader, but is not byte-identical to ncompress,
+ * which only clears the dictionary when the compression ratio drops.
+ *
+ * It is the caller's responsibility to call Close on the UnixZWriter when
+ * finished writing.
+ */
+export class UnixZWriter implements Writer, Closer {
+    // w is the writer that compressed bytes are written to.
+    w!: Writer // set in init
+
+    // maxBits is the maximum width in bits of a code
+    maxBits: number = 0 // uint
+
+    // bits, nBits, width and group are the state for converting a
+    // code stream into a byte stream. group counts the codes written
+    // to the current group of 8.
+    nBits: number = 0 // uint
+    width: number = 0 // uint
+    bits: number = 0 // uint32
+    group: number = 0 // uint
+
+    // freeEnt is the code the next dictionary entry is assigned.
+    freeEnt: number = 0 // uint
+
+    // savedCode is the accumulated code at the end of the most recent Write
+	// call. It is equal to decoderInvalidCode if there was no such call.
+    savedCode: number = decoderInvalidCode // uint32
+
+    // wroteHeader is set once the 3 byte header has been written
+    wroteHeader: boolean = false
+
+    // err is the first error encountered during writing. Closing the writer
+	// will make any future Write calls return errClosed
+    err: Error | null = null
+
+    // keys and values form a hash table from 24-bit keys to 16-bit codes,
+    // with collisions resolved by linear probing. A key is a 16-bit code prefix
+    // and an 8-bit byte suffix, stored plus one so that zero marks an empty slot.
+    keys: Int32Array = new Int32Array(tableSize)
+    values: Uint16Array = new Uint16Array(tableSize)
+
+    // Pending output bytes, flushed to w when full and on Close. This replaces
+    // a bufio.Writer
+    private buf: Uint8Array = new Uint8Array(DefaultBufferSize)
+    private n: number = 0 // write index into buf
+
+    /**
+     * Creates a new UnixZWriter. Writes to the returned UnixZWriter are compressed and written to dst.
+     *
+     * @param dst The writer to write compressed bytes to
+     * @param maxBits The maximum code width, must be in the range [9,16] and is typically 16
+     */
+    constructor(dst: Writer, maxBits: number = 16) {
+        this.init(dst, maxBits)
+    }
+
+    // Reset clears the UnixZWriter's state and allows it to be reused again
+    // as a new UnixZWriter.
+    Reset(dst: Writer, maxBits: number = 16) {
+        this.init(dst, maxBits)
+    }
+
+    // init sets up the writer state for a new stream, reusing the hash table
+    // and write buffer.
+    private init(dst: Writer, maxBits: number) {
+        if(maxBits < initWidth || maxWidth < maxBits) {
+            throw new Error("unixz: maxBits out of range")
+        }
+
+        this.w = dst
+        this.maxBits = maxBits
+        this.nBits = 0
+        this.bits = 0
+        this.width = initWidth
+        this.group = 0
+        this.freeEnt = firstCode
+        this.savedCode = decoderInvalidCode
+        this.wroteHeader = false
+        this.err = null
+        this.keys.fill(0)
+        this.n = 0
+    }
+
+    // writeHeader writes the magic bytes and flags if they have not been written yet.
+    private writeHeader(): Error | null {
+        if (this.wroteHeader) {
+            return null
+        }
+        this.wroteHeader = true
+
+        for (let b of [magic[0], magic[1], this.maxBits | blockModeFlag]) {
+            let err = this.writeByte(b)
+            if (err) {
+                return err
+            }
+        }
+
+        return null
+    }
+
+    // writeBits writes the code c at the current width, LSB first.
+    private writeBits(c: number): Error | null {
+        this.bits |= c << this.nBits
+        this.nBits += this.width
+        while (this.nBits >= 8) {
+            let err = this.writeByte(this.bits & 0xff)
+
+            if (err) {
+                return err
+            }
+
+            this.bits >>>= 8
+            this.nBits -= 8
+        }
+
+        this.group = (this.group + 1) & 7
+        return null
+    }
+
+    // padGroup fills the rest of the current group of 8 codes with zero codes,
+    // as the reader skips to the end of the group on width changes and CLEAR codes.
+    private padGroup(): Error | null {
+        while (this.group != 0) {
+            let err = this.writeBits(0)
+
+            if (err) {
+                return err
+            }
+        }
+
+        return null
+    }
+
+    // write writes the code c, growing the code width first if the reader
+    // will have done so before reading it.
+    //
+    // The reader adds the entry for a code only once it sees the next code,
+    // so its free code lags one behind freeEnt here.
+    private write(c: number): Error | null {
+        if (this.width < this.maxBits && this.freeEnt - 1 > (1 << this.width) - 1) {
+            let err = this.padGroup()
+            if (err) {
+                return err
+            }
+
+            this.width++
+        }
+
+        return this.writeBits(c)
+    }
+
+    // writeByte buffers a single byte, flushing the buffer to w when it is full.
+    // Equivalent to w.w.WriteByte() on a bufio.Writer
+    private writeByte(b: number): Error | null {
+        if (this.n == this.buf.length) {
+            let err = this.flush()
+
+            if (err) {
+                return err
+            }
+        }
+
+        this.buf[this.n] = b
+        this.n++
+        return null
+    }
+
+    // flush writes any buffered bytes to w.
+    // Equivalent to w.w.Flush() on a bufio.Writer
+    private flush(): Error | null {
+        if (this.n == 0) {
+            return null
+        }
+
+        let [n, err] = this.w.Write(this.buf.subarray(0, this.n))
+
+        if (n < this.n && err == null) {
+            err = IOErrors.ShortWrite
+        }
+
+        if (err) {
+            return err
+        }
+
+        this.n = 0
+        return null
+    }
+
+    // clear sends a CLEAR code followed by its padding and resets the dictionary.
+    private clear(): Error | null {
+        let err = this.write(clearCode)
+        if (err) {
+            return err
+        }
+
+        err = this.padGroup()
+        if (err) {
+            return err
+        }
+
+        this.width = initWidth
+        this.freeEnt = firstCode
+        this.keys.fill(0)
+        return null
+    }
+
+    // Write writes a compressed representation of p to w's underlying writer.
+    Write(p: Uint8Array): [number, Error | null] {
+        if (this.err) {
+            return [0, this.err]
+        }
+
+        if (p.length == 0) {
+            return [0, null]
+        }
+
+        this.err = this.writeHeader()
+        if (this.err) {
+            return [0, this.err]
+        }
+
+        let n = p.length
+        let code = this.savedCode
+
+        if (code == decoderInvalidCode) {
+            // This is the first write, the first code is always a literal.
+            code = p[0]
+            p = p.subarray(1) // code, p = uint32(p[0]), p[1:]
+        }
+
+        loop: for (let i = 0; i < p.length; i++) {
+            let literal = p[i]
+            let key = ((code << 8) | literal) + 1
+
+            // If there is a hash table hit for this key then we continue the loop
+            // and do not emit a code yet.
+            let hash = Math.imul(key, 0x9e3779b1) >>> tableShift
+            for (let t = this.keys[hash]; t != 0;) {
+                if (t == key) {
+                    code = this.values[hash]
+                    continue loop
+                }
+                hash = (hash + 1) & tableMask
+                t = this.keys[hash]
+            }
+
+            // Otherwise, write the current code, and literal becomes the start of
+            // the next emitted code.
+            this.err = this.write(code)
+            if (this.err) {
+                return [0, this.err]
+            }
+            code = literal
+
+            // hash now points at an empty slot, insert key -> freeEnt there.
+            this.keys[hash] = key
+            this.values[hash] = this.freeEnt
+            this.freeEnt++
+
+            // If we run out of codes, reset the dictionary and continue.
+            if (this.freeEnt == 1 << this.maxBits) {
+                this.err = this.clear()
+                if (this.err) {
+                    return [0, this.err]
+                }
+            }
+        }
+
+        this.savedCode = code
+        return [n, null]
+    }
+
+    // Close closes the UnixZWriter, flushing any pending output. It does not close
+    // w's underlying writer.
+    Close(): Error | null {
+        if (this.err) {
+            if (this.err == errClosed) {
+                return null
+            }
+            return this.err
+        }
+
+        // Make any future calls to Write return errClosed.
+        this.err = errClosed
+
+        // An empty stream is just the header.
+        let err = this.writeHeader()
+        if (err) {
+            return err
+        }
+
+        // Write the savedCode if valid.
+        if (this.savedCode != decoderInvalidCode) {
+            err = this.write(this.savedCode)
+            if (err) {
+                return err
+            }
+        }
+
+        // Write the final bits.
+        if (this.nBits > 0) {
+            err = this.writeByte(this.bits & 0xff)
+            if (err) {
+                return err
+            }
+        }
+
+        return this.flush()
+    }
+}
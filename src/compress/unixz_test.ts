@@ -0,0 +1,192 @@
+// Tests for compress/unixz
+//
+// Not present in the Go code.
+//
+// The .Z files in testdata were written by a port of compress() from ncompress
+// 4.2.4: block mode, with a CLEAR code whenever the compression ratio drops at a
+// checkpoint once the dictionary is full, and codes padded to the end of their
+// group of 8 on width changes and after CLEAR. Each one decompresses correctly
+// with gzip -d.
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { latin1Bytes } from "../builtins/tshelpers/strings"
+import { Copy, Errors as IOErrors, ReadAll } from "../io"
+import * as iotest from "../testing/iotest"
+import { magic, UnixZReader, UnixZWriter } from "./unixz"
+
+function readFile(name: string): Uint8Array {
+    return new Uint8Array(fs.readFileSync(name))
+}
+
+// mixed returns n copies of gettysburg.txt followed by the first m digits of
+// pi.txt. It compresses well and then badly, so ncompress clears the dictionary
+// when it reaches the digits.
+function mixed(n: number, m: number): Uint8Array {
+    let g = readFile("src/compress/testdata/gettysburg.txt")
+    let pi = readFile("src/compress/testdata/pi.txt").subarray(0, m)
+    let b = new Uint8Array(n * g.length + pi.length)
+    for (let i = 0; i < n; i++) {
+        b.set(g, i * g.length)
+    }
+    b.set(pi, n * g.length)
+    return b
+}
+
+// decompress reads all of the .Z stream in compressed
+function decompress(compressed: Uint8Array): [Uint8Array, Error | null] {
+    let r = new UnixZReader(new GoBuffer(compressed))
+    let b = new GoBuffer(new Uint8Array(0))
+    let [, err] = Copy(b, r)
+    r.Close()
+    return [b.underlyingArray, err]
+}
+
+// compress returns golden compressed with the given maxBits, written in chunks
+// of varying size
+function compress(golden: Uint8Array, maxBits: number): Uint8Array {
+    let b = new GoBuffer(new Uint8Array(0))
+    let w = new UnixZWriter(b, maxBits)
+    for (let i = 0, n = 1; i < golden.length; i += n, n = n * 3 % 4093) {
+        let [, err] = w.Write(golden.subarray(i, i + n))
+        assert.equal(err, null, "Write")
+    }
+    assert.equal(w.Close(), null, "Close")
+    return b.underlyingArray
+}
+
+const fixtures: { name: string, maxBits: number, raw: () => Uint8Array }[] = [
+    // Widens codes from 9 to 11 bits, padding each group
+    { name: "gettysburg.txt.Z", maxBits: 16, raw: () => readFile("src/compress/testdata/gettysburg.txt") },
+    // The dictionary fills at 10 bits, and a CLEAR in the middle of a group
+    // starts it over
+    { name: "mixed.b10.Z", maxBits: 10, raw: () => mixed(8, 10000) },
+    // As above at 12 bits, after which codes widen from 9 bits again
+    { name: "mixed.b12.Z", maxBits: 12, raw: () => mixed(8, 20000) },
+]
+
+test("TestReaderFixtures", () => {
+    for (let tt of fixtures) {
+        let compressed = readFile("src/compress/testdata/" + tt.name)
+        assert.deepEqual(Array.from(compressed.subarray(0, 3)), [magic[0], magic[1], 0x80 | tt.maxBits], `${tt.name}: header`)
+
+        let [got, err] = decompress(compressed)
+        assert.equal(err, null, `${tt.name}: Copy`)
+        assert.deepEqual(got, tt.raw(), `${tt.name}: output`)
+
+        // A source without ReadByte, read a byte at a time
+        err = iotest.TestReader(new UnixZReader(iotest.OneByteReader(new GoBuffer(compressed))), tt.raw())
+        assert.equal(err, null, `${tt.name}: TestReader: ${err?.message}`)
+    }
+})
+
+test("TestReaderNonBlockMode", () => {
+    // Without the block mode flag, code 256 is an ordinary code: here it is
+    // "aa", between two literal 'a's
+    let [got, err] = decompress(new Uint8Array([0x1f, 0x9d, 0x10, 0x61, 0x00, 0x86, 0x01]))
+    assert.equal(err, null)
+    assert.deepEqual(got, latin1Bytes("aaaa"))
+})
+
+test("TestRoundTrip", () => {
+    let inputs: [string, Uint8Array][] = [
+        ["empty", new Uint8Array(0)],
+        ["one byte", latin1Bytes("a")],
+        ["two bytes", latin1Bytes("ab")],
+        ["gettysburg.txt", readFile("src/compress/testdata/gettysburg.txt")],
+        ["e.txt", readFile("src/compress/testdata/e.txt")],
+        ["pi.txt", readFile("src/compress/testdata/pi.txt")],
+        ["mixed", mixed(8, 20000)],
+    ]
+    for (let maxBits = 9; maxBits <= 16; maxBits++) {
+        for (let [name, golden] of inputs) {
+            let compressed = compress(golden, maxBits)
+            assert.deepEqual(Array.from(compressed.subarray(0, 3)), [magic[0], magic[1], 0x80 | maxBits], `${name} maxBits=${maxBits}: header`)
+
+            let [got, err] = decompress(compressed)
+            assert.equal(err, null, `${name} maxBits=${maxBits}: Copy`)
+            assert.deepEqual(got, golden, `${name} maxBits=${maxBits}: output`)
+        }
+    }
+})
+
+test("TestWriterReset", () => {
+    let golden = readFile("src/compress/testdata/gettysburg.txt")
+    let b1 = new GoBuffer(new Uint8Array(0))
+    let w = new UnixZWriter(b1, 12)
+    w.Write(golden)
+    w.Close()
+
+    let b2 = new GoBuffer(new Uint8Array(0))
+    w.Reset(b2, 12)
+    w.Write(golden)
+    w.Close()
+    assert.deepEqual(b2.underlyingArray, b1.underlyingArray)
+
+    assert.throws(() => new UnixZWriter(b1, 8), /maxBits out of range/)
+    assert.throws(() => new UnixZWriter(b1, 17), /maxBits out of range/)
+})
+
+test("TestReaderReset", () => {
+    let r = new UnixZReader(new GoBuffer(readFile("src/compress/testdata/mixed.b10.Z")))
+    let [got, err] = ReadAll(r)
+    assert.equal(err, null)
+    assert.deepEqual(got, mixed(8, 10000))
+
+    r.Reset(new GoBuffer(readFile("src/compress/testdata/gettysburg.txt.Z")));
+    [got, err] = ReadAll(r)
+    assert.equal(err, null)
+    assert.deepEqual(got, readFile("src/compress/testdata/gettysburg.txt"))
+})
+
+test("TestReaderErrors", () => {
+    let testCases: { desc: string, compressed: Uint8Array, err: Error | string }[] = [
+        { desc: "empty", compressed: new Uint8Array(0), err: IOErrors.UnexpectedEOF },
+        { desc: "magic only", compressed: new Uint8Array([0x1f, 0x9d]), err: IOErrors.UnexpectedEOF },
+        { desc: "gzip magic", compressed: new Uint8Array([0x1f, 0x8b, 0x08, 0x00]), err: "unixz: invalid header" },
+        { desc: "pack magic", compressed: new Uint8Array([0x1f, 0x1e, 0x90, 0x00]), err: "unixz: invalid header" },
+        { desc: "maxbits 8", compressed: new Uint8Array([0x1f, 0x9d, 0x88, 0x61, 0x00]), err: "unixz: unsupported maxbits 8" },
+        { desc: "maxbits 17", compressed: new Uint8Array([0x1f, 0x9d, 0x91, 0x61, 0x00]), err: "unixz: unsupported maxbits 17" },
+        // The first code must be a literal
+        { desc: "first code CLEAR", compressed: new Uint8Array([0x1f, 0x9d, 0x90, 0x00, 0x01]), err: "unixz: corrupt input" },
+        // 'a', then 258 when the next free code is 257
+        { desc: "code past free", compressed: new Uint8Array([0x1f, 0x9d, 0x90, 0x61, 0x04, 0x02]), err: "unixz: corrupt input" },
+    ]
+    for (let tt of testCases) {
+        let [, err] = decompress(tt.compressed)
+        assert.ok(err != null, `${tt.desc}: got no error`)
+        if (typeof tt.err == "string") {
+            assert.equal(err.message, tt.err, tt.desc)
+        } else {
+            assert.equal(err, tt.err, tt.desc)
+        }
+    }
+})
+
+test("TestReaderTruncated", () => {
+    // .Z streams have no end code, so like gzip -d, the reader stops
+    // at the end of its input and returns what it decoded, wherever the stream
+    // was cut. Only a cut header is an error.
+    for (let tt of fixtures) {
+        let compressed = readFile("src/compress/testdata/" + tt.name)
+        let raw = tt.raw()
+        let last = 0
+        for (let n of [3, 4, 5, 100, compressed.length >> 1, compressed.length - 1]) {
+            let [got, err] = decompress(compressed.subarray(0, n))
+            assert.equal(err, null, `${tt.name}[:${n}]`)
+            assert.ok(got.length < raw.length, `${tt.name}[:${n}]: got all ${got.length} bytes`)
+            assert.ok(got.length >= last, `${tt.name}[:${n}]: got ${got.length} bytes, fewer than the ${last} of a shorter input`)
+            assert.deepEqual(got, raw.subarray(0, got.length), `${tt.name}[:${n}]: not a prefix`)
+            last = got.length
+        }
+    }
+})
+
+test("TestReaderClose", () => {
+    let r = new UnixZReader(new GoBuffer(readFile("src/compress/testdata/gettysburg.txt.Z")))
+    assert.equal(r.Close(), null)
+    let [n, err] = r.Read(new Uint8Array(10))
+    assert.equal(n, 0)
+    assert.equal(err?.message, "unixz: reader/writer is closed")
+})
@@ -0,0 +1,334 @@
+// Tests for the errors package
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/errors/errors_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/errors/wrap_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/errors/join_test.go
+// and the Errorf tests of https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/fmt/errors_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import * as errors from "./index"
+
+test("TestNewEqual", () => {
+    // Different allocations should not be equal.
+    assert.notEqual(errors.New("abc"), errors.New("abc"), `New("abc") == New("abc")`)
+    assert.notEqual(errors.New("abc"), errors.New("xyz"), `New("abc") == New("xyz")`)
+
+    // Same allocation should be equal to itself (not crash).
+    let err = errors.New("jkl")
+    assert.equal(err, err, `err != err`)
+})
+
+test("TestErrorMethod", () => {
+    let err = errors.New("abc")
+    assert.equal(err.message, "abc", `New("abc").message = ${JSON.stringify(err.message)}, want "abc"`)
+})
+
+class poser extends Error {
+    f: (err: Error) => boolean
+
+    constructor(msg: string, f: (err: Error) => boolean) {
+        super(msg)
+        this.f = f
+    }
+
+    Is(err: Error): boolean {
+        return this.f(err)
+    }
+
+    As(target: unknown): Error | null {
+        if (target == errorT) {
+            return new errorT("poser")
+        }
+        if (target == poser) {
+            return poserErr
+        }
+        return null
+    }
+}
+
+class errorT extends Error {
+    s: string
+
+    constructor(s: string) {
+        super(`errorT(${s})`)
+        this.s = s
+    }
+}
+
+class errorUncomparable extends Error {
+    f: string[] = []
+
+    constructor() {
+        super("uncomparable error")
+    }
+
+    Is(target: Error): boolean {
+        return target instanceof errorUncomparable
+    }
+}
+
+class wrapped extends Error {
+    err: Error | null
+
+    constructor(msg: string, err: Error | null) {
+        super(msg)
+        this.err = err
+    }
+
+    Unwrap(): Error | null {
+        return this.err
+    }
+}
+
+class multiErr extends Error {
+    errs: Error[]
+
+    constructor(...errs: Error[]) {
+        super("multiError")
+        this.errs = errs
+    }
+
+    Unwrap(): Error[] {
+        return this.errs
+    }
+}
+
+const poserErr = new poser("either 1 or 3", (err) => err == err1 || err == err3)
+const err1 = errors.New("1")
+const err3 = errors.New("3")
+
+test("TestIs", () => {
+    let erra = new wrapped("wrap 2", err1)
+    let errb = new wrapped("wrap 3", erra)
+
+    let err3 = errors.New("3")
+
+    let testCases: [Error | null, Error | null, boolean][] = [
+        [null, null, true],
+        [null, err1, false],
+        [err1, null, false],
+        [err1, err1, true],
+        [erra, err1, true],
+        [errb, err1, true],
+        [err1, err3, false],
+        [erra, err3, false],
+        [errb, err3, false],
+        [poserErr, err1, true],
+        [poserErr, err3, false],
+        [poserErr, erra, false],
+        [poserErr, errb, false],
+        [new errorUncomparable(), new errorUncomparable(), true],
+        [new errorUncomparable(), err1, false],
+        [new multiErr(), err1, false],
+        [new multiErr(err1, err3), err1, true],
+        [new multiErr(err3, err1), err1, true],
+        [new multiErr(err1, err3), errors.New("x"), false],
+        [new multiErr(err3, errb), errb, true],
+        [new multiErr(err3, errb), erra, true],
+        [new multiErr(err3, errb), err1, true],
+        [new multiErr(errb, err3), err1, true],
+        [new multiErr(poserErr), err1, true],
+        [new multiErr(poserErr), err3, false],
+    ]
+    testCases.forEach(([err, target, match], i) => {
+        assert.equal(errors.Is(err, target), match, `${i}: Is(${err?.message}, ${target?.message}) should be ${match}`)
+    })
+})
+
+class errorWithCause extends Error {
+    constructor(msg: string, cause: Error) {
+        super(msg, { cause })
+    }
+}
+
+// Not present in the Go code
+test("TestIsCause", () => {
+    let err = new errorWithCause("outer", new wrapped("inner", err1))
+    assert.ok(errors.Is(err, err1), "Is did not follow cause")
+    assert.equal(errors.Unwrap(err)?.message, "inner")
+    assert.ok(errors.As(err, wrapped) != null, "As did not follow cause")
+
+    // An Unwrap method takes precedence over cause
+    let w = new wrapped("wrapped", null)
+    w.cause = err1
+    assert.ok(!errors.Is(w, err1), "Is followed cause of an error with an Unwrap method")
+})
+
+class errorT2 extends Error {}
+
+test("TestAs", () => {
+    let errP = new errorT("P")
+
+    let testCases: [Error | null, abstract new (...args: any[]) => unknown, unknown][] = [
+        [null, errorT, null],
+        [new wrapped("pitied the fool", new errorT("T")), errorT, new errorT("T")],
+        [errP, errorT, errP],
+        [new errorT("T"), errorT2, null],
+        [err1, errorT, null],
+        [new wrapped("path error", errP), errorT, errP],
+        [poserErr, errorT, new errorT("poser")],
+        [poserErr, poser, poserErr],
+        [new multiErr(), errorT, null],
+        [new multiErr(errors.New("a"), new errorT("T")), errorT, new errorT("T")],
+        [new multiErr(new errorT("T"), errors.New("a")), errorT, new errorT("T")],
+        [new multiErr(new errorT("a"), new errorT("b")), errorT, new errorT("a")],
+        [new multiErr(new multiErr(errors.New("a"), new errorT("a")), new errorT("b")), errorT, new errorT("a")],
+        [new multiErr(new wrapped("path error", errP)), errorT, errP],
+        [new multiErr(poserErr), errorT, new errorT("poser")],
+    ]
+    testCases.forEach(([err, target, want], i) => {
+        let got = errors.As(err, target)
+        assert.equal(got != null, want != null, `#${i}: As(${err?.message}, ${target.name}) = ${got}, want ${want}`)
+        if (want instanceof errorT) {
+            assert.equal((got as errorT).s, want.s, `#${i}: As(${err?.message}, ${target.name})`)
+        } else if (want != null) {
+            assert.equal(got, want, `#${i}: As(${err?.message}, ${target.name})`)
+        }
+    })
+})
+
+test("TestAsValidation", () => {
+    assert.throws(() => errors.As(errors.New("error"), null as any), /target must be a class/)
+})
+
+test("TestUnwrap", () => {
+    let err1 = errors.New("1")
+    let erra = new wrapped("wrap 2", err1)
+
+    let testCases: [Error | null, Error | null][] = [
+        [new wrapped("wrapped", null), null],
+        [err1, null],
+        [erra, err1],
+        [new wrapped("wrap 3", erra), erra],
+        [new multiErr(err1), null],
+    ]
+    for (let [err, want] of testCases) {
+        assert.equal(errors.Unwrap(err), want, `Unwrap(${err?.message})`)
+    }
+})
+
+test("TestJoinReturnsNil", () => {
+    assert.equal(errors.Join(), null)
+    assert.equal(errors.Join(null), null)
+    assert.equal(errors.Join(null, null), null)
+})
+
+test("TestJoin", () => {
+    let err1 = errors.New("err1")
+    let err2 = errors.New("err2")
+    let testCases: [(Error | null)[], Error[]][] = [
+        [[err1], [err1]],
+        [[err1, err2], [err1, err2]],
+        [[err1, null, err2], [err1, err2]],
+    ]
+    for (let [errs, want] of testCases) {
+        let got = errors.Join(...errs) as Error & { Unwrap(): Error[] }
+        assert.deepEqual(got.Unwrap(), want, `Join(${errs}) = ${got.Unwrap()}; want ${want}`)
+        for (let err of want) {
+            assert.ok(errors.Is(got, err), `Is(Join(...), ${err.message}) = false`)
+        }
+    }
+})
+
+test("TestJoinErrorMethod", () => {
+    let err1 = errors.New("err1")
+    let err2 = errors.New("err2")
+    let testCases: [(Error | null)[], string][] = [
+        [[err1], "err1"],
+        [[err1, err2], "err1\nerr2"],
+        [[err1, null, err2], "err1\nerr2"],
+    ]
+    for (let [errs, want] of testCases) {
+        let got = errors.Join(...errs)!.message
+        assert.equal(got, want, `Join(${errs}).message = ${JSON.stringify(got)}; want ${JSON.stringify(want)}`)
+    }
+})
+
+class stringer {
+    String(): string {
+        return "stringer"
+    }
+}
+
+test("TestErrorf", () => {
+    // noVetErrorf is an alias for Errorf that does not trigger vet warnings for
+    // %w format strings.
+    let noVetErrorf = errors.Errorf
+
+    let wrapped = errors.New("inner error")
+    let testCases: { err: Error, wantText: string, wantUnwrap?: Error | null, wantSplit?: Error[] }[] = [
+        {
+            err: errors.Errorf("%w", wrapped),
+            wantText: "inner error",
+            wantUnwrap: wrapped,
+        }, {
+            err: errors.Errorf("added context: %w", wrapped),
+            wantText: "added context: inner error",
+            wantUnwrap: wrapped,
+        }, {
+            err: errors.Errorf("%w with added context", wrapped),
+            wantText: "inner error with added context",
+            wantUnwrap: wrapped,
+        }, {
+            err: errors.Errorf("%s %w %v", "prefix", wrapped, "suffix"),
+            wantText: "prefix inner error suffix",
+            wantUnwrap: wrapped,
+        }, {
+            err: errors.Errorf("%v", wrapped),
+            wantText: "inner error",
+        }, {
+            err: noVetErrorf("added context: %v", wrapped),
+            wantText: "added context: inner error",
+        }, {
+            err: noVetErrorf("%w", null),
+            wantText: "%!w(<nil>)",
+            wantUnwrap: null, // still nil
+        }, {
+            err: noVetErrorf("%w %w", wrapped, wrapped),
+            wantText: "inner error inner error",
+            wantSplit: [wrapped, wrapped],
+        }, {
+            err: noVetErrorf("%w %w %w", wrapped, wrapped, wrapped),
+            wantText: "inner error inner error inner error",
+            wantSplit: [wrapped, wrapped, wrapped],
+        }, {
+            err: noVetErrorf("%w %v %w", wrapped, 1, wrapped),
+            wantText: "inner error 1 inner error",
+            wantSplit: [wrapped, wrapped],
+        }, {
+            err: noVetErrorf("%w", "not an error"),
+            wantText: "%!w(string=not an error)",
+        }, {
+            // Not present in the Go code
+            err: errors.Errorf("%d %q %x %X %t %v %s %v %%", 42, "q", 255, new Uint8Array([0xab]), true, new Uint8Array([1, 2]), new stringer(), [1, "a"]),
+            wantText: `42 "q" ff AB true [1 2] stringer [1 a] %`,
+        }, {
+            // Not present in the Go code
+            err: errors.Errorf("%q %q", new Uint8Array([0x68, 0x69]), new Uint8Array([0x22, 0x5c, 0x0a, 0x00, 0xff, 0xe2, 0x98, 0xba])),
+            wantText: `"hi" "\\"\\\\\\n\\x00\\xff☺"`,
+        }, {
+            // Not present in the Go code
+            err: errors.Errorf("%q", "\x07\x7f\u00ad\u2028日本\u{e0001}\u{1f600}"),
+            wantText: `"\\a\\x7f\\u00ad\\u2028日本\\U000e0001😀"`,
+        }, {
+            // Not present in the Go code
+            err: noVetErrorf("%d %s", "x"),
+            wantText: "%!d(string=x) %!s(MISSING)",
+        }, {
+            // Not present in the Go code
+            err: noVetErrorf("%s", "a", 1),
+            wantText: "a%!(EXTRA number=1)",
+        },
+    ]
+    for (let test of testCases) {
+        if (test.wantSplit) {
+            let got = (test.err as Error & { Unwrap(): Error[] }).Unwrap()
+            assert.deepEqual(got, test.wantSplit, `Formatted error: ${test.wantText}`)
+            for (let err of test.wantSplit) {
+                assert.ok(errors.Is(test.err, err))
+            }
+        } else {
+            assert.equal(errors.Unwrap(test.err), test.wantUnwrap ?? null, `Formatted error: ${test.wantText}`)
+        }
+        assert.equal(test.err.message, test.wantText)
+    }
+})
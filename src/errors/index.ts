@@ -0,0 +1,500 @@
+// Package errors implements functions to manipulate errors.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/errors/
+//
+// The New function creates errors whose only content is a text message.
+//
+// An error e wraps another error if e's type has one of the methods
+//
+//	Unwrap(): Error | null
+//	Unwrap(): Error[]
+//
+// If e.Unwrap() returns a non-null error w or an array containing w,
+// then we say that e wraps w. A null error returned from e.Unwrap()
+// indicates that e does not wrap any error. It is invalid for an
+// Unwrap method to return an array that contains a null error value.
+//
+// An easy way to create wrapped errors is to call Errorf and apply
+// the %w verb to the error argument:
+//
+//	wrapsErr = errors.Errorf("... %w ...", ..., err, ...)
+//
+// Successive unwrapping of an error creates a tree. The Is and As
+// functions inspect an error's tree by examining first the error
+// itself followed by the tree of each of its children in turn
+// (pre-order, depth-first traversal).
+//
+// Is examines the tree of its first argument looking for an error that
+// matches the second. It reports whether it finds a match. It should be
+// used in preference to simple equality checks:
+//
+//	if (errors.Is(err, io.ErrClosedPipe))
+//
+// is preferable to
+//
+//	if (err == io.ErrClosedPipe)
+//
+// because the former will succeed if err wraps io.ErrClosedPipe.
+//
+// As examines the tree of its first argument looking for an error that is
+// an instance of the class given as its second argument, and returns it.
+//
+//	let perr = errors.As(err, LZWError)
+//	if (perr) {
+//		console.log(perr.offset)
+//	}
+//
+// is preferable to
+//
+//	if (err instanceof LZWError) {
+//		console.log(err.offset)
+//	}
+//
+// because the former will succeed if err wraps an LZWError.
+//
+// *SEMANTIC DIFFERENCES TO GO:*
+//
+// Errors are JS Error objects, and compared by identity. An error created with
+// `new Error(message, { cause })` that has no Unwrap method wraps its cause, so
+// that errors from other JS code can be inspected too.
+
+import * as utf8 from "../unicode/utf8"
+
+/**
+ * New returns an error that formats as the given text.
+ * Each call to New returns a distinct error value even if the text is identical.
+ */
+export function New(text: string): Error {
+    return new Error(text)
+}
+
+/**
+ * ErrUnsupported indicates that a requested operation cannot be performed,
+ * because it is unsupported. For example, a call to os.Link when using a
+ * file system that does not support hard links.
+ *
+ * Functions and methods should not return this error but should instead
+ * return an error including appropriate context that satisfies
+ *
+ *	errors.Is(err, errors.ErrUnsupported)
+ *
+ * either by directly wrapping ErrUnsupported or by implementing an Is method.
+ *
+ * Functions and methods should document the cases in which an error
+ * wrapping this will be returned.
+ */
+export const ErrUnsupported = New("unsupported operation")
+
+// unwrap returns the result of calling the Unwrap method on err, or err's
+// cause when it has no Unwrap method.
+//
+// Not present in the Go code: Go tells Unwrap() error and Unwrap() []error
+// apart by their types, which only exist at compile time in TS.
+function unwrap(err: Error): Error | Error[] | null {
+    let u = (err as { Unwrap?: unknown }).Unwrap
+    if (typeof u == "function") {
+        return u.call(err) ?? null
+    }
+    if (err.cause instanceof Error) {
+        return err.cause
+    }
+    return null
+}
+
+/**
+ * Unwrap returns the result of calling the Unwrap method on err, if err's
+ * type contains an Unwrap method returning error.
+ * Otherwise, Unwrap returns null.
+ *
+ * Unwrap only calls a method of the form "Unwrap(): Error".
+ * In particular Unwrap does not unwrap errors returned by [Join].
+ */
+export function Unwrap(err: Error | null): Error | null {
+    if (err == null) {
+        return null
+    }
+    let u = unwrap(err)
+    if (Array.isArray(u)) {
+        return null
+    }
+    return u
+}
+
+/**
+ * Is reports whether any error in err's tree matches target.
+ *
+ * The tree consists of err itself, followed by the errors obtained by repeatedly
+ * calling Unwrap. When err wraps multiple errors, Is examines err followed by a
+ * depth-first traversal of its children.
+ *
+ * An error is considered to match a target if it is equal to that target or if
+ * it implements a method Is(target: Error): boolean such that Is(target) returns true.
+ *
+ * An error type might provide an Is method so it can be treated as equivalent
+ * to an existing error. For example, if MyError defines
+ *
+ *	Is(target: Error): boolean { return target == fs.ErrExist }
+ *
+ * then Is(new MyError(), fs.ErrExist) returns true. An Is method should only
+ * shallowly compare err and the target and not call Unwrap on either.
+ */
+export function Is(err: Error | null, target: Error | null): boolean {
+    if (err == null || target == null) {
+        return err == target
+    }
+    return is(err, target)
+}
+
+function is(err: Error, target: Error): boolean {
+    while (true) { // for {}
+        if (err == target) {
+            return true
+        }
+        let x = err as { Is?: unknown }
+        if (typeof x.Is == "function" && x.Is(target)) {
+            return true
+        }
+        let u = unwrap(err)
+        if (Array.isArray(u)) {
+            for (let err of u) {
+                if (is(err, target)) {
+                    return true
+                }
+            }
+            return false
+        }
+        if (u == null) {
+            return false
+        }
+        err = u
+    }
+}
+
+/**
+ * As finds the first error in err's tree that is an instance of target, and if
+ * one is found, returns it. Otherwise, it returns null.
+ *
+ * The tree consists of err itself, followed by the errors obtained by repeatedly
+ * calling Unwrap. When err wraps multiple errors, As examines err followed by a
+ * depth-first traversal of its children.
+ *
+ * An error matches target if it is an instance of target, or if it has a method
+ * As(target) that returns a non-null value. In the latter case, the As method
+ * is responsible for the value it returns being an instance of target.
+ *
+ * An error type might provide an As method so it can be treated as if it were a
+ * different error type.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Go's As takes a pointer to the variable to set and returns a bool. Here target
+ * is the class to look for, and the error found is returned instead.
+ *
+ * @param err The error to examine
+ * @param target The class of the error to find
+ */
+export function As<T>(err: Error | null, target: abstract new (...args: any[]) => T): T | null {
+    if (err == null) {
+        return null
+    }
+    if (typeof target != "function") {
+        throw new Error("errors: target must be a class")
+    }
+    return as(err, target)
+}
+
+function as<T>(err: Error, target: abstract new (...args: any[]) => T): T | null {
+    while (true) { // for {}
+        if (err instanceof target) {
+            return err
+        }
+        let x = err as { As?: unknown }
+        if (typeof x.As == "function") {
+            let v = x.As(target)
+            if (v != null) {
+                return v
+            }
+        }
+        let u = unwrap(err)
+        if (Array.isArray(u)) {
+            for (let err of u) {
+                if (err == null) {
+                    continue
+                }
+                let v = as(err, target)
+                if (v != null) {
+                    return v
+                }
+            }
+            return null
+        }
+        if (u == null) {
+            return null
+        }
+        err = u
+    }
+}
+
+/**
+ * Join returns an error that wraps the given errors.
+ * Any null error values are discarded.
+ * Join returns null if every value in errs is null.
+ * The error formats as the concatenation of the strings obtained
+ * by calling the Error method of each element of errs, with a newline
+ * between each string.
+ *
+ * A non-null error returned by Join implements the Unwrap(): Error[] method.
+ */
+export function Join(...errs: (Error | null)[]): Error | null {
+    let e = errs.filter((err): err is Error => err != null)
+    if (e.length == 0) {
+        return null
+    }
+    return new joinError(e)
+}
+
+class joinError extends Error {
+    errs: Error[]
+
+    constructor(errs: Error[]) {
+        super(errs.map((err) => err.message).join("\n"))
+        this.errs = errs
+    }
+
+    Unwrap(): Error[] {
+        return this.errs
+    }
+}
+
+/**
+ * Errorf formats according to a format specifier and returns the string as a
+ * value that satisfies error.
+ *
+ * If the format specifier includes a %w verb with an error operand,
+ * the returned error will implement an Unwrap method returning the operand.
+ * If there is more than one %w verb, the returned error will implement an
+ * Unwrap method returning an Error[] containing all the %w operands in the
+ * order they appear in the arguments.
+ * It is invalid to supply the %w verb with an operand that does not implement
+ * the error interface. The %w verb is otherwise a synonym for %v.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * This is fmt.Errorf, as there is no fmt package. Only the verbs %v, %s, %d,
+ * %q, %x, %X, %t and %w are supported, without flags, width or precision.
+ *
+ * @param format The format specifier
+ * @param a The operands
+ */
+export function Errorf(format: string, ...a: unknown[]): Error {
+    let wrapped: number[] = []
+    let s = sprintf(format, a, wrapped)
+    switch (wrapped.length) {
+        case 0:
+            return New(s)
+        case 1:
+            return new wrapError(s, a[wrapped[0]] as Error)
+        default:
+            // Go sorts the operands into argument order, and the verbs already are
+            return new wrapErrors(s, wrapped.map((i) => a[i] as Error))
+    }
+}
+
+class wrapError extends Error {
+    err: Error
+
+    constructor(msg: string, err: Error) {
+        super(msg)
+        this.err = err
+    }
+
+    Unwrap(): Error {
+        return this.err
+    }
+}
+
+class wrapErrors extends Error {
+    errs: Error[]
+
+    constructor(msg: string, errs: Error[]) {
+        super(msg)
+        this.errs = errs
+    }
+
+    Unwrap(): Error[] {
+        return this.errs
+    }
+}
+
+// sprintf formats a with format, like fmt.Sprintf, appending the index of each
+// error operand of a %w verb to wrapped
+function sprintf(format: string, a: unknown[], wrapped: number[]): string {
+    let out = ""
+    let argNum = 0
+    for (let i = 0; i < format.length; i++) {
+        let c = format[i]
+        if (c != "%") {
+            out += c
+            continue
+        }
+        i++
+        if (i >= format.length) {
+            out += "%!(NOVERB)"
+            break
+        }
+        let verb = format[i]
+        if (verb == "%") {
+            out += "%"
+            continue
+        }
+        if (argNum >= a.length) {
+            out += `%!${verb}(MISSING)`
+            continue
+        }
+        let arg = a[argNum]
+        if (verb == "w") {
+            if (!(arg instanceof Error)) {
+                out += formatValue(arg, verb)
+                argNum++
+                continue
+            }
+            wrapped.push(argNum)
+            verb = "v"
+        }
+        out += formatValue(arg, verb)
+        argNum++
+    }
+    if (argNum < a.length) {
+        out += "%!(EXTRA " + a.slice(argNum).map((arg) => `${typeName(arg)}=${formatValue(arg, "v")}`).join(", ") + ")"
+    }
+    return out
+}
+
+// typeName returns a description of the type of v, for errors in a format
+function typeName(v: unknown): string {
+    if (v == null) {
+        return "<nil>"
+    }
+    if (typeof v == "object") {
+        return v.constructor?.name ?? "object"
+    }
+    return typeof v
+}
+
+// formatValue formats v according to verb
+function formatValue(v: unknown, verb: string): string {
+    if (v == null) {
+        return verb == "v" || verb == "s" ? "<nil>" : `%!${verb}(<nil>)`
+    }
+    switch (verb) {
+        case "v":
+        case "s":
+            if (v instanceof Error) {
+                return v.message
+            }
+            if (v instanceof Uint8Array) {
+                return verb == "s" ? new TextDecoder().decode(v) : `[${v.join(" ")}]`
+            }
+            if (Array.isArray(v)) {
+                return `[${v.map((e) => formatValue(e, verb)).join(" ")}]`
+            }
+            if (typeof (v as { String?: unknown }).String == "function") {
+                return (v as { String(): string }).String()
+            }
+            return String(v)
+        case "d":
+            if (typeof v == "number" || typeof v == "bigint") {
+                return v.toString()
+            }
+            break
+        case "q":
+            if (typeof v == "string") {
+                return quote(new TextEncoder().encode(v))
+            }
+            if (v instanceof Uint8Array) {
+                return quote(v)
+            }
+            break
+        case "x":
+        case "X": {
+            let s: string | undefined
+            if (typeof v == "number" || typeof v == "bigint") {
+                s = v.toString(16)
+            } else if (typeof v == "string") {
+                s = Array.from(new TextEncoder().encode(v), (b) => b.toString(16).padStart(2, "0")).join("")
+            } else if (v instanceof Uint8Array) {
+                s = Array.from(v, (b) => b.toString(16).padStart(2, "0")).join("")
+            }
+            if (s != undefined) {
+                return verb == "X" ? s.toUpperCase() : s
+            }
+            break
+        }
+        case "t":
+            if (typeof v == "boolean") {
+                return String(v)
+            }
+            break
+    }
+    return `%!${verb}(${typeName(v)}=${formatValue(v, "v")})`
+}
+
+// quote returns a double-quoted Go string literal representing the UTF-8 in s,
+// like strconv.Quote. Control characters, non-printable runes and invalid
+// bytes are escaped.
+function quote(s: Uint8Array): string {
+    let out = '"'
+    while (s.length > 0) {
+        let [r, width] = utf8.DecodeRune(s)
+        if (width == 1 && r == utf8.RuneError) {
+            out += "\\x" + hex(s[0], 2)
+        } else {
+            out += escapeRune(r)
+        }
+        s = s.subarray(width)
+    }
+    return out + '"'
+}
+
+// escapeRune returns r as it appears inside a double-quoted Go string literal
+function escapeRune(r: number): string {
+    if (r == 0x22 || r == 0x5c) { // '"' or '\\'
+        return "\\" + String.fromCodePoint(r)
+    }
+    if (isPrint(r)) {
+        return String.fromCodePoint(r)
+    }
+    switch (r) {
+        case 0x07:
+            return "\\a"
+        case 0x08:
+            return "\\b"
+        case 0x0c:
+            return "\\f"
+        case 0x0a:
+            return "\\n"
+        case 0x0d:
+            return "\\r"
+        case 0x09:
+            return "\\t"
+        case 0x0b:
+            return "\\v"
+    }
+    if (r < 0x20 || r == 0x7f) {
+        return "\\x" + hex(r, 2)
+    }
+    if (r < 0x10000) {
+        return "\\u" + hex(r, 4)
+    }
+    return "\\U" + hex(r, 8)
+}
+
+// isPrint reports whether r is printable, like unicode.IsPrint: a letter,
+// mark, number, punctuation, symbol or the ASCII space.
+function isPrint(r: number): boolean {
+    return r == 0x20 || /[\p{L}\p{M}\p{N}\p{P}\p{S}]/u.test(String.fromCodePoint(r))
+}
+
+// hex formats v in lowercase hexadecimal, zero-padded to n digits
+function hex(v: number, n: number): string {
+    return v.toString(16).padStart(n, "0")
+}
@@ -0,0 +1,165 @@
+// A minimal port of Go's image/color package
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/color/color.go
+
+/**
+ * color.Color from Golang
+ *
+ * Color can convert itself to alpha-premultiplied 16-bits per channel RGBA.
+ * The conversion may be lossy.
+ */
+export interface Color {
+    // RGBA returns the alpha-premultiplied red, green, blue and alpha values
+    // for the color. Each value ranges within [0, 0xffff]
+    RGBA(): [number, number, number, number] // uint32 each
+}
+
+/**
+ * color.RGBA from Golang
+ *
+ * RGBA represents a traditional 32-bit alpha-premultiplied color, having 8
+ * bits for each of red, green, blue and alpha.
+ *
+ * An alpha-premultiplied color component C has been scaled by alpha (A), so
+ * has valid values 0 <= C <= A.
+ */
+export class RGBA implements Color {
+    R: number // uint8
+    G: number // uint8
+    B: number // uint8
+    A: number // uint8
+
+    constructor(r: number = 0, g: number = 0, b: number = 0, a: number = 0) {
+        this.R = r
+        this.G = g
+        this.B = b
+        this.A = a
+    }
+
+    RGBA(): [number, number, number, number] {
+        let r = this.R
+        r |= r << 8
+        let g = this.G
+        g |= g << 8
+        let b = this.B
+        b |= b << 8
+        let a = this.A
+        a |= a << 8
+        return [r, g, b, a]
+    }
+}
+
+/**
+ * color.Palette from Golang
+ *
+ * Palette is a palette of colors.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Palette is a plain array, so Go's Palette.Convert and Palette.Index methods are
+ * the PaletteConvert and PaletteIndex functions
+ */
+export type Palette = Color[]
+
+/**
+ * PaletteConvert returns the palette color closest to c in Euclidean R,G,B space.
+ */
+export function PaletteConvert(p: Palette, c: Color): Color | null {
+    if (p.length == 0) {
+        return null
+    }
+    return p[PaletteIndex(p, c)]
+}
+
+/**
+ * PaletteIndex returns the index of the palette color closest to c in Euclidean
+ * R,G,B,A space.
+ */
+export function PaletteIndex(p: Palette, c: Color): number {
+    let [cr, cg, cb, ca] = c.RGBA()
+    let [ret, bestSum] = [0, 0xffffffff]
+    for (let i = 0; i < p.length; i++) {
+        let [vr, vg, vb, va] = p[i].RGBA()
+        let sum = sqDiff(cr, vr) + sqDiff(cg, vg) + sqDiff(cb, vb) + sqDiff(ca, va)
+        if (sum < bestSum) {
+            if (sum == 0) {
+                return i
+            }
+            [ret, bestSum] = [i, sum]
+        }
+    }
+    return ret
+}
+
+// sqDiff returns the squared-difference of x and y, shifted by 2 so that
+// adding four of those won't overflow a uint32.
+//
+// x and y are both assumed to be in the range [0, 0xffff].
+function sqDiff(x: number, y: number): number {
+    let d = x - y
+    return (d * d) >>> 2
+}
+
+/**
+ * color.RGBA64 from Golang
+ *
+ * RGBA64 represents a 64-bit alpha-premultiplied color, having 16 bits for
+ * each of red, green, blue and alpha.
+ *
+ * An alpha-premultiplied color component C has been scaled by alpha (A), so
+ * has valid values 0 <= C <= A.
+ */
+export class RGBA64 implements Color {
+    R: number // uint16
+    G: number // uint16
+    B: number // uint16
+    A: number // uint16
+
+    constructor(r: number = 0, g: number = 0, b: number = 0, a: number = 0) {
+        this.R = r
+        this.G = g
+        this.B = b
+        this.A = a
+    }
+
+    RGBA(): [number, number, number, number] {
+        return [this.R, this.G, this.B, this.A]
+    }
+}
+
+/**
+ * color.NRGBA from Golang
+ *
+ * NRGBA represents a non-alpha-premultiplied 32-bit color, as found in the
+ * data of a canvas ImageData.
+ */
+export class NRGBA implements Color {
+    R: number // uint8
+    G: number // uint8
+    B: number // uint8
+    A: number // uint8
+
+    constructor(r: number = 0, g: number = 0, b: number = 0, a: number = 0) {
+        this.R = r
+        this.G = g
+        this.B = b
+        this.A = a
+    }
+
+    RGBA(): [number, number, number, number] {
+        let r = this.R
+        r |= r << 8
+        r *= this.A
+        r = Math.floor(r / 0xff)
+        let g = this.G
+        g |= g << 8
+        g *= this.A
+        g = Math.floor(g / 0xff)
+        let b = this.B
+        b |= b << 8
+        b *= this.A
+        b = Math.floor(b / 0xff)
+        let a = this.A
+        a |= a << 8
+        return [r, g, b, a]
+    }
+}
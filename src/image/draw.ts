@@ -0,0 +1,380 @@
+// A minimal port of Go's image/draw package, covering what image/gif needs
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/draw/draw.go
+import { Color, Palette, RGBA as RGBAColor, RGBA64 } from "./color"
+import { Image as BaseImage, NRGBA, Paletted, Point, RGBA, Rectangle } from "./index"
+
+/**
+ * draw.Image from Golang
+ *
+ * Image is an image.Image with a Set method to change a single pixel.
+ */
+export interface Image extends BaseImage {
+    Set(x: number, y: number, c: Color): void
+}
+
+/**
+ * draw.Quantizer from Golang
+ *
+ * Quantizer produces a palette for an image.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Arrays have no capacity, so the maximum size of the returned palette is passed as cap
+ */
+export interface Quantizer {
+    // Quantize appends up to cap - len(p) colors to p and returns the
+    // updated palette suitable for converting m to a paletted image.
+    Quantize(p: Palette, m: BaseImage, cap: number): Palette
+}
+
+/**
+ * draw.Drawer from Golang
+ *
+ * Drawer contains the Draw method.
+ */
+export interface Drawer {
+    // Draw aligns r.Min in dst with sp in src and then replaces the
+    // rectangle r in dst with the result of drawing src on dst.
+    Draw(dst: Image, r: Rectangle, src: BaseImage, sp: Point): void
+}
+
+/**
+ * Src is a Drawer that replaces the destination pixels with the source pixels,
+ * picking the closest palette color for a Paletted destination.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Go's Src is an Op. Ops and the Over operator are not ported
+ */
+export const Src: Drawer = {
+    Draw(dst: Image, r: Rectangle, src: BaseImage, sp: Point) {
+        [r, sp] = clip(dst, r, src, sp)
+        if (r.Empty()) {
+            return
+        }
+        if (dst instanceof Paletted) {
+            drawPaletted(dst, r, src, sp, false)
+            return
+        }
+        // Not present in the Go code, which has fast paths for every pair of image types
+        for (let y = 0; y != r.Dy(); y++) {
+            for (let x = 0; x != r.Dx(); x++) {
+                dst.Set(r.Min.X + x, r.Min.Y + y, src.At(sp.X + x, sp.Y + y)!)
+            }
+        }
+    }
+}
+
+/**
+ * FloydSteinberg is a Drawer that is the Src Op with Floyd-Steinberg error
+ * diffusion.
+ */
+export const FloydSteinberg: Drawer = {
+    Draw(dst: Image, r: Rectangle, src: BaseImage, sp: Point) {
+        [r, sp] = clip(dst, r, src, sp)
+        if (r.Empty()) {
+            return
+        }
+        drawPaletted(dst, r, src, sp, true)
+    }
+}
+
+// clip clips r against each image's bounds (after translating into the
+// destination image's coordinate space) and shifts the point sp by
+// the same amount as the change in r.Min.
+//
+// Not present in the Go code: r and sp are returned rather than updated in place
+function clip(dst: Image, r: Rectangle, src: BaseImage, sp: Point): [Rectangle, Point] {
+    let orig = r.Min
+    r = r.Intersect(dst.Bounds())
+    r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+    let dx = r.Min.X - orig.X
+    let dy = r.Min.Y - orig.Y
+    if (dx == 0 && dy == 0) {
+        return [r, sp]
+    }
+    return [r, new Point(sp.X + dx, sp.Y + dy)]
+}
+
+// clamp clamps i to the interval [0, 0xffff].
+function clamp(i: number): number {
+    if (i < 0) {
+        return 0
+    }
+    if (i > 0xffff) {
+        return 0xffff
+    }
+    return i
+}
+
+// sqDiff returns the squared-difference of x and y, shifted by 2 so that
+// adding four of those won't overflow a uint32.
+//
+// x and y are both assumed to be in the range [0, 0xffff].
+function sqDiff(x: number, y: number): number {
+    let d = x - y
+    return (d * d) >>> 2
+}
+
+function drawPaletted(dst: Image, r: Rectangle, src: BaseImage, sp: Point, floydSteinberg: boolean) {
+    // TODO(nigeltao): handle the case where the dst and src overlap.
+    // Does it even make sense to try and do Floyd-Steinberg whilst
+    // walking the image backward (right-to-left bottom-to-top)?
+
+    // If dst is an *image.Paletted, we have a fast path for dst.Set and
+    // dst.At. The dst.Set equivalent is a batch version of the algorithm
+    // used by color.Palette's Index method in image/color/color.go, plus
+    // optional Floyd-Steinberg error diffusion.
+    let palette: Int32Array[] | null = null, pix: Uint8Array | null = null, stride = 0
+    if (dst instanceof Paletted) {
+        palette = new Array(dst.Palette.length)
+        for (let i = 0; i < dst.Palette.length; i++) {
+            palette[i] = Int32Array.from(dst.Palette[i].RGBA())
+        }
+        pix = dst.Pix.subarray(dst.PixOffset(r.Min.X, r.Min.Y))
+        stride = dst.Stride
+    }
+
+    // quantErrorCurr and quantErrorNext are the Floyd-Steinberg quantization
+    // errors that have been propagated to the pixels in the current and next
+    // rows. The +2 simplifies calculation near the edges.
+    //
+    // Not present in the Go code: each row is flattened to 4 int32s per pixel
+    let quantErrorCurr = new Int32Array(0), quantErrorNext = new Int32Array(0)
+    if (floydSteinberg) {
+        quantErrorCurr = new Int32Array(4 * (r.Dx() + 2))
+        quantErrorNext = new Int32Array(4 * (r.Dx() + 2))
+    }
+    let pxRGBA = (x: number, y: number) => src.At(x, y)!.RGBA()
+    // Fast paths for special cases to avoid excessive use of the color.Color
+    // interface which escapes to the heap but need to be discovered for
+    // each pixel on r. See also https://golang.org/issues/15759.
+    if (src instanceof RGBA) {
+        pxRGBA = (x, y) => src.RGBAAt(x, y).RGBA()
+    } else if (src instanceof NRGBA) {
+        pxRGBA = (x, y) => src.NRGBAAt(x, y).RGBA()
+    }
+
+    // Loop over each source pixel.
+    let out = new RGBA64(0, 0, 0, 0xffff)
+    for (let y = 0; y != r.Dy(); y++) {
+        for (let x = 0; x != r.Dx(); x++) {
+            // er, eg and eb are the pixel's R,G,B values plus the
+            // optional Floyd-Steinberg error.
+            let [sr, sg, sb, sa] = pxRGBA(sp.X + x, sp.Y + y)
+            let er = sr, eg = sg, eb = sb, ea = sa
+            if (floydSteinberg) {
+                let e = 4 * (x + 1)
+                er = clamp(er + Math.trunc(quantErrorCurr[e + 0] / 16))
+                eg = clamp(eg + Math.trunc(quantErrorCurr[e + 1] / 16))
+                eb = clamp(eb + Math.trunc(quantErrorCurr[e + 2] / 16))
+                ea = clamp(ea + Math.trunc(quantErrorCurr[e + 3] / 16))
+            }
+
+            if (palette != null) {
+                // Find the closest palette color in Euclidean R,G,B,A space:
+                // the one that minimizes sum-squared-difference.
+                // TODO(nigeltao): consider smarter algorithms.
+                let bestIndex = 0, bestSum = 0xffffffff
+                for (let index = 0; index < palette.length; index++) {
+                    let p = palette[index]
+                    let sum = sqDiff(er, p[0]) + sqDiff(eg, p[1]) + sqDiff(eb, p[2]) + sqDiff(ea, p[3])
+                    if (sum < bestSum) {
+                        bestIndex = index
+                        bestSum = sum
+                        if (sum == 0) {
+                            break
+                        }
+                    }
+                }
+                pix![y * stride + x] = bestIndex
+
+                if (!floydSteinberg) {
+                    continue
+                }
+                er -= palette[bestIndex][0]
+                eg -= palette[bestIndex][1]
+                eb -= palette[bestIndex][2]
+                ea -= palette[bestIndex][3]
+
+            } else {
+                out.R = er & 0xffff
+                out.G = eg & 0xffff
+                out.B = eb & 0xffff
+                out.A = ea & 0xffff
+                dst.Set(r.Min.X + x, r.Min.Y + y, out)
+
+                if (!floydSteinberg) {
+                    continue
+                }
+                [sr, sg, sb, sa] = dst.At(r.Min.X + x, r.Min.Y + y)!.RGBA()
+                er -= sr
+                eg -= sg
+                eb -= sb
+                ea -= sa
+            }
+
+            // Propagate the Floyd-Steinberg quantization error.
+            let e = 4 * x
+            quantErrorNext[e + 0] += er * 3
+            quantErrorNext[e + 1] += eg * 3
+            quantErrorNext[e + 2] += eb * 3
+            quantErrorNext[e + 3] += ea * 3
+            quantErrorNext[e + 4] += er * 5
+            quantErrorNext[e + 5] += eg * 5
+            quantErrorNext[e + 6] += eb * 5
+            quantErrorNext[e + 7] += ea * 5
+            quantErrorNext[e + 8] += er * 1
+            quantErrorNext[e + 9] += eg * 1
+            quantErrorNext[e + 10] += eb * 1
+            quantErrorNext[e + 11] += ea * 1
+            quantErrorCurr[e + 8] += er * 7
+            quantErrorCurr[e + 9] += eg * 7
+            quantErrorCurr[e + 10] += eb * 7
+            quantErrorCurr[e + 11] += ea * 7
+        }
+
+        // Recycle the quantization error buffers.
+        if (floydSteinberg) {
+            [quantErrorCurr, quantErrorNext] = [quantErrorNext, quantErrorCurr]
+            quantErrorNext.fill(0)
+        }
+    }
+}
+
+// Not present in the Go code, which leaves quantization to the caller
+
+// colorBox is a box of the RGBA color space used by MedianCut. It holds the
+// distinct 8-bit premultiplied colors that fall inside it, packed as R<<24|G<<16|B<<8|A,
+// together with the number of pixels of each color.
+class colorBox {
+    colors: Uint32Array
+    counts: Uint32Array
+    pixels: number = 0 // total of counts
+    channel: number = 0 // channel with the widest range, 0 (R) to 3 (A)
+    range: number = 0 // width of that range
+
+    constructor(colors: Uint32Array, counts: Uint32Array) {
+        this.colors = colors
+        this.counts = counts
+        for (let i = 0; i < counts.length; i++) {
+            this.pixels += counts[i]
+        }
+
+        for (let c = 0; c < 4; c++) {
+            let shift = 24 - 8 * c
+            let lo = 0xff, hi = 0
+            for (let i = 0; i < colors.length; i++) {
+                let v = (colors[i] >>> shift) & 0xff
+                lo = Math.min(lo, v)
+                hi = Math.max(hi, v)
+            }
+            if (hi - lo > this.range) {
+                this.range = hi - lo
+                this.channel = c
+            }
+        }
+    }
+
+    // split divides the box at the pixel-weighted median of its widest channel.
+    // Both halves are non-empty, so the box must hold at least two colors.
+    split(): [colorBox, colorBox] {
+        let shift = 24 - 8 * this.channel
+        let order = Array.from(this.colors.keys())
+        order.sort((a, b) => ((this.colors[a] >>> shift) & 0xff) - ((this.colors[b] >>> shift) & 0xff))
+
+        let colors = Uint32Array.from(order, (i) => this.colors[i])
+        let counts = Uint32Array.from(order, (i) => this.counts[i])
+
+        let mid = 1, seen = counts[0]
+        while (mid < counts.length - 1 && seen + counts[mid] <= this.pixels / 2) {
+            seen += counts[mid]
+            mid++
+        }
+
+        return [
+            new colorBox(colors.subarray(0, mid), counts.subarray(0, mid)),
+            new colorBox(colors.subarray(mid), counts.subarray(mid)),
+        ]
+    }
+
+    // average returns the pixel-weighted mean color of the box.
+    average(): Color {
+        let sum = [0, 0, 0, 0]
+        for (let i = 0; i < this.colors.length; i++) {
+            for (let c = 0; c < 4; c++) {
+                sum[c] += ((this.colors[i] >>> (24 - 8 * c)) & 0xff) * this.counts[i]
+            }
+        }
+        let [r, g, b, a] = sum.map((s) => Math.round(s / this.pixels))
+        return new RGBAColor(r, g, b, a)
+    }
+}
+
+/**
+ * MedianCut is a Quantizer that builds a palette with Heckbert's median cut
+ * algorithm. The box that is split next is the one with the largest product of
+ * its pixel count and its widest channel range, so that large flat areas and
+ * small but varied details both get colors. If the image has fully transparent
+ * pixels, one entry is reserved for them.
+ *
+ * Images with no more distinct colors than the palette has room for are
+ * reproduced exactly.
+ */
+export const MedianCut: Quantizer = {
+    Quantize(p: Palette, m: BaseImage, cap: number): Palette {
+        let n = cap - p.length
+        if (n <= 0) {
+            return p
+        }
+
+        let histogram = new Map<number, number>()
+        let b = m.Bounds()
+        for (let y = b.Min.Y; y < b.Max.Y; y++) {
+            for (let x = b.Min.X; x < b.Max.X; x++) {
+                let c = m.At(x, y)
+                if (c == null) {
+                    continue
+                }
+                let [r, g, bl, a] = c.RGBA()
+                let key = ((r >>> 8) << 24 | (g >>> 8) << 16 | (bl >>> 8) << 8 | (a >>> 8)) >>> 0
+                histogram.set(key, (histogram.get(key) ?? 0) + 1)
+            }
+        }
+
+        // Fully transparent pixels are all 0 once premultiplied. They get an entry of
+        // their own rather than being averaged into opaque colors, as GIF can only
+        // represent them with a palette entry that has zero alpha
+        if (histogram.has(0) && (histogram.size == 1 || n > 1)) {
+            histogram.delete(0)
+            p = p.concat([new RGBAColor(0, 0, 0, 0)])
+            n--
+        }
+        if (histogram.size == 0) {
+            return p
+        }
+
+        let boxes = [new colorBox(Uint32Array.from(histogram.keys()), Uint32Array.from(histogram.values()))]
+        while (boxes.length < n) {
+            let best = -1, bestScore = 0
+            for (let i = 0; i < boxes.length; i++) {
+                let box = boxes[i]
+                if (box.colors.length < 2) {
+                    continue
+                }
+                let score = box.pixels * box.range
+                if (score > bestScore) {
+                    best = i
+                    bestScore = score
+                }
+            }
+            if (best < 0) {
+                // Every box holds a single color
+                break
+            }
+            boxes.splice(best, 1, ...boxes[best].split())
+        }
+
+        return p.concat(boxes.map((box) => box.average()))
+    }
+}
@@ -0,0 +1,201 @@
+// Tests for image/draw
+//
+// TestFloydSteinbergCheckerboard is taken from
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/draw/draw_test.go.
+// The other tests are not present in the Go code, which has no MedianCut.
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { Color, Palette, RGBA as RGBAColor, RGBA64 } from "./color"
+import { FloydSteinberg, MedianCut, Src } from "./draw"
+import { Decode } from "./gif"
+import { Image, NewPaletted, NewRGBA, Point, Rect, Rectangle, RGBA } from "./index"
+
+// uniform is an infinite-sized Image of uniform color, like Go's image.Uniform
+class uniform implements Image {
+    C: Color
+
+    constructor(c: Color) {
+        this.C = c
+    }
+
+    Bounds(): Rectangle {
+        return Rect(-1e9, -1e9, 1e9, 1e9)
+    }
+
+    At(_x: number, _y: number): Color {
+        return this.C
+    }
+}
+
+// video001 returns testdata/video-001.gif as an RGBA image
+function video001(): RGBA {
+    let [m, err] = Decode(new GoBuffer(new Uint8Array(fs.readFileSync("src/image/testdata/video-001.gif"))))
+    assert.equal(err, null)
+    let b = m!.Bounds()
+    let rgba = NewRGBA(b)
+    Src.Draw(rgba, b, m!, b.Min)
+    return rgba
+}
+
+// averageDelta returns the average delta in RGB space, as in the image/gif tests.
+// The two images must have the same bounds.
+function averageDelta(m0: Image, m1: Image): number {
+    let b = m0.Bounds()
+    let sum = 0, n = 0
+    for (let y = b.Min.Y; y < b.Max.Y; y++) {
+        for (let x = b.Min.X; x < b.Max.X; x++) {
+            let [r0, g0, b0] = m0.At(x, y)!.RGBA()
+            let [r1, g1, b1] = m1.At(x, y)!.RGBA()
+            sum += Math.abs(r0 - r1) + Math.abs(g0 - g1) + Math.abs(b0 - b1)
+            n += 3
+        }
+    }
+    return Math.trunc(sum / n)
+}
+
+// key packs the 8-bit RGBA values of c, to compare colors
+function key(c: Color): string {
+    return c.RGBA().map((v) => v >>> 8).join(",")
+}
+
+test("TestFloydSteinbergCheckerboard", () => {
+    let b = Rect(0, 0, 640, 480)
+    // We can't represent 50% exactly, but 0x7fff / 0xffff is close enough.
+    let src = new uniform(new RGBA64(0x7fff, 0x7fff, 0x7fff, 0xffff))
+    let dst = NewPaletted(b, [new RGBAColor(0, 0, 0, 0xff), new RGBAColor(0xff, 0xff, 0xff, 0xff)])
+    FloydSteinberg.Draw(dst, b, src, new Point())
+    for (let y = b.Min.Y; y < b.Max.Y; y++) {
+        for (let x = b.Min.X; x < b.Max.X; x++) {
+            let got = dst.Pix[dst.PixOffset(x, y)]
+            let want = (x + y) % 2
+            assert.equal(got, want, `at (${x}, ${y})`)
+        }
+    }
+})
+
+test("TestSrcPaletted", () => {
+    // Without error diffusion every pixel gets the closest color, here black
+    let b = Rect(0, 0, 8, 8)
+    let src = new uniform(new RGBA64(0x7fff, 0x7fff, 0x7fff, 0xffff))
+    let dst = NewPaletted(b, [new RGBAColor(0, 0, 0, 0xff), new RGBAColor(0xff, 0xff, 0xff, 0xff)])
+    dst.Pix.fill(1)
+    Src.Draw(dst, b, src, new Point())
+    assert.deepEqual(dst.Pix, new Uint8Array(64))
+})
+
+test("TestDrawClip", () => {
+    // Only the part of r inside dst and, once aligned, src is drawn
+    let src = NewRGBA(Rect(0, 0, 4, 4))
+    for (let y = 0; y < 4; y++) {
+        for (let x = 0; x < 4; x++) {
+            src.Set(x, y, new RGBAColor(0x10 * x, 0x10 * y, 0, 0xff))
+        }
+    }
+    for (let drawer of [Src, FloydSteinberg]) {
+        let dst = NewRGBA(Rect(0, 0, 4, 4))
+        drawer.Draw(dst, Rect(-1, 1, 3, 8), src, new Point(0, 0))
+        for (let y = 0; y < 4; y++) {
+            for (let x = 0; x < 4; x++) {
+                let want = new RGBAColor()
+                if (x < 3 && y >= 1) {
+                    // r.Min (-1, 1) is aligned with (0, 0) in src
+                    want = new RGBAColor(0x10 * (x + 1), 0x10 * (y - 1), 0, 0xff)
+                }
+                assert.deepEqual(dst.At(x, y), want, `at (${x}, ${y})`)
+            }
+        }
+    }
+})
+
+test("TestFloydSteinbergNonPaletted", () => {
+    // A destination that can hold every source color exactly leaves no error
+    // to diffuse
+    let src = video001()
+    let b = src.Bounds()
+    let dst = NewRGBA(b)
+    FloydSteinberg.Draw(dst, b, src, b.Min)
+    assert.equal(averageDelta(src, dst), 0)
+})
+
+test("TestMedianCutExact", () => {
+    // Images with no more colors than there is room for keep all of them
+    let colors = [
+        new RGBAColor(0xff, 0x00, 0x00, 0xff),
+        new RGBAColor(0x00, 0xff, 0x00, 0xff),
+        new RGBAColor(0x00, 0x00, 0xff, 0xff),
+        new RGBAColor(0x80, 0x80, 0x80, 0xff),
+    ]
+    let m = NewRGBA(Rect(0, 0, 10, 10))
+    for (let y = 0; y < 10; y++) {
+        for (let x = 0; x < 10; x++) {
+            m.Set(x, y, colors[(x * y) % colors.length])
+        }
+    }
+    for (let cap of [4, 5, 256]) {
+        let p = MedianCut.Quantize([], m, cap)
+        assert.deepEqual(p.map(key).sort(), colors.map(key).sort(), `cap=${cap}`)
+
+        let pm = NewPaletted(m.Bounds(), p)
+        FloydSteinberg.Draw(pm, m.Bounds(), m, new Point())
+        assert.equal(averageDelta(m, pm), 0, `cap=${cap}`)
+    }
+})
+
+test("TestMedianCutCap", () => {
+    let m = video001()
+    let prefix: Palette = [new RGBAColor(0, 0, 0, 0xff), new RGBAColor(0xff, 0xff, 0xff, 0xff)]
+    for (let cap of [1, 2, 16, 64]) {
+        let p = MedianCut.Quantize(prefix, m, cap)
+        // The colors already in p are kept, and never exceeded
+        assert.equal(p.length, Math.max(cap, prefix.length), `cap=${cap}`)
+        assert.deepEqual(p.slice(0, 2), prefix, `cap=${cap}`)
+    }
+    assert.equal(MedianCut.Quantize(prefix, m, 2), prefix)
+
+    // The palette doesn't depend on the colors already in p
+    let a = MedianCut.Quantize([], m, 16)
+    let b = MedianCut.Quantize(prefix, m, 18)
+    assert.deepEqual(b.slice(2), a)
+
+    // An empty image adds no colors
+    assert.deepEqual(MedianCut.Quantize([], NewRGBA(new Rectangle()), 16), [])
+})
+
+test("TestMedianCutQuality", () => {
+    // The more colors, the closer the dithered image gets to the original
+    let m = video001()
+    let b = m.Bounds()
+    let last = Infinity
+    for (let cap of [4, 16, 64, 256]) {
+        let pm = NewPaletted(b, MedianCut.Quantize([], m, cap))
+        FloydSteinberg.Draw(pm, b, m, b.Min)
+        let d = averageDelta(m, pm)
+        assert.ok(d < last, `cap=${cap}: average delta ${d}, not less than ${last} with fewer colors`)
+        last = d
+    }
+    assert.equal(last, 0, "video-001.gif has at most 256 colors")
+})
+
+test("TestMedianCutTransparent", () => {
+    // Fully transparent pixels get an entry of their own
+    let m = video001()
+    let b = m.Bounds()
+    for (let x = b.Min.X; x < b.Max.X; x++) {
+        m.Set(x, b.Min.Y, new RGBAColor())
+    }
+    let p = MedianCut.Quantize([], m, 8)
+    assert.equal(p.length, 8)
+    assert.equal(p.filter((c) => c.RGBA()[3] == 0).length, 1)
+    assert.deepEqual(p[0], new RGBAColor())
+
+    // Unless there is only room for one color
+    p = MedianCut.Quantize([], m, 1)
+    assert.equal(p.length, 1)
+    assert.notEqual(p[0].RGBA()[3], 0)
+
+    // An image that is all transparent gets just that entry
+    let t = NewRGBA(Rect(0, 0, 4, 4))
+    assert.deepEqual(MedianCut.Quantize([], t, 16), [new RGBAColor()])
+})
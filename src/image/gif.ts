@@ -0,0 +1,1212 @@
+// Decodes and encodes GIF images
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/gif/reader.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/gif/writer.go
+//
+// The GIF specification is at https://www.w3.org/Graphics/GIF/spec-gif89a.txt.
+//
+// When decoding untrusted input, read dimensions with DecodeConfig before
+// calling Decode or DecodeAll.
+import { BufferedByteReader } from "../builtins/tshelpers/bufferedbytereader"
+import { BufferedWriter } from "../builtins/tshelpers/bufferedwriter"
+import { LZWReader, LZWWriter, Order } from "../compress/lzw"
+import * as errors from "../errors"
+import { ByteReader, ByteWriter, Errors as IOErrors, isByteReader, isByteWriter, ReadFull, Reader, Writer } from "../io"
+import { Config, Image, NewPaletted, Paletted, Point, Rectangle } from "./index"
+import { Palette, PaletteConvert, RGBA } from "./color"
+import { Drawer, FloydSteinberg, MedianCut, Quantizer } from "./draw"
+
+const errNotEnough = errors.New("gif: not enough image data")
+const errTooMuch = errors.New("gif: too much image data")
+const errBadPixel = errors.New("gif: invalid pixel value")
+
+// If the io.Reader does not also have ReadByte, then decode will introduce its own buffering.
+type reader = Reader & ByteReader
+
+// Masks etc.
+
+// Fields.
+const fColorTable = 1 << 7
+const fInterlace = 1 << 6
+const fColorTableBitsMask = 7
+
+// Graphic control flags.
+const gcTransparentColorSet = 1 << 0
+const gcDisposalMethodMask = 7 << 2
+
+// Disposal Methods.
+export const DisposalNone = 0x01
+export const DisposalBackground = 0x02
+export const DisposalPrevious = 0x03
+
+// Section indicators.
+const sExtension = 0x21
+const sImageDescriptor = 0x2C
+const sTrailer = 0x3B
+
+// Extensions.
+const eText = 0x01 // Plain Text
+const eGraphicControl = 0xF9 // Graphic Control
+const eComment = 0xFE // Comment
+const eApplication = 0xFF // Application
+
+// readFull reads exactly b.length bytes from r, turning an EOF into an unexpected EOF.
+function readFull(r: Reader, b: Uint8Array): Error | null {
+    let [, err] = ReadFull(r, b)
+    if (err == IOErrors.EOF) {
+        err = IOErrors.UnexpectedEOF
+    }
+    return err
+}
+
+function readByte(r: ByteReader): [number, Error | null] {
+    let [b, err] = r.ReadByte()
+    if (err == IOErrors.EOF) {
+        err = IOErrors.UnexpectedEOF
+    }
+    return [b, err]
+}
+
+// decoder is the type used to decode a GIF file.
+class decoder {
+    r!: reader // set in decode
+
+    // From header.
+    vers: string = ""
+    width: number = 0
+    height: number = 0
+    loopCount: number = 0
+    delayTime: number = 0
+    backgroundIndex: number = 0 // byte
+    disposalMethod: number = 0 // byte
+
+    // From image descriptor.
+    imageFields: number = 0 // byte
+
+    // From graphics control.
+    transparentIndex: number = 0 // byte
+    hasTransparentIndex: boolean = false
+
+    // Computed.
+    globalColorTable: Palette | null = null
+
+    // Used when decoding.
+    delay: number[] = []
+    disposal: number[] = []
+    image: Paletted[] = []
+    tmp: Uint8Array = new Uint8Array(1024) // must be at least 768 so we can read color table
+
+    // Not present in the Go code
+    //
+    // comments holds the text of each comment extension, in order
+    comments: string[] = []
+
+    // Not present in the Go code
+    //
+    // lzwr is reused across frames, so that its tables are only allocated once
+    lzwr: LZWReader | undefined
+
+    // decode reads a GIF image from r and stores the result in d.
+    decode(r: Reader, configOnly: boolean, keepAllFrames: boolean): Error | null {
+        // Add buffering if r does not provide ReadByte.
+        if (isByteReader(r)) {
+            this.r = r
+        } else {
+            this.r = new BufferedByteReader(r)
+        }
+
+        this.loopCount = -1
+
+        let err = this.readHeaderAndScreenDescriptor()
+        if (err) {
+            return err
+        }
+        if (configOnly) {
+            return null
+        }
+
+        while (true) /* for */ {
+            let [c, err] = readByte(this.r)
+            if (err) {
+                return new Error(`gif: reading frames: ${err.message}`)
+            }
+
+            switch (c) {
+                case sExtension:
+                    err = this.readExtension()
+                    if (err) {
+                        return err
+                    }
+                    break
+
+                case sImageDescriptor:
+                    err = this.readImageDescriptor(keepAllFrames)
+                    if (err) {
+                        return err
+                    }
+
+                    if (!keepAllFrames && this.image.length == 1) {
+                        return null
+                    }
+                    break
+
+                case sTrailer:
+                    if (this.image.length == 0) {
+                        return new Error("gif: missing image data")
+                    }
+                    return null
+
+                default:
+                    return new Error(`gif: unknown block type: 0x${hex2(c)}`)
+            }
+        }
+    }
+
+    readHeaderAndScreenDescriptor(): Error | null {
+        let err = readFull(this.r, this.tmp.subarray(0, 13))
+        if (err) {
+            return new Error(`gif: reading header: ${err.message}`)
+        }
+        this.vers = String.fromCharCode(...this.tmp.subarray(0, 6))
+        if (this.vers != "GIF87a" && this.vers != "GIF89a") {
+            return new Error(`gif: can't recognize format ${JSON.stringify(this.vers)}`)
+        }
+        this.width = this.tmp[6] + (this.tmp[7] << 8)
+        this.height = this.tmp[8] + (this.tmp[9] << 8)
+        let fields = this.tmp[10]
+        if ((fields & fColorTable) != 0) {
+            this.backgroundIndex = this.tmp[11]
+            // readColorTable overwrites the contents of d.tmp, but that's OK.
+            let [p, err] = this.readColorTable(fields)
+            if (err) {
+                return err
+            }
+            this.globalColorTable = p
+        }
+        // d.tmp[12] is the Pixel Aspect Ratio, which is ignored.
+        return null
+    }
+
+    readColorTable(fields: number): [Palette | null, Error | null] {
+        let n = 1 << (1 + (fields & fColorTableBitsMask))
+        let err = readFull(this.r, this.tmp.subarray(0, 3 * n))
+        if (err) {
+            return [null, new Error(`gif: reading color table: ${err.message}`)]
+        }
+        let j = 0
+        let p: Palette = new Array(n)
+        for (let i = 0; i < p.length; i++) {
+            p[i] = new RGBA(this.tmp[j + 0], this.tmp[j + 1], this.tmp[j + 2], 0xFF)
+            j += 3
+        }
+        return [p, null]
+    }
+
+    readExtension(): Error | null {
+        let [extension, err] = readByte(this.r)
+        if (err) {
+            return new Error(`gif: reading extension: ${err.message}`)
+        }
+        let size = 0
+        switch (extension) {
+            case eText:
+                size = 13
+                break
+            case eGraphicControl:
+                return this.readGraphicControl()
+            case eComment:
+                // Not present in the Go code
+                //
+                // Go only skips comments, they are kept here
+                return this.readComment()
+            case eApplication: {
+                let [b, err] = readByte(this.r)
+                if (err) {
+                    return new Error(`gif: reading extension: ${err.message}`)
+                }
+                // The spec requires size be 11, but Adobe sometimes uses 10.
+                size = b
+                break
+            }
+            default:
+                return new Error(`gif: unknown extension 0x${hex2(extension)}`)
+        }
+        if (size > 0) {
+            let err = readFull(this.r, this.tmp.subarray(0, size))
+            if (err) {
+                return new Error(`gif: reading extension: ${err.message}`)
+            }
+        }
+
+        // Application Extension with "NETSCAPE2.0" as string and 1 in data means
+        // this extension defines a loop count.
+        if (extension == eApplication && String.fromCharCode(...this.tmp.subarray(0, size)) == "NETSCAPE2.0") {
+            let [n, err] = this.readBlock()
+            if (err) {
+                return new Error(`gif: reading extension: ${err.message}`)
+            }
+            if (n == 0) {
+                return null
+            }
+            if (n == 3 && this.tmp[0] == 1) {
+                this.loopCount = this.tmp[1] | (this.tmp[2] << 8)
+            }
+        }
+        while (true) /* for */ {
+            let [n, err] = this.readBlock()
+            if (err) {
+                return new Error(`gif: reading extension: ${err.message}`)
+            }
+            if (n == 0) {
+                return null
+            }
+        }
+    }
+
+    // Not present in the Go code
+    //
+    // readComment reads the sub-blocks of a comment extension into d.comments.
+    readComment(): Error | null {
+        let text = ""
+        while (true) /* for */ {
+            let [n, err] = this.readBlock()
+            if (err) {
+                return new Error(`gif: reading extension: ${err.message}`)
+            }
+            if (n == 0) {
+                this.comments.push(text)
+                return null
+            }
+            // Comments are 7-bit ASCII according to the spec
+            text += String.fromCharCode(...this.tmp.subarray(0, n))
+        }
+    }
+
+    readGraphicControl(): Error | null {
+        let err = readFull(this.r, this.tmp.subarray(0, 6))
+        if (err) {
+            return new Error(`gif: can't read graphic control: ${err.message}`)
+        }
+        if (this.tmp[0] != 4) {
+            return new Error(`gif: invalid graphic control extension block size: ${this.tmp[0]}`)
+        }
+        let flags = this.tmp[1]
+        this.disposalMethod = (flags & gcDisposalMethodMask) >> 2
+        this.delayTime = this.tmp[2] | (this.tmp[3] << 8)
+        if ((flags & gcTransparentColorSet) != 0) {
+            this.transparentIndex = this.tmp[4]
+            this.hasTransparentIndex = true
+        }
+        if (this.tmp[5] != 0) {
+            return new Error(`gif: invalid graphic control extension block terminator: ${this.tmp[5]}`)
+        }
+        return null
+    }
+
+    readImageDescriptor(keepAllFrames: boolean): Error | null {
+        let [m, err] = this.newImageFromDescriptor()
+        if (err || !m) {
+            return err
+        }
+        let useLocalColorTable = (this.imageFields & fColorTable) != 0
+        if (useLocalColorTable) {
+            let [p, err] = this.readColorTable(this.imageFields)
+            if (err || !p) {
+                return err
+            }
+            m.Palette = p
+        } else {
+            if (this.globalColorTable == null) {
+                return new Error("gif: no color table")
+            }
+            m.Palette = this.globalColorTable
+        }
+        if (this.hasTransparentIndex) {
+            if (!useLocalColorTable) {
+                // Clone the global color table.
+                m.Palette = m.Palette.slice()
+            }
+            let ti = this.transparentIndex
+            if (ti < m.Palette.length) {
+                m.Palette[ti] = new RGBA()
+            } else {
+                // The transparentIndex is out of range, which is an error
+                // according to the spec, but Firefox and Google Chrome
+                // seem OK with this, so we enlarge the palette with
+                // transparent colors. See golang.org/issue/15059.
+                let p: Palette = m.Palette.slice()
+                for (let i = m.Palette.length; i < ti + 1; i++) {
+                    p.push(new RGBA())
+                }
+                m.Palette = p
+            }
+        }
+        let litWidth: number
+        [litWidth, err] = readByte(this.r)
+        if (err) {
+            return new Error(`gif: reading image data: ${err.message}`)
+        }
+        if (litWidth < 2 || litWidth > 8) {
+            return new Error(`gif: pixel size in decode out of range: ${litWidth}`)
+        }
+        // A wonderfully Go-like piece of magic.
+        let br = new blockReader(this)
+        if (this.lzwr) {
+            this.lzwr.Reset(br, Order.LSB, litWidth)
+        } else {
+            this.lzwr = new LZWReader(br, Order.LSB, litWidth)
+        }
+        let lzwr = this.lzwr
+        try {
+            err = readFull(lzwr, m.Pix)
+            if (err) {
+                if (!errors.Is(err, IOErrors.UnexpectedEOF)) {
+                    return new Error(`gif: reading image data: ${err.message}`)
+                }
+                return errNotEnough
+            }
+            // In theory, both lzwr and br should be exhausted. Reading from them
+            // should yield (0, io.EOF).
+            //
+            // The spec (Appendix F - Compression), says that "An End of
+            // Information code... must be the last code output by the encoder
+            // for an image". In practice, though, giflib (a widely used C
+            // library) does not enforce this, so we also accept lzwr returning
+            // io.ErrUnexpectedEOF (meaning that the encoded stream hit io.EOF
+            // before the LZW decoder saw an explicit end code), provided that
+            // the io.ReadFull call above successfully read len(m.Pix) bytes.
+            // See https://golang.org/issue/9856 for an example GIF.
+            let n: number
+            [n, err] = lzwr.Read(this.tmp.subarray(256, 257))
+            if (n != 0 || (err != IOErrors.EOF && !errors.Is(err, IOErrors.UnexpectedEOF))) {
+                if (err) {
+                    return new Error(`gif: reading image data: ${err.message}`)
+                }
+                return errTooMuch
+            }
+        } finally {
+            lzwr.Close()
+        }
+
+        // In practice, some GIFs have an extra byte in the data sub-block
+        // stream, which we ignore. See https://golang.org/issue/16146.
+        err = br.close()
+        if (err == errTooMuch) {
+            return err
+        } else if (err) {
+            return new Error(`gif: reading image data: ${err.message}`)
+        }
+
+        // Check that the color indexes are inside the palette.
+        if (m.Palette.length < 256) {
+            for (let i = 0; i < m.Pix.length; i++) {
+                if (m.Pix[i] >= m.Palette.length) {
+                    return errBadPixel
+                }
+            }
+        }
+
+        // Undo the interlacing if necessary.
+        if ((this.imageFields & fInterlace) != 0) {
+            uninterlace(m)
+        }
+
+        if (keepAllFrames || this.image.length == 0) {
+            this.image.push(m)
+            this.delay.push(this.delayTime)
+            this.disposal.push(this.disposalMethod)
+        }
+        // The GIF89a spec, Section 23 (Graphic Control Extension) says:
+        // "The scope of this extension is the first graphic rendering block
+        // to follow." We therefore reset the GCE fields to zero.
+        this.delayTime = 0
+        this.hasTransparentIndex = false
+        return null
+    }
+
+    newImageFromDescriptor(): [Paletted | null, Error | null] {
+        let err = readFull(this.r, this.tmp.subarray(0, 9))
+        if (err) {
+            return [null, new Error(`gif: can't read image descriptor: ${err.message}`)]
+        }
+        let left = this.tmp[0] + (this.tmp[1] << 8)
+        let top = this.tmp[2] + (this.tmp[3] << 8)
+        let width = this.tmp[4] + (this.tmp[5] << 8)
+        let height = this.tmp[6] + (this.tmp[7] << 8)
+        this.imageFields = this.tmp[8]
+
+        // The GIF89a spec, Section 20 (Image Descriptor) says: "Each image must
+        // fit within the boundaries of the Logical Screen, as defined in the
+        // Logical Screen Descriptor."
+        //
+        // Note that, by construction, left >= 0 && top >= 0, so we only have to
+        // explicitly compare frameBounds.Max (left+width, top+height) against
+        // imageBounds.Max (d.width, d.height) and not frameBounds.Min (left, top)
+        // against imageBounds.Min (0, 0).
+        if (left + width > this.width || top + height > this.height) {
+            return [null, new Error("gif: frame bounds larger than image bounds")]
+        }
+        return [NewPaletted(new Rectangle(
+            new Point(left, top),
+            new Point(left + width, top + height),
+        ), []), null]
+    }
+
+    readBlock(): [number, Error | null] {
+        let [n, err] = readByte(this.r)
+        if (n == 0 || err) {
+            return [0, err]
+        }
+        err = readFull(this.r, this.tmp.subarray(0, n))
+        if (err) {
+            return [0, err]
+        }
+        return [n, null]
+    }
+}
+
+// blockReader parses the block structure of GIF image data, which comprises
+// (n, (n bytes)) blocks, with 1 <= n <= 255. It is the reader given to the
+// LZW decoder, which is thus immune to the blocking. After the LZW decoder
+// completes, there will be a 0-byte block remaining (0, ()), which is
+// consumed when checking that the blockReader is exhausted.
+//
+// To avoid the allocation of a bufio.Reader for the lzw Reader, blockReader
+// implements io.ByteReader and buffers blocks into the decoder's "tmp" buffer.
+class blockReader implements Reader, ByteReader {
+    d: decoder
+    i: number = 0 // uint8, d.tmp[i:j] contains the buffered bytes
+    j: number = 0 // uint8
+    err: Error | null = null
+
+    constructor(d: decoder) {
+        this.d = d
+    }
+
+    fill() {
+        if (this.err) {
+            return
+        }
+        [this.j, this.err] = readByte(this.d.r)
+        if (this.j == 0 && this.err == null) {
+            this.err = IOErrors.EOF
+        }
+        if (this.err) {
+            return
+        }
+
+        this.i = 0
+        this.err = readFull(this.d.r, this.d.tmp.subarray(0, this.j))
+        if (this.err) {
+            this.j = 0
+        }
+    }
+
+    ReadByte(): [number, Error | null] {
+        if (this.i == this.j) {
+            this.fill()
+            if (this.err) {
+                return [0, this.err]
+            }
+        }
+
+        let c = this.d.tmp[this.i]
+        this.i++
+        return [c, null]
+    }
+
+    // blockReader must implement io.Reader, but its Read shouldn't ever actually
+    // be called in practice. The compress/lzw package will only call ReadByte.
+    Read(p: Uint8Array): [number, Error | null] {
+        if (p.length == 0 || this.err) {
+            return [0, this.err]
+        }
+        if (this.i == this.j) {
+            this.fill()
+            if (this.err) {
+                return [0, this.err]
+            }
+        }
+
+        let n = Math.min(p.length, this.j - this.i)
+        p.set(this.d.tmp.subarray(this.i, this.i + n))
+        this.i += n
+        return [n, null]
+    }
+
+    // close primarily detects whether or not a block terminator was encountered
+    // after reading a sequence of data sub-blocks. It allows at most one trailing
+    // sub-block worth of data. I.e., if some number of bytes exist in one sub-block
+    // following the end of LZW data, the very next sub-block must be the block
+    // terminator. If the very end of LZW data happened to fill one sub-block, at
+    // most one more sub-block of length 1 may exist before the block-terminator.
+    // These accommodations allow us to support GIFs created by less strict encoders.
+    // See https://golang.org/issue/16146.
+    close(): Error | null {
+        if (this.err == IOErrors.EOF) {
+            // A clean block-sequence terminator was encountered while reading.
+            return null
+        } else if (this.err) {
+            // Some other error was encountered while reading.
+            return this.err
+        }
+
+        if (this.i == this.j) {
+            // We reached the end of a sub block reading LZW data. We'll allow at
+            // most one more sub block of data with a length of 1 byte.
+            this.fill()
+            if (this.err == IOErrors.EOF) {
+                return null
+            } else if (this.err) {
+                return this.err
+            } else if (this.j > 1) {
+                return errTooMuch
+            }
+        }
+
+        // Part of a sub-block remains buffered. We expect that the next attempt to
+        // buffer a sub-block will reach the block terminator.
+        this.fill()
+        if (this.err == IOErrors.EOF) {
+            return null
+        } else if (this.err) {
+            return this.err
+        }
+
+        return errTooMuch
+    }
+}
+
+// interlaceScan defines the ordering for a pass of the interlace algorithm.
+interface interlaceScan {
+    skip: number
+    start: number
+}
+
+// interlacing represents the set of scans in an interlaced GIF image.
+const interlacing: interlaceScan[] = [
+    { skip: 8, start: 0 }, // Group 1 : Every 8th. row, starting with row 0.
+    { skip: 8, start: 4 }, // Group 2 : Every 8th. row, starting with row 4.
+    { skip: 4, start: 2 }, // Group 3 : Every 4th. row, starting with row 2.
+    { skip: 2, start: 1 }, // Group 4 : Every 2nd. row, starting with row 1.
+]
+
+// uninterlace rearranges the pixels in m to account for interlaced input.
+function uninterlace(m: Paletted) {
+    let dx = m.Bounds().Dx()
+    let dy = m.Bounds().Dy()
+    let nPix = new Uint8Array(dx * dy)
+    let offset = 0 // steps through the input by sequential scan lines.
+    for (let pass of interlacing) {
+        let nOffset = pass.start * dx // steps through the output as defined by pass.
+        for (let y = pass.start; y < dy; y += pass.skip) {
+            nPix.set(m.Pix.subarray(offset, offset + dx), nOffset)
+            offset += dx
+            nOffset += dx * pass.skip
+        }
+    }
+    m.Pix = nPix
+}
+
+// hex2 formats b as two lowercase hex digits, like %.2x
+function hex2(b: number): string {
+    return b.toString(16).padStart(2, "0")
+}
+
+/**
+ * Decode reads a GIF image from r and returns the first embedded
+ * image.
+ *
+ * When decoding images from untrusted sources, it is safest to
+ * first call DecodeConfig and check the image size so
+ * that unexpectedly large memory allocations may be safely
+ * avoided.
+ */
+export function Decode(r: Reader): [Paletted | null, Error | null] {
+    let d = new decoder()
+    let err = d.decode(r, false, false)
+    if (err) {
+        return [null, err]
+    }
+    return [d.image[0], null]
+}
+
+/**
+ * GIF represents the possibly multiple images stored in a GIF file.
+ */
+export interface GIF {
+    Image: Paletted[] // The successive images.
+    Delay: number[] // The successive delay times, one per frame, in 100ths of a second.
+
+    // LoopCount controls the number of times an animation will be
+    // restarted during display.
+    // A LoopCount of 0 means to loop forever.
+    // A LoopCount of -1 means to show each frame only once.
+    // Otherwise, the animation is looped LoopCount+1 times.
+    LoopCount: number
+
+    // Disposal is the successive disposal methods, one per frame. For
+    // backwards compatibility, a null Disposal is valid to pass to EncodeAll,
+    // and implies that each frame's disposal method is 0 (no disposal
+    // specified).
+    Disposal: number[] | null
+
+    // Config is the global color table (palette), width and height. A null or
+    // empty Config.ColorModel means that each frame has its own
+    // color table and there is no global color table. Each frame's bounds must
+    // be within the rectangle defined by the two points (0, 0) and
+    // (Config.Width, Config.Height).
+    //
+    // For backwards compatibility, a zero-valued Config is valid to pass to
+    // EncodeAll, and implies that the overall GIF's width and height equals
+    // the first frame's bounds' Rectangle.Max point.
+    Config: Config
+
+    // BackgroundIndex is the background index in the global color table, for
+    // use with the DisposalBackground disposal method.
+    BackgroundIndex: number // byte
+
+    // Not present in the Go code
+    //
+    // Comments is the text of each comment extension, in the order they appear.
+    Comments?: string[]
+}
+
+/**
+ * DecodeAll reads a GIF image from r and returns the sequential frames
+ * and timing information.
+ *
+ * Like Decode, this allocates a paletted buffer per frame from width and
+ * height in the image descriptors. DecodeAll retains every decoded frame in
+ * memory. For untrusted input, call DecodeConfig first to verify the
+ * logical screen size and reject inputs that would require excessive memory.
+ */
+export function DecodeAll(r: Reader): [GIF | null, Error | null] {
+    let d = new decoder()
+    let err = d.decode(r, false, true)
+    if (err) {
+        return [null, err]
+    }
+    let gif: GIF = {
+        Image: d.image,
+        LoopCount: d.loopCount,
+        Delay: d.delay,
+        Disposal: d.disposal,
+        Config: {
+            ColorModel: d.globalColorTable,
+            Width: d.width,
+            Height: d.height,
+        },
+        BackgroundIndex: d.backgroundIndex,
+        Comments: d.comments,
+    }
+    return [gif, null]
+}
+
+/**
+ * DecodeConfig returns the global color model and dimensions of a GIF image
+ * without decoding the entire image.
+ *
+ * It reads the logical screen descriptor and global color table only; it does
+ * not allocate pixel buffers for frames. Use it to check width and height
+ * before calling Decode or DecodeAll.
+ */
+export function DecodeConfig(r: Reader): [Config, Error | null] {
+    let d = new decoder()
+    let err = d.decode(r, true, false)
+    if (err) {
+        return [{ ColorModel: null, Width: 0, Height: 0 }, err]
+    }
+    return [{
+        ColorModel: d.globalColorTable,
+        Width: d.width,
+        Height: d.height,
+    }, null]
+}
+
+// Graphic control extension fields.
+const gcLabel = 0xF9
+const gcBlockSize = 0x04
+
+function log2(x: number): number {
+    if (x < 2) {
+        return 0
+    }
+    // bits.Len(uint(x-1)) - 1
+    return 32 - Math.clz32(x - 1) - 1
+}
+
+// putUint16 writes v to b at offset i in little endian order
+function putUint16(b: Uint8Array, i: number, v: number) {
+    b[i] = v & 0xff
+    b[i + 1] = (v >>> 8) & 0xff
+}
+
+// writer is a buffered writer.
+type writer = Writer & ByteWriter & { Flush(): Error | null }
+
+// isWriter reports whether w is already a buffered writer, like the type
+// assertion w.(writer)
+function isWriter(w: Writer): w is writer {
+    return isByteWriter(w) && typeof (w as Partial<writer>).Flush == "function"
+}
+
+// encoder encodes an image to the GIF format.
+class encoder {
+    // w is the writer to write to. err is the first error encountered during
+    // writing. All attempted writes after the first error become no-ops.
+    w: writer
+    err: Error | null = null
+    // g is a reference to the data that is being encoded.
+    g: GIF
+    // globalCT is the size in bytes of the global color table.
+    globalCT: number = 0
+    // buf is a scratch buffer. It must be at least 256 for the blockWriter.
+    buf: Uint8Array = new Uint8Array(256)
+    globalColorTable: Uint8Array = new Uint8Array(3 * 256)
+    localColorTable: Uint8Array = new Uint8Array(3 * 256)
+
+    constructor(w: writer, g: GIF) {
+        this.w = w
+        this.g = g
+    }
+
+    flush() {
+        if (this.err) {
+            return
+        }
+        this.err = this.w.Flush()
+    }
+
+    write(p: Uint8Array) {
+        if (this.err) {
+            return
+        }
+        [, this.err] = this.w.Write(p)
+    }
+
+    writeByte(b: number) {
+        if (this.err) {
+            return
+        }
+        this.err = this.w.WriteByte(b)
+    }
+
+    writeHeader() {
+        if (this.err) {
+            return
+        }
+        this.write(new TextEncoder().encode("GIF89a"))
+        if (this.err) {
+            return
+        }
+
+        // Logical screen width and height.
+        putUint16(this.buf, 0, this.g.Config.Width)
+        putUint16(this.buf, 2, this.g.Config.Height)
+        this.write(this.buf.subarray(0, 4))
+
+        let p = this.g.Config.ColorModel
+        if (p && p.length > 0) {
+            let paddedSize = log2(p.length) // Size of Global Color Table: 2^(1+n).
+            this.buf[0] = fColorTable | paddedSize
+            this.buf[1] = this.g.BackgroundIndex
+            this.buf[2] = 0x00 // Pixel Aspect Ratio.
+            this.write(this.buf.subarray(0, 3))
+            let err: Error | null
+            [this.globalCT, err] = encodeColorTable(this.globalColorTable, p, paddedSize)
+            if (err && this.err == null) {
+                this.err = err
+                return
+            }
+            this.write(this.globalColorTable.subarray(0, this.globalCT))
+        } else {
+            // All frames have a local color table, so a global color table
+            // is not needed.
+            this.buf[0] = 0x00
+            this.buf[1] = 0x00 // Background Color Index.
+            this.buf[2] = 0x00 // Pixel Aspect Ratio.
+            this.write(this.buf.subarray(0, 3))
+        }
+
+        // Add animation info if necessary.
+        if (this.g.Image.length > 1 && this.g.LoopCount >= 0) {
+            this.buf[0] = 0x21 // Extension Introducer.
+            this.buf[1] = 0xff // Application Label.
+            this.buf[2] = 0x0b // Block Size.
+            this.write(this.buf.subarray(0, 3))
+            this.write(new TextEncoder().encode("NETSCAPE2.0")) // Application Identifier.
+            this.buf[0] = 0x03 // Block Size.
+            this.buf[1] = 0x01 // Sub-block Index.
+            putUint16(this.buf, 2, this.g.LoopCount)
+            this.buf[4] = 0x00 // Block Terminator.
+            this.write(this.buf.subarray(0, 5))
+        }
+    }
+
+    colorTablesMatch(localLen: number, transparentIndex: number): boolean {
+        let localSize = 3 * localLen
+        if (transparentIndex >= 0) {
+            let trOff = 3 * transparentIndex
+            return bytesEqual(this.globalColorTable.subarray(0, trOff), this.localColorTable.subarray(0, trOff)) &&
+                bytesEqual(this.globalColorTable.subarray(trOff + 3, localSize), this.localColorTable.subarray(trOff + 3, localSize))
+        }
+        return bytesEqual(this.globalColorTable.subarray(0, localSize), this.localColorTable.subarray(0, localSize))
+    }
+
+    writeImageBlock(pm: Paletted, delay: number, disposal: number) {
+        if (this.err) {
+            return
+        }
+
+        if (pm.Palette.length == 0) {
+            this.err = new Error("gif: cannot encode image block with empty palette")
+            return
+        }
+
+        let b = pm.Bounds()
+        if (b.Min.X < 0 || b.Max.X >= 1 << 16 || b.Min.Y < 0 || b.Max.Y >= 1 << 16) {
+            this.err = new Error("gif: image block is too large to encode")
+            return
+        }
+        if (!b.In(new Rectangle(new Point(), new Point(this.g.Config.Width, this.g.Config.Height)))) {
+            this.err = new Error("gif: image block is out of bounds")
+            return
+        }
+
+        let transparentIndex = -1
+        for (let i = 0; i < pm.Palette.length; i++) {
+            let c = pm.Palette[i]
+            if (c == null) {
+                this.err = new Error("gif: cannot encode color table with nil entries")
+                return
+            }
+            let [, , , a] = c.RGBA()
+            if (a == 0) {
+                transparentIndex = i
+                break
+            }
+        }
+
+        if (delay > 0 || disposal != 0 || transparentIndex != -1) {
+            this.buf[0] = sExtension // Extension Introducer.
+            this.buf[1] = gcLabel // Graphic Control Label.
+            this.buf[2] = gcBlockSize // Block Size.
+            if (transparentIndex != -1) {
+                this.buf[3] = 0x01 | disposal << 2
+            } else {
+                this.buf[3] = 0x00 | disposal << 2
+            }
+            putUint16(this.buf, 4, delay) // Delay Time (1/100ths of a second)
+
+            // Transparent color index.
+            if (transparentIndex != -1) {
+                this.buf[6] = transparentIndex
+            } else {
+                this.buf[6] = 0x00
+            }
+            this.buf[7] = 0x00 // Block Terminator.
+            this.write(this.buf.subarray(0, 8))
+        }
+        this.buf[0] = sImageDescriptor
+        putUint16(this.buf, 1, b.Min.X)
+        putUint16(this.buf, 3, b.Min.Y)
+        putUint16(this.buf, 5, b.Dx())
+        putUint16(this.buf, 7, b.Dy())
+        this.write(this.buf.subarray(0, 9))
+
+        // To determine whether or not this frame's palette is the same as the
+        // global palette, we can check a couple things. First, do they actually
+        // point to the same []color.Color? If so, they are equal so long as the
+        // frame's palette is not longer than the global palette...
+        let paddedSize = log2(pm.Palette.length) // Size of Local Color Table: 2^(1+n).
+        let gp = this.g.Config.ColorModel
+        if (gp && pm.Palette.length <= gp.length && gp === pm.Palette) {
+            this.writeByte(0) // Use the global color table.
+        } else {
+            let [ct, err] = encodeColorTable(this.localColorTable, pm.Palette, paddedSize)
+            if (err) {
+                if (this.err == null) {
+                    this.err = err
+                }
+                return
+            }
+            // This frame's palette is not the very same slice as the global
+            // palette, but it might be a copy, possibly with one value turned into
+            // transparency by DecodeAll.
+            if (ct <= this.globalCT && this.colorTablesMatch(pm.Palette.length, transparentIndex)) {
+                this.writeByte(0) // Use the global color table.
+            } else {
+                // Use a local color table.
+                this.writeByte(fColorTable | paddedSize)
+                this.write(this.localColorTable.subarray(0, ct))
+            }
+        }
+
+        let litWidth = paddedSize + 1
+        if (litWidth < 2) {
+            litWidth = 2
+        }
+        this.writeByte(litWidth) // LZW Minimum Code Size.
+
+        let bw = new blockWriter(this)
+        bw.setup()
+        let lzww = new LZWWriter(bw, Order.LSB, litWidth)
+        let dx = b.Dx()
+        if (dx == pm.Stride) {
+            [, this.err] = lzww.Write(pm.Pix.subarray(0, dx * b.Dy()))
+            if (this.err) {
+                lzww.Close()
+                return
+            }
+        } else {
+            for (let i = 0, y = b.Min.Y; y < b.Max.Y; i += pm.Stride, y++) {
+                [, this.err] = lzww.Write(pm.Pix.subarray(i, i + dx))
+                if (this.err) {
+                    lzww.Close()
+                    return
+                }
+            }
+        }
+        lzww.Close() // flush to bw
+        bw.close() // flush to e.w
+    }
+}
+
+// blockWriter writes the block structure of GIF image data, which
+// comprises (n, (n bytes)) blocks, with 1 <= n <= 255. It is the
+// writer given to the LZW encoder, which is thus immune to the
+// blocking.
+class blockWriter implements Writer, ByteWriter {
+    e: encoder
+
+    constructor(e: encoder) {
+        this.e = e
+    }
+
+    setup() {
+        this.e.buf[0] = 0
+    }
+
+    Flush(): Error | null {
+        return this.e.err
+    }
+
+    WriteByte(c: number): Error | null {
+        if (this.e.err) {
+            return this.e.err
+        }
+
+        // Append c to buffered sub-block.
+        this.e.buf[0]++
+        this.e.buf[this.e.buf[0]] = c
+        if (this.e.buf[0] < 255) {
+            return null
+        }
+
+        // Flush block
+        this.e.write(this.e.buf.subarray(0, 256))
+        this.e.buf[0] = 0
+        return this.e.err
+    }
+
+    // Not present in the Go code: LZWWriter does its own buffering, so unlike in Go
+    // this is what it calls
+    Write(data: Uint8Array): [number, Error | null] {
+        for (let i = 0; i < data.length; i++) {
+            let err = this.WriteByte(data[i])
+            if (err) {
+                return [i, err]
+            }
+        }
+        return [data.length, null]
+    }
+
+    close() {
+        // Write the block terminator (0x00), either by itself, or along with a
+        // pending sub-block.
+        if (this.e.buf[0] == 0) {
+            this.e.writeByte(0)
+        } else {
+            let n = this.e.buf[0]
+            this.e.buf[n + 1] = 0
+            this.e.write(this.e.buf.subarray(0, n + 2))
+        }
+        this.e.flush()
+    }
+}
+
+function encodeColorTable(dst: Uint8Array, p: Palette, size: number): [number, Error | null] {
+    if (size >= 8 || size < 0) {
+        return [0, new Error("gif: cannot encode color table with more than 256 entries")]
+    }
+    for (let i = 0; i < p.length; i++) {
+        let c = p[i]
+        if (c == null) {
+            return [0, new Error("gif: cannot encode color table with nil entries")]
+        }
+        let r: number, g: number, b: number
+        // It is most likely that the palette is full of color.RGBAs, so they
+        // get a fast path.
+        if (c instanceof RGBA) {
+            [r, g, b] = [c.R, c.G, c.B]
+        } else {
+            let [rr, gg, bb] = c.RGBA()
+            r = rr >>> 8
+            g = gg >>> 8
+            b = bb >>> 8
+        }
+        dst[3 * i + 0] = r
+        dst[3 * i + 1] = g
+        dst[3 * i + 2] = b
+    }
+    let n = 1 << (size + 1)
+    if (n > p.length) {
+        // Pad with black.
+        dst.fill(0, 3 * p.length, 3 * n)
+    }
+    return [3 * n, null]
+}
+
+// bytesEqual reports whether a and b are the same length and contain the same bytes
+function bytesEqual(a: Uint8Array, b: Uint8Array): boolean {
+    if (a.length != b.length) {
+        return false
+    }
+    for (let i = 0; i < a.length; i++) {
+        if (a[i] != b[i]) {
+            return false
+        }
+    }
+    return true
+}
+
+/**
+ * Options are the encoding parameters.
+ */
+export interface Options {
+    // NumColors is the maximum number of colors used in the image.
+    // It ranges from 1 to 256.
+    NumColors?: number
+
+    // Quantizer is used to produce a palette with size NumColors.
+    // MedianCut is used in place of a null Quantizer.
+    //
+    // Not present in the Go code: Go uses palette.Plan9 in place of a nil Quantizer,
+    // which is not ported. MedianCut fits the palette to the image instead.
+    Quantizer?: Quantizer | null
+
+    // Drawer is used to convert the source image to the desired palette.
+    // FloydSteinberg is used in place of a null Drawer.
+    Drawer?: Drawer | null
+}
+
+/**
+ * EncodeAll writes the images in g to w in GIF format with the
+ * given loop count and delay between frames.
+ */
+export function EncodeAll(w: Writer, g: GIF): Error | null {
+    if (g.Image.length == 0) {
+        return new Error("gif: must provide at least one image")
+    }
+
+    if (g.Image.length != g.Delay.length) {
+        return new Error("gif: mismatched image and delay lengths")
+    }
+
+    // The GIF.Disposal, GIF.Config and GIF.BackgroundIndex fields were added
+    // in Go 1.5. Valid Go 1.4 code, such as when the Disposal field is omitted
+    // in a GIF struct literal, should still produce valid GIFs.
+    if (g.Disposal && g.Image.length != g.Disposal.length) {
+        return new Error("gif: mismatched image and disposal lengths")
+    }
+
+    let config = g.Config
+    if (config.ColorModel == null && config.Width == 0 && config.Height == 0) {
+        let p = g.Image[0].Bounds().Max
+        config = { ColorModel: null, Width: p.X, Height: p.Y }
+    }
+
+    let ww: writer
+    if (isWriter(w)) {
+        ww = w
+    } else {
+        ww = new BufferedWriter(w)
+    }
+
+    let e = new encoder(ww, { ...g, Config: config })
+    e.writeHeader()
+    for (let i = 0; i < g.Image.length; i++) {
+        let disposal = 0
+        if (g.Disposal) {
+            disposal = g.Disposal[i]
+        }
+        e.writeImageBlock(g.Image[i], g.Delay[i], disposal)
+    }
+    e.writeByte(sTrailer)
+    e.flush()
+    return e.err
+}
+
+/**
+ * Encode writes the Image m to w in GIF format.
+ *
+ * Other images whose ColorModel is a Palette are first converted to that
+ * palette. Images that have no palette, or more than NumColors colors, are
+ * converted with the Quantizer and Drawer from o. This means RGBA and NRGBA
+ * images, such as canvas frames, can be encoded directly.
+ */
+export function Encode(w: Writer, m: Image, o: Options | null = null): Error | null {
+    // Check for bounds and size restrictions.
+    let b = m.Bounds()
+    if (b.Dx() >= 1 << 16 || b.Dy() >= 1 << 16) {
+        return new Error("gif: image is too large to encode")
+    }
+
+    let numColors = o?.NumColors ?? 0
+    if (numColors < 1 || 256 < numColors) {
+        numColors = 256
+    }
+    let quantizer = o?.Quantizer ?? MedianCut
+    let drawer = o?.Drawer ?? FloydSteinberg
+
+    let pm = m instanceof Paletted ? m : null
+    if (pm == null) {
+        // Not present in the Go code: an empty palette is left to the Quantizer,
+        // as Go would panic converting colors to it
+        let cp = m.ColorModel?.() ?? null
+        if (cp != null && cp.length > 0) {
+            pm = NewPaletted(b, cp)
+            for (let y = b.Min.Y; y < b.Max.Y; y++) {
+                for (let x = b.Min.X; x < b.Max.X; x++) {
+                    pm.Set(x, y, PaletteConvert(cp, m.At(x, y)!)!)
+                }
+            }
+        }
+    }
+    if (pm == null || pm.Palette.length > numColors) {
+        // Set pm to be a palettedized copy of m, including its bounds, which
+        // might not start at (0, 0).
+        pm = NewPaletted(b, quantizer.Quantize([], m, numColors))
+        drawer.Draw(pm, b, m, b.Min)
+    }
+
+    // When calling Encode instead of EncodeAll, the single-frame image is
+    // translated such that its top-left corner is (0, 0), so that the single
+    // frame completely fills the overall GIF's bounds.
+    if (!pm.Rect.Min.Eq(new Point())) {
+        pm = new Paletted(pm.Pix, pm.Stride, pm.Rect.Sub(pm.Rect.Min), pm.Palette)
+    }
+
+    return EncodeAll(w, {
+        Image: [pm],
+        Delay: [0],
+        LoopCount: 0,
+        Disposal: null,
+        Config: {
+            ColorModel: pm.Palette,
+            Width: b.Dx(),
+            Height: b.Dy(),
+        },
+        BackgroundIndex: 0,
+    })
+}
@@ -0,0 +1,916 @@
+// Tests for image/gif
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/gif/reader_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/gif/writer_test.go
+//
+// image/png is not ported, so the tests that read .png files use the .gif ones
+import * as assert from "node:assert/strict"
+import { createHash } from "node:crypto"
+import * as fs from "node:fs"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { latin1Bytes } from "../builtins/tshelpers/strings"
+import { LZWWriter, Order } from "../compress/lzw"
+import { Discard } from "../io"
+import { Color, Palette, RGBA } from "./color"
+import { Src } from "./draw"
+import { Decode, DecodeAll, DecodeConfig, DisposalNone, Encode, EncodeAll, GIF } from "./gif"
+import { Image, NewPaletted, NewRGBA, Paletted, Point, Rect, Rectangle } from "./index"
+
+// header, palette and trailer are parts of a valid 2x1 GIF image.
+const headerStr = "GIF89a" +
+    "\x02\x00\x01\x00" + // width=2, height=1
+    "\x80\x00\x00" // headerFields=(a color table of 2 pixels), backgroundIndex, aspect
+const paletteStr = "\x10\x20\x30\x40\x50\x60" // the color table, also known as a palette
+const trailerStr = "\x3b"
+
+// lzwEncode returns an LZW encoding (with 2-bit literals) of in.
+function lzwEncode(input: Uint8Array): Uint8Array {
+    let b = new GoBuffer(new Uint8Array(0))
+    let w = new LZWWriter(b, Order.LSB, 2)
+    let [, err] = w.Write(input)
+    if (err) {
+        throw err
+    }
+    err = w.Close()
+    if (err) {
+        throw err
+    }
+    return b.underlyingArray
+}
+
+test("TestDecode", () => {
+    // extra contains superfluous bytes to inject into the GIF, either at the end
+    // of an existing data sub-block (past the LZW End of Information code) or in
+    // a separate data sub-block. The 0x02 values are arbitrary.
+    const extra = "\x02\x02\x02\x02"
+
+    let testCases: {
+        nPix: number // The number of pixels in the image data.
+        // If non-zero, write this many extra bytes inside the data sub-block
+        // containing the LZW end code.
+        extraExisting: number
+        // If non-zero, write an extra block of this many bytes.
+        extraSeparate: number
+        wantErr: string | null
+    }[] = [
+        { nPix: 0, extraExisting: 0, extraSeparate: 0, wantErr: "gif: not enough image data" },
+        { nPix: 1, extraExisting: 0, extraSeparate: 0, wantErr: "gif: not enough image data" },
+        { nPix: 2, extraExisting: 0, extraSeparate: 0, wantErr: null },
+        // An extra data sub-block after the compressed section with 1 byte which we
+        // silently skip.
+        { nPix: 2, extraExisting: 0, extraSeparate: 1, wantErr: null },
+        // An extra data sub-block after the compressed section with 2 bytes. In
+        // this case we complain that there is too much data.
+        { nPix: 2, extraExisting: 0, extraSeparate: 2, wantErr: "gif: too much image data" },
+        // Too much pixel data.
+        { nPix: 3, extraExisting: 0, extraSeparate: 0, wantErr: "gif: too much image data" },
+        // An extra byte after LZW data, but inside the same data sub-block.
+        { nPix: 2, extraExisting: 1, extraSeparate: 0, wantErr: null },
+        // Two extra bytes after LZW data, but inside the same data sub-block.
+        { nPix: 2, extraExisting: 2, extraSeparate: 0, wantErr: null },
+        // Extra data exists in the final sub-block with LZW data, AND there is
+        // a bogus sub-block following.
+        { nPix: 2, extraExisting: 1, extraSeparate: 1, wantErr: "gif: too much image data" },
+    ]
+    for (let tc of testCases) {
+        let desc = `nPix=${tc.nPix}, extraExisting=${tc.extraExisting}, extraSeparate=${tc.extraSeparate}`
+        let b = new GoBuffer(new Uint8Array(0))
+        b.Write(latin1Bytes(headerStr))
+        b.Write(latin1Bytes(paletteStr))
+        // Write an image with bounds 2x1 but tc.nPix pixels. If tc.nPix != 2
+        // then this should result in an invalid GIF image. First, write a
+        // magic 0x2c (image descriptor) byte, bounds=(0,0)-(2,1), a flags
+        // byte, and 2-bit LZW literals.
+        b.Write(latin1Bytes("\x2c\x00\x00\x00\x00\x02\x00\x01\x00\x00\x02"))
+        if (tc.nPix > 0) {
+            let enc = lzwEncode(new Uint8Array(tc.nPix))
+            assert.ok(enc.length + tc.extraExisting <= 0xff, `${desc}: compressed length ${enc.length} is too large`)
+
+            // Write the size of the data sub-block containing the LZW data.
+            b.WriteByte(enc.length + tc.extraExisting)
+
+            // Write the LZW data.
+            b.Write(enc)
+
+            // Write extra bytes inside the same data sub-block where LZW data
+            // ended. Each arbitrarily 0x02.
+            b.Write(latin1Bytes(extra.slice(0, tc.extraExisting)))
+        }
+
+        if (tc.extraSeparate > 0) {
+            // Data sub-block size. This indicates how many extra bytes follow.
+            b.WriteByte(tc.extraSeparate)
+            b.Write(latin1Bytes(extra.slice(0, tc.extraSeparate)))
+        }
+        b.WriteByte(0x00) // An empty block signifies the end of the image data.
+        b.Write(latin1Bytes(trailerStr))
+
+        let [got, err] = Decode(b)
+        assert.equal(err?.message ?? null, tc.wantErr, desc)
+
+        if (tc.wantErr != null) {
+            continue
+        }
+        let want = new Paletted(
+            new Uint8Array([0, 0]),
+            2,
+            Rect(0, 0, 2, 1),
+            [
+                new RGBA(0x10, 0x20, 0x30, 0xff),
+                new RGBA(0x40, 0x50, 0x60, 0xff),
+            ],
+        )
+        assert.deepEqual(got, want, desc)
+    }
+})
+
+test("TestTransparentIndex", () => {
+    let b = new GoBuffer(new Uint8Array(0))
+    b.Write(latin1Bytes(headerStr))
+    b.Write(latin1Bytes(paletteStr))
+    for (let transparentIndex = 0; transparentIndex < 3; transparentIndex++) {
+        if (transparentIndex < 2) {
+            // Write the graphic control for the transparent index.
+            b.Write(latin1Bytes("\x21\xf9\x04\x01\x00\x00"))
+            b.WriteByte(transparentIndex)
+            b.WriteByte(0)
+        }
+        // Write an image with bounds 2x1, as per TestDecode.
+        b.Write(latin1Bytes("\x2c\x00\x00\x00\x00\x02\x00\x01\x00\x00\x02"))
+        let enc = lzwEncode(new Uint8Array([0x00, 0x00]))
+        assert.ok(enc.length <= 0xff, `compressed length ${enc.length} is too large`)
+        b.WriteByte(enc.length)
+        b.Write(enc)
+        b.WriteByte(0x00)
+    }
+    b.Write(latin1Bytes(trailerStr))
+
+    let [g, err] = DecodeAll(b)
+    assert.equal(err, null, "DecodeAll")
+    let p = latin1Bytes(paletteStr)
+    let c0 = new RGBA(p[0], p[1], p[2], 0xff)
+    let c1 = new RGBA(p[3], p[4], p[5], 0xff)
+    let cz = new RGBA()
+    let wants = [
+        [cz, c1],
+        [c0, cz],
+        [c0, c1],
+    ]
+    assert.equal(g!.Image.length, wants.length)
+    for (let i = 0; i < wants.length; i++) {
+        assert.deepEqual(g!.Image[i].Palette, wants[i], `palette #${i}`)
+    }
+})
+
+// testGIF is a simple GIF that we can modify to test different scenarios.
+const testGIF = new Uint8Array([
+    0x47, 0x49, 0x46, 0x38, 0x39, 0x61, // "GIF89a"
+    1, 0, 1, 0, // w=1, h=1 (6)
+    128, 0, 0, // headerFields, bg, aspect (10)
+    0, 0, 0, 1, 1, 1, // color table and graphics control (13)
+    0x21, 0xf9, 0x04, 0x00, 0x00, 0x00, 0xff, 0x00, // (19)
+    // frame 1 (0,0 - 1,1)
+    0x2c,
+    0x00, 0x00, 0x00, 0x00,
+    0x01, 0x00, 0x01, 0x00, // (32)
+    0x00,
+    0x02, 0x02, 0x4c, 0x01, 0x00, // lzw pixels
+    // trailer
+    0x3b,
+])
+
+function tryDecode(b: Uint8Array, want: string) {
+    let [, err] = DecodeAll(new GoBuffer(b))
+    let got = ""
+    if (err) {
+        got = err.message
+    }
+    assert.equal(got, want)
+}
+
+test("TestBounds", () => {
+    // Make a local copy of testGIF.
+    let gif = testGIF.slice()
+    // Make the bounds too big, just by one.
+    gif[32] = 2
+    let want = "gif: frame bounds larger than image bounds"
+    tryDecode(gif, want)
+
+    // Make the bounds too small; does not trigger bounds
+    // check, but now there's too much data.
+    gif[32] = 0
+    want = "gif: too much image data"
+    tryDecode(gif, want)
+    gif[32] = 1
+
+    // Make the bounds really big, expect an error.
+    want = "gif: frame bounds larger than image bounds"
+    for (let i = 0; i < 4; i++) {
+        gif[32 + i] = 0xff
+    }
+    tryDecode(gif, want)
+})
+
+test("TestNoPalette", () => {
+    let b = new GoBuffer(new Uint8Array(0))
+
+    // Manufacture a GIF with no palette, so any pixel at all
+    // will be invalid.
+    b.Write(latin1Bytes(headerStr.slice(0, headerStr.length - 3)))
+    b.Write(latin1Bytes("\x00\x00\x00")) // No global palette.
+
+    // Image descriptor: 2x1, no local palette, and 2-bit LZW literals.
+    b.Write(latin1Bytes("\x2c\x00\x00\x00\x00\x02\x00\x01\x00\x00\x02"))
+
+    // Encode the pixels: neither is in range, because there is no palette.
+    let enc = lzwEncode(new Uint8Array([0x00, 0x03]))
+    b.WriteByte(enc.length)
+    b.Write(enc)
+    b.WriteByte(0x00) // An empty block signifies the end of the image data.
+
+    b.Write(latin1Bytes(trailerStr))
+
+    tryDecode(b.underlyingArray, "gif: no color table")
+})
+
+test("TestPixelOutsidePaletteRange", () => {
+    for (let pval of [0, 1, 2, 3]) {
+        let b = new GoBuffer(new Uint8Array(0))
+
+        // Manufacture a GIF with a 2 color palette.
+        b.Write(latin1Bytes(headerStr))
+        b.Write(latin1Bytes(paletteStr))
+
+        // Image descriptor: 2x1, no local palette, and 2-bit LZW literals.
+        b.Write(latin1Bytes("\x2c\x00\x00\x00\x00\x02\x00\x01\x00\x00\x02"))
+
+        // Encode the pixels; some pvals trigger the expected error.
+        let enc = lzwEncode(new Uint8Array([pval, pval]))
+        b.WriteByte(enc.length)
+        b.Write(enc)
+        b.WriteByte(0x00) // An empty block signifies the end of the image data.
+
+        b.Write(latin1Bytes(trailerStr))
+
+        // No error expected, unless the pixels are beyond the 2 color palette.
+        let want = ""
+        if (pval >= 2) {
+            want = "gif: invalid pixel value"
+        }
+        tryDecode(b.underlyingArray, want)
+    }
+})
+
+test("TestTransparentPixelOutsidePaletteRange", () => {
+    let b = new GoBuffer(new Uint8Array(0))
+
+    // Manufacture a GIF with a 2 color palette.
+    b.Write(latin1Bytes(headerStr))
+    b.Write(latin1Bytes(paletteStr))
+
+    // Graphic Control Extension: transparency, transparent color index = 3.
+    //
+    // This index, 3, is out of range of the global palette and there is no
+    // local palette in the subsequent image descriptor. This is an error
+    // according to the spec, but Firefox and Google Chrome seem OK with this.
+    //
+    // See golang.org/issue/15059.
+    b.Write(latin1Bytes("\x21\xf9\x04\x01\x00\x00\x03\x00"))
+
+    // Image descriptor: 2x1, no local palette, and 2-bit LZW literals.
+    b.Write(latin1Bytes("\x2c\x00\x00\x00\x00\x02\x00\x01\x00\x00\x02"))
+
+    // Encode the pixels.
+    let enc = lzwEncode(new Uint8Array([0x03, 0x03]))
+    b.WriteByte(enc.length)
+    b.Write(enc)
+    b.WriteByte(0x00) // An empty block signifies the end of the image data.
+
+    b.Write(latin1Bytes(trailerStr))
+
+    tryDecode(b.underlyingArray, "")
+})
+
+test("TestLoopCount", async (t) => {
+    let testCases: { name: string, data: Uint8Array, loopCount: number }[] = [
+        {
+            name: "loopcount-missing",
+            data: latin1Bytes("GIF89a000\x00000" +
+                ",0\x00\x00\x00\n\x00\n\x00\x80000000" + // image 0 descriptor & color table
+                "\x02\b\xf01u\xb9\xfdal\x05\x00;"), // image 0 image data & trailer
+            loopCount: -1,
+        },
+        {
+            name: "loopcount-0",
+            data: latin1Bytes("GIF89a000\x00000" +
+                "!\xff\vNETSCAPE2.0\x03\x01\x00\x00\x00" + // loop count = 0
+                ",0\x00\x00\x00\n\x00\n\x00\x80000000" + // image 0 descriptor & color table
+                "\x02\b\xf01u\xb9\xfdal\x05\x00" + // image 0 image data
+                ",0\x00\x00\x00\n\x00\n\x00\x80000000" + // image 1 descriptor & color table
+                "\x02\b\xf01u\xb9\xfdal\x05\x00;"), // image 1 image data & trailer
+            loopCount: 0,
+        },
+        {
+            name: "loopcount-1",
+            data: latin1Bytes("GIF89a000\x00000" +
+                "!\xff\vNETSCAPE2.0\x03\x01\x01\x00\x00" + // loop count = 1
+                ",0\x00\x00\x00\n\x00\n\x00\x80000000" + // image 0 descriptor & color table
+                "\x02\b\xf01u\xb9\xfdal\x05\x00" + // image 0 image data
+                ",0\x00\x00\x00\n\x00\n\x00\x80000000" + // image 1 descriptor & color table
+                "\x02\b\xf01u\xb9\xfdal\x05\x00;"), // image 1 image data & trailer
+            loopCount: 1,
+        },
+    ]
+
+    for (let tc of testCases) {
+        await t.test(tc.name, () => {
+            let [img, err] = DecodeAll(new GoBuffer(tc.data))
+            assert.equal(err, null, "DecodeAll")
+            let w = new GoBuffer(new Uint8Array(0))
+            err = EncodeAll(w, img!)
+            assert.equal(err, null, "EncodeAll")
+            let [img1, err1] = DecodeAll(w)
+            assert.equal(err1, null, "DecodeAll")
+            assert.equal(img!.LoopCount, tc.loopCount, "loop count mismatch")
+            assert.equal(img!.LoopCount, img1!.LoopCount, "loop count failed round-trip")
+        })
+    }
+})
+
+test("TestUnexpectedEOF", () => {
+    for (let i = testGIF.length - 1; i >= 0; i--) {
+        let [, err] = DecodeAll(new GoBuffer(testGIF.subarray(0, i)))
+        if (err?.message == "gif: not enough image data") {
+            continue
+        }
+        let text = ""
+        if (err) {
+            text = err.message
+        }
+        assert.ok(text.startsWith("gif:") && text.endsWith(": unexpected EOF"), `Decode(testGIF[:${i}]) = ${err}, want gif: ...: unexpected EOF`)
+    }
+})
+
+// TestDecodeMemoryConsumption is not ported: the heap can't be measured
+// reliably without exposing the garbage collector.
+
+// Not present in the Go code, which only reads ../testdata/video-001.gif in
+// BenchmarkDecode. The hashes of the pixels are those decoded by Go's image/gif
+test("TestDecodeTestdata", () => {
+    let testCases: { filename: string, paletteLen: number, sha256: string }[] = [
+        { filename: "video-001.gif", paletteLen: 256, sha256: "747c193767d5a1208a01e7612ee245049705b539b5eefd975a290c8c6da554ac" },
+        // The same pixels as video-001.gif
+        { filename: "video-001.interlaced.gif", paletteLen: 256, sha256: "747c193767d5a1208a01e7612ee245049705b539b5eefd975a290c8c6da554ac" },
+        { filename: "video-001.5bpp.gif", paletteLen: 32, sha256: "9efd1e64f077d07a85062a4f533a092f98dfbf215a6bcea142adc3627fb4a8dc" },
+    ]
+    for (let tc of testCases) {
+        let data = new Uint8Array(fs.readFileSync("src/image/testdata/" + tc.filename))
+
+        let [cfg, err] = DecodeConfig(new GoBuffer(data))
+        assert.equal(err, null, `${tc.filename}: DecodeConfig`)
+        assert.equal(cfg.Width, 150, tc.filename)
+        assert.equal(cfg.Height, 103, tc.filename)
+        assert.equal(cfg.ColorModel?.length, tc.paletteLen, tc.filename)
+
+        let [m, err1] = Decode(new GoBuffer(data))
+        assert.equal(err1, null, `${tc.filename}: Decode`)
+        assert.ok(m!.Bounds().Eq(Rect(0, 0, 150, 103)), tc.filename)
+        assert.equal(m!.Palette.length, tc.paletteLen, tc.filename)
+        assert.equal(createHash("sha256").update(m!.Pix).digest("hex"), tc.sha256, tc.filename)
+    }
+})
+
+test("TestReencodeExtendedPalette", () => {
+    let data = new Uint8Array(Buffer.from("4749463839616c02020157220221ff0b280154ffffffff00000021474946306127dc213000ff84ff840000000000800021ffffffff8f4e4554530041508f8f0202020000000000000000000000000202020202020207020202022f31050000000000000021f904ab2c3826002c00000000c00001009800462b07fc1f02061202020602020202220202930202020202020202020202020286090222202222222222222222222222222222222222222222222222222220222222222222222222222222222222222222222222222222221a22222222332223222222222222222222222222222222222222224b222222222222002200002b474946312829021f0000000000cbff002f0202073121f904ab2c2c000021f92c3803002c00e0c0000000f932", "hex"))
+    let [img, err] = Decode(new GoBuffer(data))
+    assert.equal(err, null, "Decode")
+    err = Encode(Discard, img!, { NumColors: 1 })
+    assert.equal(err, null, "Encode")
+})
+
+// plan9 and webSafe return the palettes of Go's image/color/palette, which is
+// not ported. They are generated as in its gen.go
+function plan9(): Palette {
+    let p: Palette = new Array(256)
+    for (let r = 0, i = 0; r != 4; r++) {
+        for (let v = 0; v != 4; v++, i += 16) {
+            for (let g = 0, j = v - r; g != 4; g++) {
+                for (let b = 0; b != 4; b++, j++) {
+                    let den = Math.max(r, g, b)
+                    let c: RGBA
+                    if (den == 0) {
+                        c = new RGBA(0x11 * v, 0x11 * v, 0x11 * v, 0xff)
+                    } else {
+                        let num = 17 * (4 * den + v)
+                        c = new RGBA(Math.trunc(r * num / den), Math.trunc(g * num / den), Math.trunc(b * num / den), 0xff)
+                    }
+                    p[i + (j & 0x0f)] = c
+                }
+            }
+        }
+    }
+    return p
+}
+
+function webSafe(): Palette {
+    let p: Palette = new Array(6 * 6 * 6)
+    for (let r = 0; r < 6; r++) {
+        for (let g = 0; g < 6; g++) {
+            for (let b = 0; b < 6; b++) {
+                p[36 * r + 6 * g + b] = new RGBA(0x33 * r, 0x33 * g, 0x33 * b, 0xff)
+            }
+        }
+    }
+    return p
+}
+
+function readGIF(filename: string): [GIF | null, Error | null] {
+    return DecodeAll(new GoBuffer(new Uint8Array(fs.readFileSync(filename))))
+}
+
+function readImg(filename: string): [Paletted | null, Error | null] {
+    return Decode(new GoBuffer(new Uint8Array(fs.readFileSync(filename))))
+}
+
+function delta(u0: number, u1: number): number {
+    return Math.abs(u0 - u1)
+}
+
+// averageDelta returns the average delta in RGB space. The two images must
+// have the same bounds.
+function averageDelta(m0: Image, m1: Image): number {
+    let b = m0.Bounds()
+    return averageDeltaBound(m0, m1, b, b)
+}
+
+// averageDeltaBound returns the average delta in RGB space. The average delta is
+// calculated in the specified bounds.
+function averageDeltaBound(m0: Image, m1: Image, b0: Rectangle, b1: Rectangle): number {
+    let sum = 0, n = 0
+    for (let y = b0.Min.Y; y < b0.Max.Y; y++) {
+        for (let x = b0.Min.X; x < b0.Max.X; x++) {
+            let c0 = m0.At(x, y)!
+            let c1 = m1.At(x - b0.Min.X + b1.Min.X, y - b0.Min.Y + b1.Min.Y)!
+            let [r0, g0, bl0] = c0.RGBA()
+            let [r1, g1, bl1] = c1.RGBA()
+            sum += delta(r0, r1)
+            sum += delta(g0, g1)
+            sum += delta(bl0, bl1)
+            n += 3
+        }
+    }
+    return Math.trunc(sum / n)
+}
+
+// toRGBA returns a copy of m as an RGBA image
+function toRGBA(m: Image): Image {
+    let b = m.Bounds()
+    let rgba = NewRGBA(b)
+    Src.Draw(rgba, b, m, b.Min)
+    return rgba
+}
+
+const testCase: { filename: string, tolerance: number, rgba?: boolean }[] = [
+    // Go encodes ../testdata/video-001.png here. This is the same picture, as
+    // an RGBA image, so it goes through the Quantizer and Drawer
+    { filename: "src/image/testdata/video-001.gif", tolerance: 1 << 12, rgba: true },
+    { filename: "src/image/testdata/video-001.gif", tolerance: 0 },
+    { filename: "src/image/testdata/video-001.interlaced.gif", tolerance: 0 },
+]
+
+test("TestWriter", () => {
+    for (let tc of testCase) {
+        let [m, err] = readImg(tc.filename)
+        assert.equal(err, null, tc.filename)
+        let m0: Image = tc.rgba ? toRGBA(m!) : m!
+        let buf = new GoBuffer(new Uint8Array(0))
+        err = Encode(buf, m0, null)
+        assert.equal(err, null, tc.filename)
+        let [m1, err1] = Decode(buf)
+        assert.equal(err1, null, tc.filename)
+        assert.ok(m0.Bounds().Eq(m1!.Bounds()), `${tc.filename}, bounds differ`)
+        // Compare the average delta to the tolerance level.
+        let avgDelta = averageDelta(m0, m1!)
+        assert.ok(avgDelta <= tc.tolerance, `${tc.filename}: average delta is too high. expected: ${tc.tolerance}, got ${avgDelta}`)
+    }
+})
+
+test("TestSubImage", () => {
+    let [m, err] = readImg("src/image/testdata/video-001.gif")
+    assert.equal(err, null, "readImg")
+    let m0 = m!.SubImage(Rect(0, 0, 50, 30))
+    let buf = new GoBuffer(new Uint8Array(0))
+    err = Encode(buf, m0, null)
+    assert.equal(err, null, "Encode")
+    let [m1, err1] = Decode(buf)
+    assert.equal(err1, null, "Decode")
+    assert.ok(m0.Bounds().Eq(m1!.Bounds()), "bounds differ")
+    assert.equal(averageDelta(m0, m1!), 0, "images differ")
+})
+
+// colorsEqual reports whether c and d are the same type of color with the same
+// value, like == on Go's color.Color interface values
+function colorsEqual(c: Color | null, d: Color | null): boolean {
+    if (c == null || d == null) {
+        return c == d
+    }
+    return c.constructor == d.constructor && c.RGBA().every((v, i) => v == d.RGBA()[i])
+}
+
+// palettesEqual reports whether two color.Palette values are equal, ignoring
+// any trailing opaque-black palette entries.
+function palettesEqual(p: Palette, q: Palette): boolean {
+    let n = Math.min(p.length, q.length)
+    for (let i = 0; i < n; i++) {
+        if (!colorsEqual(p[i], q[i])) {
+            return false
+        }
+    }
+    for (let c of [...p.slice(n), ...q.slice(n)]) {
+        let [r, g, b, a] = c.RGBA()
+        if (r != 0 || g != 0 || b != 0 || a != 0xffff) {
+            return false
+        }
+    }
+    return true
+}
+
+const frames = [
+    "src/image/testdata/video-001.gif",
+    "src/image/testdata/video-005.gray.gif",
+]
+
+function testEncodeAll(go1Dot5Fields: boolean, useGlobalColorModel: boolean) {
+    const width = 150, height = 103
+
+    let g0: GIF = {
+        Image: new Array(frames.length),
+        Delay: new Array(frames.length).fill(0),
+        LoopCount: 5,
+        Disposal: null,
+        Config: { ColorModel: null, Width: 0, Height: 0 },
+        BackgroundIndex: 0,
+    }
+    for (let i = 0; i < frames.length; i++) {
+        let [g, err] = readGIF(frames[i])
+        assert.equal(err, null, frames[i])
+        let m = g!.Image[0]
+        assert.ok(m.Bounds().Dx() == width && m.Bounds().Dy() == height,
+            `frame ${i} had unexpected bounds: got ${m.Bounds().Dx()}x${m.Bounds().Dy()}, want width/height = ${width}/${height}`)
+        g0.Image[i] = m
+    }
+    // The GIF.Disposal, GIF.Config and GIF.BackgroundIndex fields were added
+    // in Go 1.5. Valid Go 1.4 or earlier code should still produce valid GIFs.
+    let globalColorModel: Palette | null = null, backgroundIndex = 0
+    if (useGlobalColorModel) {
+        [globalColorModel, backgroundIndex] = [webSafe(), 1]
+    }
+    if (go1Dot5Fields) {
+        g0.Disposal = new Array(g0.Image.length).fill(DisposalNone)
+        g0.Config = {
+            ColorModel: globalColorModel,
+            Width: width,
+            Height: height,
+        }
+        g0.BackgroundIndex = backgroundIndex
+    }
+
+    let buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(EncodeAll(buf, g0), null, "EncodeAll")
+    let encoded = buf.underlyingArray
+    let [config, err] = DecodeConfig(new GoBuffer(encoded))
+    assert.equal(err, null, "DecodeConfig")
+    let [g1, err1] = DecodeAll(new GoBuffer(encoded))
+    assert.equal(err1, null, "DecodeAll")
+
+    assert.deepEqual(config, g1!.Config, "DecodeConfig inconsistent with DecodeAll")
+    assert.ok(palettesEqual(g1!.Config.ColorModel ?? [], globalColorModel ?? []), "unexpected global color model")
+    assert.equal(g1!.Config.Width, width)
+    assert.equal(g1!.Config.Height, height)
+
+    assert.equal(g0.LoopCount, g1!.LoopCount, "loop counts differ")
+    assert.equal(backgroundIndex, g1!.BackgroundIndex, "background indexes differ")
+    assert.equal(g0.Image.length, g1!.Image.length, "image lengths differ")
+    assert.equal(g1!.Image.length, g1!.Delay.length, "image and delay lengths differ")
+    assert.equal(g1!.Image.length, g1!.Disposal!.length, "image and disposal lengths differ")
+
+    for (let i = 0; i < g0.Image.length; i++) {
+        let [m0, m1] = [g0.Image[i], g1!.Image[i]]
+        assert.ok(m0.Bounds().Eq(m1.Bounds()), `frame ${i}: bounds differ`)
+        assert.equal(g0.Delay[i], g1!.Delay[i], `frame ${i}: delay values differ`)
+        let p0 = 0
+        if (go1Dot5Fields) {
+            p0 = DisposalNone
+        }
+        assert.equal(p0, g1!.Disposal![i], `frame ${i}: disposal values differ`)
+    }
+}
+
+test("TestEncodeAllGo1Dot4", () => testEncodeAll(false, false))
+test("TestEncodeAllGo1Dot5", () => testEncodeAll(true, false))
+test("TestEncodeAllGo1Dot5GlobalColorModel", () => testEncodeAll(true, true))
+
+// newGIF returns a GIF of images with the zero values of the other fields
+function newGIF(images: Paletted[], delay: number[], disposal: number[] | null = null): GIF {
+    return {
+        Image: images,
+        Delay: delay,
+        LoopCount: 0,
+        Disposal: disposal,
+        Config: { ColorModel: null, Width: 0, Height: 0 },
+        BackgroundIndex: 0,
+    }
+}
+
+test("TestEncodeMismatchDelay", () => {
+    let images = [
+        NewPaletted(Rect(0, 0, 5, 5), plan9()),
+        NewPaletted(Rect(0, 0, 5, 5), plan9()),
+    ]
+
+    let g0 = newGIF(images, [0])
+    assert.ok(EncodeAll(Discard, g0) != null, "expected error from mismatched delay and image slice lengths")
+
+    let g1 = newGIF(images, new Array(images.length).fill(0), [DisposalNone])
+    assert.ok(EncodeAll(Discard, g1) != null, "expected error from mismatched disposal and image slice lengths")
+})
+
+test("TestEncodeZeroGIF", () => {
+    assert.ok(EncodeAll(Discard, newGIF([], [])) != null, "expected error from providing empty gif")
+})
+
+test("TestEncodeAllFramesOutOfBounds", () => {
+    let images = [
+        NewPaletted(Rect(0, 0, 5, 5), plan9()),
+        NewPaletted(Rect(2, 2, 8, 8), plan9()),
+        NewPaletted(Rect(3, 3, 4, 4), plan9()),
+    ]
+    for (let upperBound of [6, 10]) {
+        let g = newGIF(images, new Array(images.length).fill(0), new Array(images.length).fill(0))
+        g.Config = { ColorModel: null, Width: upperBound, Height: upperBound }
+        let err = EncodeAll(Discard, g)
+        if (upperBound >= 8) {
+            assert.equal(err, null, `upperBound=${upperBound}`)
+        } else {
+            assert.ok(err != null, `upperBound=${upperBound}: got nil error, want non-nil`)
+        }
+    }
+})
+
+test("TestEncodeNonZeroMinPoint", () => {
+    let points = [
+        new Point(-8, -9),
+        new Point(-4, -4),
+        new Point(-3, +3),
+        new Point(+0, +0),
+        new Point(+2, +2),
+    ]
+    for (let p of points) {
+        let src = NewPaletted(new Rectangle(p, p.Add(new Point(6, 6))), plan9())
+        let buf = new GoBuffer(new Uint8Array(0))
+        assert.equal(Encode(buf, src, null), null, `p=${p.X},${p.Y}: Encode`)
+        let [m, err] = Decode(buf)
+        assert.equal(err, null, `p=${p.X},${p.Y}: Decode`)
+        assert.ok(m!.Bounds().Eq(Rect(0, 0, 6, 6)), `p=${p.X},${p.Y}: got bounds ${JSON.stringify(m!.Bounds())}`)
+    }
+
+    // Also test having a source image (gray on the diagonal) that has a
+    // non-zero Bounds().Min, but isn't an image.Paletted.
+    let p = new Point(+2, +2)
+    let src = NewRGBA(new Rectangle(p, p.Add(new Point(6, 6))))
+    src.Set(2, 2, new RGBA(0x22, 0x22, 0x22, 0xFF))
+    src.Set(3, 3, new RGBA(0x33, 0x33, 0x33, 0xFF))
+    src.Set(4, 4, new RGBA(0x44, 0x44, 0x44, 0xFF))
+    src.Set(5, 5, new RGBA(0x55, 0x55, 0x55, 0xFF))
+    src.Set(6, 6, new RGBA(0x66, 0x66, 0x66, 0xFF))
+    src.Set(7, 7, new RGBA(0x77, 0x77, 0x77, 0xFF))
+
+    let buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(Encode(buf, src, null), null, "gray-diagonal: Encode")
+    let [m, err] = Decode(buf)
+    assert.equal(err, null, "gray-diagonal: Decode")
+    assert.ok(m!.Bounds().Eq(Rect(0, 0, 6, 6)), "gray-diagonal: bounds")
+
+    let rednessAt = (x: number, y: number): number => {
+        let [r] = m!.At(x, y)!.RGBA()
+        // Shift by 8 to convert from 16 bit color to 8 bit color.
+        return r >>> 8
+    }
+
+    // Round-tripping a still (non-animated) image.Image through
+    // Encode+Decode should shift the origin to (0, 0).
+    assert.equal(rednessAt(0, 0), 0x22, "gray-diagonal: rednessAt(0, 0)")
+    assert.equal(rednessAt(5, 5), 0x77, "gray-diagonal: rednessAt(5, 5)")
+})
+
+test("TestEncodeImplicitConfigSize", () => {
+    // For backwards compatibility for Go 1.4 and earlier code, the Config
+    // field is optional, and if zero, the width and height is implied by the
+    // first (and in this case only) frame's width and height.
+    //
+    // A Config only specifies a width and height (two integers) while an
+    // image.Image's Bounds method returns an image.Rectangle (four integers).
+    // For a gif.GIF, the overall bounds' top-left point is always implicitly
+    // (0, 0), and any frame whose bounds have a negative X or Y will be
+    // outside those overall bounds, so encoding should fail.
+    for (let lowerBound of [-1, 0, 1]) {
+        let images = [
+            NewPaletted(Rect(lowerBound, lowerBound, 4, 4), plan9()),
+        ]
+        let err = EncodeAll(Discard, newGIF(images, new Array(images.length).fill(0)))
+        if (lowerBound >= 0) {
+            assert.equal(err, null, `lowerBound=${lowerBound}`)
+        } else {
+            assert.ok(err != null, `lowerBound=${lowerBound}: got nil error, want non-nil`)
+        }
+    }
+})
+
+test("TestEncodePalettes", () => {
+    const w = 5, h = 5
+    let pals: Palette[] = [[
+        new RGBA(0x00, 0x00, 0x00, 0xff),
+        new RGBA(0x01, 0x00, 0x00, 0xff),
+        new RGBA(0x02, 0x00, 0x00, 0xff),
+    ], [
+        new RGBA(0x00, 0x00, 0x00, 0xff),
+        new RGBA(0x00, 0x01, 0x00, 0xff),
+    ], [
+        new RGBA(0x00, 0x00, 0x03, 0xff),
+        new RGBA(0x00, 0x00, 0x02, 0xff),
+        new RGBA(0x00, 0x00, 0x01, 0xff),
+        new RGBA(0x00, 0x00, 0x00, 0xff),
+    ], [
+        new RGBA(0x10, 0x07, 0xf0, 0xff),
+        new RGBA(0x20, 0x07, 0xf0, 0xff),
+        new RGBA(0x30, 0x07, 0xf0, 0xff),
+        new RGBA(0x40, 0x07, 0xf0, 0xff),
+        new RGBA(0x50, 0x07, 0xf0, 0xff),
+    ]]
+    let g0 = newGIF(
+        pals.map((p) => NewPaletted(Rect(0, 0, w, h), p)),
+        new Array(pals.length).fill(0),
+        new Array(pals.length).fill(0),
+    )
+    g0.Config = { ColorModel: pals[2], Width: w, Height: h }
+
+    let buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(EncodeAll(buf, g0), null, "EncodeAll")
+    let [g1, err] = DecodeAll(buf)
+    assert.equal(err, null, "DecodeAll")
+    assert.equal(g0.Image.length, g1!.Image.length, "image lengths differ")
+    for (let i = 0; i < g1!.Image.length; i++) {
+        assert.ok(palettesEqual(g1!.Image[i].Palette, pals[i]), `frame ${i}`)
+    }
+})
+
+test("TestEncodeBadPalettes", () => {
+    const w = 5, h = 5
+    for (let n of [256, 257]) {
+        for (let nilColors of [false, true]) {
+            let pal: Palette = new Array(n)
+            if (!nilColors) {
+                pal.fill(new RGBA(0, 0, 0, 0xff))
+            }
+
+            let g = newGIF([NewPaletted(Rect(0, 0, w, h), pal)], [0], [0])
+            g.Config = { ColorModel: pal, Width: w, Height: h }
+            let err = EncodeAll(Discard, g)
+
+            let got = err != null
+            let want = n > 256 || nilColors
+            assert.equal(got, want, `n=${n}, nilColors=${nilColors}: err != nil`)
+        }
+    }
+})
+
+// Not present in the Go code in this form: Go's test calls the unexported
+// encodeColorTable and colorTablesMatch. This checks their result instead, that
+// a frame whose palette is the global one with trIdx made transparent, like
+// DecodeAll returns, uses the global color table.
+test("TestColorTablesMatch", () => {
+    const trIdx = 100
+    let global = plan9()
+    let rgb = global[trIdx] as RGBA
+    assert.ok(rgb.R != 0 || rgb.G != 0 || rgb.B != 0, `trIdx (${trIdx}) is already black`)
+
+    // Make a copy of the palette, substituting trIdx's slot with transparent,
+    // just like decoder.decode.
+    let local = global.slice()
+    local[trIdx] = new RGBA()
+
+    let g = newGIF([NewPaletted(Rect(0, 0, 5, 5), local)], [0], [0])
+    g.Config = { ColorModel: global, Width: 5, Height: 5 }
+    let buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(EncodeAll(buf, g), null, "EncodeAll")
+
+    // The header and global color table, then the graphic control extension for
+    // the transparent index, then the image descriptor
+    let b = buf.underlyingArray
+    let desc = 13 + 3 * 256 + 8
+    assert.equal(b[desc], 0x2c, "image descriptor")
+    assert.equal(b[desc + 9], 0x00, "local color table flags") // packed fields, after the bounds
+})
+
+test("TestEncodeCroppedSubImages", () => {
+    // This test means to ensure that Encode honors the Bounds and Strides of
+    // images correctly when encoding.
+    let whole = NewPaletted(Rect(0, 0, 100, 100), plan9())
+    let subImages = [
+        Rect(0, 0, 50, 50),
+        Rect(50, 0, 100, 50),
+        Rect(0, 50, 50, 50),
+        Rect(50, 50, 100, 100),
+        Rect(25, 25, 75, 75),
+        Rect(0, 0, 100, 50),
+        Rect(0, 50, 100, 100),
+        Rect(0, 0, 50, 100),
+        Rect(50, 0, 100, 100),
+    ]
+    for (let sr of subImages) {
+        let si = whole.SubImage(sr)
+        let buf = new GoBuffer(new Uint8Array(0))
+        assert.equal(Encode(buf, si, null), null, `Encode: sr=${JSON.stringify(sr)}`)
+        let [, err] = Decode(buf)
+        assert.equal(err, null, `Decode: sr=${JSON.stringify(sr)}`)
+    }
+})
+
+// offsetImage is an image.Image with the pixels of another one, but other bounds
+class offsetImage implements Image {
+    Image: Image
+    Rect: Rectangle
+
+    constructor(m: Image, r: Rectangle) {
+        this.Image = m
+        this.Rect = r
+    }
+
+    ColorModel(): Palette | null {
+        return this.Image.ColorModel?.() ?? null
+    }
+
+    Bounds(): Rectangle {
+        return this.Rect
+    }
+
+    At(x: number, y: number): Color | null {
+        return this.Image.At(x, y)
+    }
+}
+
+test("TestEncodeWrappedImage", () => {
+    let [m0, err] = readImg("src/image/testdata/video-001.gif")
+    assert.equal(err, null, "readImg")
+
+    // Case 1: Encode a wrapped image.Image
+    let buf = new GoBuffer(new Uint8Array(0))
+    let w0 = new offsetImage(m0!, m0!.Bounds())
+    assert.equal(Encode(buf, w0, null), null, "Encode")
+    let [w1, err1] = Decode(buf)
+    assert.equal(err1, null, "Decode")
+    let avgDelta = averageDelta(m0!, w1!)
+    assert.equal(avgDelta, 0, `Wrapped: average delta is too high. expected: 0, got ${avgDelta}`)
+
+    // Case 2: Encode a wrapped image.Image with offset
+    let b0 = new Rectangle(new Point(128, 64), new Point(256, 128))
+    w0 = new offsetImage(m0!, b0)
+    buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(Encode(buf, w0, null), null, "Encode")
+    let [w2, err2] = Decode(buf)
+    assert.equal(err2, null, "Decode")
+
+    let b1 = new Rectangle(new Point(0, 0), new Point(128, 64))
+    avgDelta = averageDeltaBound(m0!, w2!, b0, b1)
+    assert.equal(avgDelta, 0, `Wrapped and offset: average delta is too high. expected: 0, got ${avgDelta}`)
+})
+
+// Not present in the Go code
+test("TestEncodePaletteColorModel", () => {
+    // An image that is not a Paletted but has a Palette color model is encoded
+    // with that palette, so its colors and their order round-trip
+    let pal: Palette = [
+        new RGBA(0xff, 0x00, 0x00, 0xff),
+        new RGBA(0x00, 0x00, 0xff, 0xff),
+        new RGBA(0x00, 0xff, 0x00, 0xff),
+    ]
+    let pm = NewPaletted(Rect(0, 0, 4, 4), pal)
+    for (let i = 0; i < pm.Pix.length; i++) {
+        pm.Pix[i] = i % 3
+    }
+
+    let buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(Encode(buf, new offsetImage(pm, pm.Bounds()), null), null, "Encode")
+    let [m, err] = Decode(buf)
+    assert.equal(err, null, "Decode")
+    assert.ok(palettesEqual(m!.Palette, pal), "palettes differ")
+    assert.deepEqual(m!.Pix, pm.Pix)
+
+    // Images without a ColorModel are quantized
+    let plain: Image = { Bounds: () => pm.Bounds(), At: (x, y) => pm.At(x, y) }
+    buf = new GoBuffer(new Uint8Array(0))
+    assert.equal(Encode(buf, plain, null), null, "Encode")
+    ;[m, err] = Decode(buf)
+    assert.equal(err, null, "Decode")
+    assert.equal(averageDelta(pm, m!), 0)
+})
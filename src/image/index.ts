@@ -0,0 +1,449 @@
+// A minimal port of Go's image package, covering what image/gif needs
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/image/image.go
+
+import { Color, NRGBA as NRGBAColor, Palette, PaletteIndex, RGBA as RGBAColor } from "./color"
+
+/**
+ * image.Point from Golang
+ *
+ * A Point is an X, Y coordinate pair. The axes increase right and down.
+ */
+export class Point {
+    X: number
+    Y: number
+
+    constructor(x: number = 0, y: number = 0) {
+        this.X = x
+        this.Y = y
+    }
+
+    // Add returns the vector p+q.
+    Add(q: Point): Point {
+        return new Point(this.X + q.X, this.Y + q.Y)
+    }
+
+    // Sub returns the vector p-q.
+    Sub(q: Point): Point {
+        return new Point(this.X - q.X, this.Y - q.Y)
+    }
+
+    // In reports whether p is in r.
+    In(r: Rectangle): boolean {
+        return r.Min.X <= this.X && this.X < r.Max.X &&
+            r.Min.Y <= this.Y && this.Y < r.Max.Y
+    }
+
+    // Eq reports whether p and q are equal.
+    Eq(q: Point): boolean {
+        return this.X == q.X && this.Y == q.Y
+    }
+}
+
+/**
+ * image.Rectangle from Golang
+ *
+ * A Rectangle contains the points with Min.X <= X < Max.X, Min.Y <= Y < Max.Y.
+ * It is well-formed if Min.X <= Max.X and likewise for Y. Points are always
+ * well-formed. A rectangle's methods always return well-formed outputs for
+ * well-formed inputs.
+ */
+export class Rectangle {
+    Min: Point
+    Max: Point
+
+    constructor(min: Point = new Point(), max: Point = new Point()) {
+        this.Min = min
+        this.Max = max
+    }
+
+    // Dx returns r's width.
+    Dx(): number {
+        return this.Max.X - this.Min.X
+    }
+
+    // Dy returns r's height.
+    Dy(): number {
+        return this.Max.Y - this.Min.Y
+    }
+
+    // Add returns the rectangle r translated by p.
+    Add(p: Point): Rectangle {
+        return new Rectangle(
+            new Point(this.Min.X + p.X, this.Min.Y + p.Y),
+            new Point(this.Max.X + p.X, this.Max.Y + p.Y),
+        )
+    }
+
+    // Sub returns the rectangle r translated by -p.
+    Sub(p: Point): Rectangle {
+        return new Rectangle(
+            new Point(this.Min.X - p.X, this.Min.Y - p.Y),
+            new Point(this.Max.X - p.X, this.Max.Y - p.Y),
+        )
+    }
+
+    // Intersect returns the largest rectangle contained by both r and s. If the
+    // two rectangles do not overlap then the zero rectangle will be returned.
+    Intersect(s: Rectangle): Rectangle {
+        let r = new Rectangle(new Point(this.Min.X, this.Min.Y), new Point(this.Max.X, this.Max.Y))
+        if (r.Min.X < s.Min.X) {
+            r.Min.X = s.Min.X
+        }
+        if (r.Min.Y < s.Min.Y) {
+            r.Min.Y = s.Min.Y
+        }
+        if (r.Max.X > s.Max.X) {
+            r.Max.X = s.Max.X
+        }
+        if (r.Max.Y > s.Max.Y) {
+            r.Max.Y = s.Max.Y
+        }
+        // Letting r0 and s0 be the values of r and s at the time that the method
+        // is called, this next line is equivalent to:
+        //
+        // if max(r0.Min.X, s0.Min.X) >= min(r0.Max.X, s0.Max.X) || likewiseForY { etc }
+        if (r.Empty()) {
+            return new Rectangle()
+        }
+        return r
+    }
+
+    // Empty reports whether the rectangle contains no points.
+    Empty(): boolean {
+        return this.Min.X >= this.Max.X || this.Min.Y >= this.Max.Y
+    }
+
+    // Eq reports whether r and s contain the same set of points. All empty
+    // rectangles are considered equal.
+    Eq(s: Rectangle): boolean {
+        return (this.Min.Eq(s.Min) && this.Max.Eq(s.Max)) ||
+            (this.Empty() && s.Empty())
+    }
+
+    // In reports whether every point in r is in s.
+    In(s: Rectangle): boolean {
+        if (this.Empty()) {
+            return true
+        }
+        // Note that r.Max is an exclusive bound for r, so that r.In(s)
+        // does not require that r.Max.In(s).
+        return s.Min.X <= this.Min.X && this.Max.X <= s.Max.X &&
+            s.Min.Y <= this.Min.Y && this.Max.Y <= s.Max.Y
+    }
+}
+
+/**
+ * Rect is shorthand for new Rectangle(new Point(x0, y0), new Point(x1, y1)). The returned
+ * rectangle has minimum and maximum coordinates swapped if necessary so that
+ * it is well-formed.
+ */
+export function Rect(x0: number, y0: number, x1: number, y1: number): Rectangle {
+    if (x0 > x1) {
+        [x0, x1] = [x1, x0]
+    }
+    if (y0 > y1) {
+        [y0, y1] = [y1, y0]
+    }
+    return new Rectangle(new Point(x0, y0), new Point(x1, y1))
+}
+
+/**
+ * image.Config from Golang
+ *
+ * Config holds an image's color model and dimensions.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * The only color model is a Palette, null stands for a nil color model
+ */
+export interface Config {
+    ColorModel: Palette | null
+    Width: number
+    Height: number
+}
+
+/**
+ * image.Image from Golang
+ *
+ * Image is a finite rectangular grid of colors.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * As with Config, the only color model is a Palette. ColorModel is optional, and
+ * returns null for images whose color model is not a Palette
+ */
+export interface Image {
+    // ColorModel returns the Image's color model.
+    ColorModel?(): Palette | null
+
+    // Bounds returns the domain for which At can return non-zero color.
+    // The bounds do not necessarily contain the point (0, 0).
+    Bounds(): Rectangle
+
+    // At returns the color of the pixel at (x, y).
+    // At(Bounds().Min.X, Bounds().Min.Y) returns the upper-left pixel of the grid.
+    // At(Bounds().Max.X-1, Bounds().Max.Y-1) returns the lower-right one.
+    At(x: number, y: number): Color | null
+}
+
+/**
+ * image.Paletted from Golang
+ *
+ * Paletted is an in-memory image of uint8 indices into a given palette.
+ */
+export class Paletted implements Image {
+    // Pix holds the image's pixels, as palette indices. The pixel at
+    // (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*1].
+    Pix: Uint8Array
+
+    // Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+    Stride: number
+
+    // Rect is the image's bounds.
+    Rect: Rectangle
+
+    // Palette is the image's palette.
+    Palette: Palette
+
+    constructor(pix: Uint8Array, stride: number, rect: Rectangle, palette: Palette) {
+        this.Pix = pix
+        this.Stride = stride
+        this.Rect = rect
+        this.Palette = palette
+    }
+
+    ColorModel(): Palette | null {
+        return this.Palette
+    }
+
+    Bounds(): Rectangle {
+        return this.Rect
+    }
+
+    At(x: number, y: number): Color | null {
+        if (this.Palette.length == 0) {
+            return null
+        }
+        if (!new Point(x, y).In(this.Rect)) {
+            return this.Palette[0]
+        }
+        let i = this.PixOffset(x, y)
+        return this.Palette[this.Pix[i]]
+    }
+
+    // PixOffset returns the index of the first element of Pix that corresponds to
+    // the pixel at (x, y).
+    PixOffset(x: number, y: number): number {
+        return (y - this.Rect.Min.Y) * this.Stride + (x - this.Rect.Min.X) * 1
+    }
+
+    Set(x: number, y: number, c: Color) {
+        if (!new Point(x, y).In(this.Rect)) {
+            return
+        }
+        let i = this.PixOffset(x, y)
+        this.Pix[i] = PaletteIndex(this.Palette, c)
+    }
+
+    ColorIndexAt(x: number, y: number): number {
+        if (!new Point(x, y).In(this.Rect)) {
+            return 0
+        }
+        let i = this.PixOffset(x, y)
+        return this.Pix[i]
+    }
+
+    SetColorIndex(x: number, y: number, index: number) {
+        if (!new Point(x, y).In(this.Rect)) {
+            return
+        }
+        let i = this.PixOffset(x, y)
+        this.Pix[i] = index
+    }
+
+    // SubImage returns an image representing the portion of the image p visible
+    // through r. The returned value shares pixels with the original image.
+    SubImage(r: Rectangle): Image {
+        r = r.Intersect(this.Rect)
+        // If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+        // either r1 or r2 if the intersection is empty. Without explicitly checking for
+        // this, the Pix[i:] expression below can panic.
+        if (r.Empty()) {
+            return new Paletted(new Uint8Array(0), 0, r, this.Palette)
+        }
+        let i = this.PixOffset(r.Min.X, r.Min.Y)
+        return new Paletted(this.Pix.subarray(i), this.Stride, this.Rect.Intersect(r), this.Palette)
+    }
+}
+
+/**
+ * NewPaletted returns a new Paletted image with the given width, height and
+ * palette.
+ */
+export function NewPaletted(r: Rectangle, p: Palette): Paletted {
+    let w = r.Dx(), h = r.Dy()
+    if (w < 0 || h < 0 || w * h > 0x7fffffff) {
+        throw new Error("image: NewPaletted Rectangle has huge or negative dimensions")
+    }
+    return new Paletted(new Uint8Array(w * h), 1 * w, r, p)
+}
+
+/**
+ * image.RGBA from Golang
+ *
+ * RGBA is an in-memory image whose At method returns color.RGBA values.
+ */
+export class RGBA implements Image {
+    // Pix holds the image's pixels, in R, G, B, A order. The pixel at
+    // (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+    Pix: Uint8Array
+
+    // Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+    Stride: number
+
+    // Rect is the image's bounds.
+    Rect: Rectangle
+
+    constructor(pix: Uint8Array, stride: number, rect: Rectangle) {
+        this.Pix = pix
+        this.Stride = stride
+        this.Rect = rect
+    }
+
+    ColorModel(): Palette | null {
+        return null
+    }
+
+    Bounds(): Rectangle {
+        return this.Rect
+    }
+
+    At(x: number, y: number): Color {
+        return this.RGBAAt(x, y)
+    }
+
+    RGBAAt(x: number, y: number): RGBAColor {
+        if (!new Point(x, y).In(this.Rect)) {
+            return new RGBAColor(0, 0, 0, 0)
+        }
+        let i = this.PixOffset(x, y)
+        let s = this.Pix
+        return new RGBAColor(s[i + 0], s[i + 1], s[i + 2], s[i + 3])
+    }
+
+    // PixOffset returns the index of the first element of Pix that corresponds to
+    // the pixel at (x, y).
+    PixOffset(x: number, y: number): number {
+        return (y - this.Rect.Min.Y) * this.Stride + (x - this.Rect.Min.X) * 4
+    }
+
+    Set(x: number, y: number, c: Color) {
+        if (!new Point(x, y).In(this.Rect)) {
+            return
+        }
+        let i = this.PixOffset(x, y)
+        let [r, g, b, a] = c.RGBA()
+        let s = this.Pix
+        s[i + 0] = r >>> 8
+        s[i + 1] = g >>> 8
+        s[i + 2] = b >>> 8
+        s[i + 3] = a >>> 8
+    }
+}
+
+/**
+ * NewRGBA returns a new RGBA image with the given bounds.
+ */
+export function NewRGBA(r: Rectangle): RGBA {
+    let w = r.Dx(), h = r.Dy()
+    if (w < 0 || h < 0 || w * h * 4 > 0x7fffffff) {
+        throw new Error("image: NewRGBA Rectangle has huge or negative dimensions")
+    }
+    return new RGBA(new Uint8Array(4 * w * h), 4 * w, r)
+}
+
+/**
+ * image.NRGBA from Golang
+ *
+ * NRGBA is an in-memory image whose At method returns color.NRGBA values.
+ *
+ * This is the layout of a canvas ImageData, which can be wrapped without copying:
+ *
+ *     new NRGBA(new Uint8Array(data.data.buffer), 4 * data.width, Rect(0, 0, data.width, data.height))
+ */
+export class NRGBA implements Image {
+    // Pix holds the image's pixels, in R, G, B, A order. The pixel at
+    // (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+    Pix: Uint8Array
+
+    // Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+    Stride: number
+
+    // Rect is the image's bounds.
+    Rect: Rectangle
+
+    constructor(pix: Uint8Array, stride: number, rect: Rectangle) {
+        this.Pix = pix
+        this.Stride = stride
+        this.Rect = rect
+    }
+
+    ColorModel(): Palette | null {
+        return null
+    }
+
+    Bounds(): Rectangle {
+        return this.Rect
+    }
+
+    At(x: number, y: number): Color {
+        return this.NRGBAAt(x, y)
+    }
+
+    NRGBAAt(x: number, y: number): NRGBAColor {
+        if (!new Point(x, y).In(this.Rect)) {
+            return new NRGBAColor(0, 0, 0, 0)
+        }
+        let i = this.PixOffset(x, y)
+        let s = this.Pix
+        return new NRGBAColor(s[i + 0], s[i + 1], s[i + 2], s[i + 3])
+    }
+
+    // PixOffset returns the index of the first element of Pix that corresponds to
+    // the pixel at (x, y).
+    PixOffset(x: number, y: number): number {
+        return (y - this.Rect.Min.Y) * this.Stride + (x - this.Rect.Min.X) * 4
+    }
+
+    Set(x: number, y: number, c: Color) {
+        if (!new Point(x, y).In(this.Rect)) {
+            return
+        }
+        let i = this.PixOffset(x, y)
+        let [r, g, b, a] = c.RGBA()
+        let s = this.Pix
+        if (a == 0) {
+            s[i + 0] = 0
+            s[i + 1] = 0
+            s[i + 2] = 0
+            s[i + 3] = 0
+            return
+        }
+        // Since Color.RGBA returns an alpha-premultiplied color, we should have r <= a && g <= a && b <= a.
+        s[i + 0] = Math.floor(r * 0xffff / a) >>> 8
+        s[i + 1] = Math.floor(g * 0xffff / a) >>> 8
+        s[i + 2] = Math.floor(b * 0xffff / a) >>> 8
+        s[i + 3] = a >>> 8
+    }
+}
+
+/**
+ * NewNRGBA returns a new NRGBA image with the given bounds.
+ */
+export function NewNRGBA(r: Rectangle): NRGBA {
+    let w = r.Dx(), h = r.Dy()
+    if (w < 0 || h < 0 || w * h * 4 > 0x7fffffff) {
+        throw new Error("image: NewNRGBA Rectangle has huge or negative dimensions")
+    }
+    return new NRGBA(new Uint8Array(4 * w * h), 4 * w, r)
+}
@@ -0,0 +1,350 @@
+// Promise based counterparts of the io interfaces, for data that arrives asynchronously
+//
+// Not present in the Go code: Go's Read and Write block until data is available, which
+// JS can't do. Sockets, fetch bodies and file handles are read by awaiting instead, so
+// each interface here has the same methods as its io counterpart, returning promises.
+//
+// A sync method can be awaited too, so the functions in this file accept both kinds of
+// Reader and Writer.
+import { nextSliceCap } from "../builtins/tshelpers/arrays"
+import { EOF, Errors, ErrShortBuffer, ErrShortWrite, ErrUnexpectedEOF, LimitedReader, Reader, ReaderAt, Seeker, Writer, WriterAt } from "./index"
+
+/**
+ * AsyncReader is the asynchronous counterpart of [Reader].
+ *
+ * Read reads up to len(p) bytes into p, with the same contract as Reader.Read. p must not
+ * be used by the caller until the returned promise settles.
+ */
+export interface AsyncReader {
+    Read(p: Uint8Array): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncWriter is the asynchronous counterpart of [Writer].
+ *
+ * Write writes len(p) bytes from p, with the same contract as Writer.Write. p must not be
+ * modified until the returned promise settles.
+ */
+export interface AsyncWriter {
+    Write(p: Uint8Array): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncReaderAt is the asynchronous counterpart of [ReaderAt].
+ */
+export interface AsyncReaderAt {
+    ReadAt(p: Uint8Array, off: number): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncWriterAt is the asynchronous counterpart of [WriterAt].
+ */
+export interface AsyncWriterAt {
+    WriteAt(p: Uint8Array, off: number): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncSeeker is the asynchronous counterpart of [Seeker].
+ */
+export interface AsyncSeeker {
+    Seek(offset: number, whence: number): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncCloser is the asynchronous counterpart of [Closer].
+ *
+ * Close may also return its result directly, so every Closer is an AsyncCloser.
+ * Callers should await the result either way.
+ */
+export interface AsyncCloser {
+    Close(): Promise<Error | null> | Error | null
+}
+
+/**
+ * AsyncReaderFrom is the asynchronous counterpart of [ReaderFrom].
+ */
+export interface AsyncReaderFrom {
+    ReadFrom(r: AsyncReader): Promise<[number, Error | null]>
+}
+
+/**
+ * AsyncWriterTo is the asynchronous counterpart of [WriterTo].
+ */
+export interface AsyncWriterTo {
+    WriteTo(w: AsyncWriter): Promise<[number, Error | null]>
+}
+
+// AsyncReadWriter is the interface that groups the basic Read and Write methods.
+export interface AsyncReadWriter extends AsyncReader, AsyncWriter {}
+
+// AsyncReadCloser is the interface that groups the basic Read and Close methods.
+export interface AsyncReadCloser extends AsyncReader, AsyncCloser {}
+
+// AsyncWriteCloser is the interface that groups the basic Write and Close methods.
+export interface AsyncWriteCloser extends AsyncWriter, AsyncCloser {}
+
+// AsyncReadWriteCloser is the interface that groups the basic Read, Write and Close methods.
+export interface AsyncReadWriteCloser extends AsyncReader, AsyncWriter, AsyncCloser {}
+
+// AsyncReadSeeker is the interface that groups the basic Read and Seek methods.
+export interface AsyncReadSeeker extends AsyncReader, AsyncSeeker {}
+
+// AsyncReadSeekCloser is the interface that groups the basic Read, Seek and Close
+// methods.
+export interface AsyncReadSeekCloser extends AsyncReader, AsyncSeeker, AsyncCloser {}
+
+/**
+ * AsyncCopy is the asynchronous counterpart of [Copy]. It copies from src to dst until
+ * either EOF is reached on src or an error occurs. It returns the number of bytes
+ * copied and the first error encountered while copying, if any.
+ *
+ * A successful AsyncCopy returns err == null, not err == EOF.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * The WriterTo and ReaderFrom shortcuts are not taken: their sync and async forms have
+ * the same method names and can't be told apart, and a sync WriteTo or ReadFrom could
+ * not wait for the other side anyway. The copy always goes through a buffer.
+ *
+ * @param dst Writer or AsyncWriter to copy to
+ * @param src Reader or AsyncReader to copy from
+ */
+export function AsyncCopy(dst: Writer | AsyncWriter, src: Reader | AsyncReader): Promise<[number, Error | null]> {
+    return asyncCopyBuffer(dst, src, null)
+}
+
+/**
+ * AsyncCopyBuffer is identical to AsyncCopy except that it stages through the
+ * provided buffer rather than allocating a temporary one. If buf is null, one is
+ * allocated; otherwise if it has zero length, AsyncCopyBuffer panics.
+ *
+ * @param dst Writer or AsyncWriter to copy to
+ * @param src Reader or AsyncReader to copy from
+ * @param buf Buffer to use
+ */
+export function AsyncCopyBuffer(dst: Writer | AsyncWriter, src: Reader | AsyncReader, buf: Uint8Array | null): Promise<[number, Error | null]> {
+    if (buf != null && buf.length == 0) {
+        throw new Error("empty buffer in AsyncCopyBuffer")
+    }
+    return asyncCopyBuffer(dst, src, buf)
+}
+
+// asyncCopyBuffer is the actual implementation of AsyncCopy and AsyncCopyBuffer.
+// if buf is null, one is allocated.
+async function asyncCopyBuffer(dst: Writer | AsyncWriter, src: Reader | AsyncReader, buf: Uint8Array | null): Promise<[number, Error | null]> {
+    if (buf == null) {
+        let size = 32 * 1024
+        if (src instanceof LimitedReader && size > src.n) {
+            if (src.n < 1) {
+                size = 1
+            } else {
+                size = src.n
+            }
+        }
+        buf = new Uint8Array(size)
+    }
+
+    let written = 0
+    let err: Error | null = null
+    while (true) { // for {}
+        let [nr, er] = await src.Read(buf)
+        if (nr > 0) {
+            let [nw, ew] = await dst.Write(buf.subarray(0, nr))
+            if (nw < 0 || nr < nw) {
+                nw = 0
+                if (ew == null) {
+                    ew = Errors.InvalidWrite
+                }
+            }
+            written += nw
+            if (ew != null) {
+                err = ew
+                break
+            }
+            if (nr != nw) {
+                err = ErrShortWrite
+                break
+            }
+        }
+        if (er != null) {
+            if (er != EOF) {
+                err = er
+            }
+            break
+        }
+    }
+    return [written, err]
+}
+
+/**
+ * AsyncReadAtLeast is the asynchronous counterpart of [ReadAtLeast]. It reads from r
+ * into buf until it has read at least min bytes.
+ * It returns the number of bytes copied and an error if fewer bytes were read.
+ * The error is EOF only if no bytes were read.
+ * If an EOF happens after reading fewer than min bytes,
+ * AsyncReadAtLeast returns [ErrUnexpectedEOF].
+ * If min is greater than the length of buf, AsyncReadAtLeast returns [ErrShortBuffer].
+ * On return, n >= min if and only if err == null.
+ * If r returns an error having read at least min bytes, the error is dropped.
+ */
+export async function AsyncReadAtLeast(r: Reader | AsyncReader, buf: Uint8Array, min: number): Promise<[number, Error | null]> {
+    if (buf.length < min) {
+        return [0, ErrShortBuffer]
+    }
+    let n = 0
+    let err: Error | null = null
+    while (n < min && err == null) {
+        let nn: number;
+        [nn, err] = await r.Read(buf.subarray(n))
+        n += nn
+    }
+    if (n >= min) {
+        err = null
+    } else if (n > 0 && err == EOF) {
+        err = ErrUnexpectedEOF
+    }
+    return [n, err]
+}
+
+/**
+ * AsyncReadFull is the asynchronous counterpart of [ReadFull]. It reads exactly
+ * len(buf) bytes from r into buf.
+ * It returns the number of bytes copied and an error if fewer bytes were read.
+ * The error is EOF only if no bytes were read.
+ * If an EOF happens after reading some but not all the bytes,
+ * AsyncReadFull returns [ErrUnexpectedEOF].
+ * On return, n == len(buf) if and only if err == null.
+ * If r returns an error having read at least len(buf) bytes, the error is dropped.
+ */
+export function AsyncReadFull(r: Reader | AsyncReader, buf: Uint8Array): Promise<[number, Error | null]> {
+    return AsyncReadAtLeast(r, buf, buf.length)
+}
+
+/**
+ * AsyncReadAll is the asynchronous counterpart of [ReadAll]. It reads from r until an
+ * error or EOF and returns the data it read.
+ * A successful call returns err == null, not err == EOF. Because AsyncReadAll is
+ * defined to read from src until EOF, it does not treat an EOF from Read
+ * as an error to be reported.
+ *
+ * Like ReadAll, it grows a single buffer as Go's append does, and the result may be a
+ * view into that larger buffer.
+ */
+export async function AsyncReadAll(r: Reader | AsyncReader): Promise<[Uint8Array, Error | null]> {
+    let b = new Uint8Array(512)
+    let len = 0
+    while (true) { // for {}
+        let [n, err] = await r.Read(b.subarray(len))
+        len += n
+        if (err != null) {
+            if (err == EOF) {
+                err = null
+            }
+            return [b.subarray(0, len), err]
+        }
+
+        if (len == b.length) {
+            // Add more capacity (let append pick how much).
+            let grown = new Uint8Array(nextSliceCap(len + 1, b.length))
+            grown.set(b)
+            b = grown
+        }
+    }
+}
+
+/**
+ * ToAsyncReader returns an [AsyncReader] that reads from r. Each Read calls r.Read
+ * once and resolves with its result.
+ */
+export function ToAsyncReader(r: Reader): AsyncReader {
+    return new asyncReader(r)
+}
+
+class asyncReader implements AsyncReader {
+    private r: Reader
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    async Read(p: Uint8Array): Promise<[number, Error | null]> {
+        return this.r.Read(p)
+    }
+}
+
+/**
+ * ToAsyncWriter returns an [AsyncWriter] that writes to w. Each Write calls w.Write
+ * once and resolves with its result.
+ */
+export function ToAsyncWriter(w: Writer): AsyncWriter {
+    return new asyncWriter(w)
+}
+
+class asyncWriter implements AsyncWriter {
+    private w: Writer
+
+    constructor(w: Writer) {
+        this.w = w
+    }
+
+    async Write(p: Uint8Array): Promise<[number, Error | null]> {
+        return this.w.Write(p)
+    }
+}
+
+/**
+ * ToAsyncReaderAt returns an [AsyncReaderAt] that reads from r.
+ */
+export function ToAsyncReaderAt(r: ReaderAt): AsyncReaderAt {
+    return new asyncReaderAt(r)
+}
+
+class asyncReaderAt implements AsyncReaderAt {
+    private r: ReaderAt
+
+    constructor(r: ReaderAt) {
+        this.r = r
+    }
+
+    async ReadAt(p: Uint8Array, off: number): Promise<[number, Error | null]> {
+        return this.r.ReadAt(p, off)
+    }
+}
+
+/**
+ * ToAsyncWriterAt returns an [AsyncWriterAt] that writes to w.
+ */
+export function ToAsyncWriterAt(w: WriterAt): AsyncWriterAt {
+    return new asyncWriterAt(w)
+}
+
+class asyncWriterAt implements AsyncWriterAt {
+    private w: WriterAt
+
+    constructor(w: WriterAt) {
+        this.w = w
+    }
+
+    async WriteAt(p: Uint8Array, off: number): Promise<[number, Error | null]> {
+        return this.w.WriteAt(p, off)
+    }
+}
+
+/**
+ * ToAsyncSeeker returns an [AsyncSeeker] that seeks s.
+ */
+export function ToAsyncSeeker(s: Seeker): AsyncSeeker {
+    return new asyncSeeker(s)
+}
+
+class asyncSeeker implements AsyncSeeker {
+    private s: Seeker
+
+    constructor(s: Seeker) {
+        this.s = s
+    }
+
+    async Seek(offset: number, whence: number): Promise<[number, Error | null]> {
+        return this.s.Seek(offset, whence)
+    }
+}
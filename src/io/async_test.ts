@@ -0,0 +1,192 @@
+// Tests for the async io counterparts
+//
+// Not present in the Go code: the Copy and ReadAtLeast tests are adapted from
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/io_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { bytes, str } from "../builtins/tshelpers/strings"
+import {
+    AsyncCopy, AsyncCopyBuffer, AsyncReadAll, AsyncReadAtLeast, AsyncReader, AsyncReadFull, AsyncWriter, Errors,
+    LimitedReader, NewOffsetWriter, NewSectionReader, Pipe, ReaderAt, SeekCurrent, SeekStart, ToAsyncReader,
+    ToAsyncReaderAt, ToAsyncSeeker, ToAsyncWriter, ToAsyncWriterAt, WriterAt,
+} from "./index"
+
+// sleep resolves after ms milliseconds, like time.Sleep
+function sleep(ms: number): Promise<void> {
+    return new Promise((resolve) => setTimeout(resolve, ms))
+}
+
+// readerAt is a ReaderAt over b
+function readerAt(b: Uint8Array): ReaderAt {
+    return {
+        ReadAt(p: Uint8Array, off: number): [number, Error | null] {
+            let n = Math.max(0, Math.min(p.length, b.length - off))
+            p.set(b.subarray(off, off + n))
+            return [n, n < p.length ? Errors.EOF : null]
+        },
+    }
+}
+
+// asyncBuffer is an AsyncReader and AsyncWriter over a GoBuffer, that only
+// settles its calls on a later turn of the event loop
+class asyncBuffer implements AsyncReader, AsyncWriter {
+    b = new GoBuffer(new Uint8Array(0))
+
+    async Read(p: Uint8Array): Promise<[number, Error | null]> {
+        await sleep(0)
+        return this.b.Read(p)
+    }
+
+    async Write(p: Uint8Array): Promise<[number, Error | null]> {
+        await sleep(0)
+        return this.b.Write(p)
+    }
+
+    String(): string {
+        return str(this.b.underlyingArray)
+    }
+}
+
+test("TestAsyncCopy", async () => {
+    let rb = new asyncBuffer()
+    let wb = new asyncBuffer()
+    await rb.Write(bytes("hello, world."))
+    await AsyncCopy(wb, rb)
+    assert.equal(wb.String(), "hello, world.", "AsyncCopy did not work properly")
+})
+
+test("TestAsyncCopyNegative", async () => {
+    let rb = new GoBuffer(bytes("hello"))
+    let wb = new asyncBuffer()
+    await AsyncCopy(wb, new LimitedReader(rb, -1))
+    assert.equal(wb.String(), "", "AsyncCopy on LimitedReader with N<0 copied data")
+})
+
+test("TestAsyncCopyBuffer", async () => {
+    let rb = new asyncBuffer()
+    let wb = new asyncBuffer()
+    await rb.Write(bytes("hello, world."))
+    await AsyncCopyBuffer(wb, rb, new Uint8Array(1)) // Tiny buffer to keep it honest.
+    assert.equal(wb.String(), "hello, world.", "AsyncCopyBuffer did not work properly")
+    await assert.rejects(async () => AsyncCopyBuffer(wb, rb, new Uint8Array(0)), /empty buffer/)
+})
+
+test("TestAsyncCopyReadErrWriteErr", async () => {
+    let er = new Error("readError"), ew = new Error("writeError")
+    let r: AsyncReader = {
+        Read: async (p) => {
+            p[0] = 0
+            return [1, er]
+        },
+    }
+    let w: AsyncWriter = { Write: async () => [0, ew] }
+    let [n, err] = await AsyncCopy(w, r)
+    assert.equal(n, 0)
+    assert.equal(err, ew)
+})
+
+test("TestAsyncCopyPipe", async () => {
+    let [pr, pw] = Pipe()
+    let writes = (async () => {
+        for (let s of ["hello", ", ", "world."]) {
+            await pw.Write(bytes(s))
+        }
+        pw.Close()
+    })()
+    let wb = new GoBuffer(new Uint8Array(0)) // A sync Writer works too
+    assert.deepEqual(await AsyncCopy(wb, pr), [13, null])
+    await writes
+    assert.equal(str(wb.underlyingArray), "hello, world.")
+})
+
+test("TestAsyncReadAtLeast", async () => {
+    let rb = new asyncBuffer()
+    await rb.Write(bytes("0123"))
+    let buf = new Uint8Array(2)
+    assert.deepEqual(await AsyncReadAtLeast(rb, buf, 2), [2, null])
+
+    let [n, err] = await AsyncReadAtLeast(rb, buf, 4)
+    assert.equal(err, Errors.ShortBuffer, "expected ShortBuffer")
+    assert.equal(n, 0, "expected to have read 0 bytes");
+
+    [n, err] = await AsyncReadAtLeast(rb, buf, 1)
+    assert.equal(err, null)
+    assert.equal(n, 2, "expected to have read 2 bytes");
+
+    [n, err] = await AsyncReadAtLeast(rb, buf, 2)
+    assert.equal(err, Errors.EOF, "expected EOF")
+    assert.equal(n, 0, "expected to have read 0 bytes")
+
+    await rb.Write(bytes("4"));
+    [n, err] = await AsyncReadAtLeast(rb, buf, 2)
+    assert.equal(err, Errors.UnexpectedEOF)
+    assert.equal(n, 1, "expected to have read 1 bytes")
+})
+
+test("TestAsyncReadFullPipe", async () => {
+    let [pr, pw] = Pipe()
+    let writes = (async () => {
+        await pw.Write(bytes("hel"))
+        await pw.Write(bytes("lo"))
+        pw.Close()
+    })()
+    let buf = new Uint8Array(4)
+    assert.deepEqual(await AsyncReadFull(pr, buf), [4, null])
+    assert.equal(str(buf), "hell")
+
+    let [n, err] = await AsyncReadFull(pr, buf)
+    assert.equal(n, 1)
+    assert.equal(err, Errors.UnexpectedEOF)
+    await writes
+})
+
+test("TestAsyncReadAll", async () => {
+    let [pr, pw] = Pipe()
+    let data = new Uint8Array(10000).map((_, i) => i)
+    let writes = (async () => {
+        for (let i = 0; i < data.length; i += 999) {
+            await pw.Write(data.subarray(i, i + 999))
+        }
+        pw.CloseWithError(new Error("done"))
+    })()
+    let [got, err] = await AsyncReadAll(pr)
+    await writes
+    assert.equal(err?.message, "done")
+    assert.deepEqual(got, data)
+
+    assert.deepEqual(await AsyncReadAll(ToAsyncReader(new GoBuffer(bytes("sync")))), [bytes("sync"), null])
+})
+
+test("TestToAsync", async () => {
+    let rb = new GoBuffer(bytes("hello"))
+    let r = ToAsyncReader(rb)
+    let buf = new Uint8Array(3)
+    let p = r.Read(buf)
+    assert.ok(p instanceof Promise)
+    assert.deepEqual(await p, [3, null])
+
+    let wb = new GoBuffer(new Uint8Array(0))
+    assert.deepEqual(await ToAsyncWriter(wb).Write(bytes("hey")), [3, null])
+    assert.equal(str(wb.underlyingArray), "hey")
+
+    let sr = NewSectionReader(readerAt(bytes("0123456789")), 0, 10)
+    let s = ToAsyncSeeker(sr)
+    assert.deepEqual(await s.Seek(4, SeekStart), [4, null])
+    assert.deepEqual(await s.Seek(2, SeekCurrent), [6, null])
+    let [, err] = await s.Seek(-1, SeekStart)
+    assert.equal(err, Errors.Offset)
+
+    assert.deepEqual(await ToAsyncReaderAt(sr).ReadAt(buf, 2), [3, null])
+    assert.equal(str(buf), "234")
+
+    let target = new Uint8Array(4)
+    let w: WriterAt = {
+        WriteAt(p: Uint8Array, off: number): [number, Error | null] {
+            target.set(p, off)
+            return [p.length, null]
+        },
+    }
+    assert.deepEqual(await ToAsyncWriterAt(NewOffsetWriter(w, 1)).WriteAt(bytes("ab"), 1), [2, null])
+    assert.deepEqual(target, new Uint8Array([0, 0, 97, 98]))
+})
@@ -0,0 +1,423 @@
+// Tests for io/fs
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/format_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/glob_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/readdir_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/readfile_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/stat_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/sub_test.go
+import * as assert from "node:assert/strict"
+import * as nodefs from "node:fs"
+import * as os from "node:os"
+import * as nodepath from "node:path"
+import { test } from "node:test"
+import { bytes, str } from "../../builtins/tshelpers/strings"
+import * as errors from "../../errors"
+import { ErrBadPattern } from "../../path"
+import { MapFS } from "../../testing/fstest"
+import {
+    DirEntry, ErrNotExist, File, FileInfo, FileInfoToDirEntry, FileMode, FormatDirEntry, FormatFileInfo, FS, Glob, GlobFS,
+    ModeDir, ModeIrregular, ModeSetuid, ModeSocket, PathError, ReadDir, ReadDirFS, ReadFile, ReadFileFS, Stat, StatFS, Sub,
+    SubFS, ValidPath,
+} from "./index"
+import { DirFS } from "./node"
+
+let sysValue = 0
+
+const testFsys = new MapFS({
+    "hello.txt": {
+        Data: bytes("hello, world"),
+        Mode: 0o456,
+        ModTime: new Date(),
+        Sys: sysValue,
+    },
+    "sub/goodbye.txt": {
+        Data: bytes("goodbye, world"),
+        Mode: 0o456,
+        ModTime: new Date(),
+        Sys: sysValue,
+    },
+})
+
+// openOnly hides all but the Open method of fsys
+class openOnly implements FS {
+    private fsys: FS
+
+    constructor(fsys: FS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return this.fsys.Open(name)
+    }
+}
+
+class readDirOnly implements ReadDirFS {
+    private fsys: ReadDirFS
+
+    constructor(fsys: ReadDirFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return [null, ErrNotExist]
+    }
+
+    ReadDir(name: string): [DirEntry[], Error | null] {
+        return this.fsys.ReadDir(name)
+    }
+}
+
+class readFileOnly implements ReadFileFS {
+    private fsys: ReadFileFS
+
+    constructor(fsys: ReadFileFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return [null, ErrNotExist]
+    }
+
+    ReadFile(name: string): [Uint8Array, Error | null] {
+        return this.fsys.ReadFile(name)
+    }
+}
+
+class statOnly implements StatFS {
+    private fsys: StatFS
+
+    constructor(fsys: StatFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return [null, ErrNotExist]
+    }
+
+    Stat(name: string): [FileInfo | null, Error | null] {
+        return this.fsys.Stat(name)
+    }
+}
+
+class subOnly implements SubFS {
+    private fsys: SubFS
+
+    constructor(fsys: SubFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return [null, ErrNotExist]
+    }
+
+    Sub(dir: string): [FS | null, Error | null] {
+        return this.fsys.Sub(dir)
+    }
+}
+
+class globOnly implements GlobFS {
+    private fsys: GlobFS
+
+    constructor(fsys: GlobFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return [null, ErrNotExist]
+    }
+
+    Glob(pattern: string): [string[], Error | null] {
+        return this.fsys.Glob(pattern)
+    }
+}
+
+// formatTest implements FileInfo to test FormatFileInfo,
+// and implements DirEntry to test FormatDirEntry.
+class formatTest implements FileInfo, DirEntry {
+    name: string
+    size: number
+    mode: FileMode
+    modTime: Date
+    isDir: boolean
+
+    constructor(name: string, size: number, mode: FileMode, modTime: Date, isDir: boolean) {
+        this.name = name
+        this.size = size
+        this.mode = mode
+        this.modTime = modTime
+        this.isDir = isDir
+    }
+
+    Name(): string { return this.name }
+    Size(): number { return this.size }
+    Mode(): FileMode { return this.mode }
+    ModTime(): Date { return this.modTime }
+    IsDir(): boolean { return this.isDir }
+    Sys(): unknown { return null }
+    Type(): FileMode { return FileMode.Type(this.mode) }
+    Info(): [FileInfo | null, Error | null] { return [null, errors.New("unimplemented")] }
+}
+
+// The times are local, as FormatFileInfo formats them in the local time zone
+const formatTests: { input: formatTest, wantFileInfo: string, wantDirEntry: string }[] = [
+    {
+        input: new formatTest("hello.go", 100, 0o644, new Date(1970, 0, 1, 12, 0, 0), false),
+        wantFileInfo: "-rw-r--r-- 100 1970-01-01 12:00:00 hello.go",
+        wantDirEntry: "- hello.go",
+    },
+    {
+        input: new formatTest("home/gopher", 0, ModeDir | 0o755, new Date(1970, 0, 1, 12, 0, 0), true),
+        wantFileInfo: "drwxr-xr-x 0 1970-01-01 12:00:00 home/gopher/",
+        wantDirEntry: "d home/gopher/",
+    },
+    {
+        input: new formatTest("big", Number.MAX_SAFE_INTEGER, ModeIrregular | 0o644, new Date(1970, 0, 1, 12, 0, 0), false),
+        wantFileInfo: "?rw-r--r-- 9007199254740991 1970-01-01 12:00:00 big",
+        wantDirEntry: "? big",
+    },
+    {
+        input: new formatTest("small", Number.MIN_SAFE_INTEGER, ModeSocket | ModeSetuid | 0o644, new Date(1970, 0, 1, 12, 0, 0), false),
+        wantFileInfo: "Surw-r--r-- -9007199254740991 1970-01-01 12:00:00 small",
+        wantDirEntry: "S small",
+    },
+]
+
+test("TestFormatFileInfo", () => {
+    for (let [i, tt] of formatTests.entries()) {
+        let got = FormatFileInfo(tt.input)
+        assert.equal(got, tt.wantFileInfo, `${i}) FormatFileInfo`)
+    }
+})
+
+test("TestFormatDirEntry", () => {
+    for (let [i, tt] of formatTests.entries()) {
+        let got = FormatDirEntry(tt.input)
+        assert.equal(got, tt.wantDirEntry, `${i}) FormatDirEntry`)
+    }
+})
+
+// Not present in the Go code
+test("TestFileMode", () => {
+    let m = (ModeDir | 0o755) >>> 0
+    assert.equal(FileMode.String(m), "drwxr-xr-x")
+    assert.equal(FileMode.String(0), "----------")
+    assert.ok(FileMode.IsDir(m))
+    assert.ok(FileMode.IsDir(ModeDir | 0o755), "signed bits")
+    assert.ok(!FileMode.IsRegular(m))
+    assert.ok(FileMode.IsRegular(0o644))
+    assert.equal(FileMode.Perm(m), 0o755)
+    assert.equal(FileMode.Type(m), ModeDir)
+    assert.equal(FileMode.Type(ModeDir | 0o755), ModeDir, "signed bits")
+})
+
+// Not present in the Go code
+test("TestValidPath", () => {
+    const tests: [string, boolean][] = [
+        [".", true],
+        ["x", true],
+        ["x/y/z", true],
+        ["x/.y/z..", true],
+        ["x\\y", true],
+        ["c:", true],
+        ["", false],
+        ["..", false],
+        ["/", false],
+        ["/x", false],
+        ["x/", false],
+        ["x//y", false],
+        ["x/./y", false],
+        ["x/../y", false],
+        ["./x", false],
+        ["x\uD800", false],
+        ["é\u{1F600}", true],
+    ]
+    for (let [name, want] of tests) {
+        assert.equal(ValidPath(name), want, `ValidPath(${JSON.stringify(name)})`)
+    }
+})
+
+// Not present in the Go code
+test("TestPathError", () => {
+    let err = new PathError("open", "x", ErrNotExist)
+    assert.equal(err.message, "open x: file does not exist")
+    assert.ok(errors.Is(err, ErrNotExist))
+    err.Path = "y"
+    assert.equal(err.message, "open y: file does not exist")
+    assert.equal(errors.As(err, PathError), err)
+})
+
+test("TestGlob", () => {
+    const globTests: { fs: FS, pattern: string, result: string }[] = [
+        { fs: DirFS("src/io/fs"), pattern: "glob.ts", result: "glob.ts" },
+        { fs: DirFS("src/io/fs"), pattern: "gl?b.ts", result: "glob.ts" },
+        { fs: DirFS("src/io/fs"), pattern: "gl\\ob.ts", result: "glob.ts" },
+        { fs: DirFS("src/io/fs"), pattern: "*", result: "glob.ts" },
+        { fs: DirFS("src/io"), pattern: "*/glob.ts", result: "fs/glob.ts" },
+    ]
+    for (let tt of globTests) {
+        let [matches, err] = Glob(tt.fs, tt.pattern)
+        assert.equal(err, null, `Glob error for ${JSON.stringify(tt.pattern)}`)
+        assert.ok(matches.includes(tt.result), `Glob(${JSON.stringify(tt.pattern)}) = ${JSON.stringify(matches)} want ${tt.result}`)
+    }
+    for (let pattern of ["no_match", "../*/no_match", "\\*"]) {
+        let [matches, err] = Glob(DirFS("src/io/fs"), pattern)
+        assert.equal(err, null, `Glob error for ${JSON.stringify(pattern)}`)
+        assert.deepEqual(matches, [], `Glob(${JSON.stringify(pattern)})`)
+    }
+})
+
+test("TestGlobError", () => {
+    let bad = ["[]", "nonexist/[]"]
+    for (let pattern of bad) {
+        let [, err] = Glob(DirFS("src/io/fs"), pattern)
+        assert.equal(err, ErrBadPattern, `Glob(fs, ${JSON.stringify(pattern)})`)
+    }
+})
+
+test("TestCVE202230630", () => {
+    // Prior to CVE-2022-30630, a stack exhaustion would occur given a large
+    // number of separators. There is now a limit of 10,000.
+    let [, err] = Glob(DirFS("src/io/fs"), "/*" + "/".repeat(10001))
+    assert.equal(err, ErrBadPattern)
+})
+
+test("TestGlobMethod", () => {
+    let check = (desc: string, names: string[], err: Error | null) => {
+        assert.equal(err, null, `Glob(${desc})`)
+        assert.deepEqual(names, ["hello.txt"], `Glob(${desc})`)
+    }
+
+    // Test that Glob uses the method when present.
+    let [names, err] = Glob(new globOnly(testFsys), "*.txt")
+    check("globOnly", names, err);
+
+    // Test that Glob uses Open when the method is not present.
+    [names, err] = Glob(new openOnly(testFsys), "*.txt")
+    check("openOnly", names, err)
+})
+
+test("TestReadDir", () => {
+    let check = (desc: string, dirs: DirEntry[], err: Error | null) => {
+        assert.equal(err, null, `ReadDir(${desc})`)
+        assert.deepEqual(dirs.map((d) => d.Name()), ["hello.txt", "sub"], `ReadDir(${desc})`)
+    }
+
+    // Test that ReadDir uses the method when present.
+    let [dirs, err] = ReadDir(new readDirOnly(testFsys), ".")
+    check("readDirOnly", dirs, err);
+
+    // Test that ReadDir uses Open when the method is not present.
+    [dirs, err] = ReadDir(new openOnly(testFsys), ".")
+    check("openOnly", dirs, err)
+
+    // Test that ReadDir on Sub of . works (TestSub checks non-trivial subs).
+    let [sub, serr] = Sub(testFsys, ".")
+    assert.equal(serr, null);
+    [dirs, err] = ReadDir(sub!, ".")
+    check("sub(.)", dirs, err)
+})
+
+test("TestFileInfoToDirEntry", () => {
+    let testFs = new MapFS({
+        "notadir.txt": {
+            Data: bytes("hello, world"),
+            Mode: 0,
+            ModTime: new Date(),
+            Sys: sysValue,
+        },
+        "adir": {
+            Mode: ModeDir,
+            ModTime: new Date(),
+            Sys: sysValue,
+        },
+    })
+
+    const tests: { path: string, wantMode: FileMode, wantDir: boolean }[] = [
+        { path: "notadir.txt", wantMode: 0, wantDir: false },
+        { path: "adir", wantMode: ModeDir, wantDir: true },
+    ]
+
+    for (let tt of tests) {
+        let [fi, err] = Stat(testFs, tt.path)
+        assert.equal(err, null, tt.path)
+
+        let dirEntry = FileInfoToDirEntry(fi)!
+        assert.equal(dirEntry.Type(), tt.wantMode, `${tt.path}: FileMode mismatch`)
+        assert.equal(dirEntry.Name(), tt.path, `${tt.path}: Name mismatch`)
+        assert.equal(dirEntry.IsDir(), tt.wantDir, `${tt.path}: IsDir mismatch`)
+    }
+    assert.equal(FileInfoToDirEntry(null), null)
+})
+
+test("TestReadFile", () => {
+    // Test that ReadFile uses the method when present.
+    let [data, err] = ReadFile(new readFileOnly(testFsys), "hello.txt")
+    assert.equal(err, null)
+    assert.equal(str(data), "hello, world", `ReadFile(readFileOnly, "hello.txt")`);
+
+    // Test that ReadFile uses Open when the method is not present.
+    [data, err] = ReadFile(new openOnly(testFsys), "hello.txt")
+    assert.equal(err, null)
+    assert.equal(str(data), "hello, world", `ReadFile(openOnly, "hello.txt")`)
+
+    // Test that ReadFile on Sub of . works (TestSub checks non-trivial subs).
+    let [sub, serr] = Sub(testFsys, ".")
+    assert.equal(serr, null);
+    [data, err] = ReadFile(sub!, "hello.txt")
+    assert.equal(err, null)
+    assert.equal(str(data), "hello, world", `ReadFile(sub(.), "hello.txt")`)
+})
+
+test("TestReadFilePath", () => {
+    let dir = nodefs.mkdtempSync(nodepath.join(os.tmpdir(), "fs-test-"))
+    try {
+        let fsys = DirFS(dir)
+        let [, err1] = ReadFile(fsys, "non-existent")
+        let [, err2] = ReadFile(new openOnly(fsys), "non-existent")
+        assert.ok(err1 instanceof PathError && err2 instanceof PathError)
+        assert.equal(err1.Path, err2.Path)
+    } finally {
+        nodefs.rmSync(dir, { recursive: true })
+    }
+})
+
+test("TestStat", () => {
+    let check = (desc: string, info: FileInfo | null, err: Error | null) => {
+        assert.equal(err, null, `Stat(${desc})`)
+        assert.ok(info != null, `Stat(${desc})`)
+        assert.equal(info.Mode(), 0o456, `Stat(${desc}) = ${FormatFileInfo(info)}, want Mode:0456`)
+    }
+
+    // Test that Stat uses the method when present.
+    let [info, err] = Stat(new statOnly(testFsys), "hello.txt")
+    check("statOnly", info, err);
+
+    // Test that Stat uses Open when the method is not present.
+    [info, err] = Stat(new openOnly(testFsys), "hello.txt")
+    check("openOnly", info, err)
+})
+
+test("TestSub", () => {
+    let check = (desc: string, sub: FS | null, err: Error | null) => {
+        assert.equal(err, null, `Sub(${desc})`)
+        let [data, rerr] = ReadFile(sub!, "goodbye.txt")
+        assert.equal(rerr, null)
+        assert.equal(str(data), "goodbye, world", `ReadFile(${desc}, "goodbye.txt")`)
+
+        let [dirs, derr] = ReadDir(sub!, ".")
+        assert.equal(derr, null)
+        assert.deepEqual(dirs.map((d) => d.Name()), ["goodbye.txt"], `ReadDir(${desc}, ".")`)
+    }
+
+    // Test that Sub uses the method when present.
+    let [sub, err] = Sub(new subOnly(testFsys), "sub")
+    check("subOnly", sub, err);
+
+    // Test that Sub uses Open when the method is not present.
+    [sub, err] = Sub(new openOnly(testFsys), "sub")
+    check("openOnly", sub, err);
+
+    [, err] = sub!.Open("nonexist")
+    assert.ok(err instanceof PathError, `Open(nonexist): error is ${err}, want PathError`)
+    assert.equal(err.Path, "nonexist", "Open(nonexist): err.Path")
+})
@@ -0,0 +1,164 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/glob.go
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import * as path from "../../path"
+import { FS } from "./index"
+import { ReadDir } from "./readdir"
+import { Stat } from "./stat"
+
+/**
+ * A GlobFS is a file system with a Glob method.
+ */
+export interface GlobFS extends FS {
+    /**
+     * Glob returns the names of all files matching pattern,
+     * providing an implementation of the top-level
+     * Glob function.
+     */
+    Glob(pattern: string): [string[], Error | null]
+}
+
+/**
+ * isGlobFS reports whether v implements [GlobFS]
+ */
+export function isGlobFS(v: unknown): v is GlobFS {
+    return hasMethods(v, "Open", "Glob")
+}
+
+/**
+ * Glob returns the names of all files matching pattern or an empty array
+ * if there is no matching file. The syntax of patterns is the same
+ * as in path.Match. The pattern may describe hierarchical names such as
+ * usr/*\/bin/ed.
+ *
+ * Glob ignores file system errors such as I/O errors reading directories.
+ * The only possible returned error is path.ErrBadPattern, reporting that
+ * the pattern is malformed.
+ *
+ * If fs implements [GlobFS], Glob calls fs.Glob.
+ * Otherwise, Glob uses [ReadDir] to traverse the directory tree
+ * and look for matches for the pattern.
+ */
+export function Glob(fsys: FS, pattern: string): [string[], Error | null] {
+    return globWithLimit(fsys, pattern)
+}
+
+// globWithLimit is Glob with a limit on the number of directories with meta
+// characters in pattern.
+//
+// *SEMANTIC DIFFERENCES TO GO:*
+//
+// Go recurses on the directory part of the pattern, which JS stacks are too
+// small for at the limit. Here the recursion is unrolled: the loop splits off the
+// file parts until the directory part has no meta characters, and the file parts
+// are then matched from the innermost directory outward
+function globWithLimit(fsys: FS, pattern: string): [string[], Error | null] {
+    // This limit is added to prevent stack exhaustion issues. See
+    // CVE-2022-30630.
+    const pathSeparatorsLimit = 10000
+    if (isGlobFS(fsys)) {
+        return fsys.Glob(pattern)
+    }
+
+    // files holds the file parts split off pattern, the innermost last.
+    let files: string[] = []
+    let matches: string[]
+    while (true) { // for {}
+        if (files.length > pathSeparatorsLimit) {
+            return [[], path.ErrBadPattern]
+        }
+
+        // Check pattern is well-formed.
+        let [, err] = path.Match(pattern, "")
+        if (err != null) {
+            return [[], err]
+        }
+        if (!hasMeta(pattern)) {
+            [, err] = Stat(fsys, pattern)
+            matches = err != null ? [] : [pattern]
+            break
+        }
+
+        let [dir, file] = path.Split(pattern)
+        dir = cleanGlobPath(dir)
+
+        if (!hasMeta(dir)) {
+            [matches, err] = glob(fsys, dir, file, [])
+            if (err != null) {
+                return [matches, err]
+            }
+            break
+        }
+
+        // Prevent infinite recursion. See issue 15879.
+        if (dir == pattern) {
+            return [[], path.ErrBadPattern]
+        }
+
+        files.push(file)
+        pattern = dir
+    }
+
+    while (files.length > 0) {
+        let file = files.pop()!
+        let dirMatches = matches
+        matches = []
+        for (let d of dirMatches) {
+            let err: Error | null;
+            [matches, err] = glob(fsys, d, file, matches)
+            if (err != null) {
+                return [matches, err]
+            }
+        }
+    }
+    return [matches, null]
+}
+
+// cleanGlobPath prepares path for glob matching.
+function cleanGlobPath(path: string): string {
+    switch (path) {
+        case "":
+            return "."
+        default:
+            return path.slice(0, path.length - 1) // chop off trailing separator
+    }
+}
+
+// glob searches for files matching pattern in the directory dir
+// and appends them to matches, returning the updated array.
+// If the directory cannot be opened, glob returns the existing matches.
+// New matches are added in lexicographical order.
+function glob(fs: FS, dir: string, pattern: string, matches: string[]): [string[], Error | null] {
+    let m = matches
+    let [infos, err] = ReadDir(fs, dir)
+    if (err != null) {
+        return [m, null] // ignore I/O error
+    }
+
+    for (let info of infos) {
+        let n = info.Name()
+        let matched: boolean;
+        [matched, err] = path.Match(pattern, n)
+        if (err != null) {
+            return [m, err]
+        }
+        if (matched) {
+            m.push(path.Join(dir, n))
+        }
+    }
+    return [m, null]
+}
+
+// hasMeta reports whether path contains any of the magic characters
+// recognized by path.Match.
+function hasMeta(path: string): boolean {
+    for (let i = 0; i < path.length; i++) {
+        switch (path[i]) {
+            case "*":
+            case "?":
+            case "[":
+            case "\\":
+                return true
+        }
+    }
+    return false
+}
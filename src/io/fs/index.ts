@@ -0,0 +1,376 @@
+// Package fs defines basic interfaces to a file system.
+// A file system can be provided by the host operating system
+// but also by other packages.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/
+//
+// See the testing/fstest package for support with testing
+// implementations of file systems, and io/fs/node for DirFS, a file
+// system over a directory of the host's, through node:fs.
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import * as errors from "../../errors"
+import { Closer, Reader } from "../index"
+
+/**
+ * An FS provides access to a hierarchical file system.
+ *
+ * The FS interface is the minimum implementation required of the file system.
+ * A file system may implement additional interfaces,
+ * such as [ReadFileFS], to provide additional or optimized functionality.
+ */
+export interface FS {
+    /**
+     * Open opens the named file.
+     *
+     * When Open returns an error, it should be of type [PathError]
+     * with the Op field set to "open", the Path field set to name,
+     * and the Err field describing the problem.
+     *
+     * Open should reject attempts to open names that do not satisfy
+     * ValidPath(name), returning a [PathError] with Err set to
+     * [ErrInvalid] or [ErrNotExist].
+     */
+    Open(name: string): [File | null, Error | null]
+}
+
+/**
+ * isFS reports whether v implements [FS]
+ */
+export function isFS(v: unknown): v is FS {
+    return hasMethods(v, "Open")
+}
+
+/**
+ * ValidPath reports whether the given path name
+ * is valid for use in a call to Open.
+ *
+ * Path names passed to open are UTF-8-encoded,
+ * unrooted, slash-separated sequences of path elements, like “x/y/z”.
+ * Path names must not contain an element that is “.” or “..” or the empty string,
+ * except for the special case that the root directory is named “.”.
+ * Paths must not start or end with a slash: “/x” and “x/” are invalid.
+ *
+ * Note that paths are slash-separated on all systems, even Windows.
+ * Paths containing other characters such as backslash and colon
+ * are accepted as valid, but those characters must never be
+ * interpreted by an [FS] implementation as path element separators.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Names are JS strings, which can't hold invalid UTF-8: a lone surrogate
+ * makes a name invalid instead
+ */
+export function ValidPath(name: string): boolean {
+    if (/\p{Cs}/u.test(name)) {
+        return false
+    }
+
+    if (name == ".") {
+        // special case
+        return true
+    }
+
+    // Iterate over elements in name, checking each.
+    while (true) { // for {}
+        let i = 0
+        while (i < name.length && name[i] != "/") {
+            i++
+        }
+        let elem = name.slice(0, i)
+        if (elem == "" || elem == "." || elem == "..") {
+            return false
+        }
+        if (i == name.length) {
+            return true // reached clean ending
+        }
+        name = name.slice(i + 1)
+    }
+}
+
+/**
+ * A File provides access to a single file.
+ * The File interface is the minimum implementation required of the file.
+ * Directory files should also implement [ReadDirFile].
+ * A file may implement io.ReaderAt or io.Seeker as optimizations.
+ */
+export interface File extends Reader, Closer {
+    Stat(): [FileInfo | null, Error | null]
+}
+
+/**
+ * A DirEntry is an entry read from a directory
+ * (using the [ReadDir] function or a [ReadDirFile]'s ReadDir method).
+ */
+export interface DirEntry {
+    /**
+     * Name returns the name of the file (or subdirectory) described by the entry.
+     * This name is only the final element of the path (the base name), not the entire path.
+     * For example, Name would return "hello.go" not "home/gopher/hello.go".
+     */
+    Name(): string
+
+    /**
+     * IsDir reports whether the entry describes a directory.
+     */
+    IsDir(): boolean
+
+    /**
+     * Type returns the type bits for the entry.
+     * The type bits are a subset of the usual FileMode bits, those returned by the FileMode.Type method.
+     */
+    Type(): FileMode
+
+    /**
+     * Info returns the FileInfo for the file or subdirectory described by the entry.
+     * The returned FileInfo may be from the time of the original directory read
+     * or from the time of the call to Info. If the file has been removed or renamed
+     * since the directory read, Info may return an error satisfying errors.Is(err, ErrNotExist).
+     * If the entry denotes a symbolic link, Info reports the information about the link itself,
+     * not the link's target.
+     */
+    Info(): [FileInfo | null, Error | null]
+}
+
+/**
+ * A ReadDirFile is a directory file whose entries can be read with the ReadDir method.
+ * Every directory file should implement this interface.
+ * (It is permissible for any file to implement this interface,
+ * but if so ReadDir should return an error for non-directories.)
+ */
+export interface ReadDirFile extends File {
+    /**
+     * ReadDir reads the contents of the directory and returns
+     * an array of up to n DirEntry values in directory order.
+     * Subsequent calls on the same file will yield further DirEntry values.
+     *
+     * If n > 0, ReadDir returns at most n DirEntry structures.
+     * In this case, if ReadDir returns an empty array, it will return
+     * a non-null error explaining why.
+     * At the end of a directory, the error is io.EOF.
+     * (ReadDir must return io.EOF itself, not an error wrapping io.EOF.)
+     *
+     * If n <= 0, ReadDir returns all the DirEntry values from the directory
+     * in a single array. In this case, if ReadDir succeeds (reads all the way
+     * to the end of the directory), it returns the array and a null error.
+     * If it encounters an error before the end of the directory,
+     * ReadDir returns the DirEntry list read until that point and a non-null error.
+     */
+    ReadDir(n: number): [DirEntry[], Error | null]
+}
+
+/**
+ * isReadDirFile reports whether v implements [ReadDirFile]
+ */
+export function isReadDirFile(v: unknown): v is ReadDirFile {
+    return hasMethods(v, "Stat", "Read", "Close", "ReadDir")
+}
+
+// Generic file system errors.
+// Errors returned by file systems can be tested against these errors
+// using errors.Is.
+export const ErrInvalid = errors.New("invalid argument")
+export const ErrPermission = errors.New("permission denied")
+export const ErrExist = errors.New("file already exists")
+export const ErrNotExist = errors.New("file does not exist")
+export const ErrClosed = errors.New("file already closed")
+
+/**
+ * A FileInfo describes a file and is returned by Stat.
+ */
+export interface FileInfo {
+    Name(): string // base name of the file
+    Size(): number // length in bytes for regular files; system-dependent for others
+    Mode(): FileMode // file mode bits
+    ModTime(): Date // modification time
+    IsDir(): boolean // abbreviation for FileMode.IsDir(Mode())
+    Sys(): unknown // underlying data source (can return null)
+}
+
+/**
+ * A FileMode represents a file's mode and permission bits.
+ * The bits have the same definition on all systems, so that
+ * information about files can be moved from one system
+ * to another portably. Not all bits apply to all systems.
+ * The only required bit is [ModeDir] for directories.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * A FileMode is an unsigned 32 bit number, so Go's methods on it are the
+ * functions of the FileMode object: FileMode.IsDir(m) instead of m.IsDir()
+ */
+export type FileMode = number
+
+// The defined file mode bits are the most significant bits of the FileMode.
+// The nine least-significant bits are the standard Unix rwxrwxrwx permissions.
+// The values of these bits should be considered part of the public API and
+// may be used in wire protocols or disk representations: they must not be
+// changed, although new bits might be added.
+
+// The single letters are the abbreviations
+// used by the String method's formatting.
+export const ModeDir = 2 ** 31 // d: is a directory
+export const ModeAppend = 2 ** 30 // a: append-only
+export const ModeExclusive = 2 ** 29 // l: exclusive use
+export const ModeTemporary = 2 ** 28 // T: temporary file; Plan 9 only
+export const ModeSymlink = 2 ** 27 // L: symbolic link
+export const ModeDevice = 2 ** 26 // D: device file
+export const ModeNamedPipe = 2 ** 25 // p: named pipe (FIFO)
+export const ModeSocket = 2 ** 24 // S: Unix domain socket
+export const ModeSetuid = 2 ** 23 // u: setuid
+export const ModeSetgid = 2 ** 22 // g: setgid
+export const ModeCharDevice = 2 ** 21 // c: Unix character device, when ModeDevice is set
+export const ModeSticky = 2 ** 20 // t: sticky
+export const ModeIrregular = 2 ** 19 // ?: non-regular file; nothing else is known about this file
+
+// Mask for the type bits. For regular files, none will be set.
+export const ModeType = (ModeDir | ModeSymlink | ModeNamedPipe | ModeSocket | ModeDevice | ModeCharDevice | ModeIrregular) >>> 0
+
+export const ModePerm = 0o777 // Unix permission bits
+
+/**
+ * The methods of [FileMode]. Bitwise operators work on signed 32 bit integers
+ * in JS, so these return unsigned numbers that can be compared with the Mode
+ * constants.
+ */
+export const FileMode = {
+    String(m: FileMode): string {
+        const str = "dalTLDpSugct?"
+        let buf = ""
+        for (let i = 0; i < str.length; i++) {
+            if ((m & (1 << (32 - 1 - i))) != 0) {
+                buf += str[i]
+            }
+        }
+        if (buf == "") {
+            buf = "-"
+        }
+        const rwx = "rwxrwxrwx"
+        for (let i = 0; i < rwx.length; i++) {
+            if ((m & (1 << (9 - 1 - i))) != 0) {
+                buf += rwx[i]
+            } else {
+                buf += "-"
+            }
+        }
+        return buf
+    },
+
+    /**
+     * IsDir reports whether m describes a directory.
+     * That is, it tests for the ModeDir bit being set in m.
+     */
+    IsDir(m: FileMode): boolean {
+        return (m & ModeDir) != 0
+    },
+
+    /**
+     * IsRegular reports whether m describes a regular file.
+     * That is, it tests that no mode type bits are set.
+     */
+    IsRegular(m: FileMode): boolean {
+        return (m & ModeType) == 0
+    },
+
+    /**
+     * Perm returns the Unix permission bits in m (m & ModePerm).
+     */
+    Perm(m: FileMode): FileMode {
+        return (m & ModePerm) >>> 0
+    },
+
+    /**
+     * Type returns type bits in m (m & ModeType).
+     */
+    Type(m: FileMode): FileMode {
+        return (m & ModeType) >>> 0
+    },
+}
+
+/**
+ * PathError records an error and the operation and file path that caused it.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * The message is built from the fields when it is read, so that it follows
+ * changes to them, like Go's Error method
+ */
+export class PathError extends Error {
+    Op: string
+    Path: string
+    Err: Error
+
+    constructor(op: string, path: string, err: Error) {
+        super()
+        this.name = "PathError"
+        this.Op = op
+        this.Path = path
+        this.Err = err
+        Object.defineProperty(this, "message", {
+            get: () => this.Op + " " + this.Path + ": " + this.Err.message,
+            configurable: true,
+        })
+    }
+
+    Unwrap(): Error {
+        return this.Err
+    }
+}
+
+/**
+ * FormatFileInfo returns a formatted version of info for human readability.
+ * Implementations of [FileInfo] can call this from a String method.
+ * The output for a file named "hello.go", 100 bytes, mode 0o644, created
+ * January 1, 1970 at noon is
+ *
+ *	-rw-r--r-- 100 1970-01-01 12:00:00 hello.go
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * The time is formatted in the local time zone, as Date has no time zone of its own
+ */
+export function FormatFileInfo(info: FileInfo): string {
+    let name = info.Name()
+    let b = FileMode.String(info.Mode()) + " "
+    b += info.Size().toString() + " "
+    b += formatDateTime(info.ModTime()) + " "
+    b += name
+    if (info.IsDir()) {
+        b += "/"
+    }
+    return b
+}
+
+// formatDateTime formats t like Go's time.DateTime layout, "2006-01-02 15:04:05"
+function formatDateTime(t: Date): string {
+    let pad = (n: number, w: number = 2) => n.toString().padStart(w, "0")
+    return `${pad(t.getFullYear(), 4)}-${pad(t.getMonth() + 1)}-${pad(t.getDate())} ${pad(t.getHours())}:${pad(t.getMinutes())}:${pad(t.getSeconds())}`
+}
+
+/**
+ * FormatDirEntry returns a formatted version of dir for human readability.
+ * Implementations of [DirEntry] can call this from a String method.
+ * The outputs for a directory named subdir and a file named hello.go are:
+ *
+ *	d subdir/
+ *	- hello.go
+ */
+export function FormatDirEntry(dir: DirEntry): string {
+    let name = dir.Name()
+
+    // The Type method does not return any permission bits,
+    // so strip them from the string.
+    let mode = FileMode.String(dir.Type())
+    mode = mode.slice(0, mode.length - 9)
+
+    let b = mode + " " + name
+    if (dir.IsDir()) {
+        b += "/"
+    }
+    return b
+}
+
+export * from "./glob"
+export * from "./readdir"
+export * from "./readfile"
+export * from "./stat"
+export * from "./sub"
+export * from "./walk"
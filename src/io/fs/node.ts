@@ -0,0 +1,470 @@
+// DirFS, a file system over a directory tree of the host, through node:fs
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/os/file.go
+//
+// Not present in the io/fs package of Go, where DirFS is part of os. This file depends on
+// node:fs, so it is not exported from the io/fs index: import it from "io/fs/node".
+import * as nodefs from "node:fs"
+import * as nodepath from "node:path"
+import * as errors from "../../errors"
+import { EOF, ReaderAt, SeekCurrent, SeekEnd, Seeker, SeekStart } from "../index"
+import {
+    DirEntry,
+    ErrClosed,
+    ErrExist,
+    ErrInvalid,
+    ErrNotExist,
+    ErrPermission,
+    File,
+    FileInfo,
+    FileMode,
+    FS,
+    ModeCharDevice,
+    ModeDevice,
+    ModeDir,
+    ModeNamedPipe,
+    ModeSetgid,
+    ModeSetuid,
+    ModeSocket,
+    ModeSticky,
+    ModeSymlink,
+    PathError,
+    ReadDirFile,
+    ReadDirFS,
+    ReadFileFS,
+    StatFS,
+    ValidPath,
+} from "./index"
+
+/**
+ * DirFS returns a file system (an fs.FS) for the tree of files rooted at the directory dir.
+ *
+ * Note that DirFS("/prefix") only guarantees that the Open calls it makes to the
+ * operating system will begin with "/prefix": DirFS("/prefix").Open("file") is the
+ * same as node:fs opening "/prefix/file". So if /prefix/file is a symbolic link pointing outside
+ * the /prefix tree, then using DirFS does not stop the access any more than using
+ * node:fs does. Additionally, the root of the fs.FS returned for a relative path,
+ * DirFS("prefix"), will be affected by later calls to process.chdir. DirFS is
+ * therefore not a general substitute for a chroot-style security mechanism when the
+ * directory tree contains arbitrary content.
+ *
+ * The directory dir must not be "".
+ *
+ * The result implements [ReadFileFS], [StatFS], and [ReadDirFS]. The files it
+ * opens implement io.ReaderAt and io.Seeker, and directories implement [ReadDirFile].
+ *
+ * Errors are [PathError]s wrapping the error thrown by node:fs, which is kept as
+ * the cause. They match [ErrNotExist], [ErrExist] and [ErrPermission] with errors.Is
+ * where Go's syscall errors would.
+ */
+export function DirFS(dir: string): FS {
+    return new dirFS(dir)
+}
+
+class dirFS implements ReadFileFS, ReadDirFS, StatFS {
+    private dir: string
+
+    constructor(dir: string) {
+        this.dir = dir
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        let [fullname, err] = this.join(name)
+        if (err != null) {
+            return [null, new PathError("open", name, err)]
+        }
+        let fd: number
+        try {
+            fd = nodefs.openSync(fullname, "r")
+        } catch (e) {
+            // DirFS takes a path appropriate for the platform,
+            // while the name argument here is always slash separated.
+            // join will have mixed the two, so report the error
+            // with name.
+            return [null, new PathError("open", name, syscallError(e))]
+        }
+        return [new dirFile(fd, name, fullname), null]
+    }
+
+    ReadFile(name: string): [Uint8Array, Error | null] {
+        let [fullname, err] = this.join(name)
+        if (err != null) {
+            return [new Uint8Array(0), new PathError("readfile", name, err)]
+        }
+        try {
+            let data = nodefs.readFileSync(fullname)
+            return [new Uint8Array(data.buffer, data.byteOffset, data.length), null]
+        } catch (e) {
+            // See comment in Open.
+            return [new Uint8Array(0), new PathError("open", name, syscallError(e))]
+        }
+    }
+
+    /**
+     * ReadDir reads the named directory, returning all its directory entries sorted
+     * by filename. Through this method, DirFS implements [ReadDirFS].
+     */
+    ReadDir(name: string): [DirEntry[], Error | null] {
+        let [fullname, err] = this.join(name)
+        if (err != null) {
+            return [[], new PathError("readdir", name, err)]
+        }
+        let dirents: nodefs.Dirent[]
+        try {
+            dirents = nodefs.readdirSync(fullname, { withFileTypes: true })
+        } catch (e) {
+            // See comment in Open.
+            return [[], new PathError("open", name, syscallError(e))]
+        }
+        let list = dirents.map((d) => new dirEntry(fullname, d))
+        list.sort((a, b) => (a.Name() < b.Name() ? -1 : a.Name() > b.Name() ? 1 : 0))
+        return [list, null]
+    }
+
+    Stat(name: string): [FileInfo | null, Error | null] {
+        let [fullname, err] = this.join(name)
+        if (err != null) {
+            return [null, new PathError("stat", name, err)]
+        }
+        try {
+            return [new fileStat(nodepath.basename(fullname), nodefs.statSync(fullname)), null]
+        } catch (e) {
+            // See comment in Open.
+            return [null, new PathError("stat", name, syscallError(e))]
+        }
+    }
+
+    // join returns the path for name in dir.
+    private join(name: string): [string, Error | null] {
+        if (this.dir == "") {
+            return ["", errors.New("os: DirFS with empty root")]
+        }
+        if (!ValidPath(name)) {
+            return ["", ErrInvalid]
+        }
+        let [local, err] = fromFS(name)
+        if (err != null) {
+            return ["", ErrInvalid]
+        }
+        if (isPathSeparator(this.dir[this.dir.length - 1])) {
+            return [this.dir + local, null]
+        }
+        return [this.dir + nodepath.sep + local, null]
+    }
+}
+
+// fromFS converts a slash-separated path into an operating-system path,
+// like Go's internal/safefilepath.FromFS.
+function fromFS(name: string): [string, Error | null] {
+    if (name.includes("\0")) {
+        return ["", ErrInvalid]
+    }
+    if (process.platform == "win32") {
+        // On Windows, backslashes and colons would be read as separators or volume names
+        if (name.includes("\\") || name.includes(":")) {
+            return ["", ErrInvalid]
+        }
+        return [name.replaceAll("/", "\\"), null]
+    }
+    return [name, null]
+}
+
+function isPathSeparator(c: string): boolean {
+    return c == "/" || (process.platform == "win32" && c == "\\")
+}
+
+/**
+ * dirFile is a file opened by DirFS: what an *os.File is to Go. It reads with the
+ * synchronous node:fs calls, at an offset of its own so that Seek and ReadAt work.
+ */
+class dirFile implements ReadDirFile, ReaderAt, Seeker {
+    private fd: number
+    private name: string
+    private fullname: string
+    private offset = 0
+    private closed = false
+    private dirents: nodefs.Dirent[] | null = null
+
+    constructor(fd: number, name: string, fullname: string) {
+        this.fd = fd
+        this.name = name
+        this.fullname = fullname
+    }
+
+    // checkValid checks whether f is valid for use.
+    // If not, it returns an appropriate error, perhaps incorporating the operation name op.
+    private checkValid(op: string): Error | null {
+        if (this.closed) {
+            return new PathError(op, this.name, ErrClosed)
+        }
+        return null
+    }
+
+    Read(b: Uint8Array): [number, Error | null] {
+        let err = this.checkValid("read")
+        if (err != null) {
+            return [0, err]
+        }
+        if (b.length == 0) {
+            return [0, null]
+        }
+        let n: number
+        try {
+            n = nodefs.readSync(this.fd, b, 0, b.length, this.offset)
+        } catch (e) {
+            return [0, new PathError("read", this.name, syscallError(e))]
+        }
+        if (n == 0) {
+            return [0, EOF]
+        }
+        this.offset += n
+        return [n, null]
+    }
+
+    ReadAt(b: Uint8Array, off: number): [number, Error | null] {
+        let err = this.checkValid("read")
+        if (err != null) {
+            return [0, err]
+        }
+
+        if (off < 0) {
+            return [0, new PathError("readat", this.name, errors.New("negative offset"))]
+        }
+
+        let n = 0
+        while (n < b.length) {
+            let m: number
+            try {
+                m = nodefs.readSync(this.fd, b, n, b.length - n, off)
+            } catch (e) {
+                return [n, new PathError("read", this.name, syscallError(e))]
+            }
+            if (m == 0) {
+                return [n, EOF]
+            }
+            n += m
+            off += m
+        }
+        return [n, null]
+    }
+
+    Seek(offset: number, whence: number): [number, Error | null] {
+        let err = this.checkValid("seek")
+        if (err != null) {
+            return [0, err]
+        }
+        switch (whence) {
+            case SeekStart:
+                break
+            case SeekCurrent:
+                offset += this.offset
+                break
+            case SeekEnd:
+                try {
+                    offset += nodefs.fstatSync(this.fd).size
+                } catch (e) {
+                    return [0, new PathError("seek", this.name, syscallError(e))]
+                }
+                break
+            default:
+                return [0, new PathError("seek", this.name, ErrInvalid)]
+        }
+        if (offset < 0) {
+            return [0, new PathError("seek", this.name, ErrInvalid)]
+        }
+        this.offset = offset
+        return [offset, null]
+    }
+
+    Stat(): [FileInfo | null, Error | null] {
+        let err = this.checkValid("stat")
+        if (err != null) {
+            return [null, err]
+        }
+        try {
+            return [new fileStat(nodepath.basename(this.fullname), nodefs.fstatSync(this.fd)), null]
+        } catch (e) {
+            return [null, new PathError("stat", this.name, syscallError(e))]
+        }
+    }
+
+    ReadDir(n: number): [DirEntry[], Error | null] {
+        let err = this.checkValid("readdir")
+        if (err != null) {
+            return [[], err]
+        }
+        if (this.dirents == null) {
+            try {
+                this.dirents = nodefs.readdirSync(this.fullname, { withFileTypes: true })
+            } catch (e) {
+                return [[], new PathError("readdirent", this.name, syscallError(e))]
+            }
+        }
+
+        let count = this.dirents.length
+        if (n > 0 && count > n) {
+            count = n
+        }
+        let list = this.dirents.slice(0, count).map((d) => new dirEntry(this.fullname, d))
+        this.dirents = this.dirents.slice(count)
+        if (n > 0 && list.length == 0) {
+            return [list, EOF]
+        }
+        return [list, null]
+    }
+
+    Close(): Error | null {
+        if (this.closed) {
+            return new PathError("close", this.name, ErrClosed)
+        }
+        this.closed = true
+        try {
+            nodefs.closeSync(this.fd)
+        } catch (e) {
+            return new PathError("close", this.name, syscallError(e))
+        }
+        return null
+    }
+}
+
+// A fileStat is the FileInfo of a node:fs Stats.
+class fileStat implements FileInfo {
+    private name: string
+    private stats: nodefs.Stats
+
+    constructor(name: string, stats: nodefs.Stats) {
+        this.name = name
+        this.stats = stats
+    }
+
+    Name(): string {
+        return this.name
+    }
+
+    Size(): number {
+        return this.stats.size
+    }
+
+    Mode(): FileMode {
+        let s = this.stats
+        let mode = s.mode & 0o777
+        if (s.isBlockDevice()) {
+            mode |= ModeDevice
+        } else if (s.isCharacterDevice()) {
+            mode |= ModeDevice | ModeCharDevice
+        } else if (s.isDirectory()) {
+            mode |= ModeDir
+        } else if (s.isFIFO()) {
+            mode |= ModeNamedPipe
+        } else if (s.isSymbolicLink()) {
+            mode |= ModeSymlink
+        } else if (s.isSocket()) {
+            mode |= ModeSocket
+        }
+        if ((s.mode & nodefs.constants.S_ISGID) != 0) {
+            mode |= ModeSetgid
+        }
+        if ((s.mode & nodefs.constants.S_ISUID) != 0) {
+            mode |= ModeSetuid
+        }
+        if ((s.mode & nodefs.constants.S_ISVTX) != 0) {
+            mode |= ModeSticky
+        }
+        return mode >>> 0
+    }
+
+    ModTime(): Date {
+        return this.stats.mtime
+    }
+
+    IsDir(): boolean {
+        return this.stats.isDirectory()
+    }
+
+    Sys(): unknown {
+        return this.stats
+    }
+}
+
+// A dirEntry is the DirEntry of a node:fs Dirent, read from the directory parent.
+class dirEntry implements DirEntry {
+    private parent: string
+    private d: nodefs.Dirent
+
+    constructor(parent: string, d: nodefs.Dirent) {
+        this.parent = parent
+        this.d = d
+    }
+
+    Name(): string {
+        return this.d.name
+    }
+
+    IsDir(): boolean {
+        return this.d.isDirectory()
+    }
+
+    Type(): FileMode {
+        let d = this.d
+        if (d.isBlockDevice()) {
+            return ModeDevice
+        } else if (d.isCharacterDevice()) {
+            return (ModeDevice | ModeCharDevice) >>> 0
+        } else if (d.isDirectory()) {
+            return ModeDir
+        } else if (d.isFIFO()) {
+            return ModeNamedPipe
+        } else if (d.isSymbolicLink()) {
+            return ModeSymlink
+        } else if (d.isSocket()) {
+            return ModeSocket
+        }
+        return 0
+    }
+
+    Info(): [FileInfo | null, Error | null] {
+        let fullname = nodepath.join(this.parent, this.d.name)
+        try {
+            return [new fileStat(this.d.name, nodefs.lstatSync(fullname)), null]
+        } catch (e) {
+            return [null, new PathError("lstat", fullname, syscallError(e))]
+        }
+    }
+}
+
+/**
+ * An Errno is an error thrown by node:fs, like Go's syscall.Errno. Its message is the
+ * description of the error code, without the code and path node adds to it; the
+ * original error is its cause.
+ */
+export class Errno extends Error {
+    Code: string
+
+    constructor(code: string, message: string, cause: unknown) {
+        super(message, { cause })
+        this.name = "Errno"
+        this.Code = code
+    }
+
+    Is(target: Error): boolean {
+        switch (target) {
+            case ErrPermission:
+                return this.Code == "EACCES" || this.Code == "EPERM"
+            case ErrExist:
+                return this.Code == "EEXIST" || this.Code == "ENOTEMPTY"
+            case ErrNotExist:
+                return this.Code == "ENOENT"
+        }
+        return false
+    }
+}
+
+// syscallError returns e, thrown by node:fs, as an Errno
+function syscallError(e: unknown): Error {
+    let err = e as NodeJS.ErrnoException
+    if (!(err instanceof Error) || err.code == null) {
+        return err instanceof Error ? err : new Error(String(e))
+    }
+    // "ENOENT: no such file or directory, open 'x'" has the description after the code
+    let m = /^[A-Z0-9_]+: ([^,]*)/.exec(err.message)
+    return new Errno(err.code, m != null ? m[1] : err.message, e)
+}
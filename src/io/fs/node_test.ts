@@ -0,0 +1,159 @@
+// Tests for DirFS
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/os/os_test.go
+import * as assert from "node:assert/strict"
+import * as nodefs from "node:fs"
+import * as os from "node:os"
+import * as nodepath from "node:path"
+import { test } from "node:test"
+import { str } from "../../builtins/tshelpers/strings"
+import * as errors from "../../errors"
+import { TestFS } from "../../testing/fstest"
+import { EOF, isReaderAt, isSeeker, ReadAll, SeekEnd, SeekStart } from "../index"
+import { ErrClosed, ErrInvalid, ErrNotExist, PathError, ReadDir, ValidPath, WalkDir } from "./index"
+import { DirFS, Errno } from "./node"
+
+// withTempDir runs f with a new temporary directory holding the files of
+// testdata/dirfs in Go, and removes it afterwards
+function withTempDir(f: (dir: string) => void) {
+    let dir = nodefs.mkdtempSync(nodepath.join(os.tmpdir(), "dirfs-test-"))
+    try {
+        nodefs.writeFileSync(nodepath.join(dir, "a"), "")
+        nodefs.writeFileSync(nodepath.join(dir, "b"), "")
+        nodefs.mkdirSync(nodepath.join(dir, "dir"))
+        nodefs.writeFileSync(nodepath.join(dir, "dir", "x"), "")
+        f(dir)
+    } finally {
+        nodefs.rmSync(dir, { recursive: true })
+    }
+}
+
+test("TestDirFS", () => {
+    withTempDir((dir) => {
+        let fsys = DirFS(dir)
+        assert.equal(TestFS(fsys, "a", "b", "dir/x"), null)
+
+        // Test that the error message does not contain a backslash,
+        // and does not contain the DirFS argument.
+        const nonesuch = "dir/nonesuch"
+        let [, err] = fsys.Open(nonesuch)
+        assert.ok(err != null, "fs.Open of nonexistent file succeeded")
+        assert.ok(err.message.includes(nonesuch), `error ${err.message} does not contain ${nonesuch}`)
+        assert.ok(err instanceof PathError)
+        assert.ok(!err.Path.includes(dir), `error ${err.message} contains ${dir}`)
+        assert.ok(errors.Is(err, ErrNotExist))
+
+        // Test that Open does not accept backslash as separator.
+        let d = DirFS(nodepath.dirname(dir));
+        [, err] = d.Open(nodepath.basename(dir) + "\\dir")
+        assert.ok(err != null, "Open with a backslash succeeded")
+    })
+})
+
+test("TestDirFSRootDir", () => {
+    withTempDir((dir) => {
+        let root = nodepath.parse(dir).root
+        let rel = dir.slice(root.length).split(nodepath.sep).join("/")
+
+        // Test that Open can open a path starting at /.
+        let d = DirFS(root)
+        let [f, err] = d.Open(rel + "/a")
+        assert.equal(err, null)
+        f!.Close()
+    })
+})
+
+test("TestDirFSEmptyDir", () => {
+    withTempDir((dir) => {
+        let d = DirFS("")
+        let cwd = process.cwd()
+        for (let path of [
+            nodepath.relative(cwd, dir).split(nodepath.sep).join("/") + "/a", // not DirFS(".")
+            dir.split(nodepath.sep).join("/") + "/a", // not DirFS("/")
+        ]) {
+            let [, err] = d.Open(path)
+            assert.ok(err != null, `DirFS("").Open(${JSON.stringify(path)}) succeeded`)
+        }
+    })
+})
+
+test("TestDirFSPathsValid", { skip: process.platform == "win32" }, () => {
+    let d = nodefs.mkdtempSync(nodepath.join(os.tmpdir(), "dirfs-test-"))
+    try {
+        nodefs.writeFileSync(nodepath.join(d, "control.txt"), "Hello, world!")
+        nodefs.writeFileSync(nodepath.join(d, "e:xperi\\ment.txt"), "Hello, colon and backslash!")
+
+        let fsys = DirFS(d)
+        let err = WalkDir(fsys, ".", (path, e, err) => {
+            assert.ok(ValidPath(e!.Name()), `${JSON.stringify(e!.Name())} INVALID`)
+            return null
+        })
+        assert.equal(err, null)
+    } finally {
+        nodefs.rmSync(d, { recursive: true })
+    }
+})
+
+// Not present in the Go code
+test("TestDirFSFile", () => {
+    withTempDir((dir) => {
+        nodefs.writeFileSync(nodepath.join(dir, "a"), "hello, world")
+        let [f, err] = DirFS(dir).Open("a")
+        assert.equal(err, null)
+        assert.ok(isReaderAt(f) && isSeeker(f))
+
+        let p = new Uint8Array(5)
+        let n: number;
+        [n, err] = f.ReadAt(p, 7)
+        assert.equal(err, null)
+        assert.equal(str(p.subarray(0, n)), "world");
+        [n, err] = f.ReadAt(p, 10)
+        assert.equal(err, EOF)
+        assert.equal(str(p.subarray(0, n)), "ld");
+        [, err] = f.ReadAt(p, -1)
+        assert.ok(err != null)
+
+        let off: number;
+        [off, err] = f.Seek(-5, SeekEnd)
+        assert.equal(err, null)
+        assert.equal(off, 7)
+        let data: Uint8Array;
+        [data, err] = ReadAll(f)
+        assert.equal(err, null)
+        assert.equal(str(data), "world");
+        [, err] = f.Seek(-1, SeekStart)
+        assert.ok(errors.Is(err, ErrInvalid))
+
+        assert.equal(f.Close(), null)
+        err = f.Close()
+        assert.ok(errors.Is(err, ErrClosed), `second Close = ${err}`);
+        [, err] = f.Read(p)
+        assert.ok(errors.Is(err, ErrClosed), `Read after Close = ${err}`)
+    })
+})
+
+// Not present in the Go code
+test("TestDirFSErrors", () => {
+    withTempDir((dir) => {
+        let fsys = DirFS(dir)
+        let [, err] = fsys.Open("../a")
+        assert.ok(err instanceof PathError)
+        assert.equal(err.Err, ErrInvalid);
+
+        [, err] = ReadDir(fsys, "nonesuch")
+        assert.ok(err instanceof PathError)
+        assert.equal(err.Path, "nonesuch")
+        assert.ok(errors.Is(err, ErrNotExist))
+        let errno = errors.As(err, Errno)
+        assert.ok(errno != null)
+        assert.equal(errno.Code, "ENOENT")
+        assert.equal(errno.message, "no such file or directory")
+
+        // Reading a directory fails
+        let [d] = fsys.Open("dir")
+        let n: number;
+        [n, err] = d!.Read(new Uint8Array(1))
+        assert.equal(n, 0)
+        assert.ok(err != null && err != EOF)
+        d!.Close()
+    })
+})
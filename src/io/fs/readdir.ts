@@ -0,0 +1,99 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/readdir.go
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import * as errors from "../../errors"
+import { DirEntry, FileInfo, FileMode, FormatDirEntry, FS, isReadDirFile, PathError } from "./index"
+
+/**
+ * ReadDirFS is the interface implemented by a file system
+ * that provides an optimized implementation of [ReadDir].
+ */
+export interface ReadDirFS extends FS {
+    /**
+     * ReadDir reads the named directory
+     * and returns a list of directory entries sorted by filename.
+     */
+    ReadDir(name: string): [DirEntry[], Error | null]
+}
+
+/**
+ * isReadDirFS reports whether v implements [ReadDirFS]
+ */
+export function isReadDirFS(v: unknown): v is ReadDirFS {
+    return hasMethods(v, "Open", "ReadDir")
+}
+
+/**
+ * ReadDir reads the named directory
+ * and returns a list of directory entries sorted by filename.
+ *
+ * If fs implements [ReadDirFS], ReadDir calls fs.ReadDir.
+ * Otherwise ReadDir calls fs.Open and uses ReadDir and Close
+ * on the returned file.
+ */
+export function ReadDir(fsys: FS, name: string): [DirEntry[], Error | null] {
+    if (isReadDirFS(fsys)) {
+        return fsys.ReadDir(name)
+    }
+
+    let [file, err] = fsys.Open(name)
+    if (err != null) {
+        return [[], err]
+    }
+    try {
+        if (!isReadDirFile(file)) {
+            return [[], new PathError("readdir", name, errors.New("not implemented"))]
+        }
+
+        let list: DirEntry[];
+        [list, err] = file.ReadDir(-1)
+        list.sort((a, b) => compareNames(a.Name(), b.Name()))
+        return [list, err]
+    } finally {
+        file!.Close()
+    }
+}
+
+// compareNames orders names like Go's string comparison
+function compareNames(a: string, b: string): number {
+    return a < b ? -1 : a > b ? 1 : 0
+}
+
+// dirInfo is a DirEntry based on a FileInfo.
+class dirInfo implements DirEntry {
+    private fileInfo: FileInfo
+
+    constructor(fileInfo: FileInfo) {
+        this.fileInfo = fileInfo
+    }
+
+    IsDir(): boolean {
+        return this.fileInfo.IsDir()
+    }
+
+    Type(): FileMode {
+        return FileMode.Type(this.fileInfo.Mode())
+    }
+
+    Info(): [FileInfo | null, Error | null] {
+        return [this.fileInfo, null]
+    }
+
+    Name(): string {
+        return this.fileInfo.Name()
+    }
+
+    String(): string {
+        return FormatDirEntry(this)
+    }
+}
+
+/**
+ * FileInfoToDirEntry returns a [DirEntry] that returns information from info.
+ * If info is null, FileInfoToDirEntry returns null.
+ */
+export function FileInfoToDirEntry(info: FileInfo | null): DirEntry | null {
+    if (info == null) {
+        return null
+    }
+    return new dirInfo(info)
+}
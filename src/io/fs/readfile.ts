@@ -0,0 +1,82 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/readfile.go
+import { nextSliceCap } from "../../builtins/tshelpers/arrays"
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import { EOF } from "../index"
+import { FS } from "./index"
+
+/**
+ * ReadFileFS is the interface implemented by a file system
+ * that provides an optimized implementation of [ReadFile].
+ */
+export interface ReadFileFS extends FS {
+    /**
+     * ReadFile reads the named file and returns its contents.
+     * A successful call returns a null error, not io.EOF.
+     * (Because ReadFile reads the whole file, the expected EOF
+     * from the final Read is not treated as an error to be reported.)
+     *
+     * The caller is permitted to modify the returned Uint8Array.
+     * This method should return a copy of the underlying data.
+     */
+    ReadFile(name: string): [Uint8Array, Error | null]
+}
+
+/**
+ * isReadFileFS reports whether v implements [ReadFileFS]
+ */
+export function isReadFileFS(v: unknown): v is ReadFileFS {
+    return hasMethods(v, "Open", "ReadFile")
+}
+
+/**
+ * ReadFile reads the named file from the file system fs and returns its contents.
+ * A successful call returns err == null, not err == EOF.
+ * (Because ReadFile reads the whole file, the expected EOF
+ * from the final Read is not treated as an error to be reported.)
+ *
+ * If fs implements [ReadFileFS], ReadFile calls fs.ReadFile.
+ * Otherwise ReadFile calls fs.Open and uses Read and Close
+ * on the returned [File].
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * On error, an empty Uint8Array is returned where Go returns a nil slice
+ */
+export function ReadFile(fsys: FS, name: string): [Uint8Array, Error | null] {
+    if (isReadFileFS(fsys)) {
+        return fsys.ReadFile(name)
+    }
+
+    let [file, err] = fsys.Open(name)
+    if (err != null) {
+        return [new Uint8Array(0), err]
+    }
+    try {
+        let size = 0
+        let [info, statErr] = file!.Stat()
+        if (statErr == null && Number.isSafeInteger(info!.Size())) {
+            size = info!.Size()
+        }
+
+        let data = new Uint8Array(size + 1)
+        let len = 0
+        while (true) { // for {}
+            if (len >= data.length) {
+                let grown = new Uint8Array(nextSliceCap(len + 1, data.length))
+                grown.set(data)
+                data = grown
+            }
+            let n: number;
+            [n, err] = file!.Read(data.subarray(len))
+            len += n
+            if (err != null) {
+                if (err == EOF) {
+                    err = null
+                }
+                return [data.subarray(0, len), err]
+            }
+        }
+    } finally {
+        file!.Close()
+    }
+}
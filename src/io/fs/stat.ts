@@ -0,0 +1,43 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/stat.go
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import { FileInfo, FS } from "./index"
+
+/**
+ * A StatFS is a file system with a Stat method.
+ */
+export interface StatFS extends FS {
+    /**
+     * Stat returns a FileInfo describing the file.
+     * If there is an error, it should be of type [PathError].
+     */
+    Stat(name: string): [FileInfo | null, Error | null]
+}
+
+/**
+ * isStatFS reports whether v implements [StatFS]
+ */
+export function isStatFS(v: unknown): v is StatFS {
+    return hasMethods(v, "Open", "Stat")
+}
+
+/**
+ * Stat returns a [FileInfo] describing the named file from the file system.
+ *
+ * If fs implements [StatFS], Stat calls fs.Stat.
+ * Otherwise, Stat opens the [File] to stat it.
+ */
+export function Stat(fsys: FS, name: string): [FileInfo | null, Error | null] {
+    if (isStatFS(fsys)) {
+        return fsys.Stat(name)
+    }
+
+    let [file, err] = fsys.Open(name)
+    if (err != null) {
+        return [null, err]
+    }
+    try {
+        return file!.Stat()
+    } finally {
+        file!.Close()
+    }
+}
@@ -0,0 +1,156 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/sub.go
+import { hasMethods } from "../../builtins/tshelpers/methods"
+import * as errors from "../../errors"
+import * as path from "../../path"
+import { DirEntry, ErrInvalid, File, FS, PathError, ValidPath } from "./index"
+import { Glob } from "./glob"
+import { ReadDir } from "./readdir"
+import { ReadFile } from "./readfile"
+
+/**
+ * A SubFS is a file system with a Sub method.
+ */
+export interface SubFS extends FS {
+    /**
+     * Sub returns an FS corresponding to the subtree rooted at dir.
+     */
+    Sub(dir: string): [FS | null, Error | null]
+}
+
+/**
+ * isSubFS reports whether v implements [SubFS]
+ */
+export function isSubFS(v: unknown): v is SubFS {
+    return hasMethods(v, "Open", "Sub")
+}
+
+/**
+ * Sub returns an [FS] corresponding to the subtree rooted at fsys's dir.
+ *
+ * If dir is ".", Sub returns fsys unchanged.
+ * Otherwise, if fs implements [SubFS], Sub returns fsys.Sub(dir).
+ * Otherwise, Sub returns a new [FS] implementation sub that,
+ * in effect, implements sub.Open(name) as fsys.Open(path.Join(dir, name)).
+ * The implementation also translates calls to ReadDir, ReadFile, and Glob appropriately.
+ *
+ * Note that Sub("/prefix") does not prevent access to files outside of
+ * prefix: Sub("..") and other paths are all rejected as invalid, but
+ * a symbolic link inside prefix pointing outside it is followed by
+ * file systems like io/fs/node's DirFS.
+ */
+export function Sub(fsys: FS, dir: string): [FS | null, Error | null] {
+    if (!ValidPath(dir)) {
+        return [null, new PathError("sub", dir, ErrInvalid)]
+    }
+    if (dir == ".") {
+        return [fsys, null]
+    }
+    if (isSubFS(fsys)) {
+        return fsys.Sub(dir)
+    }
+    return [new subFS(fsys, dir), null]
+}
+
+class subFS implements FS {
+    private fsys: FS
+    private dir: string
+
+    constructor(fsys: FS, dir: string) {
+        this.fsys = fsys
+        this.dir = dir
+    }
+
+    // fullName maps name to the fully-qualified name dir/name.
+    private fullName(op: string, name: string): [string, Error | null] {
+        if (!ValidPath(name)) {
+            return ["", new PathError(op, name, ErrInvalid)]
+        }
+        return [path.Join(this.dir, name), null]
+    }
+
+    // shorten maps name, which should start with f.dir, back to the suffix after f.dir.
+    private shorten(name: string): [string, boolean] {
+        if (name == this.dir) {
+            return [".", true]
+        }
+        if (name.length >= this.dir.length + 2 && name[this.dir.length] == "/" && name.slice(0, this.dir.length) == this.dir) {
+            return [name.slice(this.dir.length + 1), true]
+        }
+        return ["", false]
+    }
+
+    // fixErr shortens any reported names in PathErrors by stripping f.dir.
+    private fixErr(err: Error | null): Error | null {
+        if (err instanceof PathError) {
+            let [short, ok] = this.shorten(err.Path)
+            if (ok) {
+                err.Path = short
+            }
+        }
+        return err
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        let [full, err] = this.fullName("open", name)
+        if (err != null) {
+            return [null, err]
+        }
+        let file: File | null;
+        [file, err] = this.fsys.Open(full)
+        return [file, this.fixErr(err)]
+    }
+
+    ReadDir(name: string): [DirEntry[], Error | null] {
+        let [full, err] = this.fullName("read", name)
+        if (err != null) {
+            return [[], err]
+        }
+        let dir: DirEntry[];
+        [dir, err] = ReadDir(this.fsys, full)
+        return [dir, this.fixErr(err)]
+    }
+
+    ReadFile(name: string): [Uint8Array, Error | null] {
+        let [full, err] = this.fullName("read", name)
+        if (err != null) {
+            return [new Uint8Array(0), err]
+        }
+        let data: Uint8Array;
+        [data, err] = ReadFile(this.fsys, full)
+        return [data, this.fixErr(err)]
+    }
+
+    Glob(pattern: string): [string[], Error | null] {
+        // Check pattern is well-formed.
+        let [, err] = path.Match(pattern, "")
+        if (err != null) {
+            return [[], err]
+        }
+        if (pattern == ".") {
+            return [["."], null]
+        }
+
+        let full = this.dir + "/" + pattern
+        let list: string[];
+        [list, err] = Glob(this.fsys, full)
+        for (let i = 0; i < list.length; i++) {
+            let [name, ok] = this.shorten(list[i])
+            if (!ok) {
+                return [[], errors.New("invalid result from inner fsys Glob: " + name + " not in " + this.dir)]
+            }
+            list[i] = name
+        }
+        return [list, this.fixErr(err)]
+    }
+
+    Sub(dir: string): [FS | null, Error | null] {
+        if (dir == ".") {
+            return [this, null]
+        }
+        let [full, err] = this.fullName("sub", dir)
+        if (err != null) {
+            return [null, err]
+        }
+        return [new subFS(this.fsys, full), null]
+    }
+}
@@ -0,0 +1,135 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/walk.go
+import * as errors from "../../errors"
+import * as path from "../../path"
+import { DirEntry, FS } from "./index"
+import { FileInfoToDirEntry, ReadDir } from "./readdir"
+import { Stat } from "./stat"
+
+/**
+ * SkipDir is used as a return value from [WalkDirFunc]s to indicate that
+ * the directory named in the call is to be skipped. It is not returned
+ * as an error by any function.
+ */
+export const SkipDir = errors.New("skip this directory")
+
+/**
+ * SkipAll is used as a return value from [WalkDirFunc]s to indicate that
+ * all remaining files and directories are to be skipped. It is not returned
+ * as an error by any function.
+ */
+export const SkipAll = errors.New("skip everything and stop the walk")
+
+/**
+ * WalkDirFunc is the type of the function called by [WalkDir] to visit
+ * each file or directory.
+ *
+ * The path argument contains the argument to [WalkDir] as a prefix.
+ * That is, if WalkDir is called with root argument "dir" and finds a file
+ * named "a" in that directory, the walk function will be called with
+ * argument "dir/a".
+ *
+ * The d argument is the [DirEntry] for the named path.
+ *
+ * The error result returned by the function controls how [WalkDir]
+ * continues. If the function returns the special value [SkipDir], WalkDir
+ * skips the current directory (path if d.IsDir() is true, otherwise
+ * path's parent directory). If the function returns the special value
+ * [SkipAll], WalkDir skips all remaining files and directories. Otherwise,
+ * if the function returns a non-null error, WalkDir stops entirely and
+ * returns that error.
+ *
+ * The err argument reports an error related to path, signaling that
+ * [WalkDir] will not walk into that directory. The function can decide how
+ * to handle that error; as described earlier, returning the error will
+ * cause WalkDir to stop walking the entire tree.
+ *
+ * [WalkDir] calls the function with a non-null err argument in two cases.
+ *
+ * First, if the initial [Stat] on the root directory fails, WalkDir
+ * calls the function with path set to root, d set to null, and err set to
+ * the error from [Stat].
+ *
+ * Second, if a directory's ReadDir method (see [ReadDirFile]) fails, WalkDir
+ * calls the function with path set to the directory's path, d set to an
+ * [DirEntry] describing the directory, and err set to the error from
+ * ReadDir. In this second case, the function is called twice with the
+ * path of the directory: the first call is before the directory read is
+ * attempted and has err set to null, giving the function a chance to
+ * return [SkipDir] or [SkipAll] and avoid the ReadDir entirely. The second
+ * call is after a failed ReadDir and reports the error from ReadDir.
+ * (If ReadDir succeeds, there is no second call.)
+ *
+ * The differences between WalkDirFunc compared to path/filepath.WalkFunc are:
+ *
+ *   - The second argument has type [DirEntry] instead of [FileInfo].
+ *   - The function is called before reading a directory, to allow [SkipDir]
+ *     or [SkipAll] to bypass the directory read entirely or skip all remaining
+ *     files and directories respectively.
+ *   - If a directory read fails, the function is called a second time
+ *     for that directory to report the error.
+ */
+export type WalkDirFunc = (path: string, d: DirEntry | null, err: Error | null) => Error | null
+
+// walkDir recursively descends path, calling walkDirFn.
+function walkDir(fsys: FS, name: string, d: DirEntry, walkDirFn: WalkDirFunc): Error | null {
+    let err = walkDirFn(name, d, null)
+    if (err != null || !d.IsDir()) {
+        if (err == SkipDir && d.IsDir()) {
+            // Successfully skipped directory.
+            err = null
+        }
+        return err
+    }
+
+    let dirs: DirEntry[];
+    [dirs, err] = ReadDir(fsys, name)
+    if (err != null) {
+        // Second call, to report ReadDir error.
+        err = walkDirFn(name, d, err)
+        if (err != null) {
+            if (err == SkipDir && d.IsDir()) {
+                err = null
+            }
+            return err
+        }
+    }
+
+    for (let d1 of dirs) {
+        let name1 = path.Join(name, d1.Name())
+        let err = walkDir(fsys, name1, d1, walkDirFn)
+        if (err != null) {
+            if (err == SkipDir) {
+                break
+            }
+            return err
+        }
+    }
+    return null
+}
+
+/**
+ * WalkDir walks the file tree rooted at root, calling fn for each file or
+ * directory in the tree, including root.
+ *
+ * All errors that arise visiting files and directories are filtered by fn:
+ * see the [WalkDirFunc] documentation for details.
+ *
+ * The files are walked in lexical order, which makes the output deterministic
+ * but requires WalkDir to read an entire directory into memory before proceeding
+ * to walk that directory.
+ *
+ * WalkDir does not follow symbolic links found in directories,
+ * but if root itself is a symbolic link, its target will be walked.
+ */
+export function WalkDir(fsys: FS, root: string, fn: WalkDirFunc): Error | null {
+    let [info, err] = Stat(fsys, root)
+    if (err != null) {
+        err = fn(root, null, err)
+    } else {
+        err = walkDir(fsys, root, FileInfoToDirEntry(info)!, fn)
+    }
+    if (err == SkipDir || err == SkipAll) {
+        return null
+    }
+    return err
+}
@@ -0,0 +1,159 @@
+// Tests for WalkDir
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/fs/walk_test.go
+import * as assert from "node:assert/strict"
+import * as nodefs from "node:fs"
+import * as os from "node:os"
+import * as nodepath from "node:path"
+import { test } from "node:test"
+import { Join } from "../../path"
+import { MapFS } from "../../testing/fstest"
+import { DirEntry, FS, ModeDir, SkipAll, SkipDir, WalkDir } from "./index"
+import { DirFS } from "./node"
+
+interface Node {
+    name: string
+    entries: Node[] | null // null if the entry is a file
+    mark: number
+}
+
+const tree: Node = {
+    name: "testdata",
+    entries: [
+        { name: "a", entries: null, mark: 0 },
+        { name: "b", entries: [], mark: 0 },
+        { name: "c", entries: null, mark: 0 },
+        {
+            name: "d",
+            entries: [
+                { name: "x", entries: null, mark: 0 },
+                { name: "y", entries: [], mark: 0 },
+                {
+                    name: "z",
+                    entries: [
+                        { name: "u", entries: null, mark: 0 },
+                        { name: "v", entries: null, mark: 0 },
+                    ],
+                    mark: 0,
+                },
+            ],
+            mark: 0,
+        },
+    ],
+    mark: 0,
+}
+
+function walkTree(n: Node, path: string, f: (path: string, n: Node) => void) {
+    f(path, n)
+    for (let e of n.entries ?? []) {
+        walkTree(e, Join(path, e.name), f)
+    }
+}
+
+function makeTree(): FS {
+    let fsys = new MapFS()
+    walkTree(tree, tree.name, (path, n) => {
+        if (n.entries == null) {
+            fsys.set(path, {})
+        } else {
+            fsys.set(path, { Mode: ModeDir })
+        }
+    })
+    return fsys
+}
+
+function checkMarks(report: boolean) {
+    walkTree(tree, tree.name, (path, n) => {
+        if (n.mark != 1 && report) {
+            assert.fail(`node ${path} mark = ${n.mark}; expected 1`)
+        }
+        n.mark = 0
+    })
+}
+
+// Assumes that each node name is unique. Good enough for a test.
+function mark(entry: DirEntry | null, err: Error | null, errors: Error[], clear: boolean): Error | null {
+    let name = entry!.Name()
+    walkTree(tree, tree.name, (path, n) => {
+        if (n.name == name) {
+            n.mark++
+        }
+    })
+    return null
+}
+
+test("TestWalkDir", () => {
+    let fsys = makeTree()
+    let errors: Error[] = []
+    let clear = true
+    let markFn = (path: string, entry: DirEntry | null, err: Error | null) => {
+        return mark(entry, err, errors, clear)
+    }
+    // Expect no errors.
+    let err = WalkDir(fsys, ".", markFn)
+    assert.equal(err, null)
+    assert.deepEqual(errors, [])
+    checkMarks(true)
+})
+
+test("TestIssue51617", () => {
+    let dir = nodefs.mkdtempSync(nodepath.join(os.tmpdir(), "walk-test-"))
+    for (let sub of ["a", nodepath.join("a", "bad"), nodepath.join("a", "next")]) {
+        nodefs.mkdirSync(nodepath.join(dir, sub), 0o755)
+    }
+    let bad = nodepath.join(dir, "a", "bad")
+    nodefs.chmodSync(bad, 0)
+    try {
+        let saw: string[] = []
+        let err = WalkDir(DirFS(dir), ".", (path, d, err) => {
+            if (err != null) {
+                return SkipDir
+            }
+            if (d!.IsDir()) {
+                saw.push(path)
+            }
+            return null
+        })
+        assert.equal(err, null)
+        assert.deepEqual(saw, [".", "a", "a/bad", "a/next"])
+    } finally {
+        nodefs.chmodSync(bad, 0o700) // avoid errors on cleanup
+        nodefs.rmSync(dir, { recursive: true })
+    }
+})
+
+// Not present in the Go code
+test("TestWalkDirSkip", () => {
+    let fsys = makeTree()
+    let saw: string[] = []
+    let err = WalkDir(fsys, ".", (path, d, err) => {
+        saw.push(path)
+        if (path == "testdata/b") {
+            return SkipDir
+        }
+        if (path == "testdata/d/x") {
+            // Skips the rest of testdata/d
+            return SkipDir
+        }
+        return null
+    })
+    assert.equal(err, null)
+    assert.deepEqual(saw, [".", "testdata", "testdata/a", "testdata/b", "testdata/c", "testdata/d", "testdata/d/x"])
+
+    saw = []
+    err = WalkDir(fsys, ".", (path, d, err) => {
+        saw.push(path)
+        return path == "testdata/c" ? SkipAll : null
+    })
+    assert.equal(err, null)
+    assert.deepEqual(saw, [".", "testdata", "testdata/a", "testdata/b", "testdata/c"])
+
+    // A missing root is reported to fn, and its error returned
+    let rootErr: Error | null = null
+    err = WalkDir(fsys, "missing", (path, d, err) => {
+        assert.equal(d, null)
+        rootErr = err
+        return err
+    })
+    assert.ok(err != null)
+    assert.equal(err, rootErr)
+})
@@ -1,30 +1,84 @@
 // A set of core IO primitizes commonly used in Go
 
-import { mergeUint8Arrays } from "../builtins/tshelpers/arrays"
-import { is } from "../builtins/tshelpers/tsGuards"
+import { nextSliceCap } from "../builtins/tshelpers/arrays"
+import { hasMethods } from "../builtins/tshelpers/methods"
+import * as errors from "../errors"
 
-// IO Errors
-export enum Errors {
-    // EOF is the error returned by Read when no more input is available.
-    // (Read must return EOF itself, not an error wrapping EOF,
-    // because callers will test for EOF using ==.)
-    // Functions should return EOF only to signal a graceful end of input.
-    // If the EOF occurs unexpectedly in a structured data stream,
-    // the appropriate error is either [ErrUnexpectedEOF] or some other error
-    // giving more detail.
-    EOF = "EOF",
+// EOF is the error returned by Read when no more input is available.
+// (Read must return EOF itself, not an error wrapping EOF,
+// because callers will test for EOF using ==.)
+// Functions should return EOF only to signal a graceful end of input.
+// If the EOF occurs unexpectedly in a structured data stream,
+// the appropriate error is either [ErrUnexpectedEOF] or some other error
+// giving more detail.
+export const EOF = errors.New("EOF")
 
-    // ErrUnexpectedEOF means that EOF was encountered in the
-    // middle of reading a fixed-size block or data structure.
-    UnexpectedEOF = "unexpected EOF",
+// ErrUnexpectedEOF means that EOF was encountered in the
+// middle of reading a fixed-size block or data structure.
+export const ErrUnexpectedEOF = errors.New("unexpected EOF")
 
-    // errInvalidWrite means that a write returned an impossible count.
-    InvalidWrite = "invalid write result",
+// errInvalidWrite means that a write returned an impossible count.
+const errInvalidWrite = errors.New("invalid write result")
 
-    // ErrShortWrite means that a write accepted fewer bytes than requested
-    // but failed to return an explicit error.
-    ShortWrite = "short write"
-}
+// ErrShortWrite means that a write accepted fewer bytes than requested
+// but failed to return an explicit error.
+export const ErrShortWrite = errors.New("short write")
+
+// ErrShortBuffer means that a read required a longer buffer than was provided.
+export const ErrShortBuffer = errors.New("short buffer")
+
+// ErrNoProgress is returned by some clients of a Reader when
+// many calls to Read have failed to return any data or error,
+// usually the sign of a broken Reader implementation.
+export const ErrNoProgress = errors.New("multiple Read calls return no data or error")
+
+// ErrClosedPipe is the error used for read or write operations on a closed pipe.
+export const ErrClosedPipe = errors.New("io: read/write on closed pipe")
+
+// errWhence means that Seek was called with an invalid whence value.
+const errWhence = errors.New("Seek: invalid whence")
+
+// errOffset means that Seek or WriteAt was called with an offset before the start.
+const errOffset = errors.New("Seek: invalid offset")
+
+/**
+ * IO Errors
+ *
+ * Not present in the Go code: Errors holds the errors above under shorter names, and also
+ * gives the other files of this package access to the unexported ones. Errors.EOF is EOF,
+ * so either can be compared with.
+ */
+export const Errors = {
+    EOF,
+    UnexpectedEOF: ErrUnexpectedEOF,
+    InvalidWrite: errInvalidWrite,
+    ShortWrite: ErrShortWrite,
+    ShortBuffer: ErrShortBuffer,
+    NoProgress: ErrNoProgress,
+    ClosedPipe: ErrClosedPipe,
+    Whence: errWhence,
+    Offset: errOffset,
+} as const
+
+// Seek whence values.
+export const SeekStart = 0 // seek relative to the origin of the file
+export const SeekCurrent = 1 // seek relative to the current offset
+export const SeekEnd = 2 // seek relative to the end
+
+// MaxConsecutiveEmptyReads is the number of reads in a row that may return no
+// data and no error before a Reader is taken to be broken and [ErrNoProgress]
+// is returned.
+//
+// Not present in the Go code: this is bufio's maxConsecutiveEmptyReads, shared
+// by the readers of this library that stand in for a bufio.Reader.
+export const MaxConsecutiveEmptyReads = 100
+
+// DefaultBufferSize is the size of the buffers that the readers and writers of
+// this library keep in front of a Reader or Writer.
+//
+// Not present in the Go code: this is bufio's defaultBufSize, the size of the
+// bufio.Reader and bufio.Writer that Go wraps such sources and destinations in.
+export const DefaultBufferSize = 4096
 
 /**
  * io.Reader from Golang 
@@ -43,6 +97,13 @@ export interface Reader {
     Read(p: Uint8Array): [number, Error | null]
 }
 
+/**
+ * isReader reports whether v implements [Reader], like the Go type assertion v.(io.Reader)
+ */
+export function isReader(v: unknown): v is Reader {
+    return hasMethods(v, "Read")
+}
+
 /**
  * io.ByteReader from Golang 
  * 
@@ -54,6 +115,71 @@ export interface ByteReader {
     ReadByte(): [number, Error | null]
 }
 
+/**
+ * isByteReader reports whether v implements [ByteReader]
+ */
+export function isByteReader(v: unknown): v is ByteReader {
+    return hasMethods(v, "ReadByte")
+}
+
+/**
+ * io.ByteScanner from Golang
+ *
+ * ByteScanner is the interface that adds the UnreadByte method to the basic ReadByte method.
+ *
+ * UnreadByte causes the next call to ReadByte to return the last byte read. If the last operation was not a successful call to ReadByte, UnreadByte may return an error, unread the last byte read (or the byte prior to the last-unread byte), or (in implementations that support the Seeker interface) seek to one byte before the current offset.
+ */
+export interface ByteScanner extends ByteReader {
+    UnreadByte(): Error | null
+}
+
+/**
+ * isByteScanner reports whether v implements [ByteScanner]
+ */
+export function isByteScanner(v: unknown): v is ByteScanner {
+    return hasMethods(v, "ReadByte", "UnreadByte")
+}
+
+/**
+ * io.RuneReader from Golang
+ *
+ * RuneReader is the interface that wraps the ReadRune method.
+ *
+ * ReadRune reads a single encoded Unicode character and returns the rune and its size in bytes. If no character is available, err will be set.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * A rune is a number holding a Unicode code point, see unicode/utf8. Invalid UTF-8 is returned as utf8.RuneError (U+FFFD) with size 1
+ */
+export interface RuneReader {
+    ReadRune(): [number, number, Error | null]
+}
+
+/**
+ * isRuneReader reports whether v implements [RuneReader]
+ */
+export function isRuneReader(v: unknown): v is RuneReader {
+    return hasMethods(v, "ReadRune")
+}
+
+/**
+ * io.RuneScanner from Golang
+ *
+ * RuneScanner is the interface that adds the UnreadRune method to the basic ReadRune method.
+ *
+ * UnreadRune causes the next call to ReadRune to return the last rune read. If the last operation was not a successful call to ReadRune, UnreadRune may return an error, unread the last rune read (or the rune prior to the last-unread rune), or (in implementations that support the Seeker interface) seek to the start of the rune before the current offset.
+ */
+export interface RuneScanner extends RuneReader {
+    UnreadRune(): Error | null
+}
+
+/**
+ * isRuneScanner reports whether v implements [RuneScanner]
+ */
+export function isRuneScanner(v: unknown): v is RuneScanner {
+    return hasMethods(v, "ReadRune", "UnreadRune")
+}
+
 /**
  * io.ReaderAt from Golang
  * 
@@ -83,6 +209,13 @@ export interface ReaderAt {
     ReadAt(p: Uint8Array, off: number): [number, Error | null]
 }
 
+/**
+ * isReaderAt reports whether v implements [ReaderAt]
+ */
+export function isReaderAt(v: unknown): v is ReaderAt {
+    return hasMethods(v, "ReadAt")
+}
+
 /** 
  * io.ReaderFrom from Golang
  * 
@@ -96,6 +229,13 @@ export interface ReaderFrom {
     ReadFrom(r: Reader): [number, Error | null]
 }
 
+/**
+ * isReaderFrom reports whether v implements [ReaderFrom]
+ */
+export function isReaderFrom(v: unknown): v is ReaderFrom {
+    return hasMethods(v, "ReadFrom")
+}
+
 /**
  * io.ByteWriter from Golang
  * 
@@ -105,6 +245,13 @@ export interface ByteWriter {
     WriteByte(b: number): Error | null
 }
 
+/**
+ * isByteWriter reports whether v implements [ByteWriter]
+ */
+export function isByteWriter(v: unknown): v is ByteWriter {
+    return hasMethods(v, "WriteByte")
+}
+
 /**
  * io.Writer from Golang
  * 
@@ -122,6 +269,13 @@ export interface Writer {
     Write(p: Uint8Array): [number, Error | null]
 }
 
+/**
+ * isWriter reports whether v implements [Writer]
+ */
+export function isWriter(v: unknown): v is Writer {
+    return hasMethods(v, "Write")
+}
+
 /**
  * io.WriterAt from Golang
  * 
@@ -137,6 +291,13 @@ export interface WriterAt {
     WriteAt(p: Uint8Array, off: number): [number, Error | null]
 }
 
+/**
+ * isWriterAt reports whether v implements [WriterAt]
+ */
+export function isWriterAt(v: unknown): v is WriterAt {
+    return hasMethods(v, "WriteAt")
+}
+
 /**
  * io.WriterTo from Golang
  * 
@@ -148,6 +309,187 @@ export interface WriterTo {
     WriteTo(w: Writer): [number, Error | null]
 }
 
+/**
+ * isWriterTo reports whether v implements [WriterTo]
+ */
+export function isWriterTo(v: unknown): v is WriterTo {
+    return hasMethods(v, "WriteTo")
+}
+
+/**
+ * io.StringWriter from Golang
+ *
+ * StringWriter is the interface that wraps the WriteString method.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Strings are written as UTF-8, and n counts the UTF-8 bytes written, like len(s) in Go
+ */
+export interface StringWriter {
+    WriteString(s: string): [number, Error | null]
+}
+
+/**
+ * isStringWriter reports whether v implements [StringWriter]
+ */
+export function isStringWriter(v: unknown): v is StringWriter {
+    return hasMethods(v, "WriteString")
+}
+
+/**
+ * io.Closer from Golang
+ *
+ * Closer is the interface that wraps the basic Close method.
+ *
+ * The behavior of Close after the first call is undefined. Specific implementations may document their own behavior.
+ */
+export interface Closer {
+    Close(): Error | null
+}
+
+/**
+ * isCloser reports whether v implements [Closer]
+ */
+export function isCloser(v: unknown): v is Closer {
+    return hasMethods(v, "Close")
+}
+
+/**
+ * io.Seeker from Golang
+ *
+ * Seeker is the interface that wraps the basic Seek method.
+ *
+ * Seek sets the offset for the next Read or Write to offset, interpreted according to whence: [SeekStart] means relative to the start of the file, [SeekCurrent] means relative to the current offset, and [SeekEnd] means relative to the end (for example, offset = -2 specifies the penultimate byte of the file). Seek returns the new offset relative to the start of the file or an error, if any.
+ *
+ * Seeking to an offset before the start of the file is an error. Seeking to any positive offset may be allowed, but if the new offset exceeds the size of the underlying object the behavior of subsequent I/O operations is implementation-dependent.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Offsets are JS numbers, so they are only exact up to Number.MAX_SAFE_INTEGER
+ */
+export interface Seeker {
+    Seek(offset: number, whence: number): [number, Error | null]
+}
+
+/**
+ * isSeeker reports whether v implements [Seeker]
+ */
+export function isSeeker(v: unknown): v is Seeker {
+    return hasMethods(v, "Seek")
+}
+
+/**
+ * WriteString writes the contents of the string s to w, which accepts a slice of bytes.
+ * If w implements [StringWriter], [StringWriter.WriteString] is invoked directly.
+ * Otherwise, [Writer.Write] is called exactly once.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * s is written as UTF-8, so n counts UTF-8 bytes
+ */
+export function WriteString(w: Writer, s: string): [number, Error | null] {
+    if (isStringWriter(w)) {
+        return w.WriteString(s)
+    }
+    return w.Write(new TextEncoder().encode(s))
+}
+
+// ReadWriter is the interface that groups the basic Read and Write methods.
+export interface ReadWriter extends Reader, Writer {}
+
+// ReadCloser is the interface that groups the basic Read and Close methods.
+export interface ReadCloser extends Reader, Closer {}
+
+// WriteCloser is the interface that groups the basic Write and Close methods.
+export interface WriteCloser extends Writer, Closer {}
+
+// ReadWriteCloser is the interface that groups the basic Read, Write and Close methods.
+export interface ReadWriteCloser extends Reader, Writer, Closer {}
+
+// ReadSeeker is the interface that groups the basic Read and Seek methods.
+export interface ReadSeeker extends Reader, Seeker {}
+
+// ReadSeekCloser is the interface that groups the basic Read, Seek and Close
+// methods.
+export interface ReadSeekCloser extends Reader, Seeker, Closer {}
+
+// WriteSeeker is the interface that groups the basic Write and Seek methods.
+export interface WriteSeeker extends Writer, Seeker {}
+
+// ReadWriteSeeker is the interface that groups the basic Read, Write and Seek methods.
+export interface ReadWriteSeeker extends Reader, Writer, Seeker {}
+
+
+/**
+ * ReadAtLeast reads from r into buf until it has read at least min bytes.
+ * It returns the number of bytes copied and an error if fewer bytes were read.
+ * The error is EOF only if no bytes were read.
+ * If an EOF happens after reading fewer than min bytes,
+ * ReadAtLeast returns [ErrUnexpectedEOF].
+ * If min is greater than the length of buf, ReadAtLeast returns [ErrShortBuffer].
+ * On return, n >= min if and only if err == null.
+ * If r returns an error having read at least min bytes, the error is dropped.
+ */
+export function ReadAtLeast(r: Reader, buf: Uint8Array, min: number): [number, Error | null] {
+    if (buf.length < min) {
+        return [0, ErrShortBuffer]
+    }
+    let n = 0
+    let err: Error | null = null
+    while (n < min && err == null) {
+        let nn: number;
+        [nn, err] = r.Read(buf.subarray(n))
+        n += nn
+    }
+    if (n >= min) {
+        err = null
+    } else if (n > 0 && err == EOF) {
+        err = ErrUnexpectedEOF
+    }
+    return [n, err]
+}
+
+/**
+ * ReadFull reads exactly len(buf) bytes from r into buf.
+ * It returns the number of bytes copied and an error if fewer bytes were read.
+ * The error is EOF only if no bytes were read.
+ * If an EOF happens after reading some but not all the bytes,
+ * ReadFull returns [ErrUnexpectedEOF].
+ * On return, n == len(buf) if and only if err == null.
+ * If r returns an error having read at least len(buf) bytes, the error is dropped.
+ */
+export function ReadFull(r: Reader, buf: Uint8Array): [number, Error | null] {
+    return ReadAtLeast(r, buf, buf.length)
+}
+
+/**
+ * CopyN copies n bytes (or until an error) from src to dst.
+ * It returns the number of bytes copied and the earliest
+ * error encountered while copying.
+ * On return, written == n if and only if err == null.
+ *
+ * If dst implements [ReaderFrom], the copy is implemented using it.
+ */
+export function CopyN(dst: Writer, src: Reader, n: number): [number, Error | null] {
+    let [written, err] = Copy(dst, LimitReader(src, n))
+    if (written == n) {
+        return [n, null]
+    }
+    if (written < n && err == null) {
+        // src stopped early; must have been EOF.
+        err = EOF
+    }
+    return [written, err]
+}
+
+/**
+ * LimitReader returns a Reader that reads from r
+ * but stops with EOF after n bytes.
+ * The underlying implementation is a LimitedReader.
+ */
+export function LimitReader(r: Reader, n: number): Reader {
+    return new LimitedReader(r, n)
+}
 
 /**
  * A LimitedReader reads from R but limits the amount of
@@ -156,7 +498,6 @@ export interface WriterTo {
  * Read returns EOF when N <= 0 or when the underlying R returns EOF.
  */
 export class LimitedReader implements Reader {
-    __isLimitedReader: boolean = true // JS doesn't support typecases: https://cs.opensource.google/go/go/+/master:src/io/io.go;l=407
     private r: Reader
     n: number
 
@@ -167,7 +508,7 @@ export class LimitedReader implements Reader {
 
     Read(p: Uint8Array): [number, Error | null] {
         if (this.n <= 0) {
-            return [0, new Error(Errors.EOF)]
+            return [0, EOF]
         }
 
         if (p.length > this.n) {
@@ -180,6 +521,203 @@ export class LimitedReader implements Reader {
     }
 }
 
+/**
+ * SectionReader implements Read, Seek, and ReadAt on a section
+ * of an underlying [ReaderAt].
+ */
+export class SectionReader implements Reader, Seeker, ReaderAt {
+    private r: ReaderAt // constant after creation
+    private base: number // constant after creation
+    private off: number
+    private limit: number // constant after creation
+    private n: number // constant after creation
+
+    constructor(r: ReaderAt, base: number, off: number, limit: number, n: number) {
+        this.r = r
+        this.base = base
+        this.off = off
+        this.limit = limit
+        this.n = n
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (this.off >= this.limit) {
+            return [0, EOF]
+        }
+        let max = this.limit - this.off
+        if (p.length > max) {
+            p = p.subarray(0, max)
+        }
+        let [n, err] = this.r.ReadAt(p, this.off)
+        this.off += n
+        return [n, err]
+    }
+
+    Seek(offset: number, whence: number): [number, Error | null] {
+        switch (whence) {
+            case SeekStart:
+                offset += this.base
+                break
+            case SeekCurrent:
+                offset += this.off
+                break
+            case SeekEnd:
+                offset += this.limit
+                break
+            default:
+                return [0, errWhence]
+        }
+        if (offset < this.base) {
+            return [0, errOffset]
+        }
+        this.off = offset
+        return [offset - this.base, null]
+    }
+
+    ReadAt(p: Uint8Array, off: number): [number, Error | null] {
+        if (off < 0 || off >= this.Size()) {
+            return [0, EOF]
+        }
+        off += this.base
+        let max = this.limit - off
+        if (p.length > max) {
+            p = p.subarray(0, max)
+            let [n, err] = this.r.ReadAt(p, off)
+            if (err == null) {
+                err = EOF
+            }
+            return [n, err]
+        }
+        return this.r.ReadAt(p, off)
+    }
+
+    /**
+     * Size returns the size of the section in bytes.
+     */
+    Size(): number {
+        return this.limit - this.base
+    }
+
+    /**
+     * Outer returns the underlying [ReaderAt] and offsets for the section.
+     *
+     * The returned values are the same that were passed to [NewSectionReader]
+     * when the [SectionReader] was created.
+     */
+    Outer(): [ReaderAt, number, number] {
+        return [this.r, this.base, this.n]
+    }
+}
+
+/**
+ * NewSectionReader returns a [SectionReader] that reads from r
+ * starting at offset off and stops with EOF after n bytes.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Offsets are JS numbers, so Go's maxint64 is Number.MAX_SAFE_INTEGER
+ */
+export function NewSectionReader(r: ReaderAt, off: number, n: number): SectionReader {
+    let remaining: number
+    const maxint64 = Number.MAX_SAFE_INTEGER
+    if (off <= maxint64 - n) {
+        remaining = n + off
+    } else {
+        // Overflow, with no way to return error.
+        // Assume we can read up to an offset of Number.MAX_SAFE_INTEGER.
+        remaining = maxint64
+    }
+    return new SectionReader(r, off, off, remaining, n)
+}
+
+/**
+ * An OffsetWriter maps writes at offset base to offset base+off in the underlying writer.
+ */
+export class OffsetWriter implements Writer, WriterAt, Seeker {
+    private w: WriterAt
+    private base: number // the original offset
+    private off: number // the current offset
+
+    constructor(w: WriterAt, base: number, off: number) {
+        this.w = w
+        this.base = base
+        this.off = off
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        let [n, err] = this.w.WriteAt(p, this.off)
+        this.off += n
+        return [n, err]
+    }
+
+    WriteAt(p: Uint8Array, off: number): [number, Error | null] {
+        if (off < 0) {
+            return [0, errOffset]
+        }
+
+        off += this.base
+        return this.w.WriteAt(p, off)
+    }
+
+    Seek(offset: number, whence: number): [number, Error | null] {
+        switch (whence) {
+            case SeekStart:
+                offset += this.base
+                break
+            case SeekCurrent:
+                offset += this.off
+                break
+            default:
+                return [0, errWhence]
+        }
+        if (offset < this.base) {
+            return [0, errOffset]
+        }
+        this.off = offset
+        return [offset - this.base, null]
+    }
+}
+
+/**
+ * NewOffsetWriter returns an [OffsetWriter] that writes to w
+ * starting at offset off.
+ */
+export function NewOffsetWriter(w: WriterAt, off: number): OffsetWriter {
+    return new OffsetWriter(w, off, off)
+}
+
+/**
+ * TeeReader returns a [Reader] that writes to w what it reads from r.
+ * All reads from r performed through it are matched with
+ * corresponding writes to w. There is no internal buffering -
+ * the write must complete before the read completes.
+ * Any error encountered while writing is reported as a read error.
+ */
+export function TeeReader(r: Reader, w: Writer): Reader {
+    return new teeReader(r, w)
+}
+
+class teeReader implements Reader {
+    private r: Reader
+    private w: Writer
+
+    constructor(r: Reader, w: Writer) {
+        this.r = r
+        this.w = w
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        let [n, err] = this.r.Read(p)
+        if (n > 0) {
+            let [nw, werr] = this.w.Write(p.subarray(0, n))
+            if (werr != null) {
+                return [nw, werr]
+            }
+        }
+        return [n, err]
+    }
+}
+
 /**
  * Copy copies from src to dst until either EOF is reached
  * on src or an error occurs. It returns the number of bytes
@@ -226,12 +764,12 @@ export function CopyBuffer(dst: Writer, src: Reader, buf: Uint8Array): [number,
 function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number, Error | null] {
     // If the reader has a WriteTo method, use it to do the copy.
 	// Avoids an allocation and a copy.
-    if(is<WriterTo>(src, "WriteTo")) {
+    if(isWriterTo(src)) {
         return src.WriteTo(dst)
     }
 
     // Similarly, if the writer has a ReadFrom method, use it to do the copy.
-    if(is<ReaderFrom>(dst, "ReadFrom")) {
+    if(isReaderFrom(dst)) {
         return dst.ReadFrom(src)
     }
 
@@ -239,7 +777,7 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
         let size = 32 * 1024 
 
         // Check if LimitedReader [https://cs.opensource.google/go/go/+/master:src/io/io.go;l=419]
-        if(is<LimitedReader>(src, "__isLimitedReader")) {
+        if(src instanceof LimitedReader && size > src.n) {
             if(src.n < 1) {
                 size = 1
             } else {
@@ -247,8 +785,6 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
             }
         }
 
-        // TODO: There is a extra 'LimitedReader' here. IO port here does not have a limited reader
-        // https://cs.opensource.google/go/go/+/master:src/io/io.go;l=419
         buf = new Uint8Array(size)
     }
 
@@ -264,7 +800,7 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
             if(nw < 0 || nr < nw) {
                 nw = 0
                 if(ew == null) {
-                    ew = new Error(Errors.InvalidWrite)
+                    ew = errInvalidWrite
                 }
             }
             written += nw // written += int64(nw)
@@ -275,13 +811,13 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
             }
 
             if(nr != nw) {
-                err = new Error(Errors.ShortWrite)
+                err = ErrShortWrite
                 break
             }
         }
 
         if(er != null) {
-            if(er.message != Errors.EOF) {
+            if(er != EOF) {
                 err = er
             }
             break
@@ -291,6 +827,81 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
     return [written, err]
 }
 
+let blackHole: Uint8Array | null = null
+
+class discard implements Writer, StringWriter, ReaderFrom {
+    Write(p: Uint8Array): [number, Error | null] {
+        return [p.length, null]
+    }
+
+    WriteString(s: string): [number, Error | null] {
+        return [new TextEncoder().encode(s).length, null]
+    }
+
+    // discard implements ReaderFrom as an optimization so Copy to
+    // io.Discard can avoid doing unnecessary work.
+    ReadFrom(r: Reader): [number, Error | null] {
+        // Not present in the Go code: Go takes the buffer from a sync.Pool. JS
+        // is single threaded and the bytes are never looked at, so one
+        // buffer can be shared by every call.
+        if (blackHole == null) {
+            blackHole = new Uint8Array(8192)
+        }
+        let n = 0
+        while (true) { // for {}
+            let [readSize, err] = r.Read(blackHole)
+            n += readSize
+            if (err != null) {
+                if (err == EOF) {
+                    return [n, null]
+                }
+                return [n, err]
+            }
+        }
+    }
+}
+
+/**
+ * Discard is a [Writer] on which all Write calls succeed
+ * without doing anything.
+ */
+export const Discard: Writer = new discard()
+
+/**
+ * NopCloser returns a [ReadCloser] with a no-op Close method wrapping
+ * the provided [Reader] r.
+ * If r implements [WriterTo], the returned [ReadCloser] will implement [WriterTo]
+ * by forwarding calls to r.
+ */
+export function NopCloser(r: Reader): ReadCloser {
+    if (isWriterTo(r)) {
+        return new nopCloserWriterTo(r)
+    }
+    return new nopCloser(r)
+}
+
+class nopCloser implements ReadCloser {
+    protected r: Reader
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        return this.r.Read(p)
+    }
+
+    Close(): Error | null {
+        return null
+    }
+}
+
+class nopCloserWriterTo extends nopCloser implements WriterTo {
+    WriteTo(w: Writer): [number, Error | null] {
+        return (this.r as Reader & WriterTo).WriteTo(w)
+    }
+}
+
 /**
  * ReadAll reads from r until an error or EOF and returns the data it read.
  * A successful call returns err == null, not err == EOF. Because ReadAll is
@@ -299,29 +910,35 @@ function copyBuffer(dst: Writer, src: Reader, buf?: Uint8Array | null): [number,
  * 
  * *SEMANTIC DIFFERENCES TO GO:*
  * 
- * The internal implementation of this is slightly different to Go's due to language differences
+ * Like the slice Go returns, the result may be a view into a larger buffer: its spare
+ * capacity is the rest of the underlying ArrayBuffer
  * 
  * @param r Reader to read from
  * @returns Uint8Array of data read and error
  */
 export function ReadAll(r: Reader): [Uint8Array, Error | null] {
-    const perStream = 512 // 512 bits per stream
-
-    let b: Uint8Array[] = []
+    let b = new Uint8Array(512)
+    let len = 0
     while(true) { // for {}
-        let _buf = new Uint8Array(perStream)
-        let [n, err] = r.Read(_buf)
-
-        b.push(_buf.subarray(0, n))
-
+        let [n, err] = r.Read(b.subarray(len))
+        len += n
         if(err != null) {
-            if(err.message == Errors.EOF) {
+            if(err == EOF) {
                 err = null
             }
-            
-            let arr = mergeUint8Arrays(b)
+            return [b.subarray(0, len), err]
+        }
 
-            return [arr, err]
+        if(len == b.length) {
+            // Add more capacity (let append pick how much).
+            let grown = new Uint8Array(nextSliceCap(len + 1, b.length))
+            grown.set(b)
+            b = grown
         }
     }
-}
\ No newline at end of file
+}
+
+export * from "./async"
+export * from "./multi"
+export * from "./pipe"
+export * from "./web"
@@ -0,0 +1,752 @@
+// Tests for io
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/io_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { bytes, str } from "../builtins/tshelpers/strings"
+import * as errors from "../errors"
+import * as iotest from "../testing/iotest"
+import * as utf8 from "../unicode/utf8"
+import {
+    Copy, CopyN, Discard, EOF, ErrUnexpectedEOF, Errors, isByteReader, isByteScanner, isCloser, isReader, isReaderFrom,
+    isRuneReader, isRuneScanner, isSeeker, isStringWriter, isWriterTo,
+    LimitedReader, MultiReader, NewOffsetWriter, NewSectionReader, NopCloser, OffsetWriter, ReadAll, ReadAtLeast,
+    ReadFull, Reader, ReaderAt, ReadWriter, SeekCurrent, SeekEnd, SeekStart, StringWriter, TeeReader,
+    Writer, WriterAt, WriterTo, WriteString,
+} from "./index"
+
+// bytesReader is a Reader, ReaderAt and Seeker over b, like bytes.Reader
+class bytesReader implements Reader, ReaderAt {
+    private b: Uint8Array
+    private i = 0
+
+    constructor(b: Uint8Array) {
+        this.b = b
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (this.i >= this.b.length) {
+            return [0, Errors.EOF]
+        }
+        let n = Math.min(p.length, this.b.length - this.i)
+        p.set(this.b.subarray(this.i, this.i + n))
+        this.i += n
+        return [n, null]
+    }
+
+    ReadAt(p: Uint8Array, off: number): [number, Error | null] {
+        if (off < 0) {
+            return [0, new Error("bytes.Reader.ReadAt: negative offset")]
+        }
+        if (off >= this.b.length) {
+            return [0, Errors.EOF]
+        }
+        let n = Math.min(p.length, this.b.length - off)
+        p.set(this.b.subarray(off, off + n))
+        if (n < p.length) {
+            return [n, Errors.EOF]
+        }
+        return [n, null]
+    }
+
+    Seek(offset: number, whence: number): [number, Error | null] {
+        let abs: number
+        switch (whence) {
+            case SeekStart:
+                abs = offset
+                break
+            case SeekCurrent:
+                abs = this.i + offset
+                break
+            case SeekEnd:
+                abs = this.b.length + offset
+                break
+            default:
+                return [0, new Error("bytes.Reader.Seek: invalid whence")]
+        }
+        if (abs < 0) {
+            return [0, new Error("bytes.Reader.Seek: negative position")]
+        }
+        this.i = abs
+        return [abs, null]
+    }
+}
+
+// memFile is a growable in-memory WriterAt and ReaderAt, standing in for the
+// temporary files used by the Go tests
+class memFile implements WriterAt, ReaderAt {
+    b = new Uint8Array(0)
+
+    WriteAt(p: Uint8Array, off: number): [number, Error | null] {
+        if (off < 0) {
+            return [0, new Error("memFile.WriteAt: negative offset")]
+        }
+        if (off + p.length > this.b.length) {
+            let grown = new Uint8Array(off + p.length)
+            grown.set(this.b)
+            this.b = grown
+        }
+        this.b.set(p, off)
+        return [p.length, null]
+    }
+
+    ReadAt(p: Uint8Array, off: number): [number, Error | null] {
+        return new bytesReader(this.b).ReadAt(p, off)
+    }
+}
+
+// Buffer is a Reader and Writer that hides GoBuffer's optional interfaces,
+// like the Buffer type of the Go tests hides those of bytes.Buffer.
+class Buffer implements Reader, Writer {
+    b = new GoBuffer(new Uint8Array(0))
+
+    Read(p: Uint8Array): [number, Error | null] {
+        return this.b.Read(p)
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        return this.b.Write(p)
+    }
+
+    WriteString(s: string): [number, Error | null] {
+        return this.b.Write(bytes(s))
+    }
+
+    String(): string {
+        return str(this.b.underlyingArray)
+    }
+}
+
+test("TestCopyN", () => {
+    let rb = new Buffer()
+    let wb = new Buffer()
+    rb.WriteString("hello, world.")
+    CopyN(wb, rb, 5)
+    assert.equal(wb.String(), "hello", "CopyN did not work properly")
+})
+
+test("TestCopyNReadFrom", () => {
+    let rb = new Buffer()
+    let wb = new GoBuffer(new Uint8Array(0)) // implements ReadFrom.
+    rb.WriteString("hello")
+    CopyN(wb, rb, 5)
+    assert.equal(str(wb.underlyingArray), "hello", "CopyN did not work properly")
+})
+
+test("TestCopyNWriteTo", () => {
+    let rb = new GoBuffer(bytes("hello, world.")) // implements WriteTo.
+    let wb = new Buffer()
+    CopyN(wb, rb, 5)
+    assert.equal(wb.String(), "hello", "CopyN did not work properly")
+})
+
+// noReadFrom hides the ReadFrom method of w
+function noReadFrom(w: Writer): Writer {
+    return { Write: (p) => w.Write(p) }
+}
+
+const wantedAndErrReader: Reader = {
+    Read: (p) => [p.length, new Error("wantedAndErrReader error")],
+}
+
+test("TestCopyNEOF", () => {
+    // Test that EOF behavior is the same regardless of whether
+    // argument to CopyN has ReadFrom.
+
+    let b = new GoBuffer(new Uint8Array(0))
+
+    assert.deepEqual(CopyN(noReadFrom(b), new bytesReader(bytes("foo")), 3), [3, null], "CopyN(noReadFrom, foo, 3)")
+
+    let [n, err] = CopyN(noReadFrom(b), new bytesReader(bytes("foo")), 4)
+    assert.equal(n, 3, "CopyN(noReadFrom, foo, 4)")
+    assert.equal(err, Errors.EOF, "CopyN(noReadFrom, foo, 4)")
+
+    assert.deepEqual(CopyN(b, new bytesReader(bytes("foo")), 3), [3, null], "CopyN(bytes.Buffer, foo, 3)"); // b has read from
+
+    [n, err] = CopyN(b, new bytesReader(bytes("foo")), 4) // b has read from
+    assert.equal(n, 3, "CopyN(bytes.Buffer, foo, 4)")
+    assert.equal(err, Errors.EOF, "CopyN(bytes.Buffer, foo, 4)")
+
+    assert.deepEqual(CopyN(b, wantedAndErrReader, 5), [5, null], "CopyN(bytes.Buffer, wantedAndErrReader, 5)")
+
+    assert.deepEqual(CopyN(noReadFrom(b), wantedAndErrReader, 5), [5, null], "CopyN(noReadFrom, wantedAndErrReader, 5)")
+})
+
+// Not present in the Go code
+test("TestCopyNLargeN", () => {
+    // The copy buffer is only shrunk to fit a LimitedReader, never grown to it.
+    for (let n of [2 ** 31, 1e12]) {
+        let maxRead = 0
+        let r = new bytesReader(bytes("foo"))
+        let src: Reader = {
+            Read: (p) => {
+                maxRead = Math.max(maxRead, p.length)
+                return r.Read(p)
+            },
+        }
+        let b = new GoBuffer(new Uint8Array(0))
+        let [written, err] = CopyN(noReadFrom(b), src, n)
+        assert.equal(err, Errors.EOF, `CopyN(noReadFrom, foo, ${n})`)
+        assert.equal(written, 3, `CopyN(noReadFrom, foo, ${n})`)
+        assert.ok(maxRead <= 32 * 1024, `CopyN(noReadFrom, foo, ${n}) read into a ${maxRead} byte buffer`)
+    }
+})
+
+test("TestReadAtLeast", () => {
+    testReadAtLeast(new Buffer())
+})
+
+// dataAndErrorBuffer returns err along with the last of its data
+class dataAndErrorBuffer extends Buffer {
+    err: Error
+
+    constructor(err: Error) {
+        super()
+        this.err = err
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        let [n, err] = super.Read(p)
+        if (n > 0 && this.b.underlyingArray.length == 0 && err == null) {
+            err = this.err
+        }
+        return [n, err]
+    }
+}
+
+test("TestReadAtLeastWithDataAndEOF", () => {
+    testReadAtLeast(new dataAndErrorBuffer(Errors.EOF))
+})
+
+test("TestReadAtLeastWithDataAndError", () => {
+    testReadAtLeast(new dataAndErrorBuffer(new Error("fake error")))
+})
+
+function testReadAtLeast(rb: ReadWriter) {
+    rb.Write(bytes("0123"))
+    let buf = new Uint8Array(2)
+    assert.deepEqual(ReadAtLeast(rb, buf, 2), [2, null])
+
+    let [n, err] = ReadAtLeast(rb, buf, 4)
+    assert.equal(err, Errors.ShortBuffer, "expected ShortBuffer")
+    assert.equal(n, 0, "expected to have read 0 bytes");
+
+    [n, err] = ReadAtLeast(rb, buf, 1)
+    assert.equal(err, null)
+    assert.equal(n, 2, "expected to have read 2 bytes");
+
+    [n, err] = ReadAtLeast(rb, buf, 2)
+    assert.equal(err, Errors.EOF, "expected EOF")
+    assert.equal(n, 0, "expected to have read 0 bytes")
+
+    rb.Write(bytes("4"));
+    [n, err] = ReadAtLeast(rb, buf, 2)
+    let want = Errors.UnexpectedEOF
+    if (rb instanceof dataAndErrorBuffer && rb.err != Errors.EOF) {
+        want = rb.err
+    }
+    assert.equal(err, want)
+    assert.equal(n, 1, "expected to have read 1 bytes")
+}
+
+// Not present in the Go code
+test("TestReadFull", () => {
+    let buf = new Uint8Array(4)
+    assert.deepEqual(ReadFull(new bytesReader(bytes("0123456")), buf), [4, null])
+    assert.equal(str(buf), "0123")
+
+    let [n, err] = ReadFull(new bytesReader(bytes("01")), buf)
+    assert.equal(n, 2)
+    assert.equal(err, Errors.UnexpectedEOF);
+
+    [n, err] = ReadFull(new bytesReader(new Uint8Array(0)), buf)
+    assert.equal(n, 0)
+    assert.equal(err, Errors.EOF)
+
+    // An error returned along with the last byte needed is dropped
+    assert.deepEqual(ReadFull(wantedAndErrReader, buf), [4, null])
+})
+
+// Not present in the Go code
+test("TestReadAll", () => {
+    // Reads of uneven sizes, across many growths of the buffer
+    let data = new Uint8Array(1 << 20).map((_, i) => i * 7)
+    let chunks = 0
+    let r: Reader = {
+        Read(p: Uint8Array): [number, Error | null] {
+            if (data.length == 0) {
+                return [0, Errors.EOF]
+            }
+            let n = Math.min(p.length, 1 + (chunks++ % 1000), data.length)
+            p.set(data.subarray(0, n))
+            data = data.subarray(n)
+            return [n, null]
+        },
+    }
+    let want = new Uint8Array(1 << 20).map((_, i) => i * 7)
+    let [got, err] = ReadAll(r)
+    assert.equal(err, null)
+    assert.deepEqual(got, want)
+    // The spare capacity is bounded, as with Go's append
+    assert.ok(got.buffer.byteLength < want.length * 1.5, `buffer of ${got.buffer.byteLength} bytes for ${want.length}`)
+
+    assert.deepEqual(ReadAll(new bytesReader(new Uint8Array(0))), [new Uint8Array(0), null])
+
+    // The data read before an error is returned with it
+    let readErr = errors.New("read failed")
+    let [b, err2] = ReadAll(MultiReader(new bytesReader(bytes("hello")), { Read: () => [0, readErr] }))
+    assert.equal(str(b), "hello")
+    assert.equal(err2, readErr)
+})
+
+// Not present in the Go code
+test("TestWriteString", () => {
+    // Write is called exactly once with the UTF-8 bytes
+    let writes: string[] = []
+    let w: Writer = {
+        Write: (p) => {
+            writes.push(str(p))
+            return [p.length, null]
+        },
+    }
+    assert.deepEqual(WriteString(w, "héllo"), [6, null])
+    assert.deepEqual(writes, ["héllo"])
+
+    // WriteString is used when present
+    let called = false
+    let sw: Writer & StringWriter = {
+        Write: () => assert.fail("Write called on a StringWriter"),
+        WriteString: (s) => {
+            called = true
+            return [s.length, null]
+        },
+    }
+    assert.deepEqual(WriteString(sw, "hello"), [5, null])
+    assert.ok(called, "WriteString was not called")
+})
+
+// Not present in the Go code
+test("TestDiscard", () => {
+    assert.ok(isReaderFrom(Discard), "Discard does not implement ReaderFrom")
+    assert.deepEqual(Discard.Write(new Uint8Array(10)), [10, null])
+    assert.deepEqual(WriteString(Discard, "世界"), [6, null])
+
+    let big = new Uint8Array(100000)
+    assert.deepEqual(Copy(Discard, new bytesReader(big)), [big.length, null])
+    let [n, err] = Copy(Discard, MultiReader(new bytesReader(big), wantedAndErrReader))
+    assert.ok(n > big.length)
+    assert.equal(err?.message, "wantedAndErrReader error")
+})
+
+test("TestTeeReader", () => {
+    let src = bytes("hello, world")
+    let dst = new Uint8Array(src.length)
+    let rb: Reader = new GoBuffer(src.slice())
+    let wb = new GoBuffer(new Uint8Array(0))
+    let r = TeeReader(rb, wb)
+    assert.deepEqual(ReadFull(r, dst), [src.length, null], "ReadFull(r, dst)")
+    assert.deepEqual(dst, src, "bytes read")
+    assert.deepEqual(wb.underlyingArray, src, "bytes written")
+    let [n, err] = r.Read(dst)
+    assert.equal(n, 0, "r.Read at EOF")
+    assert.equal(err, Errors.EOF, "r.Read at EOF")
+
+    // Not present in the Go code: Go writes to a closed io.Pipe, whose Write
+    // can't be used synchronously here
+    rb = new GoBuffer(src.slice())
+    let closed: Writer = { Write: () => [0, Errors.ClosedPipe] }
+    r = TeeReader(rb, closed);
+    [n, err] = ReadFull(r, dst)
+    assert.equal(n, 0, "closed tee")
+    assert.equal(err, Errors.ClosedPipe, "closed tee")
+})
+
+test("TestSectionReader_ReadAt", () => {
+    const dat = "a long sample data, 1234567890"
+    let tests = [
+        { data: "", off: 0, n: 10, bufLen: 2, at: 0, exp: "", err: Errors.EOF },
+        { data: dat, off: 0, n: dat.length, bufLen: 0, at: 0, exp: "", err: null },
+        { data: dat, off: dat.length, n: 1, bufLen: 1, at: 0, exp: "", err: Errors.EOF },
+        { data: dat, off: 0, n: dat.length + 2, bufLen: dat.length, at: 0, exp: dat, err: null },
+        { data: dat, off: 0, n: dat.length, bufLen: dat.length / 2, at: 0, exp: dat.slice(0, dat.length / 2), err: null },
+        { data: dat, off: 0, n: dat.length, bufLen: dat.length, at: 0, exp: dat, err: null },
+        { data: dat, off: 0, n: dat.length, bufLen: dat.length / 2, at: 2, exp: dat.slice(2, 2 + dat.length / 2), err: null },
+        { data: dat, off: 3, n: dat.length, bufLen: dat.length / 2, at: 2, exp: dat.slice(5, 5 + dat.length / 2), err: null },
+        { data: dat, off: 3, n: dat.length / 2, bufLen: dat.length / 2 - 2, at: 2, exp: dat.slice(5, 5 + dat.length / 2 - 2), err: null },
+        { data: dat, off: 3, n: dat.length / 2, bufLen: dat.length / 2 + 2, at: 2, exp: dat.slice(5, 5 + dat.length / 2 - 2), err: Errors.EOF },
+        { data: dat, off: 0, n: 0, bufLen: 0, at: -1, exp: "", err: Errors.EOF },
+        { data: dat, off: 0, n: 0, bufLen: 0, at: 1, exp: "", err: Errors.EOF },
+    ]
+    tests.forEach((tt, i) => {
+        let r = new bytesReader(bytes(tt.data))
+        let s = NewSectionReader(r, tt.off, tt.n)
+        let buf = new Uint8Array(tt.bufLen)
+        let [n, err] = s.ReadAt(buf, tt.at)
+        assert.equal(n, tt.exp.length, `${i}: ReadAt(${tt.at})`)
+        assert.equal(str(buf.subarray(0, n)), tt.exp, `${i}: ReadAt(${tt.at})`)
+        assert.equal(err, tt.err, `${i}: ReadAt(${tt.at})`)
+        let [_r, off, size] = s.Outer()
+        assert.equal(_r, r, `${i}: Outer()`)
+        assert.equal(off, tt.off, `${i}: Outer()`)
+        assert.equal(size, tt.n, `${i}: Outer()`)
+    })
+})
+
+test("TestSectionReader_Seek", () => {
+    // Verifies that NewSectionReader's Seeker behaves like bytes.NewReader (which is like strings.NewReader)
+    let br = new bytesReader(bytes("foo"))
+    let sr = NewSectionReader(br, 0, "foo".length)
+
+    for (let whence of [SeekStart, SeekCurrent, SeekEnd]) {
+        for (let offset = -3; offset <= 4; offset++) {
+            let [brOff, brErr] = br.Seek(offset, whence)
+            let [srOff, srErr] = sr.Seek(offset, whence)
+            assert.equal(srErr != null, brErr != null, `For whence ${whence}, offset ${offset}`)
+            assert.equal(srOff, brOff, `For whence ${whence}, offset ${offset}`)
+        }
+    }
+
+    // And verify we can just seek past the end and get an EOF
+    assert.deepEqual(sr.Seek(100, SeekStart), [100, null], "Seek")
+
+    let [n, err] = sr.Read(new Uint8Array(10))
+    assert.equal(n, 0, "Read")
+    assert.equal(err, Errors.EOF, "Read")
+})
+
+test("TestSectionReader_Size", () => {
+    let tests = [
+        { data: "a long sample data, 1234567890", want: 30 },
+        { data: "", want: 0 },
+    ]
+
+    for (let tt of tests) {
+        let r = new bytesReader(bytes(tt.data))
+        let sr = NewSectionReader(r, 0, tt.data.length)
+        assert.equal(sr.Size(), tt.want, "Size")
+    }
+})
+
+test("TestSectionReader_Max", () => {
+    let r = new bytesReader(bytes("abcdef"))
+    const maxint64 = Number.MAX_SAFE_INTEGER
+    let sr = NewSectionReader(r, 3, maxint64)
+    assert.deepEqual(sr.Read(new Uint8Array(3)), [3, null], "Read")
+    let [n, err] = sr.Read(new Uint8Array(3))
+    assert.equal(n, 0, "Read")
+    assert.equal(err, Errors.EOF, "Read")
+    let [_r, off, size] = sr.Outer()
+    assert.equal(_r, r, "Outer")
+    assert.equal(off, 3, "Outer")
+    assert.equal(size, maxint64, "Outer")
+})
+
+test("TestOffsetWriter_Seek", async (t) => {
+    let w = NewOffsetWriter(new memFile(), 0)
+
+    // Should throw error errWhence if whence is not valid
+    await t.test("errWhence", () => {
+        for (let whence of [-3, -2, -1, 3, 4, 5]) {
+            let offset = 0
+            let [gotOff, gotErr] = w.Seek(offset, whence)
+            assert.equal(gotOff, 0, `For whence ${whence}, offset ${offset}`)
+            assert.equal(gotErr, Errors.Whence, `For whence ${whence}, offset ${offset}`)
+        }
+    })
+
+    // Should throw error errOffset if offset is negative
+    await t.test("errOffset", () => {
+        for (let whence of [SeekStart, SeekCurrent]) {
+            for (let offset = -3; offset < 0; offset++) {
+                let [gotOff, gotErr] = w.Seek(offset, whence)
+                assert.equal(gotOff, 0, `For whence ${whence}, offset ${offset}`)
+                assert.equal(gotErr, Errors.Offset, `For whence ${whence}, offset ${offset}`)
+            }
+        }
+    })
+
+    // Normal tests
+    await t.test("normal", () => {
+        let tests = [
+            // keep in order
+            { whence: SeekStart, offset: 1, returnOff: 1 },
+            { whence: SeekStart, offset: 2, returnOff: 2 },
+            { whence: SeekStart, offset: 3, returnOff: 3 },
+            { whence: SeekCurrent, offset: 1, returnOff: 4 },
+            { whence: SeekCurrent, offset: 2, returnOff: 6 },
+            { whence: SeekCurrent, offset: 3, returnOff: 9 },
+        ]
+        tests.forEach((tt, idx) => {
+            assert.deepEqual(w.Seek(tt.offset, tt.whence), [tt.returnOff, null],
+                `${idx + 1}:: For whence ${tt.whence}, offset ${tt.offset}`)
+        })
+    })
+})
+
+test("TestOffsetWriter_WriteAt", () => {
+    const content = "0123456789ABCDEF"
+    let contentSize = content.length
+
+    let work = (off: number, at: number) => {
+        let position = `off_${off}_at_${at}`
+        let f = new memFile()
+
+        let writeN = 0
+        // Writes one byte at a time, out of order
+        let steps = Array.from(bytes(content).entries()).reverse()
+        for (let [step, value] of steps) {
+            let w = NewOffsetWriter(f, off)
+            let [n, e] = w.WriteAt(new Uint8Array([value]), at + step)
+            assert.equal(e, null, `WriteAt failed. off: ${off}, at: ${at}, step: ${step}`)
+            writeN += n
+        }
+
+        // Read one more byte to reach EOF
+        let buf = new Uint8Array(contentSize + 1)
+        let [readN, err] = f.ReadAt(buf, off + at)
+        assert.equal(err, Errors.EOF, "ReadAt failed")
+        let readContent = str(buf.subarray(0, contentSize))
+        assert.equal(readN, writeN, position)
+        assert.equal(writeN, contentSize, position)
+        assert.equal(readContent, content, position)
+    }
+    for (let off = 0; off < 2; off++) {
+        for (let at = 0; at < 2; at++) {
+            work(off, at)
+        }
+    }
+})
+
+test("TestWriteAt_PositionPriorToBase", () => {
+    // start writing position in OffsetWriter
+    let offset = 10
+    // position we want to write to the file
+    let at = -1
+    let w = NewOffsetWriter(new memFile(), offset)
+    let [, e] = w.WriteAt(bytes("hello"), at)
+    assert.notEqual(e, null, "error expected to be not null")
+})
+
+test("TestOffsetWriter_Write", async (t) => {
+    const content = "0123456789ABCDEF"
+    let contentSize = content.length
+
+    let makeOffsetWriter = (): [OffsetWriter, memFile] => {
+        let f = new memFile()
+        return [NewOffsetWriter(f, 0), f]
+    }
+    let checkContent = (name: string, f: memFile) => {
+        // Read one more byte to reach EOF
+        let buf = new Uint8Array(contentSize + 1)
+        let [readN, err] = f.ReadAt(buf, 0)
+        assert.equal(err, Errors.EOF, `${name}: ReadAt failed`)
+        assert.equal(readN, contentSize, name)
+        assert.equal(str(buf.subarray(0, contentSize)), content, name)
+    }
+
+    await t.test("Write", () => {
+        // Write directly (off: 0, at: 0)
+        // Write content to file
+        let [w, f] = makeOffsetWriter()
+        for (let value of bytes(content)) {
+            let [n, err] = w.Write(new Uint8Array([value]))
+            assert.equal(err, null, `Write failed, n: ${n}`)
+        }
+        checkContent("Write", f)
+
+        // Copy -> Write
+        // Copy file f to file f2
+        let [w2, f2] = makeOffsetWriter()
+        Copy(w2, NewSectionReader(f, 0, f.b.length))
+        checkContent("Copy", f2)
+    })
+
+    // Copy -> WriteTo -> Write
+    // Note: multiReader implements the io.WriterTo interface, like strings.Reader.
+    await t.test("Write_Of_Copy_WriteTo", () => {
+        let [w, f] = makeOffsetWriter()
+        Copy(w, MultiReader(new bytesReader(bytes(content))))
+        checkContent("Write_Of_Copy_WriteTo", f)
+    })
+})
+
+test("TestNopCloserWriterToForwarding", () => {
+    let tests: { Name: string, r: Reader }[] = [
+        { Name: "not a WriterTo", r: { Read: () => [0, Errors.EOF] } },
+        {
+            Name: "a WriterTo", r: {
+                Read: () => [0, Errors.EOF],
+                WriteTo: () => [0, null],
+            } as Reader & WriterTo,
+        },
+    ]
+    for (let tc of tests) {
+        let nc = NopCloser(tc.r)
+
+        let expected = isWriterTo(tc.r)
+        let got = isWriterTo(nc)
+        assert.equal(got, expected, `NopCloser incorrectly forwards WriterTo for ${tc.Name}`)
+        assert.equal(nc.Close(), null, tc.Name)
+    }
+})
+
+// Not present in the Go code: the guards stand in for Go's type assertions
+test("TestInterfaceGuards", () => {
+    // A third-party class, with methods on its prototype chain
+    class base {
+        Read(p: Uint8Array): [number, Error | null] {
+            return [0, Errors.EOF]
+        }
+    }
+    class file extends base {
+        Seek(offset: number, whence: number): [number, Error | null] {
+            return [0, null]
+        }
+        Close(): Error | null {
+            return null
+        }
+    }
+    let f = new file()
+    assert.ok(isReader(f), "inherited Read")
+    assert.ok(isSeeker(f), "Seek")
+    assert.ok(isCloser(f), "Close")
+    assert.ok(!isWriterTo(f), "no WriteTo")
+
+    assert.ok(isSeeker(NewSectionReader(new bytesReader(bytes("foo")), 0, 3)), "SectionReader")
+    assert.ok(isSeeker(NewOffsetWriter(new memFile(), 0)), "OffsetWriter")
+    assert.ok(!isSeeker(new LimitedReader(f, 1)), "LimitedReader")
+
+    let b = new GoBuffer(new Uint8Array(0))
+    assert.ok(isByteScanner(b) && isRuneScanner(b) && isStringWriter(b), "GoBuffer")
+    assert.ok(isRuneReader({ ReadRune: () => [0, 0, EOF] }), "RuneReader")
+    assert.ok(!isRuneScanner({ ReadRune: () => [0, 0, EOF] }), "RuneReader without UnreadRune")
+
+    // Only methods count, like a Go method set
+    assert.ok(!isReader({ Read: true }), "non-function Read")
+    assert.ok(!isByteReader({ ReadByte: undefined }), "undefined ReadByte")
+    for (let v of [null, undefined, 0, "Read", true]) {
+        assert.ok(!isReader(v), `isReader(${v})`)
+    }
+    // Functions are objects, and may carry methods too
+    assert.ok(isReader(Object.assign(() => {}, { Read: f.Read })), "function with a Read method")
+})
+
+// Taken from TestRuneIO of https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/bytes/buffer_test.go
+test("TestBufferRuneIO", () => {
+    const NRune = 1000
+    // Built a test slice while we write the data
+    let b = new Uint8Array(utf8.UTFMax * NRune)
+    let buf = new GoBuffer(new Uint8Array(0))
+    let n = 0
+    for (let r = 0; r < NRune; r++) {
+        let size = utf8.EncodeRune(b.subarray(n), r)
+        let [nbytes, err] = buf.WriteRune(r)
+        assert.equal(err, null, `WriteRune(${r.toString(16)}) error: ${err}`)
+        assert.equal(nbytes, size, `WriteRune(${r.toString(16)}) expected ${size}, got ${nbytes}`)
+        n += size
+    }
+    b = b.subarray(0, n)
+
+    // Check the resulting bytes
+    assert.deepEqual(buf.underlyingArray, b, "incorrect result from WriteRune")
+
+    let p = new Uint8Array(utf8.UTFMax)
+    // Read it back with ReadRune
+    for (let r = 0; r < NRune; r++) {
+        let size = utf8.EncodeRune(p, r)
+        let [nr, nbytes, err] = buf.ReadRune()
+        assert.ok(nr == r && nbytes == size && err == null, `ReadRune(${r.toString(16)}) got ${nr.toString(16)},${nbytes} not ${r.toString(16)},${size} (err=${err})`)
+    }
+
+    // Check that UnreadRune works
+    buf = new GoBuffer(b.slice())
+    for (let r = 0; r < NRune; r++) {
+        let [r1, size, err] = buf.ReadRune()
+        assert.equal(buf.UnreadRune(), null, `UnreadRune(${r.toString(16)}) error`);
+        let r2: number, nbytes: number;
+        [r2, nbytes, err] = buf.ReadRune()
+        assert.ok(r1 == r2 && r1 == r && nbytes == size && err == null, `ReadRune(${r.toString(16)}) after UnreadRune got ${r2.toString(16)},${nbytes} not ${r1.toString(16)},${size} (err=${err})`)
+    }
+
+    // Not present in the Go code: invalid UTF-8 is read as U+FFFD, one byte at a time
+    buf = new GoBuffer(new Uint8Array([0xe2, 0x82, 0x41, 0xf0, 0x9f, 0x98, 0x80]))
+    let want: [number, number][] = [[utf8.RuneError, 1], [utf8.RuneError, 1], [0x41, 1], [0x1F600, 4]]
+    for (let [r, size] of want) {
+        assert.deepEqual(buf.ReadRune(), [r, size, null])
+    }
+    assert.deepEqual(buf.ReadRune(), [0, 0, EOF])
+})
+
+// Taken from TestUnreadByte of https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/bytes/buffer_test.go
+test("TestBufferUnreadByte", () => {
+    let b = new GoBuffer(new Uint8Array(0))
+
+    // check at EOF
+    assert.notEqual(b.UnreadByte(), null, "UnreadByte at EOF: got no error")
+    let [, err] = b.ReadByte()
+    assert.equal(err, EOF, `ReadByte at EOF: got no error`)
+    assert.notEqual(b.UnreadByte(), null, "UnreadByte after ReadByte at EOF: got no error")
+
+    // check not at EOF
+    b.WriteString("abcdefghijklmnopqrstuvwxyz")
+
+    // after unsuccessful read
+    let [n] = b.Read(new Uint8Array(0))
+    assert.equal(n, 0, `ReadByte after unsuccessful read: got ${n} bytes`)
+    assert.notEqual(b.UnreadByte(), null, "UnreadByte after unsuccessful read: got no error")
+
+    // after successful read
+    assert.equal(ReadAll(new LimitedReader(b, 3))[1], null, "ReadAll: unexpected error")
+    assert.equal(b.UnreadByte(), null, "UnreadByte: unexpected error")
+    let c: number;
+    [c, err] = b.ReadByte()
+    assert.equal(err, null, `ReadByte: unexpected error`)
+    assert.equal(String.fromCharCode(c), "c", `ReadByte = ${String.fromCharCode(c)}; want c`)
+
+    // Not present in the Go code: writes invalidate UnreadByte and UnreadRune
+    b.ReadRune()
+    b.WriteByte(0x21)
+    assert.notEqual(b.UnreadByte(), null, "UnreadByte after WriteByte: got no error")
+    assert.notEqual(b.UnreadRune(), null, "UnreadRune after WriteByte: got no error")
+    b.ReadByte()
+    assert.notEqual(b.UnreadRune(), null, "UnreadRune after ReadByte: got no error")
+})
+
+// Not present in the Go code
+test("TestReaderContract", () => {
+    let content = bytes("Now is the time for all good gophers.")
+    let readers: [string, () => Reader, Uint8Array][] = [
+        ["GoBuffer", () => new GoBuffer(content.slice()), content],
+        ["empty GoBuffer", () => new GoBuffer(new Uint8Array(0)), new Uint8Array(0)],
+        ["LimitedReader", () => new LimitedReader(new GoBuffer(content.slice()), 10), content.subarray(0, 10)],
+        ["LimitedReader past the end", () => new LimitedReader(new GoBuffer(content.slice()), 100), content],
+        ["LimitedReader of OneByteReader", () => new LimitedReader(iotest.OneByteReader(new GoBuffer(content.slice())), 20), content.subarray(0, 20)],
+        ["LimitedReader of HalfReader", () => new LimitedReader(iotest.HalfReader(new GoBuffer(content.slice())), 20), content.subarray(0, 20)],
+        ["SectionReader", () => NewSectionReader(new GoBuffer(content), 4, 10), content.subarray(4, 14)],
+        ["MultiReader", () => MultiReader(new GoBuffer(content.slice(0, 7)), new GoBuffer(content.slice(7))), content],
+        ["TeeReader", () => TeeReader(new GoBuffer(content.slice()), Discard), content],
+    ]
+    for (let [name, r, want] of readers) {
+        let err = iotest.TestReader(r(), want)
+        assert.equal(err, null, `${name}: ${err?.message}`)
+    }
+
+    // After a write, ReadAt sees the new contents
+    let b = new GoBuffer(bytes("hello"))
+    b.ReadByte()
+    b.Write(bytes(", world"))
+    assert.equal(iotest.TestReader(b, bytes("ello, world")), null)
+})
+
+// Not present in the Go code
+test("TestSentinelErrors", () => {
+    // The io errors are singletons, and errors.Is finds them through wrapping
+    assert.equal(Errors.EOF, EOF)
+    assert.equal(Errors.UnexpectedEOF, ErrUnexpectedEOF)
+    assert.ok(errors.Is(errors.Errorf("reading header: %w", ErrUnexpectedEOF), ErrUnexpectedEOF))
+    assert.ok(!errors.Is(errors.New(EOF.message), EOF), "an error with the same message is not EOF")
+
+    let [n, err] = ReadFull(new bytesReader(bytes("ab")), new Uint8Array(4))
+    assert.equal(n, 2)
+    assert.equal(err, ErrUnexpectedEOF)
+})
@@ -0,0 +1,143 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/multi.go
+import { CopyBuffer, EOF, ErrShortWrite, isStringWriter, Reader, StringWriter, Writer, WriterTo } from "./index"
+
+class eofReader implements Reader {
+    Read(_p: Uint8Array): [number, Error | null] {
+        return [0, EOF]
+    }
+}
+
+class multiReader implements Reader, WriterTo {
+    readers: Reader[]
+
+    constructor(readers: Reader[]) {
+        this.readers = readers
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        while (this.readers.length > 0) {
+            // Optimization to flatten nested multiReaders (Issue 13558).
+            if (this.readers.length == 1) {
+                let r = this.readers[0]
+                if (r instanceof multiReader) {
+                    this.readers = r.readers
+                    continue
+                }
+            }
+            let [n, err] = this.readers[0].Read(p)
+            let eof = err == EOF
+            if (eof) {
+                // Use eofReader instead of null to avoid null errors
+                // after performing flatten (Issue 18232).
+                this.readers[0] = new eofReader() // permit earlier GC
+                this.readers = this.readers.slice(1)
+            }
+            if (n > 0 || !eof) {
+                if (eof && this.readers.length > 0) {
+                    // Don't return EOF yet. More readers remain.
+                    err = null
+                }
+                return [n, err]
+            }
+        }
+        return [0, EOF]
+    }
+
+    WriteTo(w: Writer): [number, Error | null] {
+        return this.writeToWithBuffer(w, new Uint8Array(1024 * 32))
+    }
+
+    writeToWithBuffer(w: Writer, buf: Uint8Array): [number, Error | null] {
+        let sum = 0
+        for (let i = 0; i < this.readers.length; i++) {
+            let r = this.readers[i]
+            let n: number, err: Error | null
+            if (r instanceof multiReader) { // reuse buffer with nested multiReaders
+                [n, err] = r.writeToWithBuffer(w, buf)
+            } else {
+                [n, err] = CopyBuffer(w, r, buf)
+            }
+            sum += n
+            if (err) {
+                this.readers = this.readers.slice(i) // permit resume / retry after error
+                return [sum, err]
+            }
+        }
+        this.readers = []
+        return [sum, null]
+    }
+}
+
+/**
+ * MultiReader returns a Reader that's the logical concatenation of
+ * the provided input readers. They're read sequentially. Once all
+ * inputs have returned EOF, Read will return EOF.  If any of the readers
+ * return a non-null, non-EOF error, Read will return that error.
+ *
+ * The returned Reader implements WriterTo, so Copy from it does not go
+ * through an extra buffer.
+ */
+export function MultiReader(...readers: Reader[]): Reader & WriterTo {
+    return new multiReader(readers.slice())
+}
+
+class multiWriter implements Writer, StringWriter {
+    writers: Writer[]
+
+    constructor(writers: Writer[]) {
+        this.writers = writers
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        for (let w of this.writers) {
+            let [n, err] = w.Write(p)
+            if (err) {
+                return [n, err]
+            }
+            if (n != p.length) {
+                return [n, ErrShortWrite]
+            }
+        }
+        return [p.length, null]
+    }
+
+    WriteString(s: string): [number, Error | null] {
+        // Not present in the Go code: the UTF-8 bytes are needed up front for their length
+        let p = new TextEncoder().encode(s)
+        for (let w of this.writers) {
+            let n: number, err: Error | null
+            if (isStringWriter(w)) {
+                [n, err] = w.WriteString(s)
+            } else {
+                [n, err] = w.Write(p)
+            }
+            if (err) {
+                return [n, err]
+            }
+            if (n != p.length) {
+                return [n, ErrShortWrite]
+            }
+        }
+        return [p.length, null]
+    }
+}
+
+/**
+ * MultiWriter creates a writer that duplicates its writes to all the
+ * provided writers, similar to the Unix tee(1) command.
+ *
+ * Each write is written to each listed writer, one at a time.
+ * If a listed writer returns an error, that overall write operation
+ * stops and returns the error; it does not continue down the list.
+ */
+export function MultiWriter(...writers: Writer[]): Writer & StringWriter {
+    let allWriters: Writer[] = []
+    for (let w of writers) {
+        if (w instanceof multiWriter) {
+            allWriters.push(...w.writers)
+        } else {
+            allWriters.push(w)
+        }
+    }
+    return new multiWriter(allWriters)
+}
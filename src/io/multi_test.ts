@@ -0,0 +1,252 @@
+// Tests for io.MultiReader and io.MultiWriter
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/multi_test.go
+import * as assert from "node:assert/strict"
+import { createHash } from "node:crypto"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { str } from "../builtins/tshelpers/strings"
+import { Copy, Errors, LimitedReader, MultiReader, MultiWriter, ReadAll, Reader, StringWriter, Writer } from "./index"
+
+// stringReader returns a Reader over the UTF-8 bytes of s, like strings.NewReader
+function stringReader(s: string): GoBuffer {
+    return new GoBuffer(new TextEncoder().encode(s))
+}
+
+// readerFunc is a Reader implemented by the underlying func.
+function readerFunc(f: (p: Uint8Array) => [number, Error | null]): Reader {
+    return { Read: f }
+}
+
+// writerFunc is a Writer implemented by the underlying func.
+function writerFunc(f: (p: Uint8Array) => [number, Error | null]): Writer {
+    return { Write: f }
+}
+
+test("TestMultiReader", () => {
+    let mr: Reader
+    let buf: Uint8Array
+    let nread = 0
+    let withFooBar = (tests: () => void) => {
+        let r1 = stringReader("foo ")
+        let r2 = stringReader("")
+        let r3 = stringReader("bar")
+        mr = MultiReader(r1, r2, r3)
+        buf = new Uint8Array(20)
+        tests()
+    }
+    let expectRead = (size: number, expected: string, eerr: Error | null) => {
+        nread++
+        let [n, gerr] = mr.Read(buf.subarray(0, size))
+        assert.equal(n, expected.length, `#${nread}, expected ${expected.length} bytes`)
+        assert.equal(str(buf.subarray(0, n)), expected, `#${nread}`)
+        assert.equal(gerr, eerr, `#${nread}, expected error ${eerr}`)
+        buf = buf.subarray(n)
+    }
+    withFooBar(() => {
+        expectRead(2, "fo", null)
+        expectRead(5, "o ", null)
+        expectRead(5, "bar", null)
+        expectRead(5, "", Errors.EOF)
+    })
+    withFooBar(() => {
+        expectRead(4, "foo ", null)
+        expectRead(1, "b", null)
+        expectRead(3, "ar", null)
+        expectRead(1, "", Errors.EOF)
+    })
+    withFooBar(() => {
+        expectRead(5, "foo ", null)
+    })
+})
+
+test("TestMultiReaderAsWriterTo", () => {
+    let mr = MultiReader(
+        stringReader("foo "),
+        MultiReader( // Tickle the buffer reusing codepath
+            stringReader(""),
+            stringReader("bar"),
+        ),
+    )
+    let sink = new GoBuffer(new Uint8Array(0))
+    let [n, err] = mr.WriteTo(sink)
+    assert.equal(err, null)
+    assert.equal(n, 7)
+    assert.equal(str(sink.underlyingArray), "foo bar")
+})
+
+// Not present in the Go code: Copy dispatches to the WriterTo fast path
+test("TestMultiReaderCopy", () => {
+    let reads = 0
+    let counted = readerFunc((p) => {
+        reads++
+        return [0, Errors.EOF]
+    })
+    let sink = new GoBuffer(new Uint8Array(0))
+    let [n, err] = Copy(sink, MultiReader(stringReader("foo "), counted, stringReader("bar")))
+    assert.equal(err, null)
+    assert.equal(n, 7)
+    assert.equal(reads, 1)
+    assert.equal(str(sink.underlyingArray), "foo bar")
+})
+
+function testMultiWriter(sink: Writer & { String(): string }) {
+    let sha1 = createHash("sha1")
+    let hw = writerFunc((p) => {
+        sha1.update(p)
+        return [p.length, null]
+    })
+    let mw = MultiWriter(hw, sink)
+
+    let sourceString = "My input text."
+    let source = stringReader(sourceString)
+    let [written, err] = Copy(mw, source)
+
+    assert.equal(written, sourceString.length, "short write")
+    assert.equal(err, null, "unexpected error")
+    assert.equal(sha1.digest("hex"), "01cb303fa8c30a64123067c5aa6284ba7ec2d31b", "incorrect sha1 value")
+    assert.equal(sink.String(), sourceString)
+}
+
+test("TestMultiWriter", () => {
+    let sink = new GoBuffer(new Uint8Array(0))
+    testMultiWriter({
+        Write: (p) => sink.Write(p),
+        String: () => str(sink.underlyingArray),
+    })
+})
+
+test("TestMultiWriter_String", () => {
+    let sink = new GoBuffer(new Uint8Array(0))
+    testMultiWriter({
+        Write: (p) => sink.Write(p),
+        WriteString: (s: string) => sink.Write(new TextEncoder().encode(s)),
+        String: () => str(sink.underlyingArray),
+    } as Writer & StringWriter & { String(): string })
+})
+
+test("TestMultiWriter_StringCheckCall", () => {
+    let called = false
+    let c: Writer & StringWriter = {
+        WriteString(s: string): [number, Error | null] {
+            called = true
+            return [new TextEncoder().encode(s).length, null]
+        },
+        Write(p: Uint8Array): [number, Error | null] {
+            return [p.length, null]
+        },
+    }
+    let mw = MultiWriter(c)
+    mw.WriteString("foo")
+    assert.ok(called, "did not see WriteString call to writeStringChecker")
+})
+
+// Not present in the Go code: n counts UTF-8 bytes
+test("TestMultiWriter_WriteStringUTF8", () => {
+    let sink = new GoBuffer(new Uint8Array(0))
+    let mw = MultiWriter(sink)
+    assert.deepEqual(mw.WriteString("héllo, 世界"), [14, null])
+    assert.equal(str(sink.underlyingArray), "héllo, 世界")
+})
+
+// Test that MultiWriter properly flattens chained multiWriters.
+//
+// Not present in the Go code: Go checks the call depth, here the chain is made
+// long enough that writing through it unflattened would overflow the stack.
+test("TestMultiWriterSingleChainFlatten", () => {
+    let writes = 0
+    let w = MultiWriter(writerFunc((p) => {
+        writes++
+        return [0, null]
+    }))
+
+    let mw: Writer = w
+    // chain a bunch of multiWriters
+    for (let i = 0; i < 100000; i++) {
+        mw = MultiWriter(mw)
+    }
+
+    mw = MultiWriter(w, mw, w, mw)
+    mw.Write(new Uint8Array(0)) // don't care about errors
+    assert.equal(writes, 4)
+})
+
+test("TestMultiWriterError", () => {
+    let f1 = writerFunc((p) => [p.length / 2, Errors.ShortWrite])
+    let f2 = writerFunc((p) => {
+        assert.fail("MultiWriter called f2.Write")
+    })
+    let w = MultiWriter(f1, f2)
+    let [n, err] = w.Write(new Uint8Array(100))
+    assert.equal(n, 50)
+    assert.equal(err, Errors.ShortWrite)
+})
+
+// Test that MultiReader copies the input slice and is insulated from future modification.
+test("TestMultiReaderCopySlice", () => {
+    let slice: Reader[] = [stringReader("hello world")]
+    let r = MultiReader(...slice)
+    slice[0] = readerFunc(() => assert.fail("read the modified slice"))
+    let [data, err] = ReadAll(r)
+    assert.equal(err, null)
+    assert.equal(str(data), "hello world")
+})
+
+// Test that MultiWriter copies the input slice and is insulated from future modification.
+test("TestMultiWriterCopy", () => {
+    let buf = new GoBuffer(new Uint8Array(0))
+    let slice: Writer[] = [buf]
+    let w = MultiWriter(...slice)
+    slice[0] = writerFunc(() => assert.fail("wrote to the modified slice"))
+    let [n, err] = w.Write(new TextEncoder().encode("hello world"))
+    assert.equal(err, null)
+    assert.equal(n, 11)
+    assert.equal(str(buf.underlyingArray), "hello world")
+})
+
+// Test that MultiReader properly flattens chained multiReaders when Read is called
+//
+// Not present in the Go code: Go checks the call depth, here the chain is made
+// long enough that reading through it unflattened would overflow the stack.
+test("TestMultiReaderFlatten", () => {
+    let r: Reader = MultiReader(readerFunc((p) => [0, new Error("irrelevant")]))
+
+    // chain a bunch of multiReaders
+    for (let i = 0; i < 100000; i++) {
+        r = MultiReader(r)
+    }
+
+    let [, err] = r.Read(new Uint8Array(0))
+    assert.equal(err?.message, "irrelevant")
+})
+
+// byteAndEOFReader is a Reader which reads one byte (the underlying
+// byte) and EOF at once in its Read call.
+function byteAndEOFReader(b: string): Reader {
+    return readerFunc((p) => {
+        if (p.length == 0) {
+            // Read(0 bytes) is useless. We expect no such useless
+            // calls in this test.
+            throw new Error("unexpected call")
+        }
+        p[0] = b.charCodeAt(0)
+        return [1, Errors.EOF]
+    })
+}
+
+// This used to yield bytes forever; issue 16795.
+test("TestMultiReaderSingleByteWithEOF", () => {
+    let [got, err] = ReadAll(new LimitedReader(MultiReader(byteAndEOFReader("a"), byteAndEOFReader("b")), 10))
+    assert.equal(err, null)
+    assert.equal(str(got), "ab")
+})
+
+// Test that a reader returning (n, EOF) at the end of a MultiReader
+// chain continues to return EOF on its final read, rather than
+// yielding a (0, EOF).
+test("TestMultiReaderFinalEOF", () => {
+    let r = MultiReader(new GoBuffer(new Uint8Array(0)), byteAndEOFReader("a"))
+    let buf = new Uint8Array(2)
+    let [n, err] = r.Read(buf)
+    assert.equal(n, 1)
+    assert.equal(err, Errors.EOF)
+})
@@ -0,0 +1,262 @@
+// Node.js stream adapters for io readers and writers
+//
+// Not present in the Go code. This file depends on node:stream, so it is not exported from
+// the io index: import it from "io/node".
+import { Readable, ReadableOptions, Writable, WritableOptions } from "node:stream"
+import { AsyncCloser, AsyncReader, AsyncWriter, Closer, EOF, ErrClosedPipe, ErrNoProgress, ErrShortWrite, isCloser, MaxConsecutiveEmptyReads, Reader, Writer } from "./index"
+
+/**
+ * ReaderReadable is a stream.Readable that reads its data from a Reader or AsyncReader,
+ * so that io sources such as LZWReader can be used with stream.pipeline.
+ *
+ * Data is read from r only when the stream asks for it, so backpressure is kept. EOF ends
+ * the stream, and any other error destroys it with that error. When the stream is
+ * destroyed, which it is once it has ended unless autoDestroy is turned off, r is closed
+ * if it is a Closer.
+ *
+ * ```ts
+ * let r = new LZWReader(src, Order.LSB, 8)
+ * await pipeline(new ReaderReadable(r), fs.createWriteStream("out.bin"))
+ * ```
+ */
+export class ReaderReadable extends Readable {
+    private r: Reader | AsyncReader
+
+    /**
+     * @param r The reader to read from
+     * @param opts Options passed on to stream.Readable
+     */
+    constructor(r: Reader | AsyncReader, opts?: ReadableOptions) {
+        super(opts)
+        this.r = r
+    }
+
+    // _read reads once from r and pushes what it returns. The stream calls it again
+    // once it wants more data.
+    async _read(size: number) {
+        let buf = Buffer.alloc(size)
+        for (let i = 0; i < MaxConsecutiveEmptyReads; i++) {
+            let n: number, err: Error | null
+            try {
+                [n, err] = await this.r.Read(buf)
+            } catch (e) {
+                this.destroy(e as Error)
+                return
+            }
+
+            if (n > 0) {
+                // buf is not reused, so it can be pushed without a copy
+                this.push(buf.subarray(0, n))
+            }
+            if (err) {
+                if (err == EOF) {
+                    this.push(null)
+                } else {
+                    this.destroy(err)
+                }
+                return
+            }
+            if (n > 0) {
+                return
+            }
+        }
+        this.destroy(ErrNoProgress)
+    }
+
+    _destroy(err: Error | null, callback: (error?: Error | null) => void) {
+        if (isCloser(this.r)) {
+            Promise.resolve(this.r.Close()).then(
+                (cerr) => callback(err ?? cerr),
+                (e) => callback(err ?? e),
+            )
+            return
+        }
+        callback(err)
+    }
+}
+
+/**
+ * ReadableReader is an AsyncReader that reads from a stream.Readable.
+ *
+ * Read resolves with EOF once the stream has ended, and with the stream's error if it
+ * fails or is destroyed before it ends. Data is only pulled from the stream as Read
+ * asks for it, so backpressure is kept.
+ *
+ * The stream must not be in object mode. Chunks that are strings are read as UTF-8.
+ */
+export class ReadableReader implements AsyncReader, Closer {
+    private s: Readable
+    private it: AsyncIterator<Buffer | string>
+    private chunk: Uint8Array = new Uint8Array(0) // unread part of the last chunk
+    private err: Error | null = null // sticky error, EOF once the stream has ended
+
+    /**
+     * @param s The stream to read from
+     */
+    constructor(s: Readable) {
+        this.s = s
+        this.it = s[Symbol.asyncIterator]()
+    }
+
+    async Read(p: Uint8Array): Promise<[number, Error | null]> {
+        if (p.length == 0) {
+            return [0, null]
+        }
+
+        while (this.chunk.length == 0) {
+            if (this.err) {
+                return [0, this.err]
+            }
+
+            try {
+                let res = await this.it.next()
+                if (res.done) {
+                    this.err = EOF
+                } else if (typeof res.value == "string") {
+                    this.chunk = Buffer.from(res.value)
+                } else {
+                    this.chunk = res.value
+                }
+            } catch (e) {
+                this.err = e as Error
+            }
+        }
+
+        let n = Math.min(p.length, this.chunk.length)
+        p.set(this.chunk.subarray(0, n))
+        this.chunk = this.chunk.subarray(n)
+        return [n, null]
+    }
+
+    /**
+     * Close destroys the stream. Any later Read returns the error ClosedPipe.
+     */
+    Close(): Error | null {
+        if (this.err == null || this.err == EOF) {
+            this.err = ErrClosedPipe
+        }
+        this.chunk = new Uint8Array(0)
+        this.s.destroy()
+        return null
+    }
+}
+
+/**
+ * WriterWritable is a stream.Writable that writes its data to a Writer or AsyncWriter,
+ * so that io destinations such as LZWWriter can be used with stream.pipeline.
+ *
+ * Each chunk is written to w before the next one is taken, and an AsyncWriter's Write is
+ * awaited, so backpressure is kept. An error from w, or a short write, fails the stream.
+ * When the stream is ended, w is closed if it is a Closer, so that writers which buffer,
+ * such as LZWWriter, can flush.
+ *
+ * ```ts
+ * let w = new LZWWriter(new NodeFile(fd, "out.lzw"), Order.LSB, 8)
+ * await pipeline(fs.createReadStream("in.bin"), new WriterWritable(w))
+ * ```
+ */
+export class WriterWritable extends Writable {
+    private w: Writer | AsyncWriter
+
+    /**
+     * @param w The writer to write to
+     * @param opts Options passed on to stream.Writable
+     */
+    constructor(w: Writer | AsyncWriter, opts?: WritableOptions) {
+        super(opts)
+        this.w = w
+    }
+
+    _write(chunk: Buffer, _encoding: BufferEncoding, callback: (error?: Error | null) => void) {
+        this.write1(chunk).then(callback, callback)
+    }
+
+    _final(callback: (error?: Error | null) => void) {
+        if (isCloser(this.w)) {
+            Promise.resolve(this.w.Close()).then(callback, callback)
+            return
+        }
+        callback()
+    }
+
+    // write1 writes chunk to w, returning the error to fail the stream with, if any
+    private async write1(chunk: Uint8Array): Promise<Error | null> {
+        let [n, err] = await this.w.Write(chunk)
+        if (err == null && n != chunk.length) {
+            err = ErrShortWrite
+        }
+        return err
+    }
+}
+
+/**
+ * WritableWriter is an AsyncWriter that writes to a stream.Writable.
+ *
+ * p is copied, so Write resolves as soon as the stream has taken the data. When the stream
+ * is full, Write waits for it to drain, so backpressure is kept. An error from the stream
+ * is returned by the Write it happens in, or by the next Write or Close. Writing to a
+ * stream that has been ended or destroyed returns the error ClosedPipe.
+ */
+export class WritableWriter implements AsyncWriter, AsyncCloser {
+    private s: Writable
+    private err: Error | null = null // first error from the stream
+
+    /**
+     * @param s The stream to write to
+     */
+    constructor(s: Writable) {
+        this.s = s
+        s.on("error", (err) => {
+            if (this.err == null) {
+                this.err = err
+            }
+        })
+    }
+
+    async Write(p: Uint8Array): Promise<[number, Error | null]> {
+        if (this.err) {
+            return [0, this.err]
+        }
+        if (this.s.destroyed || this.s.writableEnded) {
+            return [0, ErrClosedPipe]
+        }
+
+        if (this.s.write(Buffer.from(p))) {
+            return [p.length, null]
+        }
+
+        // The stream is full: wait until it drains, or fails
+        await new Promise<void>((resolve) => {
+            let done = () => {
+                this.s.off("drain", done)
+                this.s.off("close", done)
+                this.s.off("error", done)
+                resolve()
+            }
+            this.s.on("drain", done)
+            this.s.on("close", done)
+            this.s.on("error", done)
+        })
+        if (this.err) {
+            return [0, this.err]
+        }
+        if (this.s.destroyed) {
+            return [0, ErrClosedPipe]
+        }
+        return [p.length, null]
+    }
+
+    /**
+     * Close ends the stream, and resolves once all data has been flushed to the
+     * underlying resource.
+     */
+    Close(): Promise<Error | null> {
+        if (this.err) {
+            return Promise.resolve(this.err)
+        }
+        return new Promise((resolve) => {
+            this.s.end(() => resolve(this.err))
+            this.s.once("error", (err) => resolve(err))
+        })
+    }
+}
@@ -0,0 +1,224 @@
+// Tests for the Node.js stream adapters
+//
+// Not present in the Go code
+import * as assert from "node:assert/strict"
+import * as fs from "node:fs"
+import { Readable, Writable } from "node:stream"
+import { pipeline } from "node:stream/promises"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { bytes, str } from "../builtins/tshelpers/strings"
+import { LZWReader, LZWWriter, Order } from "../compress/lzw"
+import { filenames } from "../compress/testdata/lzw"
+import { AsyncCopy, AsyncReadAll, Closer, Errors, Pipe, Reader, Writer } from "./index"
+import { ReadableReader, ReaderReadable, WritableWriter, WriterWritable } from "./node"
+
+// collect returns a Writable that appends what is written to it to chunks
+function collect(chunks: Buffer[]): Writable {
+    return new Writable({
+        write(chunk: Buffer, _encoding, callback) {
+            chunks.push(chunk)
+            callback()
+        },
+    })
+}
+
+test("TestReaderReadable", async () => {
+    let chunks: Buffer[] = []
+    let closed = false
+    let r = new GoBuffer(bytes("hello, world"))
+    let rc: Reader & Closer = {
+        Read: (p) => r.Read(p),
+        Close: () => {
+            closed = true
+            return null
+        },
+    }
+    await pipeline(new ReaderReadable(rc), collect(chunks))
+    assert.equal(str(Buffer.concat(chunks)), "hello, world")
+    assert.ok(closed, "the reader was not closed")
+})
+
+test("TestReaderReadableAsync", async () => {
+    let [pr, pw] = Pipe()
+    let writes = (async () => {
+        for (let s of ["hello", ", ", "world"]) {
+            await pw.Write(bytes(s))
+        }
+        pw.Close()
+    })()
+    let chunks: Buffer[] = []
+    await pipeline(new ReaderReadable(pr), collect(chunks))
+    await writes
+    assert.equal(str(Buffer.concat(chunks)), "hello, world")
+})
+
+test("TestReaderReadableBackpressure", async () => {
+    let reads = 0
+    let r: Reader = {
+        Read: (p) => {
+            reads++
+            p.fill(1)
+            return [p.length, null]
+        },
+    }
+    let s = new ReaderReadable(r, { highWaterMark: 16 })
+    s.on("readable", () => {}) // start reading, but never consume
+    await new Promise((resolve) => setTimeout(resolve, 20))
+    assert.ok(reads <= 2, `an endless reader was read ${reads} times without a consumer`)
+    s.destroy()
+})
+
+test("TestReaderReadableError", async () => {
+    let readErr = new Error("read failed")
+    let sent = false
+    let r: Reader = {
+        Read: (p) => {
+            if (sent) {
+                return [0, readErr]
+            }
+            sent = true
+            p[0] = 1
+            return [1, null]
+        },
+    }
+    let chunks: Buffer[] = []
+    await assert.rejects(pipeline(new ReaderReadable(r), collect(chunks)), (err) => err === readErr)
+    assert.deepEqual(Buffer.concat(chunks), Buffer.from([1]))
+
+    let empty: Reader = { Read: () => [0, null] }
+    await assert.rejects(pipeline(new ReaderReadable(empty), collect([])), (err) => err == Errors.NoProgress)
+})
+
+test("TestReadableReader", async () => {
+    let r = new ReadableReader(Readable.from([Buffer.from("hello"), "，", Buffer.from("world")]))
+    let buf = new Uint8Array(2)
+    assert.deepEqual(await r.Read(buf), [2, null])
+    assert.equal(str(buf), "he")
+    let [rest, err] = await AsyncReadAll(r)
+    assert.equal(err, null)
+    assert.equal(str(rest), "llo，world")
+
+    let n: number;
+    [n, err] = await r.Read(buf)
+    assert.equal(n, 0)
+    assert.equal(err, Errors.EOF)
+})
+
+test("TestReadableReaderError", async () => {
+    let streamErr = new Error("stream failed")
+    let s = new Readable({ read() {} })
+    s.push(Buffer.from("ab"))
+    let r = new ReadableReader(s)
+    let buf = new Uint8Array(4)
+    assert.deepEqual(await r.Read(buf), [2, null])
+    let read = r.Read(buf)
+    s.destroy(streamErr)
+    let [n, err] = await read
+    assert.equal(n, 0)
+    assert.equal(err, streamErr)
+    assert.equal((await r.Read(buf))[1], streamErr, "the error is not sticky")
+})
+
+test("TestReadableReaderClose", async () => {
+    let s = Readable.from(["abc"])
+    let r = new ReadableReader(s)
+    assert.equal(r.Close(), null)
+    assert.ok(s.destroyed)
+    let [n, err] = await r.Read(new Uint8Array(1))
+    assert.equal(n, 0)
+    assert.equal(err, Errors.ClosedPipe)
+})
+
+test("TestWriterWritable", async () => {
+    let buf = new GoBuffer(new Uint8Array(0))
+    await pipeline(Readable.from(["hello", ", ", "world"]), new WriterWritable(buf))
+    assert.equal(str(buf.underlyingArray), "hello, world")
+
+    let [pr, pw] = Pipe()
+    let done = pipeline(Readable.from(["hello", ", ", "world"]), new WriterWritable(pw))
+    let [got, err] = await AsyncReadAll(pr) // ends once the writable closes the pipe
+    await done
+    assert.equal(err, null)
+    assert.equal(str(got), "hello, world")
+})
+
+test("TestWriterWritableError", async () => {
+    let writeErr = new Error("write failed")
+    let w: Writer = { Write: () => [0, writeErr] }
+    await assert.rejects(pipeline(Readable.from(["abc"]), new WriterWritable(w)), (err) => err === writeErr)
+
+    let short: Writer = { Write: (p) => [p.length - 1, null] }
+    await assert.rejects(pipeline(Readable.from(["abc"]), new WriterWritable(short)), (err) => err == Errors.ShortWrite)
+})
+
+test("TestWritableWriter", async () => {
+    let chunks: Buffer[] = []
+    let maxBuffered = 0
+    let s: Writable = new Writable({
+        highWaterMark: 4,
+        write(chunk: Buffer, _encoding, callback) {
+            maxBuffered = Math.max(maxBuffered, s.writableLength)
+            setTimeout(() => {
+                chunks.push(chunk)
+                callback()
+            }, 1)
+        },
+    })
+    let w = new WritableWriter(s)
+    let src = new Uint8Array(100).map((_, i) => i)
+    let [n, err] = await AsyncCopy(w, new LimitedChunks(src, 10))
+    assert.equal(err, null)
+    assert.equal(n, 100)
+    assert.equal(await w.Close(), null)
+    assert.deepEqual(new Uint8Array(Buffer.concat(chunks)), src)
+    assert.ok(maxBuffered <= 10, `${maxBuffered} bytes were buffered, Write did not wait for the stream to drain`)
+    assert.ok(s.writableFinished, "Close did not wait for the stream to finish")
+
+    let [, werr] = await w.Write(bytes("late"))
+    assert.equal(werr, Errors.ClosedPipe)
+})
+
+test("TestWritableWriterError", async () => {
+    let streamErr = new Error("stream failed")
+    let s = new Writable({
+        write(_chunk, _encoding, callback) {
+            callback(streamErr)
+        },
+    })
+    let w = new WritableWriter(s)
+    await w.Write(bytes("a"))
+    await new Promise((resolve) => setImmediate(resolve))
+    let [n, err] = await w.Write(bytes("b"))
+    assert.equal(n, 0)
+    assert.equal(err, streamErr)
+    assert.equal(await w.Close(), streamErr)
+})
+
+test("TestLZWPipeline", async () => {
+    let golden = fs.readFileSync(filenames[0])
+
+    // Compress by piping into an LZWWriter, which is closed at the end
+    let compressed = new GoBuffer(new Uint8Array(0))
+    await pipeline(Readable.from([golden]), new WriterWritable(new LZWWriter(compressed, Order.LSB, 8)))
+
+    // Decompress by piping out of an LZWReader
+    let chunks: Buffer[] = []
+    await pipeline(new ReaderReadable(new LZWReader(compressed, Order.LSB, 8)), collect(chunks))
+    assert.deepEqual(Buffer.concat(chunks), golden)
+})
+
+// LimitedChunks is a Reader that returns b at most n bytes at a time
+class LimitedChunks implements Reader {
+    constructor(private b: Uint8Array, private n: number) {}
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (this.b.length == 0) {
+            return [0, Errors.EOF]
+        }
+        let n = Math.min(p.length, this.n, this.b.length)
+        p.set(this.b.subarray(0, n))
+        this.b = this.b.subarray(n)
+        return [n, null]
+    }
+}
@@ -0,0 +1,263 @@
+// Pipe adapter to connect code expecting an io.Reader
+// with code expecting an io.Writer.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/pipe.go
+//
+// *SEMANTIC DIFFERENCES TO GO:*
+//
+// JS can't block, so both halves of the pipe are awaitable: Read and Write return
+// promises that settle once Go's calls would have returned.
+import { AsyncReader, AsyncWriter, Closer, EOF, ErrClosedPipe } from "./index"
+
+// pendingRead is a Read waiting for a Write
+interface pendingRead {
+    b: Uint8Array
+    resolve: (result: [number, Error | null]) => void
+}
+
+// pendingWrite is a Write waiting for Reads to consume b
+interface pendingWrite {
+    b: Uint8Array
+    n: number // number of bytes of b consumed so far
+    resolve: (result: [number, Error | null]) => void
+}
+
+// A pipe is the shared pipe structure underlying PipeReader and PipeWriter.
+//
+// Not present in the Go code: Go hands writes to reads over channels. Here the
+// waiting calls are queued and matched up by pump.
+class pipe {
+    private reads: pendingRead[] = []
+    // writes are served in order, only the first one is consumed by reads. This
+    // serializes Write operations like Go's wrMu
+    private writes: pendingWrite[] = []
+
+    done: boolean = false
+    rerr: Error | null = null
+    werr: Error | null = null
+
+    read(b: Uint8Array): Promise<[number, Error | null]> {
+        if (this.done) {
+            return Promise.resolve([0, this.readCloseError()])
+        }
+
+        return new Promise((resolve) => {
+            this.reads.push({ b, resolve })
+            this.pump()
+        })
+    }
+
+    closeRead(err: Error | null): Error | null {
+        if (err == null) {
+            err = ErrClosedPipe
+        }
+        if (this.rerr == null) {
+            this.rerr = err
+        }
+        this.close()
+        return null
+    }
+
+    write(b: Uint8Array): Promise<[number, Error | null]> {
+        if (this.done) {
+            return Promise.resolve([0, this.writeCloseError()])
+        }
+
+        return new Promise((resolve) => {
+            this.writes.push({ b, n: 0, resolve })
+            this.pump()
+        })
+    }
+
+    closeWrite(err: Error | null): Error | null {
+        if (err == null) {
+            err = EOF
+        }
+        if (this.werr == null) {
+            this.werr = err
+        }
+        this.close()
+        return null
+    }
+
+    // pump copies the first pending write into pending reads, one read at a time,
+    // completing the write once all of it has been read.
+    // A zero length write is still matched with a read, which returns 0 bytes.
+    private pump() {
+        while (this.reads.length > 0 && this.writes.length > 0) {
+            let r = this.reads.shift()!
+            let w = this.writes[0]
+
+            let nr = Math.min(r.b.length, w.b.length - w.n)
+            r.b.set(w.b.subarray(w.n, w.n + nr))
+            w.n += nr
+            r.resolve([nr, null])
+
+            if (w.n == w.b.length) {
+                this.writes.shift()
+                w.resolve([w.n, null])
+            }
+        }
+    }
+
+    // close fails all waiting calls, once the first close error has been stored
+    private close() {
+        if (this.done) {
+            return
+        }
+        this.done = true
+
+        for (let r of this.reads) {
+            r.resolve([0, this.readCloseError()])
+        }
+        for (let w of this.writes) {
+            w.resolve([w.n, this.writeCloseError()])
+        }
+        this.reads = []
+        this.writes = []
+    }
+
+    // readCloseError is considered internal to the pipe type.
+    readCloseError(): Error {
+        if (this.rerr == null && this.werr != null) {
+            return this.werr
+        }
+        return ErrClosedPipe
+    }
+
+    // writeCloseError is considered internal to the pipe type.
+    writeCloseError(): Error {
+        if (this.werr == null && this.rerr != null) {
+            return this.rerr
+        }
+        return ErrClosedPipe
+    }
+}
+
+/**
+ * A PipeReader is the read half of a pipe.
+ */
+export class PipeReader implements AsyncReader, Closer {
+    private p: pipe
+
+    constructor(p: pipe) {
+        this.p = p
+    }
+
+    /**
+     * Read implements the standard Read interface:
+     * it reads data from the pipe, blocking until a writer
+     * arrives or the write end is closed.
+     * If the write end is closed with an error, that error is
+     * returned as err; otherwise err is EOF.
+     */
+    Read(data: Uint8Array): Promise<[number, Error | null]> {
+        return this.p.read(data)
+    }
+
+    /**
+     * Close closes the reader; subsequent writes to the
+     * write half of the pipe will return the error ClosedPipe.
+     */
+    Close(): Error | null {
+        return this.CloseWithError(null)
+    }
+
+    /**
+     * CloseWithError closes the reader; subsequent writes
+     * to the write half of the pipe will return the error err.
+     *
+     * CloseWithError never overwrites the previous error if it exists
+     * and always returns null.
+     */
+    CloseWithError(err: Error | null): Error | null {
+        return this.p.closeRead(err)
+    }
+}
+
+/**
+ * A PipeWriter is the write half of a pipe.
+ */
+export class PipeWriter implements AsyncWriter, Closer {
+    private p: pipe
+
+    constructor(p: pipe) {
+        this.p = p
+    }
+
+    /**
+     * Write implements the standard Write interface:
+     * it writes data to the pipe, blocking until one or more readers
+     * have consumed all the data or the read end is closed.
+     * If the read end is closed with an error, that err is
+     * returned as err; otherwise err is ClosedPipe.
+     *
+     * The pipe reads directly from data until the returned promise settles, so
+     * data must not be modified until then.
+     */
+    Write(data: Uint8Array): Promise<[number, Error | null]> {
+        return this.p.write(data)
+    }
+
+    /**
+     * Close closes the writer; subsequent reads from the
+     * read half of the pipe will return no bytes and EOF.
+     */
+    Close(): Error | null {
+        return this.CloseWithError(null)
+    }
+
+    /**
+     * CloseWithError closes the writer; subsequent reads from the
+     * read half of the pipe will return no bytes and the error err,
+     * or EOF if err is null.
+     *
+     * CloseWithError never overwrites the previous error if it exists
+     * and always returns null.
+     */
+    CloseWithError(err: Error | null): Error | null {
+        return this.p.closeWrite(err)
+    }
+}
+
+/**
+ * Pipe creates a synchronous in-memory pipe.
+ * It can be used to connect code expecting an io.Reader
+ * with code expecting an io.Writer.
+ *
+ * Reads and Writes on the pipe are matched one to one
+ * except when multiple Reads are needed to consume a single Write.
+ * That is, each Write to the PipeWriter blocks until it has satisfied
+ * one or more Reads from the PipeReader that fully consume
+ * the written data.
+ * The data is copied directly from the Write to the corresponding
+ * Read (or Reads); there is no internal buffering.
+ *
+ * It is safe to call Read and Write in parallel with each other or with Close.
+ * Parallel calls to Read and parallel calls to Write are also safe:
+ * the individual calls will be gated sequentially.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Blocking is done by awaiting: Read and Write return promises.
+ *
+ * ```ts
+ * let [pr, pw] = io.Pipe()
+ * upload(pr) // reads with `await pr.Read(p)`
+ * let buf = new Uint8Array(32 * 1024)
+ * while (true) {
+ *     let [n, err] = lzwReader.Read(buf)
+ *     if (n > 0) {
+ *         let [, werr] = await pw.Write(buf.subarray(0, n))
+ *         if (werr) break
+ *     }
+ *     if (err) {
+ *         pw.CloseWithError(err == io.EOF ? null : err)
+ *         break
+ *     }
+ * }
+ * ```
+ */
+export function Pipe(): [PipeReader, PipeWriter] {
+    let p = new pipe()
+    return [new PipeReader(p), new PipeWriter(p)]
+}
@@ -0,0 +1,312 @@
+// Tests for io.Pipe
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/io/pipe_test.go
+//
+// Goroutines are replaced by promises that are not awaited straight away.
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { bytes, str } from "../builtins/tshelpers/strings"
+import { AsyncReadFull, Errors, Pipe, PipeReader, PipeWriter } from "./index"
+
+// sleep resolves after ms milliseconds, like time.Sleep
+function sleep(ms: number): Promise<void> {
+    return new Promise((resolve) => setTimeout(resolve, ms))
+}
+
+async function checkWrite(w: PipeWriter, data: Uint8Array) {
+    let [n, err] = await w.Write(data)
+    assert.equal(err, null, "write")
+    assert.equal(n, data.length, "short write")
+}
+
+// Test a single read/write pair.
+test("TestPipe1", async () => {
+    let [r, w] = Pipe()
+    let buf = new Uint8Array(64)
+    let c = checkWrite(w, bytes("hello, world"))
+    let [n, err] = await r.Read(buf)
+    assert.equal(err, null, "read")
+    assert.equal(str(buf.subarray(0, n)), "hello, world", "bad read")
+    await c
+    r.Close()
+    w.Close()
+})
+
+async function reader(r: PipeReader, c: number[]) {
+    let buf = new Uint8Array(64)
+    while (true) /* for */ {
+        let [n, err] = await r.Read(buf)
+        if (err == Errors.EOF) {
+            c.push(0)
+            break
+        }
+        assert.equal(err, null, "read")
+        c.push(n)
+    }
+}
+
+// Test a sequence of read/write pairs.
+test("TestPipe2", async () => {
+    let c: number[] = []
+    let [r, w] = Pipe()
+    let done = reader(r, c)
+    let buf = new Uint8Array(64)
+    for (let i = 0; i < 5; i++) {
+        let p = buf.subarray(0, 5 + i * 10)
+        let [n, err] = await w.Write(p)
+        assert.equal(n, p.length, `wrote ${p.length}`)
+        assert.equal(err, null, "write")
+        assert.equal(c.shift(), n, `wrote ${n}, read got a different count`)
+    }
+    w.Close()
+    await done
+    assert.equal(c.shift(), 0, "final read")
+})
+
+// Test a large write that requires multiple reads to satisfy.
+test("TestPipe3", async () => {
+    let [r, w] = Pipe()
+    let wdat = new Uint8Array(128)
+    for (let i = 0; i < wdat.length; i++) {
+        wdat[i] = i
+    }
+    let c = w.Write(wdat).then((result) => {
+        w.Close()
+        return result
+    })
+    let rdat = new Uint8Array(1024)
+    let tot = 0
+    for (let n = 1; n <= 256; n *= 2) {
+        let [nn, err] = await r.Read(rdat.subarray(tot, tot + n))
+        if (err && err != Errors.EOF) {
+            assert.fail(`read: ${err.message}`)
+        }
+
+        // only final two reads should be short - 1 byte, then 0
+        let expect = n
+        if (n == 128) {
+            expect = 1
+        } else if (n == 256) {
+            expect = 0
+            assert.equal(err, Errors.EOF, "read at end")
+        }
+        assert.equal(nn, expect, `read ${n}`)
+        tot += nn
+    }
+    let [pn, perr] = await c
+    assert.equal(pn, 128, "write 128")
+    assert.equal(perr, null, "write 128")
+    assert.equal(tot, 128, "total read")
+    for (let i = 0; i < 128; i++) {
+        assert.equal(rdat[i], i, `rdat[${i}]`)
+    }
+})
+
+// Test read after/before writer close.
+
+interface closer {
+    CloseWithError(err: Error | null): Error | null
+    Close(): Error | null
+}
+
+interface pipeTest {
+    async: boolean
+    err: Error | null
+    closeWithError: boolean
+}
+
+const errShortWrite = Errors.ShortWrite
+
+const pipeTests: pipeTest[] = [
+    { async: true, err: null, closeWithError: false },
+    { async: true, err: null, closeWithError: true },
+    { async: true, err: errShortWrite, closeWithError: true },
+    { async: false, err: null, closeWithError: false },
+    { async: false, err: null, closeWithError: true },
+    { async: false, err: errShortWrite, closeWithError: true },
+]
+
+async function delayClose(cl: closer, tt: pipeTest) {
+    await sleep(1)
+    let err: Error | null
+    if (tt.closeWithError) {
+        err = cl.CloseWithError(tt.err)
+    } else {
+        err = cl.Close()
+    }
+    assert.equal(err, null, "delayClose")
+}
+
+test("TestPipeReadClose", async () => {
+    for (let tt of pipeTests) {
+        let [r, w] = Pipe()
+        let c: Promise<void> = Promise.resolve()
+        if (tt.async) {
+            c = delayClose(w, tt)
+        } else {
+            await delayClose(w, tt)
+        }
+        let buf = new Uint8Array(64)
+        let [n, err] = await r.Read(buf)
+        await c
+        if (tt.err) {
+            assert.equal(err, tt.err, "read from closed pipe")
+        } else {
+            assert.equal(err, Errors.EOF, "read from closed pipe")
+        }
+        assert.equal(n, 0, "read on closed pipe")
+        assert.equal(r.Close(), null, "r.Close")
+    }
+})
+
+// Test close on Read side during Read.
+test("TestPipeReadClose2", async () => {
+    let [r] = Pipe()
+    let c = delayClose(r, { async: false, err: null, closeWithError: false })
+    let [n, err] = await r.Read(new Uint8Array(64))
+    await c
+    assert.equal(n, 0)
+    assert.equal(err, Errors.ClosedPipe)
+})
+
+// Test write after/before reader close.
+
+test("TestPipeWriteClose", async () => {
+    for (let tt of pipeTests) {
+        let [r, w] = Pipe()
+        let c: Promise<void> = Promise.resolve()
+        if (tt.async) {
+            c = delayClose(r, tt)
+        } else {
+            await delayClose(r, tt)
+        }
+        let [n, err] = await w.Write(bytes("hello, world"))
+        await c
+        if (tt.err) {
+            assert.equal(err, tt.err, "write on closed pipe")
+        } else {
+            assert.equal(err, Errors.ClosedPipe, "write on closed pipe")
+        }
+        assert.equal(n, 0, "write on closed pipe")
+        assert.equal(w.Close(), null, "w.Close")
+    }
+})
+
+// Test close on Write side during Write.
+test("TestPipeWriteClose2", async () => {
+    let [, w] = Pipe()
+    let c = delayClose(w, { async: false, err: null, closeWithError: false })
+    let [n, err] = await w.Write(new Uint8Array(64))
+    await c
+    assert.equal(n, 0)
+    assert.equal(err, Errors.ClosedPipe)
+})
+
+test("TestWriteEmpty", async () => {
+    let [r, w] = Pipe()
+    let c = (async () => {
+        await w.Write(new Uint8Array(0))
+        w.Close()
+    })()
+    let b = new Uint8Array(2)
+    await AsyncReadFull(r, b)
+    r.Close()
+    await c
+})
+
+test("TestWriteAfterWriterClose", async () => {
+    let [r, w] = Pipe()
+    let writeErr: Error | null = null
+    let done = (async () => {
+        let [, err] = await w.Write(bytes("hello"))
+        assert.equal(err, null, "got error; expected none")
+        w.Close();
+        [, writeErr] = await w.Write(bytes("world"))
+    })()
+
+    let buf = new Uint8Array(100)
+    let [n, err] = await AsyncReadFull(r, buf)
+    assert.equal(err, Errors.UnexpectedEOF)
+    let result = str(buf.subarray(0, n))
+    await done
+
+    assert.equal(result, "hello")
+    assert.equal(writeErr, Errors.ClosedPipe)
+    r.Close()
+})
+
+test("TestPipeCloseError", async () => {
+    let testError1 = new Error("testError1")
+    let testError2 = new Error("testError2")
+
+    let [r, w] = Pipe()
+    r.CloseWithError(testError1)
+    assert.equal((await w.Write(new Uint8Array(0)))[1], testError1, "Write error")
+    r.CloseWithError(testError2)
+    assert.equal((await w.Write(new Uint8Array(0)))[1], testError1, "Write error");
+
+    [r, w] = Pipe()
+    w.CloseWithError(testError1)
+    assert.equal((await r.Read(new Uint8Array(0)))[1], testError1, "Read error")
+    w.CloseWithError(testError2)
+    assert.equal((await r.Read(new Uint8Array(0)))[1], testError1, "Read error")
+})
+
+test("TestPipeConcurrent", async (t) => {
+    const input = "0123456789abcdef"
+    const count = 8
+    const readSize = 2
+
+    await t.test("Write", async () => {
+        let [r, w] = Pipe()
+
+        let writes: Promise<void>[] = []
+        for (let i = 0; i < count; i++) {
+            writes.push((async () => {
+                await sleep(1) // Increase probability of race
+                let [n, err] = await w.Write(bytes(input))
+                assert.equal(n, input.length)
+                assert.equal(err, null)
+            })())
+        }
+
+        let buf = new Uint8Array(count * input.length)
+        for (let i = 0; i < buf.length; i += readSize) {
+            let [n, err] = await r.Read(buf.subarray(i, i + readSize))
+            assert.equal(n, readSize)
+            assert.equal(err, null)
+        }
+        await Promise.all(writes)
+
+        // Since each Write is fully gated, if multiple Read calls were needed,
+        // the contents of Write should still appear together in the output.
+        assert.equal(str(buf), input.repeat(count))
+    })
+
+    await t.test("Read", async () => {
+        let [r, w] = Pipe()
+
+        let reads: Promise<string>[] = []
+        for (let i = 0; i < count * input.length / readSize; i++) {
+            reads.push((async () => {
+                await sleep(1) // Increase probability of race
+                let buf = new Uint8Array(readSize)
+                let [n, err] = await r.Read(buf)
+                assert.equal(n, readSize)
+                assert.equal(err, null)
+                return str(buf)
+            })())
+        }
+
+        for (let i = 0; i < count; i++) {
+            let [n, err] = await w.Write(bytes(input))
+            assert.equal(n, input.length)
+            assert.equal(err, null)
+        }
+
+        // Since each read is independent, the only guarantee about the output
+        // is that it is a permutation of the input in readSized groups.
+        let got = (await Promise.all(reads)).sort().join("")
+        let want = input.repeat(count).match(/../g)!.sort().join("")
+        assert.equal(got, want)
+    })
+})
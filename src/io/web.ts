@@ -0,0 +1,327 @@
+// Web platform adapters for io readers and writers: Blob and File, ReadableStream and WritableStream
+//
+// Not present in the Go code. This file only depends on web platform APIs, so it works in
+// browsers as well as in Node.js. The Node.js stream adapters are in node.ts.
+import { AsyncCloser, AsyncReader, AsyncReaderAt, AsyncWriter, EOF, ErrClosedPipe, ErrNoProgress, ErrShortWrite, isCloser, MaxConsecutiveEmptyReads, Reader, SeekCurrent, SeekEnd, Seeker, SeekStart, Writer } from "./index"
+
+// Size of the chunks ReaderReadableStream reads from its Reader
+const readChunkSize = 32 * 1024
+
+/**
+ * BlobReader reads from a Blob or File, like bytes.Reader reads from a byte slice. Reads
+ * use Blob.slice, so only the bytes asked for are loaded.
+ *
+ * Read and ReadAt are asynchronous as the data of a Blob can only be read that way. Seek
+ * only moves the read offset, so it is synchronous.
+ *
+ * ```ts
+ * let r = new BlobReader(input.files[0])
+ * let [data, err] = await io.AsyncReadAll(new AsyncLZWReader(r, Order.LSB, 8))
+ * ```
+ */
+export class BlobReader implements AsyncReader, AsyncReaderAt, Seeker {
+    private b: Blob
+    private i: number = 0 // current reading index
+
+    /**
+     * @param b The Blob or File to read from
+     */
+    constructor(b: Blob) {
+        this.b = b
+    }
+
+    /**
+     * Len returns the number of bytes of the unread portion of the blob.
+     */
+    Len(): number {
+        return Math.max(0, this.b.size - this.i)
+    }
+
+    /**
+     * Size returns the original length of the underlying blob.
+     * The result is unaffected by any method calls except Reset.
+     */
+    Size(): number {
+        return this.b.size
+    }
+
+    // Read implements the AsyncReader interface.
+    async Read(p: Uint8Array): Promise<[number, Error | null]> {
+        if (this.i >= this.b.size) {
+            return [0, EOF]
+        }
+        if (p.length == 0) {
+            return [0, null]
+        }
+        let n = await this.copy(p, this.i)
+        this.i += n
+        return [n, null]
+    }
+
+    // ReadAt implements the AsyncReaderAt interface.
+    async ReadAt(p: Uint8Array, off: number): Promise<[number, Error | null]> {
+        if (off < 0) {
+            return [0, new Error("io.BlobReader.ReadAt: negative offset")]
+        }
+        if (off >= this.b.size) {
+            return [0, EOF]
+        }
+        let n = await this.copy(p, off)
+        if (n < p.length) {
+            return [n, EOF]
+        }
+        return [n, null]
+    }
+
+    // Seek implements the Seeker interface.
+    Seek(offset: number, whence: number): [number, Error | null] {
+        let abs: number
+        switch (whence) {
+            case SeekStart:
+                abs = offset
+                break
+            case SeekCurrent:
+                abs = this.i + offset
+                break
+            case SeekEnd:
+                abs = this.b.size + offset
+                break
+            default:
+                return [0, new Error("io.BlobReader.Seek: invalid whence")]
+        }
+        if (abs < 0) {
+            return [0, new Error("io.BlobReader.Seek: negative position")]
+        }
+        this.i = abs
+        return [abs, null]
+    }
+
+    /**
+     * Reset resets the BlobReader to be reading from b.
+     */
+    Reset(b: Blob) {
+        this.b = b
+        this.i = 0
+    }
+
+    // copy reads the bytes of the blob starting at off into p, returning how many were read
+    private async copy(p: Uint8Array, off: number): Promise<number> {
+        let data = new Uint8Array(await this.b.slice(off, off + p.length).arrayBuffer())
+        p.set(data)
+        return data.length
+    }
+}
+
+/**
+ * StreamReader is an AsyncReader that reads from a ReadableStream of bytes, such as the
+ * body of a fetch Response or the result of Blob.stream().
+ *
+ * Read never returns 0 bytes with a null error for a non-empty p: empty chunks are
+ * skipped. It resolves with EOF once the stream is done, and with the stream's error if
+ * it fails.
+ */
+export class StreamReader implements AsyncReader, AsyncCloser {
+    private r: ReadableStreamDefaultReader<Uint8Array>
+    private chunk: Uint8Array = new Uint8Array(0) // unread part of the last chunk
+    private err: Error | null = null // sticky error, EOF once the stream is done
+
+    /**
+     * StreamReader locks s to itself until it is closed.
+     *
+     * @param s The stream to read from
+     */
+    constructor(s: ReadableStream<Uint8Array>) {
+        this.r = s.getReader()
+    }
+
+    async Read(p: Uint8Array): Promise<[number, Error | null]> {
+        if (p.length == 0) {
+            return [0, null]
+        }
+
+        while (this.chunk.length == 0) {
+            if (this.err) {
+                return [0, this.err]
+            }
+
+            try {
+                let res = await this.r.read()
+                if (res.done) {
+                    this.err = EOF
+                } else {
+                    this.chunk = toUint8Array(res.value)
+                }
+            } catch (e) {
+                this.err = toError(e)
+            }
+        }
+
+        let n = Math.min(p.length, this.chunk.length)
+        p.set(this.chunk.subarray(0, n))
+        this.chunk = this.chunk.subarray(n)
+        return [n, null]
+    }
+
+    /**
+     * Close cancels the stream. Any later Read returns the error ClosedPipe.
+     */
+    async Close(): Promise<Error | null> {
+        if (this.err == null || this.err == EOF) {
+            this.err = ErrClosedPipe
+        }
+        this.chunk = new Uint8Array(0)
+        try {
+            await this.r.cancel()
+        } catch (e) {
+            return toError(e)
+        }
+        return null
+    }
+}
+
+/**
+ * StreamWriter is an AsyncWriter that writes to a WritableStream of bytes.
+ *
+ * Write resolves once the chunk has been written to the underlying sink, so a failed
+ * write is reported by that Write. p is copied, as the sink may keep the chunk.
+ */
+export class StreamWriter implements AsyncWriter, AsyncCloser {
+    private w: WritableStreamDefaultWriter<Uint8Array>
+    private err: Error | null = null // first error from the stream
+
+    /**
+     * StreamWriter locks s to itself.
+     *
+     * @param s The stream to write to
+     */
+    constructor(s: WritableStream<Uint8Array>) {
+        this.w = s.getWriter()
+    }
+
+    async Write(p: Uint8Array): Promise<[number, Error | null]> {
+        if (this.err) {
+            return [0, this.err]
+        }
+        try {
+            await this.w.write(p.slice())
+        } catch (e) {
+            this.err = toError(e)
+            return [0, this.err]
+        }
+        return [p.length, null]
+    }
+
+    /**
+     * Close closes the stream, and resolves once all data has been written to the
+     * underlying sink. Any later Write returns the error ClosedPipe.
+     */
+    async Close(): Promise<Error | null> {
+        if (this.err) {
+            return this.err
+        }
+        try {
+            await this.w.close()
+        } catch (e) {
+            this.err = toError(e)
+            return this.err
+        }
+        this.err = ErrClosedPipe
+        return null
+    }
+}
+
+/**
+ * ReaderReadableStream is a ReadableStream of the data read from a Reader or AsyncReader,
+ * for use with Response, pipeThrough and the other web APIs that take streams.
+ *
+ * r is only read when the stream is pulled, and it is closed, if it is a Closer, when the
+ * stream is cancelled.
+ */
+export class ReaderReadableStream extends ReadableStream<Uint8Array> {
+    /**
+     * @param r The reader to read from
+     */
+    constructor(r: Reader | AsyncReader) {
+        super({
+            async pull(controller) {
+                for (let i = 0; i < MaxConsecutiveEmptyReads; i++) {
+                    // A new buffer each time, as an enqueued chunk is handed on as is
+                    let buf = new Uint8Array(readChunkSize)
+                    let [n, err] = await r.Read(buf)
+                    if (n > 0) {
+                        controller.enqueue(buf.subarray(0, n))
+                    }
+                    if (err) {
+                        if (err == EOF) {
+                            controller.close()
+                            return
+                        }
+                        throw err
+                    }
+                    if (n > 0) {
+                        return
+                    }
+                }
+                throw ErrNoProgress
+            },
+            async cancel() {
+                if (isCloser(r)) {
+                    await r.Close()
+                }
+            },
+        })
+    }
+}
+
+/**
+ * WriterWritableStream is a WritableStream that writes its chunks to a Writer or
+ * AsyncWriter.
+ *
+ * An error from w, or a short write, errors the stream. When the stream is closed, w is
+ * closed if it is a Closer, so that writers which buffer, such as LZWWriter, can flush.
+ */
+export class WriterWritableStream extends WritableStream<Uint8Array> {
+    /**
+     * @param w The writer to write to
+     */
+    constructor(w: Writer | AsyncWriter) {
+        super({
+            async write(chunk) {
+                let [n, err] = await w.Write(chunk)
+                if (err == null && n != chunk.length) {
+                    err = ErrShortWrite
+                }
+                if (err) {
+                    throw err
+                }
+            },
+            async close() {
+                if (isCloser(w)) {
+                    let err = await w.Close()
+                    if (err) {
+                        throw err
+                    }
+                }
+            },
+        })
+    }
+}
+
+// toUint8Array returns the bytes of a chunk read from a stream, which may be any BufferSource
+function toUint8Array(chunk: ArrayBufferView | ArrayBuffer): Uint8Array {
+    if (chunk instanceof Uint8Array) {
+        return chunk
+    }
+    if (ArrayBuffer.isView(chunk)) {
+        return new Uint8Array(chunk.buffer, chunk.byteOffset, chunk.byteLength)
+    }
+    return new Uint8Array(chunk)
+}
+
+// toError returns e, which was thrown or used to error a stream, as an Error
+function toError(e: unknown): Error {
+    if (e instanceof Error) {
+        return e
+    }
+    return new Error(String(e))
+}
@@ -0,0 +1,268 @@
+// Tests for the Blob and WHATWG stream adapters
+//
+// Not present in the Go code
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../builtins/tshelpers/buffer"
+import { bytes, str } from "../builtins/tshelpers/strings"
+import { AsyncLZWReader } from "../compress/lzwstream"
+import { LZWWriter, Order } from "../compress/lzw"
+import {
+    AsyncCopy, AsyncReadAll, AsyncReader, AsyncReadFull, BlobReader, Errors, ReaderReadableStream, SeekCurrent, SeekEnd,
+    SeekStart, StreamReader, StreamWriter, WriterWritableStream,
+} from "./index"
+
+// streamOf returns a ReadableStream of chunks
+function streamOf(...chunks: Uint8Array[]): ReadableStream<Uint8Array> {
+    return new ReadableStream({
+        start(controller) {
+            for (let c of chunks) {
+                controller.enqueue(c)
+            }
+            controller.close()
+        },
+    })
+}
+
+// collect returns a WritableStream and the chunks written to it
+function collect(): [WritableStream<Uint8Array>, Uint8Array[]] {
+    let chunks: Uint8Array[] = []
+    let s = new WritableStream<Uint8Array>({
+        write(chunk) {
+            chunks.push(chunk)
+        },
+    })
+    return [s, chunks]
+}
+
+// compress returns s compressed with LZWWriter
+function compress(s: string, order: Order): Uint8Array {
+    let b = new GoBuffer(new Uint8Array(0))
+    let w = new LZWWriter(b, order, 8)
+    w.Write(bytes(s))
+    w.Close()
+    return b.underlyingArray
+}
+
+test("TestBlobReader", async () => {
+    let r = new BlobReader(new Blob([bytes("0123456789")]))
+    assert.equal(r.Size(), 10)
+
+    let buf = new Uint8Array(4)
+    assert.deepEqual(await r.Read(buf), [4, null])
+    assert.equal(str(buf), "0123")
+    assert.equal(r.Len(), 6)
+
+    assert.deepEqual(r.Seek(-2, SeekEnd), [8, null])
+    assert.deepEqual(await r.Read(buf), [2, null])
+    assert.equal(str(buf.subarray(0, 2)), "89")
+    let [n, err] = await r.Read(buf)
+    assert.equal(n, 0)
+    assert.equal(err, Errors.EOF)
+
+    assert.deepEqual(r.Seek(1, SeekStart), [1, null])
+    assert.deepEqual(r.Seek(1, SeekCurrent), [2, null]);
+    [, err] = r.Seek(-3, SeekCurrent)
+    assert.ok(err != null, "seek to a negative position succeeded");
+    [, err] = r.Seek(0, 42)
+    assert.ok(err != null, "seek with an invalid whence succeeded")
+
+    // Seeking past the end is allowed, and reads return EOF
+    assert.deepEqual(r.Seek(20, SeekStart), [20, null]);
+    [n, err] = await r.Read(buf)
+    assert.equal(n, 0)
+    assert.equal(err, Errors.EOF)
+    assert.equal(r.Len(), 0)
+
+    r.Reset(new Blob([bytes("ab")]))
+    assert.deepEqual(await AsyncReadAll(r), [bytes("ab"), null])
+})
+
+test("TestBlobReaderAt", async () => {
+    let r = new BlobReader(new Blob([bytes("0123456789")]))
+    let tests: { off: number, n: number, want: string, wanterr: Error | string | null }[] = [
+        { off: 0, n: 10, want: "0123456789", wanterr: null },
+        { off: 1, n: 10, want: "123456789", wanterr: Errors.EOF },
+        { off: 1, n: 9, want: "123456789", wanterr: null },
+        { off: 11, n: 10, want: "", wanterr: Errors.EOF },
+        { off: 0, n: 0, want: "", wanterr: null },
+        { off: -1, n: 0, want: "", wanterr: "io.BlobReader.ReadAt: negative offset" },
+    ]
+    for (let [i, tt] of tests.entries()) {
+        let b = new Uint8Array(tt.n)
+        let [rn, err] = await r.ReadAt(b, tt.off)
+        assert.equal(str(b.subarray(0, rn)), tt.want, `${i}. got ${JSON.stringify(str(b.subarray(0, rn)))}; want ${JSON.stringify(tt.want)}`)
+        let goterr = typeof tt.wanterr == "string" ? err?.message : err
+        assert.equal(goterr, tt.wanterr, `${i}. got error = ${err}; want ${tt.wanterr}`)
+    }
+    // ReadAt does not move the read offset
+    assert.equal(r.Len(), 10)
+})
+
+test("TestBlobReaderLZW", async () => {
+    let text = "TOBEORNOTTOBEORTOBEORNOT".repeat(100)
+    let file = new Blob([compress(text, Order.LSB)])
+    let [got, err] = await AsyncReadAll(new AsyncLZWReader(new BlobReader(file), Order.LSB, 8))
+    assert.equal(err, null)
+    assert.equal(str(got), text)
+})
+
+test("TestStreamReader", async () => {
+    // Empty chunks are skipped instead of being returned as empty reads
+    let r = new StreamReader(streamOf(bytes("hel"), new Uint8Array(0), bytes("lo, world")))
+    let buf = new Uint8Array(4)
+    assert.deepEqual(await r.Read(buf), [3, null])
+    assert.equal(str(buf.subarray(0, 3)), "hel")
+    assert.deepEqual(await r.Read(buf), [4, null])
+    assert.equal(str(buf), "lo, ")
+    assert.deepEqual(await r.Read(new Uint8Array(0)), [0, null])
+    assert.deepEqual(await AsyncReadFull(r, buf), [4, null])
+    assert.equal(str(buf), "worl")
+    assert.deepEqual(await r.Read(buf), [1, null])
+
+    for (let i = 0; i < 2; i++) {
+        let [n, err] = await r.Read(buf)
+        assert.equal(n, 0)
+        assert.equal(err, Errors.EOF)
+    }
+})
+
+test("TestStreamReaderError", async () => {
+    let streamErr = new Error("stream failed")
+    let s = new ReadableStream<Uint8Array>({
+        start(controller) {
+            controller.enqueue(bytes("data"))
+        },
+        pull(controller) {
+            controller.error(streamErr)
+        },
+    })
+    let [got, err] = await AsyncReadAll(new StreamReader(s))
+    assert.equal(err, streamErr)
+    assert.equal(str(got), "data")
+})
+
+test("TestStreamReaderClose", async () => {
+    let cancelled = false
+    let s = new ReadableStream<Uint8Array>({
+        pull(controller) {
+            controller.enqueue(bytes("more"))
+        },
+        cancel() {
+            cancelled = true
+        },
+    })
+    let r = new StreamReader(s)
+    assert.deepEqual(await r.Read(new Uint8Array(2)), [2, null])
+    assert.equal(await r.Close(), null)
+    assert.ok(cancelled, "stream was not cancelled")
+    let [n, err] = await r.Read(new Uint8Array(2))
+    assert.equal(n, 0)
+    assert.equal(err, Errors.ClosedPipe)
+})
+
+test("TestStreamReaderLZW", async () => {
+    let text = "TOBEORNOTTOBEORTOBEORNOT".repeat(100)
+    let c = compress(text, Order.MSB)
+    let chunks: Uint8Array[] = []
+    for (let i = 0; i < c.length; i += 7) {
+        chunks.push(c.subarray(i, i + 7))
+    }
+    let [got, err] = await AsyncReadAll(new AsyncLZWReader(new StreamReader(streamOf(...chunks)), Order.MSB, 8))
+    assert.equal(err, null)
+    assert.equal(str(got), text)
+})
+
+test("TestStreamWriter", async () => {
+    let [s, chunks] = collect()
+    let w = new StreamWriter(s)
+    let p = bytes("hello")
+    assert.deepEqual(await w.Write(p), [5, null])
+    p.fill(0) // The data was copied
+    assert.deepEqual(await AsyncCopy(w, new GoBuffer(bytes(", world"))), [7, null])
+    assert.equal(await w.Close(), null)
+    assert.equal(chunks.map(str).join(""), "hello, world")
+
+    let [n, err] = await w.Write(p)
+    assert.equal(n, 0)
+    assert.equal(err, Errors.ClosedPipe)
+})
+
+test("TestStreamWriterError", async () => {
+    let sinkErr = new Error("sink failed")
+    let s = new WritableStream<Uint8Array>({
+        write() {
+            throw sinkErr
+        },
+    })
+    let w = new StreamWriter(s)
+    // The failed write is reported by its own Write, and then sticks
+    for (let p of ["a", "b"]) {
+        let [n, err] = await w.Write(bytes(p))
+        assert.equal(n, 0)
+        assert.equal(err, sinkErr)
+    }
+    assert.equal(await w.Close(), sinkErr)
+})
+
+test("TestReaderReadableStream", async () => {
+    let data = new Uint8Array(100000).map((_, i) => i)
+    let s = new ReaderReadableStream(new GoBuffer(data.slice()))
+    assert.deepEqual(await AsyncReadAll(new StreamReader(s)), [data, null])
+
+    // Through Response, as a browser would use it
+    let text = "TOBEORNOTTOBEORTOBEORNOT"
+    let r = new AsyncLZWReader(new BlobReader(new Blob([compress(text, Order.LSB)])), Order.LSB, 8)
+    assert.equal(await new Response(new ReaderReadableStream(r)).text(), text)
+})
+
+test("TestReaderReadableStreamError", async () => {
+    let readErr = new Error("read failed")
+    let sent = false
+    let src: AsyncReader = {
+        Read: async (p) => {
+            if (sent) {
+                return [0, readErr]
+            }
+            sent = true
+            p.set(bytes("ok"))
+            return [2, null]
+        },
+    }
+    let [got, err] = await AsyncReadAll(new StreamReader(new ReaderReadableStream(src)))
+    assert.equal(err, readErr)
+    assert.equal(str(got), "ok");
+
+    [, err] = await AsyncReadAll(new StreamReader(new ReaderReadableStream({ Read: async () => [0, null] })))
+    assert.equal(err, Errors.NoProgress)
+})
+
+test("TestReaderReadableStreamCancel", async () => {
+    let closed = false
+    let src = {
+        Read: async (p: Uint8Array): Promise<[number, Error | null]> => {
+            p[0] = 1
+            return [1, null]
+        },
+        Close: () => {
+            closed = true
+            return null
+        },
+    }
+    let r = new StreamReader(new ReaderReadableStream(src))
+    assert.deepEqual(await r.Read(new Uint8Array(1)), [1, null])
+    await r.Close()
+    assert.ok(closed, "reader was not closed")
+})
+
+test("TestWriterWritableStream", async () => {
+    // Closing the stream closes the LZWWriter, which flushes it
+    let text = "TOBEORNOTTOBEORTOBEORNOT".repeat(10)
+    let b = new GoBuffer(new Uint8Array(0))
+    await streamOf(bytes(text.slice(0, 100)), bytes(text.slice(100))).pipeTo(new WriterWritableStream(new LZWWriter(b, Order.LSB, 8)))
+    assert.deepEqual(b.underlyingArray, compress(text, Order.LSB))
+
+    // A short write errors the stream
+    let short = new WriterWritableStream({ Write: (p) => [p.length - 1, null] })
+    await assert.rejects(streamOf(bytes("ab")).pipeTo(short), (err) => err == Errors.ShortWrite)
+})
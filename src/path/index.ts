@@ -0,0 +1,437 @@
+// Package path implements utility routines for manipulating slash-separated
+// paths.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/path/
+//
+// The path package should only be used for paths separated by forward
+// slashes, such as the paths in URLs. This package does not deal with
+// Windows paths with drive letters or backslashes; to manipulate
+// operating system paths, use node:path.
+import * as errors from "../errors"
+
+/**
+ * ErrBadPattern indicates a pattern was malformed.
+ */
+export const ErrBadPattern = errors.New("syntax error in pattern")
+
+/**
+ * Match reports whether name matches the shell pattern.
+ * The pattern syntax is:
+ *
+ *	pattern:
+ *		{ term }
+ *	term:
+ *		'*'         matches any sequence of non-/ characters
+ *		'?'         matches any single non-/ character
+ *		'[' [ '^' ] { character-range } ']'
+ *		            character class (must be non-empty)
+ *		c           matches character c (c != '*', '?', '\\', '[')
+ *		'\\' c      matches character c
+ *
+ *	character-range:
+ *		c           matches character c (c != '\\', '-', ']')
+ *		'\\' c      matches character c
+ *		lo '-' hi   matches character c for lo <= c <= hi
+ *
+ * Match requires pattern to match all of name, not just a substring.
+ * The only possible returned error is [ErrBadPattern], when pattern
+ * is malformed.
+ */
+export function Match(pattern: string, name: string): [boolean, Error | null] {
+    Pattern:
+    while (pattern.length > 0) {
+        let star: boolean
+        let chunk: string;
+        [star, chunk, pattern] = scanChunk(pattern)
+        if (star && chunk == "") {
+            // Trailing * matches rest of string unless it has a /.
+            return [name.indexOf("/") < 0, null]
+        }
+        // Look for match at current position.
+        let [t, ok, err] = matchChunk(chunk, name)
+        // if we're the last chunk, make sure we've exhausted the name
+        // otherwise we'll give a false result even if we could still match
+        // using the star
+        if (ok && (t.length == 0 || pattern.length > 0)) {
+            name = t
+            continue
+        }
+        if (err != null) {
+            return [false, err]
+        }
+        if (star) {
+            // Look for match skipping i+1 bytes.
+            // Cannot skip /.
+            for (let i = 0; i < name.length && name[i] != "/"; i++) {
+                let [t, ok, err] = matchChunk(chunk, name.slice(i + 1))
+                if (ok) {
+                    // if we're the last chunk, make sure we exhausted the name
+                    if (pattern.length == 0 && t.length > 0) {
+                        continue
+                    }
+                    name = t
+                    continue Pattern
+                }
+                if (err != null) {
+                    return [false, err]
+                }
+            }
+        }
+        // Before returning false with no error,
+        // check that the remainder of the pattern is syntactically valid.
+        while (pattern.length > 0) {
+            [, chunk, pattern] = scanChunk(pattern)
+            let [, , err] = matchChunk(chunk, "")
+            if (err != null) {
+                return [false, err]
+            }
+        }
+        return [false, null]
+    }
+    return [name.length == 0, null]
+}
+
+// scanChunk gets the next segment of pattern, which is a non-star string
+// possibly preceded by a star.
+function scanChunk(pattern: string): [boolean, string, string] {
+    let star = false
+    while (pattern.length > 0 && pattern[0] == "*") {
+        pattern = pattern.slice(1)
+        star = true
+    }
+    let inrange = false
+    let i: number
+    Scan:
+    for (i = 0; i < pattern.length; i++) {
+        switch (pattern[i]) {
+            case "\\":
+                // error check handled in matchChunk: bad pattern.
+                if (i + 1 < pattern.length) {
+                    i++
+                }
+                break
+            case "[":
+                inrange = true
+                break
+            case "]":
+                inrange = false
+                break
+            case "*":
+                if (!inrange) {
+                    break Scan
+                }
+                break
+        }
+    }
+    return [star, pattern.slice(0, i), pattern.slice(i)]
+}
+
+// decodeRune returns the code point at the start of s and its length in UTF-16
+// code units, or U+FFFD for a lone surrogate, like utf8.DecodeRuneInString
+function decodeRune(s: string): [number, number] {
+    let r = s.codePointAt(0)!
+    if (r > 0xFFFF) {
+        return [r, 2]
+    }
+    if (r >= 0xD800 && r <= 0xDFFF) {
+        return [0xFFFD, 1]
+    }
+    return [r, 1]
+}
+
+// matchChunk checks whether chunk matches the beginning of s.
+// If so, it returns the remainder of s (after the match).
+// Chunk is all single-character operators: literals, char classes, and ?.
+function matchChunk(chunk: string, s: string): [string, boolean, Error | null] {
+    // failed records whether the match has failed.
+    // After the match fails, the loop continues on processing chunk,
+    // checking that the pattern is well-formed but no longer reading s.
+    let failed = false
+    while (chunk.length > 0) {
+        if (!failed && s.length == 0) {
+            failed = true
+        }
+        switch (chunk[0]) {
+            case "[": {
+                // character class
+                let r = 0
+                if (!failed) {
+                    let n: number;
+                    [r, n] = decodeRune(s)
+                    s = s.slice(n)
+                }
+                chunk = chunk.slice(1)
+                // possibly negated
+                let negated = false
+                if (chunk.length > 0 && chunk[0] == "^") {
+                    negated = true
+                    chunk = chunk.slice(1)
+                }
+                // parse all ranges
+                let match = false
+                let nrange = 0
+                while (true) { // for {}
+                    if (chunk.length > 0 && chunk[0] == "]" && nrange > 0) {
+                        chunk = chunk.slice(1)
+                        break
+                    }
+                    let lo: number, hi: number
+                    let err: Error | null;
+                    [lo, chunk, err] = getEsc(chunk)
+                    if (err != null) {
+                        return ["", false, err]
+                    }
+                    hi = lo
+                    if (chunk[0] == "-") {
+                        [hi, chunk, err] = getEsc(chunk.slice(1))
+                        if (err != null) {
+                            return ["", false, err]
+                        }
+                    }
+                    if (lo <= r && r <= hi) {
+                        match = true
+                    }
+                    nrange++
+                }
+                if (match == negated) {
+                    failed = true
+                }
+                break
+            }
+
+            case "?":
+                if (!failed) {
+                    if (s[0] == "/") {
+                        failed = true
+                    }
+                    let [, n] = decodeRune(s)
+                    s = s.slice(n)
+                }
+                chunk = chunk.slice(1)
+                break
+
+            case "\\":
+                chunk = chunk.slice(1)
+                if (chunk.length == 0) {
+                    return ["", false, ErrBadPattern]
+                }
+            // fallthrough
+
+            default:
+                if (!failed) {
+                    if (chunk[0] != s[0]) {
+                        failed = true
+                    }
+                    s = s.slice(1)
+                }
+                chunk = chunk.slice(1)
+        }
+    }
+    if (failed) {
+        return ["", false, null]
+    }
+    return [s, true, null]
+}
+
+// getEsc gets a possibly-escaped character from chunk, for a character class.
+function getEsc(chunk: string): [number, string, Error | null] {
+    if (chunk.length == 0 || chunk[0] == "-" || chunk[0] == "]") {
+        return [0, chunk, ErrBadPattern]
+    }
+    if (chunk[0] == "\\") {
+        chunk = chunk.slice(1)
+        if (chunk.length == 0) {
+            return [0, chunk, ErrBadPattern]
+        }
+    }
+    let err: Error | null = null
+    let [r, n] = decodeRune(chunk)
+    if (r == 0xFFFD && chunk.charCodeAt(0) != 0xFFFD) {
+        // A lone surrogate, the UTF-16 form of invalid UTF-8
+        err = ErrBadPattern
+    }
+    let nchunk = chunk.slice(n)
+    if (nchunk.length == 0) {
+        err = ErrBadPattern
+    }
+    return [r, nchunk, err]
+}
+
+/**
+ * Clean returns the shortest path name equivalent to path
+ * by purely lexical processing. It applies the following rules
+ * iteratively until no further processing can be done:
+ *
+ *  1. Replace multiple slashes with a single slash.
+ *  2. Eliminate each . path name element (the current directory).
+ *  3. Eliminate each inner .. path name element (the parent directory)
+ *     along with the non-.. element that precedes it.
+ *  4. Eliminate .. elements that begin a rooted path:
+ *     that is, replace "/.." by "/" at the beginning of a path.
+ *
+ * The returned path ends in a slash only if it is the root "/".
+ *
+ * If the result of this process is an empty string, Clean
+ * returns the string ".".
+ *
+ * See also Rob Pike, “Lexical File Names in Plan 9 or
+ * Getting Dot-Dot Right,”
+ * https://9p.io/sys/doc/lexnames.html
+ */
+export function Clean(path: string): string {
+    if (path == "") {
+        return "."
+    }
+
+    let rooted = path[0] == "/"
+    let n = path.length
+
+    // Invariants:
+    //	reading from path; r is index of next byte to process.
+    //	writing to out; w is index of next byte to write.
+    //	dotdot is index in out where .. must stop, either because
+    //		it is the leading slash or it is a leading ../../.. prefix.
+    let out: string[] = []
+    let w = 0
+    let append = (c: string) => {
+        out[w++] = c
+    }
+    let r = 0, dotdot = 0
+    if (rooted) {
+        append("/")
+        r = 1
+        dotdot = 1
+    }
+
+    while (r < n) {
+        if (path[r] == "/") {
+            // empty path element
+            r++
+        } else if (path[r] == "." && (r + 1 == n || path[r + 1] == "/")) {
+            // . element
+            r++
+        } else if (path[r] == "." && path[r + 1] == "." && (r + 2 == n || path[r + 2] == "/")) {
+            // .. element: remove to last /
+            r += 2
+            if (w > dotdot) {
+                // can backtrack
+                w--
+                while (w > dotdot && out[w] != "/") {
+                    w--
+                }
+            } else if (!rooted) {
+                // cannot backtrack, but not rooted, so append .. element.
+                if (w > 0) {
+                    append("/")
+                }
+                append(".")
+                append(".")
+                dotdot = w
+            }
+        } else {
+            // real path element.
+            // add slash if needed
+            if (rooted && w != 1 || !rooted && w != 0) {
+                append("/")
+            }
+            // copy element
+            for (; r < n && path[r] != "/"; r++) {
+                append(path[r])
+            }
+        }
+    }
+
+    // Turn empty string into "."
+    if (w == 0) {
+        return "."
+    }
+    return out.slice(0, w).join("")
+}
+
+/**
+ * Split splits path immediately following the final slash,
+ * separating it into a directory and file name component.
+ * If there is no slash in path, Split returns an empty dir and
+ * file set to path.
+ * The returned values have the property that path = dir+file.
+ */
+export function Split(path: string): [string, string] {
+    let i = path.lastIndexOf("/")
+    return [path.slice(0, i + 1), path.slice(i + 1)]
+}
+
+/**
+ * Join joins any number of path elements into a single path,
+ * separating them with slashes. Empty elements are ignored.
+ * The result is Cleaned. However, if the argument list is
+ * empty or all its elements are empty, Join returns
+ * an empty string.
+ */
+export function Join(...elem: string[]): string {
+    let nonEmpty = elem.filter((e) => e != "")
+    if (nonEmpty.length == 0) {
+        return ""
+    }
+    return Clean(nonEmpty.join("/"))
+}
+
+/**
+ * Ext returns the file name extension used by path.
+ * The extension is the suffix beginning at the final dot
+ * in the final slash-separated element of path;
+ * it is empty if there is no dot.
+ */
+export function Ext(path: string): string {
+    for (let i = path.length - 1; i >= 0 && path[i] != "/"; i--) {
+        if (path[i] == ".") {
+            return path.slice(i)
+        }
+    }
+    return ""
+}
+
+/**
+ * Base returns the last element of path.
+ * Trailing slashes are removed before extracting the last element.
+ * If the path is empty, Base returns ".".
+ * If the path consists entirely of slashes, Base returns "/".
+ */
+export function Base(path: string): string {
+    if (path == "") {
+        return "."
+    }
+    // Strip trailing slashes.
+    while (path.length > 0 && path[path.length - 1] == "/") {
+        path = path.slice(0, path.length - 1)
+    }
+    // Find the last element
+    let i = path.lastIndexOf("/")
+    if (i >= 0) {
+        path = path.slice(i + 1)
+    }
+    // If empty now, it had only slashes.
+    if (path == "") {
+        return "/"
+    }
+    return path
+}
+
+/**
+ * IsAbs reports whether the path is absolute.
+ */
+export function IsAbs(path: string): boolean {
+    return path.length > 0 && path[0] == "/"
+}
+
+/**
+ * Dir returns all but the last element of path, typically the path's directory.
+ * After dropping the final element using [Split], the path is Cleaned and trailing
+ * slashes are removed.
+ * If the path is empty, Dir returns ".".
+ * If the path consists entirely of slashes followed by non-slash bytes, Dir
+ * returns a single slash. In any other case, the returned path does not end in a
+ * slash.
+ */
+export function Dir(path: string): string {
+    let [dir] = Split(path)
+    return Clean(dir)
+}
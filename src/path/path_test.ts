@@ -0,0 +1,245 @@
+// Tests for path
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/path/path_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/path/match_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { Base, Clean, Dir, ErrBadPattern, Ext, IsAbs, Join, Match, Split } from "./index"
+
+type PathTest = [string, string]
+
+const cleantests: PathTest[] = [
+    // Already clean
+    ["", "."],
+    ["abc", "abc"],
+    ["abc/def", "abc/def"],
+    ["a/b/c", "a/b/c"],
+    [".", "."],
+    ["..", ".."],
+    ["../..", "../.."],
+    ["../../abc", "../../abc"],
+    ["/abc", "/abc"],
+    ["/", "/"],
+
+    // Remove trailing slash
+    ["abc/", "abc"],
+    ["abc/def/", "abc/def"],
+    ["a/b/c/", "a/b/c"],
+    ["./", "."],
+    ["../", ".."],
+    ["../../", "../.."],
+    ["/abc/", "/abc"],
+
+    // Remove doubled slash
+    ["abc//def//ghi", "abc/def/ghi"],
+    ["//abc", "/abc"],
+    ["///abc", "/abc"],
+    ["//abc//", "/abc"],
+    ["abc//", "abc"],
+
+    // Remove . elements
+    ["abc/./def", "abc/def"],
+    ["/./abc/def", "/abc/def"],
+    ["abc/.", "abc"],
+
+    // Remove .. elements
+    ["abc/def/ghi/../jkl", "abc/def/jkl"],
+    ["abc/def/../ghi/../jkl", "abc/jkl"],
+    ["abc/def/..", "abc"],
+    ["abc/def/../..", "."],
+    ["/abc/def/../..", "/"],
+    ["abc/def/../../..", ".."],
+    ["/abc/def/../../..", "/"],
+    ["abc/def/../../../ghi/jkl/../../../mno", "../../mno"],
+
+    // Combinations
+    ["abc/./../def", "def"],
+    ["abc//./../def", "def"],
+    ["abc/../../././../def", "../../def"],
+]
+
+test("TestClean", () => {
+    for (let [path, result] of cleantests) {
+        let s = Clean(path)
+        assert.equal(s, result, `Clean(${JSON.stringify(path)}) = ${JSON.stringify(s)}, want ${JSON.stringify(result)}`)
+        s = Clean(result)
+        assert.equal(s, result, `Clean(${JSON.stringify(result)}) = ${JSON.stringify(s)}, want ${JSON.stringify(result)}`)
+    }
+})
+
+test("TestSplit", () => {
+    let splittests: [string, string, string][] = [
+        ["a/b", "a/", "b"],
+        ["a/b/", "a/b/", ""],
+        ["a/", "a/", ""],
+        ["a", "", "a"],
+        ["/", "/", ""],
+    ]
+    for (let [path, dir, file] of splittests) {
+        let [d, f] = Split(path)
+        assert.ok(d == dir && f == file, `Split(${JSON.stringify(path)}) = ${JSON.stringify(d)}, ${JSON.stringify(f)}, want ${JSON.stringify(dir)}, ${JSON.stringify(file)}`)
+    }
+})
+
+test("TestJoin", () => {
+    let jointests: [string[], string][] = [
+        // zero parameters
+        [[], ""],
+
+        // one parameter
+        [[""], ""],
+        [["a"], "a"],
+
+        // two parameters
+        [["a", "b"], "a/b"],
+        [["a", ""], "a"],
+        [["", "b"], "b"],
+        [["/", "a"], "/a"],
+        [["/", ""], "/"],
+        [["a/", "b"], "a/b"],
+        [["a/", ""], "a"],
+        [["", ""], ""],
+    ]
+    for (let [elem, path] of jointests) {
+        let p = Join(...elem)
+        assert.equal(p, path, `Join(${JSON.stringify(elem)}) = ${JSON.stringify(p)}, want ${JSON.stringify(path)}`)
+    }
+})
+
+test("TestExt", () => {
+    let exttests: PathTest[] = [
+        ["path.go", ".go"],
+        ["path.pb.go", ".go"],
+        ["a.dir/b", ""],
+        ["a.dir/b.go", ".go"],
+        ["a.dir/", ""],
+    ]
+    for (let [path, ext] of exttests) {
+        let x = Ext(path)
+        assert.equal(x, ext, `Ext(${JSON.stringify(path)}) = ${JSON.stringify(x)}, want ${JSON.stringify(ext)}`)
+    }
+})
+
+test("TestBase", () => {
+    let basetests: PathTest[] = [
+        // Already clean
+        ["", "."],
+        [".", "."],
+        ["/.", "."],
+        ["/", "/"],
+        ["////", "/"],
+        ["x/", "x"],
+        ["abc", "abc"],
+        ["abc/def", "def"],
+        ["a/b/.x", ".x"],
+        ["a/b/c.", "c."],
+        ["a/b/c.x", "c.x"],
+    ]
+    for (let [path, result] of basetests) {
+        let s = Base(path)
+        assert.equal(s, result, `Base(${JSON.stringify(path)}) = ${JSON.stringify(s)}, want ${JSON.stringify(result)}`)
+    }
+})
+
+test("TestDir", () => {
+    let dirtests: PathTest[] = [
+        ["", "."],
+        [".", "."],
+        ["/.", "/"],
+        ["/", "/"],
+        ["////", "/"],
+        ["/foo", "/"],
+        ["x/", "x"],
+        ["abc", "."],
+        ["abc/def", "abc"],
+        ["abc////def", "abc"],
+        ["a/b/.x", "a/b"],
+        ["a/b/c.", "a/b"],
+        ["a/b/c.x", "a/b"],
+    ]
+    for (let [path, result] of dirtests) {
+        let s = Dir(path)
+        assert.equal(s, result, `Dir(${JSON.stringify(path)}) = ${JSON.stringify(s)}, want ${JSON.stringify(result)}`)
+    }
+})
+
+test("TestIsAbs", () => {
+    let isAbsTests: [string, boolean][] = [
+        ["", false],
+        ["/", true],
+        ["/usr/bin/gcc", true],
+        ["..", false],
+        ["/a/../bb", true],
+        [".", false],
+        ["./", false],
+        ["lala", false],
+    ]
+    for (let [path, isAbs] of isAbsTests) {
+        let r = IsAbs(path)
+        assert.equal(r, isAbs, `IsAbs(${JSON.stringify(path)}) = ${r}, want ${isAbs}`)
+    }
+})
+
+test("TestMatch", () => {
+    let matchTests: [string, string, boolean, Error | null][] = [
+        ["abc", "abc", true, null],
+        ["*", "abc", true, null],
+        ["*c", "abc", true, null],
+        ["a*", "a", true, null],
+        ["a*", "abc", true, null],
+        ["a*", "ab/c", false, null],
+        ["a*/b", "abc/b", true, null],
+        ["a*/b", "a/c/b", false, null],
+        ["a*b*c*d*e*/f", "axbxcxdxe/f", true, null],
+        ["a*b*c*d*e*/f", "axbxcxdxexxx/f", true, null],
+        ["a*b*c*d*e*/f", "axbxcxdxe/xxx/f", false, null],
+        ["a*b*c*d*e*/f", "axbxcxdxexxx/fff", false, null],
+        ["a*b?c*x", "abxbbxdbxebxczzx", true, null],
+        ["a*b?c*x", "abxbbxdbxebxczzy", false, null],
+        ["ab[c]", "abc", true, null],
+        ["ab[b-d]", "abc", true, null],
+        ["ab[e-g]", "abc", false, null],
+        ["ab[^c]", "abc", false, null],
+        ["ab[^b-d]", "abc", false, null],
+        ["ab[^e-g]", "abc", true, null],
+        ["a\\*b", "a*b", true, null],
+        ["a\\*b", "ab", false, null],
+        ["a?b", "a☺b", true, null],
+        ["a[^a]b", "a☺b", true, null],
+        ["a???b", "a☺b", false, null],
+        ["a[^a][^a][^a]b", "a☺b", false, null],
+        ["[a-ζ]*", "α", true, null],
+        ["*[a-ζ]", "A", false, null],
+        ["a?b", "a/b", false, null],
+        ["a*b", "a/b", false, null],
+        ["[\\]a]", "]", true, null],
+        ["[\\-]", "-", true, null],
+        ["[x\\-]", "x", true, null],
+        ["[x\\-]", "-", true, null],
+        ["[x\\-]", "z", false, null],
+        ["[\\-x]", "x", true, null],
+        ["[\\-x]", "-", true, null],
+        ["[\\-x]", "a", false, null],
+        ["[]a]", "]", false, ErrBadPattern],
+        ["[-]", "-", false, ErrBadPattern],
+        ["[x-]", "x", false, ErrBadPattern],
+        ["[x-]", "-", false, ErrBadPattern],
+        ["[x-]", "z", false, ErrBadPattern],
+        ["[-x]", "x", false, ErrBadPattern],
+        ["[-x]", "-", false, ErrBadPattern],
+        ["[-x]", "a", false, ErrBadPattern],
+        ["\\", "a", false, ErrBadPattern],
+        ["[a-b-c]", "a", false, ErrBadPattern],
+        ["[", "a", false, ErrBadPattern],
+        ["[^", "a", false, ErrBadPattern],
+        ["[^bc", "a", false, ErrBadPattern],
+        ["a[", "a", false, ErrBadPattern],
+        ["a[", "ab", false, ErrBadPattern],
+        ["a[", "x", false, ErrBadPattern],
+        ["a/b[", "x", false, ErrBadPattern],
+        ["*x", "xxx", true, null],
+    ]
+    for (let [pattern, s, match, err] of matchTests) {
+        let [ok, e] = Match(pattern, s)
+        assert.ok(ok == match && e == err, `Match(${JSON.stringify(pattern)}, ${JSON.stringify(s)}) = ${ok}, ${e} want ${match}, ${err}`)
+    }
+})
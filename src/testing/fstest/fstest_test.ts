@@ -0,0 +1,123 @@
+// Tests for testing/fstest
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/fstest/mapfs_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/fstest/testfs_test.go
+import * as assert from "node:assert/strict"
+import * as nodefs from "node:fs"
+import * as os from "node:os"
+import * as nodepath from "node:path"
+import { test } from "node:test"
+import { bytes } from "../../builtins/tshelpers/strings"
+import { File, FileMode, FS, ModeDir, WalkDir } from "../../io/fs"
+import { DirFS } from "../../io/fs/node"
+import { Clean } from "../../path"
+import { MapFS, TestFS } from "./index"
+
+test("TestMapFS", () => {
+    let m = new MapFS({
+        "hello": { Data: bytes("hello, world\n") },
+        "fortune/k/ken.txt": { Data: bytes("If a program is too slow, it must have a loop.\n") },
+    })
+    assert.equal(TestFS(m, "hello", "fortune", "fortune/k", "fortune/k/ken.txt"), null)
+})
+
+test("TestMapFSChmodDot", () => {
+    let m = new MapFS({
+        "a/b.txt": { Mode: 0o666 },
+        ".": { Mode: 0o777 | ModeDir },
+    })
+    let buf = ""
+    WalkDir(m, ".", (path, d, err) => {
+        let [fi, ierr] = d!.Info()
+        if (ierr != null) {
+            return ierr
+        }
+        buf += `${path}: ${FileMode.String(fi!.Mode())}\n`
+        return null
+    })
+    let want = `
+.: drwxrwxrwx
+a: dr-xr-xr-x
+a/b.txt: -rw-rw-rw-
+`.slice(1)
+    assert.equal(buf, want)
+})
+
+test("TestSymlink", () => {
+    let tmp = nodefs.mkdtempSync(nodepath.join(os.tmpdir(), "fstest-"))
+    try {
+        let tmpfs = DirFS(tmp)
+
+        nodefs.writeFileSync(nodepath.join(tmp, "hello"), "hello, world\n", { mode: 0o644 })
+        try {
+            nodefs.symlinkSync(nodepath.join(tmp, "hello"), nodepath.join(tmp, "hello.link"))
+        } catch (e) {
+            // Symlinks may need privileges, on Windows
+            return
+        }
+
+        assert.equal(TestFS(tmpfs, "hello", "hello.link"), null)
+    } finally {
+        nodefs.rmSync(tmp, { recursive: true })
+    }
+})
+
+test("TestDash", () => {
+    let m = new MapFS({
+        "a-b/a": { Data: bytes("a-b/a") },
+    })
+    assert.equal(TestFS(m, "a-b/a"), null)
+})
+
+// Not present in the Go code
+test("TestMapFSEdit", () => {
+    let m = new MapFS()
+    assert.equal(TestFS(m), null)
+
+    // Changes to the map show in the file system
+    m.set("x/y", { Data: bytes("y") })
+    assert.equal(TestFS(m, "x/y"), null)
+    m.delete("x/y")
+    let [, err] = m.Open("x")
+    assert.ok(err != null)
+})
+
+// brokenFS is a MapFS whose Open cleans names instead of rejecting invalid ones.
+class brokenFS implements FS {
+    private m: MapFS
+
+    constructor(m: MapFS) {
+        this.m = m
+    }
+
+    Open(name: string): [File | null, Error | null] {
+        return this.m.Open(Clean(name).replace(/^\//, "") || ".")
+    }
+}
+
+// Not present in the Go code
+test("TestTestFSErrors", () => {
+    let m = new MapFS({
+        "hello": { Data: bytes("hello, world\n") },
+    })
+    let err = TestFS(m, "hello", "goodbye")
+    assert.ok(err != null)
+    assert.equal(err.message, "TestFS found errors:\nexpected but not found: goodbye")
+
+    err = TestFS(m)
+    assert.ok(err != null)
+    assert.equal(err.message, "TestFS found errors:\nexpected empty file system but found files:\nhello")
+
+    let broken = new brokenFS(new MapFS({
+        "hello": { Data: bytes("hello, world\n") },
+    }))
+    err = TestFS(broken, "hello")
+    assert.ok(err != null)
+    assert.equal(err.message, [
+        "TestFS found errors:",
+        "hello: Open(/hello) succeeded, want error",
+        "hello: Open(hello/.) succeeded, want error",
+        ".: Open(/.) succeeded, want error",
+        ".: Open(./.) succeeded, want error",
+        ".: Open(/) succeeded, want error",
+    ].join("\n"))
+})
@@ -0,0 +1,345 @@
+// Package fstest implements support for testing implementations and users of file systems.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/fstest/
+import { EOF } from "../../io"
+import * as fs from "../../io/fs"
+import * as path from "../../path"
+
+/**
+ * A MapFS is a simple in-memory file system for use in tests,
+ * represented as a map from path names (arguments to Open)
+ * to information about the files or directories they represent.
+ *
+ * The map need not include parent directories for files contained
+ * in the map; those will be synthesized if needed.
+ * But a directory can still be included by setting the MapFile.Mode's [fs.ModeDir] bit;
+ * this may be necessary for detailed control over the directory's [fs.FileInfo]
+ * or to create an empty directory.
+ *
+ * File system operations read directly from the map,
+ * so that the file system can be changed by editing the map as needed.
+ * An implication is that file system operations must not run concurrently
+ * with changes to the map, which would be a race.
+ * Another implication is that opening or reading a directory requires
+ * iterating over the entire map, so a MapFS should typically be used with not more
+ * than a few hundred entries or directory reads.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * MapFS is a Map, which can also be created from a record:
+ *
+ * ```ts
+ * let fsys = new MapFS({ "hello.txt": { Data: new TextEncoder().encode("hello, world\n") } })
+ * ```
+ */
+export class MapFS extends Map<string, MapFile> implements fs.ReadFileFS, fs.StatFS, fs.ReadDirFS, fs.GlobFS, fs.SubFS {
+    constructor(files?: Record<string, MapFile> | Iterable<readonly [string, MapFile]>) {
+        super(files == null || Symbol.iterator in files ? (files as Iterable<readonly [string, MapFile]>) : Object.entries(files))
+    }
+
+    Open(name: string): [fs.File | null, Error | null] {
+        if (!fs.ValidPath(name)) {
+            return [null, new fs.PathError("open", name, fs.ErrNotExist)]
+        }
+        let file = this.get(name)
+        if (file != null && ((file.Mode ?? 0) & fs.ModeDir) == 0) {
+            // Ordinary file
+            return [new openMapFile(name, new mapFileInfo(path.Base(name), file)), null]
+        }
+
+        // Directory, possibly synthesized.
+        // Note that file can be undefined here: the map need not contain explicit parent directories
+        // for all its files. But file can also be set, in case the user wants to set metadata
+        // for the directory explicitly. Either way, we need to construct the list of children
+        // of this directory.
+        let list: mapFileInfo[] = []
+        let elem: string
+        let need = new Set<string>()
+        if (name == ".") {
+            elem = "."
+            for (let [fname, f] of this) {
+                let i = fname.indexOf("/")
+                if (i < 0) {
+                    if (fname != ".") {
+                        list.push(new mapFileInfo(fname, f))
+                    }
+                } else {
+                    need.add(fname.slice(0, i))
+                }
+            }
+        } else {
+            elem = name.slice(name.lastIndexOf("/") + 1)
+            let prefix = name + "/"
+            for (let [fname, f] of this) {
+                if (fname.startsWith(prefix)) {
+                    let felem = fname.slice(prefix.length)
+                    let i = felem.indexOf("/")
+                    if (i < 0) {
+                        list.push(new mapFileInfo(felem, f))
+                    } else {
+                        need.add(fname.slice(prefix.length, prefix.length + i))
+                    }
+                }
+            }
+            // If the directory name is not in the map,
+            // and there are no children of the name in the map,
+            // then the directory is treated as not existing.
+            if (file == null && list.length == 0 && need.size == 0) {
+                return [null, new fs.PathError("open", name, fs.ErrNotExist)]
+            }
+        }
+        for (let fi of list) {
+            need.delete(fi.Name())
+        }
+        for (let name of need) {
+            list.push(new mapFileInfo(name, { Mode: (fs.ModeDir | 0o555) >>> 0 }))
+        }
+        list.sort((a, b) => (a.Name() < b.Name() ? -1 : a.Name() > b.Name() ? 1 : 0))
+
+        if (file == null) {
+            file = { Mode: (fs.ModeDir | 0o555) >>> 0 }
+        }
+        return [new mapDir(name, new mapFileInfo(elem, file), list), null]
+    }
+
+    ReadFile(name: string): [Uint8Array, Error | null] {
+        return fs.ReadFile(new fsOnly(this), name)
+    }
+
+    Stat(name: string): [fs.FileInfo | null, Error | null] {
+        return fs.Stat(new fsOnly(this), name)
+    }
+
+    ReadDir(name: string): [fs.DirEntry[], Error | null] {
+        return fs.ReadDir(new fsOnly(this), name)
+    }
+
+    Glob(pattern: string): [string[], Error | null] {
+        return fs.Glob(new fsOnly(this), pattern)
+    }
+
+    Sub(dir: string): [fs.FS | null, Error | null] {
+        return fs.Sub(new noSub(this), dir)
+    }
+}
+
+/**
+ * A MapFile describes a single file in a [MapFS].
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * All fields are optional. A missing field reads as Go's zero value, except
+ * ModTime, which reads as the Unix epoch
+ */
+export interface MapFile {
+    Data?: Uint8Array // file content
+    Mode?: fs.FileMode // fs.FileInfo.Mode
+    ModTime?: Date // fs.FileInfo.ModTime
+    Sys?: unknown // fs.FileInfo.Sys
+}
+
+// fsOnly is a wrapper that hides all but the fs.FS methods,
+// to avoid an infinite recursion when implementing special
+// methods in terms of helpers that would use them.
+// (In general, implementing these methods using the package fs helpers
+// is redundant and unnecessary, but having the methods may make
+// MapFS exercise more code paths when used in tests.)
+class fsOnly implements fs.FS {
+    private fsys: fs.FS
+
+    constructor(fsys: fs.FS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [fs.File | null, Error | null] {
+        return this.fsys.Open(name)
+    }
+}
+
+// noSub is a MapFS without the Sub method, so that fs.Sub does not call
+// back into MapFS.Sub.
+class noSub implements fs.ReadFileFS, fs.StatFS, fs.ReadDirFS, fs.GlobFS {
+    private fsys: MapFS
+
+    constructor(fsys: MapFS) {
+        this.fsys = fsys
+    }
+
+    Open(name: string): [fs.File | null, Error | null] {
+        return this.fsys.Open(name)
+    }
+
+    ReadFile(name: string): [Uint8Array, Error | null] {
+        return this.fsys.ReadFile(name)
+    }
+
+    Stat(name: string): [fs.FileInfo | null, Error | null] {
+        return this.fsys.Stat(name)
+    }
+
+    ReadDir(name: string): [fs.DirEntry[], Error | null] {
+        return this.fsys.ReadDir(name)
+    }
+
+    Glob(pattern: string): [string[], Error | null] {
+        return this.fsys.Glob(pattern)
+    }
+}
+
+// A mapFileInfo implements fs.FileInfo and fs.DirEntry for a given map file.
+class mapFileInfo implements fs.FileInfo, fs.DirEntry {
+    private name: string
+    f: MapFile
+
+    constructor(name: string, f: MapFile) {
+        this.name = name
+        this.f = f
+    }
+
+    Name(): string {
+        return this.name
+    }
+
+    Size(): number {
+        return this.f.Data?.length ?? 0
+    }
+
+    Mode(): fs.FileMode {
+        return (this.f.Mode ?? 0) >>> 0
+    }
+
+    Type(): fs.FileMode {
+        return fs.FileMode.Type(this.Mode())
+    }
+
+    ModTime(): Date {
+        return this.f.ModTime ?? new Date(0)
+    }
+
+    IsDir(): boolean {
+        return (this.Mode() & fs.ModeDir) != 0
+    }
+
+    Sys(): unknown {
+        return this.f.Sys ?? null
+    }
+
+    Info(): [fs.FileInfo | null, Error | null] {
+        return [this, null]
+    }
+
+    String(): string {
+        return fs.FormatFileInfo(this)
+    }
+}
+
+// An openMapFile is a regular (non-directory) fs.File open for reading.
+class openMapFile implements fs.File {
+    private path: string
+    private info: mapFileInfo
+    private offset = 0
+
+    constructor(path: string, info: mapFileInfo) {
+        this.path = path
+        this.info = info
+    }
+
+    private get data(): Uint8Array {
+        return this.info.f.Data ?? new Uint8Array(0)
+    }
+
+    Stat(): [fs.FileInfo | null, Error | null] {
+        return [this.info, null]
+    }
+
+    Close(): Error | null {
+        return null
+    }
+
+    Read(b: Uint8Array): [number, Error | null] {
+        let data = this.data
+        if (this.offset >= data.length) {
+            return [0, EOF]
+        }
+        if (this.offset < 0) {
+            return [0, new fs.PathError("read", this.path, fs.ErrInvalid)]
+        }
+        let n = Math.min(b.length, data.length - this.offset)
+        b.set(data.subarray(this.offset, this.offset + n))
+        this.offset += n
+        return [n, null]
+    }
+
+    Seek(offset: number, whence: number): [number, Error | null] {
+        let data = this.data
+        switch (whence) {
+            case 0:
+                // offset += 0
+                break
+            case 1:
+                offset += this.offset
+                break
+            case 2:
+                offset += data.length
+                break
+        }
+        if (offset < 0 || offset > data.length) {
+            return [0, new fs.PathError("seek", this.path, fs.ErrInvalid)]
+        }
+        this.offset = offset
+        return [offset, null]
+    }
+
+    ReadAt(b: Uint8Array, offset: number): [number, Error | null] {
+        let data = this.data
+        if (offset < 0 || offset > data.length) {
+            return [0, new fs.PathError("read", this.path, fs.ErrInvalid)]
+        }
+        let n = Math.min(b.length, data.length - offset)
+        b.set(data.subarray(offset, offset + n))
+        if (n < b.length) {
+            return [n, EOF]
+        }
+        return [n, null]
+    }
+}
+
+// A mapDir is a directory fs.File (so also an fs.ReadDirFile) open for reading.
+class mapDir implements fs.ReadDirFile {
+    private path: string
+    private info: mapFileInfo
+    private entry: mapFileInfo[]
+    private offset = 0
+
+    constructor(path: string, info: mapFileInfo, entry: mapFileInfo[]) {
+        this.path = path
+        this.info = info
+        this.entry = entry
+    }
+
+    Stat(): [fs.FileInfo | null, Error | null] {
+        return [this.info, null]
+    }
+
+    Close(): Error | null {
+        return null
+    }
+
+    Read(b: Uint8Array): [number, Error | null] {
+        return [0, new fs.PathError("read", this.path, fs.ErrInvalid)]
+    }
+
+    ReadDir(count: number): [fs.DirEntry[], Error | null] {
+        let n = this.entry.length - this.offset
+        if (n == 0 && count > 0) {
+            return [[], EOF]
+        }
+        if (count > 0 && n > count) {
+            n = count
+        }
+        let list = this.entry.slice(this.offset, this.offset + n)
+        this.offset += n
+        return [list, null]
+    }
+}
+
+export * from "./testfs"
@@ -0,0 +1,650 @@
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/fstest/testfs.go
+import * as errors from "../../errors"
+import { EOF, ReadAll } from "../../io"
+import * as fs from "../../io/fs"
+import * as path from "../../path"
+import * as iotest from "../iotest"
+
+/**
+ * TestFS tests a file system implementation.
+ * It walks the entire tree of files in fsys,
+ * opening and checking that each file behaves correctly.
+ * It also checks that the file system contains at least the expected files.
+ * As a special case, if no expected files are listed, fsys must be empty.
+ * Otherwise, fsys must contain at least the listed files; it can also contain others.
+ * The contents of fsys must not change concurrently with TestFS.
+ *
+ * If TestFS finds any misbehaviors, it returns an error reporting all of them.
+ * The error text spans multiple lines, one per detected misbehavior.
+ *
+ * Typical usage inside a test is:
+ *
+ * ```ts
+ * let err = fstest.TestFS(myFS, "file/that/should/be/present")
+ * assert.equal(err, null)
+ * ```
+ */
+export function TestFS(fsys: fs.FS, ...expected: string[]): Error | null {
+    let err = testFS(fsys, ...expected)
+    if (err != null) {
+        return err
+    }
+    for (let name of expected) {
+        let i = name.indexOf("/")
+        if (i >= 0) {
+            let dir = name.slice(0, i), dirSlash = name.slice(0, i + 1)
+            let subExpected: string[] = []
+            for (let name of expected) {
+                if (name.startsWith(dirSlash)) {
+                    subExpected.push(name.slice(dirSlash.length))
+                }
+            }
+            let [sub, err] = fs.Sub(fsys, dir)
+            if (err != null) {
+                return err
+            }
+            err = testFS(sub!, ...subExpected)
+            if (err != null) {
+                return errors.Errorf("testing fs.Sub(fsys, %s): %v", dir, err)
+            }
+            break // one sub-test is enough
+        }
+    }
+    return null
+}
+
+function testFS(fsys: fs.FS, ...expected: string[]): Error | null {
+    let t = new fsTester(fsys)
+    t.checkDir(".")
+    t.checkOpen(".")
+    let found = new Set<string>()
+    for (let dir of t.dirs) {
+        found.add(dir)
+    }
+    for (let file of t.files) {
+        found.add(file)
+    }
+    found.delete(".")
+    if (expected.length == 0 && found.size > 0) {
+        let list: string[] = []
+        for (let k of found) {
+            if (k != ".") {
+                list.push(k)
+            }
+        }
+        list.sort()
+        if (list.length > 15) {
+            list = [...list.slice(0, 10), "..."]
+        }
+        t.errorf("expected empty file system but found files:\n%s", list.join("\n"))
+    }
+    for (let name of expected) {
+        if (!found.has(name)) {
+            t.errorf("expected but not found: %s", name)
+        }
+    }
+    if (t.errText.length == 0) {
+        return null
+    }
+    return errors.New("TestFS found errors:\n" + t.errText)
+}
+
+// An fsTester holds state for running the test.
+class fsTester {
+    fsys: fs.FS
+    errText = ""
+    dirs: string[] = []
+    files: string[] = []
+
+    constructor(fsys: fs.FS) {
+        this.fsys = fsys
+    }
+
+    // errorf adds an error line to errText.
+    errorf(format: string, ...args: unknown[]) {
+        if (this.errText.length > 0) {
+            this.errText += "\n"
+        }
+        this.errText += errors.Errorf(format, ...args).message
+    }
+
+    openDir(dir: string): fs.ReadDirFile | null {
+        let [f, err] = this.fsys.Open(dir)
+        if (err != null) {
+            this.errorf("%s: Open: %v", dir, err)
+            return null
+        }
+        if (!fs.isReadDirFile(f)) {
+            f!.Close()
+            this.errorf("%s: Open returned File type %s, not a fs.ReadDirFile", dir, f!.constructor.name)
+            return null
+        }
+        return f
+    }
+
+    // checkDir checks the directory dir, which is expected to exist
+    // (it is either the root or was found in a directory listing with IsDir true).
+    checkDir(dir: string) {
+        // Read entire directory.
+        this.dirs.push(dir)
+        let d = this.openDir(dir)
+        if (d == null) {
+            return
+        }
+        let [list, err] = d.ReadDir(-1)
+        if (err != null) {
+            d.Close()
+            this.errorf("%s: ReadDir(-1): %v", dir, err)
+            return
+        }
+
+        // Check all children.
+        let prefix: string
+        if (dir == ".") {
+            prefix = ""
+        } else {
+            prefix = dir + "/"
+        }
+        for (let info of list) {
+            let name = info.Name()
+            if (name == "." || name == ".." || name == "") {
+                this.errorf("%s: ReadDir: child has invalid name: %q", dir, name)
+                continue
+            } else if (name.includes("/")) {
+                this.errorf("%s: ReadDir: child name contains slash: %q", dir, name)
+                continue
+            } else if (name.includes("\\")) {
+                this.errorf("%s: ReadDir: child name contains backslash: %q", dir, name)
+                continue
+            }
+            let path = prefix + name
+            this.checkStat(path, info)
+            this.checkOpen(path)
+            if (info.IsDir()) {
+                this.checkDir(path)
+            } else {
+                this.checkFile(path)
+            }
+        }
+
+        // Check ReadDir(-1) at EOF.
+        let list2: fs.DirEntry[];
+        [list2, err] = d.ReadDir(-1)
+        if (list2.length > 0 || err != null) {
+            d.Close()
+            this.errorf("%s: ReadDir(-1) at EOF = %d entries, %v, wanted 0 entries, nil", dir, list2.length, err)
+            return
+        }
+
+        // Check ReadDir(1) at EOF (different results).
+        [list2, err] = d.ReadDir(1)
+        if (list2.length > 0 || err != EOF) {
+            d.Close()
+            this.errorf("%s: ReadDir(1) at EOF = %d entries, %v, wanted 0 entries, EOF", dir, list2.length, err)
+            return
+        }
+
+        // Check that close does not report an error.
+        err = d.Close()
+        if (err != null) {
+            this.errorf("%s: Close: %v", dir, err)
+        }
+
+        // Check that closing twice doesn't crash.
+        // The return value doesn't matter.
+        d.Close()
+
+        // Reopen directory, read a second time, make sure contents match.
+        d = this.openDir(dir)
+        if (d == null) {
+            return
+        }
+        try {
+            [list2, err] = d.ReadDir(-1)
+            if (err != null) {
+                this.errorf("%s: second Open+ReadDir(-1): %v", dir, err)
+                return
+            }
+            this.checkDirList(dir, "first Open+ReadDir(-1) vs second Open+ReadDir(-1)", list, list2)
+        } finally {
+            d.Close()
+        }
+
+        // Reopen directory, read a third time in pieces, make sure contents match.
+        d = this.openDir(dir)
+        if (d == null) {
+            return
+        }
+        try {
+            list2 = []
+            while (true) { // for {}
+                let n = 1
+                if (list2.length > 0) {
+                    n = 2
+                }
+                let [frag, err] = d.ReadDir(n)
+                if (frag.length > n) {
+                    this.errorf("%s: third Open: ReadDir(%d) after %d: %d entries (too many)", dir, n, list2.length, frag.length)
+                    return
+                }
+                list2.push(...frag)
+                if (err == EOF) {
+                    break
+                }
+                if (err != null) {
+                    this.errorf("%s: third Open: ReadDir(%d) after %d: %v", dir, n, list2.length, err)
+                    return
+                }
+                if (n == 0) {
+                    this.errorf("%s: third Open: ReadDir(%d) after %d: 0 entries but nil error", dir, n, list2.length)
+                    return
+                }
+            }
+            this.checkDirList(dir, "first Open+ReadDir(-1) vs third Open+ReadDir(1,2) loop", list, list2)
+        } finally {
+            d.Close()
+        }
+
+        // If fsys has ReadDir, check that it matches and is sorted.
+        if (fs.isReadDirFS(this.fsys)) {
+            let [list2, err] = this.fsys.ReadDir(dir)
+            if (err != null) {
+                this.errorf("%s: fsys.ReadDir: %v", dir, err)
+                return
+            }
+            this.checkDirList(dir, "first Open+ReadDir(-1) vs fsys.ReadDir", list, list2)
+
+            for (let i = 0; i + 1 < list2.length; i++) {
+                if (list2[i].Name() >= list2[i + 1].Name()) {
+                    this.errorf("%s: fsys.ReadDir: list not sorted: %s before %s", dir, list2[i].Name(), list2[i + 1].Name())
+                }
+            }
+        }
+
+        // Check fs.ReadDir as well.
+        [list2, err] = fs.ReadDir(this.fsys, dir)
+        if (err != null) {
+            this.errorf("%s: fs.ReadDir: %v", dir, err)
+            return
+        }
+        this.checkDirList(dir, "first Open+ReadDir(-1) vs fs.ReadDir", list, list2)
+
+        for (let i = 0; i + 1 < list2.length; i++) {
+            if (list2[i].Name() >= list2[i + 1].Name()) {
+                this.errorf("%s: fs.ReadDir: list not sorted: %s before %s", dir, list2[i].Name(), list2[i + 1].Name())
+            }
+        }
+
+        this.checkGlob(dir, list2)
+    }
+
+    // checkGlob checks that various glob patterns work if the file system implements GlobFS.
+    checkGlob(dir: string, list: fs.DirEntry[]) {
+        if (!fs.isGlobFS(this.fsys)) {
+            return
+        }
+
+        // Make a complex glob pattern prefix that only matches dir.
+        let glob = ""
+        if (dir != ".") {
+            let elem = dir.split("/")
+            for (let i = 0; i < elem.length; i++) {
+                let pattern = ""
+                let j = 0
+                for (let r of elem[i]) {
+                    if (r == "*" || r == "?" || r == "\\" || r == "[" || r == "-") {
+                        pattern += "\\" + r
+                    } else {
+                        switch ((i + j) % 5) {
+                            case 0:
+                                pattern += r
+                                break
+                            case 1:
+                                pattern += "[" + r + "]"
+                                break
+                            case 2:
+                                pattern += "[" + r + "-" + r + "]"
+                                break
+                            case 3:
+                                pattern += "[\\" + r + "]"
+                                break
+                            case 4:
+                                pattern += "[\\" + r + "-\\" + r + "]"
+                                break
+                        }
+                    }
+                    // j is a byte index in Go
+                    j += new TextEncoder().encode(r).length
+                }
+                elem[i] = pattern
+            }
+            glob = elem.join("/") + "/"
+        }
+
+        // Test that malformed patterns are detected.
+        // The error is likely path.ErrBadPattern but need not be.
+        let [, err] = this.fsys.Glob(glob + "nonexist/[]")
+        if (err == null) {
+            this.errorf("%s: Glob(%q): bad pattern not detected", dir, glob + "nonexist/[]")
+        }
+
+        // Try to find a letter that appears in only some of the final names.
+        let c = "a".charCodeAt(0)
+        for (; c <= "z".charCodeAt(0); c++) {
+            let have = false, haveNot = false
+            for (let d of list) {
+                if (d.Name().includes(String.fromCharCode(c))) {
+                    have = true
+                } else {
+                    haveNot = true
+                }
+            }
+            if (have && haveNot) {
+                break
+            }
+        }
+        if (c > "z".charCodeAt(0)) {
+            c = "a".charCodeAt(0)
+        }
+        let letter = String.fromCharCode(c)
+        glob += "*" + letter + "*"
+
+        let want: string[] = []
+        for (let d of list) {
+            if (d.Name().includes(letter)) {
+                want.push(path.Join(dir, d.Name()))
+            }
+        }
+
+        let names: string[];
+        [names, err] = this.fsys.Glob(glob)
+        if (err != null) {
+            this.errorf("%s: Glob(%q): %v", dir, glob, err)
+            return
+        }
+        if (want.length == names.length && want.every((w, i) => w == names[i])) {
+            return
+        }
+
+        if (!names.every((n, i) => i == 0 || names[i - 1] <= n)) {
+            this.errorf("%s: Glob(%q): unsorted output:\n%s", dir, glob, names.join("\n"))
+            names = [...names].sort()
+        }
+
+        let problems: string[] = []
+        while (want.length > 0 || names.length > 0) {
+            if (want.length > 0 && names.length > 0 && want[0] == names[0]) {
+                want = want.slice(1)
+                names = names.slice(1)
+            } else if (want.length > 0 && (names.length == 0 || want[0] < names[0])) {
+                problems.push("missing: " + want[0])
+                want = want.slice(1)
+            } else {
+                problems.push("extra: " + names[0])
+                names = names.slice(1)
+            }
+        }
+        this.errorf("%s: Glob(%q): wrong output:\n%s", dir, glob, problems.join("\n"))
+    }
+
+    // checkStat checks that a direct stat of path matches entry,
+    // which was found in the parent's directory listing.
+    checkStat(path: string, entry: fs.DirEntry) {
+        let [file, err] = this.fsys.Open(path)
+        if (err != null) {
+            this.errorf("%s: Open: %v", path, err)
+            return
+        }
+        let info: fs.FileInfo | null;
+        [info, err] = file!.Stat()
+        file!.Close()
+        if (err != null) {
+            this.errorf("%s: Stat: %v", path, err)
+            return
+        }
+        let fentry = formatEntry(entry)
+        let fientry = formatInfoEntry(info!)
+        // Note: mismatch here is OK for symlink, because Open dereferences symlink.
+        if (fentry != fientry && (entry.Type() & fs.ModeSymlink) == 0) {
+            this.errorf("%s: mismatch\n\tentry = %s\n\tfile.Stat() = %s", path, fentry, fientry)
+        }
+
+        let einfo: fs.FileInfo | null;
+        [einfo, err] = entry.Info()
+        if (err != null) {
+            this.errorf("%s: entry.Info: %v", path, err)
+            return
+        }
+        let finfo = formatInfo(info!)
+        if ((entry.Type() & fs.ModeSymlink) != 0) {
+            // For symlink, just check that entry.Info matches entry on common fields.
+            // Open deferences symlink, so info itself may differ.
+            let feentry = formatInfoEntry(einfo!)
+            if (fentry != feentry) {
+                this.errorf("%s: mismatch\n\tentry = %s\n\tentry.Info() = %s\n", path, fentry, feentry)
+            }
+        } else {
+            let feinfo = formatInfo(einfo!)
+            if (feinfo != finfo) {
+                this.errorf("%s: mismatch\n\tentry.Info() = %s\n\tfile.Stat() = %s\n", path, feinfo, finfo)
+            }
+        }
+
+        // Stat should be the same as Open+Stat, even for symlinks.
+        let info2: fs.FileInfo | null;
+        [info2, err] = fs.Stat(this.fsys, path)
+        if (err != null) {
+            this.errorf("%s: fs.Stat: %v", path, err)
+            return
+        }
+        let finfo2 = formatInfo(info2!)
+        if (finfo2 != finfo) {
+            this.errorf("%s: fs.Stat(...) = %s\n\twant %s", path, finfo2, finfo)
+        }
+
+        if (fs.isStatFS(this.fsys)) {
+            let [info2, err] = this.fsys.Stat(path)
+            if (err != null) {
+                this.errorf("%s: fsys.Stat: %v", path, err)
+                return
+            }
+            let finfo2 = formatInfo(info2!)
+            if (finfo2 != finfo) {
+                this.errorf("%s: fsys.Stat(...) = %s\n\twant %s", path, finfo2, finfo)
+            }
+        }
+    }
+
+    // checkDirList checks that two directory lists contain the same files and file info.
+    // The order of the lists need not match.
+    checkDirList(dir: string, desc: string, list1: fs.DirEntry[], list2: fs.DirEntry[]) {
+        let old = new Map<string, fs.DirEntry>()
+        let checkMode = (entry: fs.DirEntry) => {
+            if (entry.IsDir() != ((entry.Type() & fs.ModeDir) != 0)) {
+                if (entry.IsDir()) {
+                    this.errorf("%s: ReadDir returned %s with IsDir() = true, Type() & ModeDir = 0", dir, entry.Name())
+                } else {
+                    this.errorf("%s: ReadDir returned %s with IsDir() = false, Type() & ModeDir = ModeDir", dir, entry.Name())
+                }
+            }
+        }
+
+        for (let entry1 of list1) {
+            old.set(entry1.Name(), entry1)
+            checkMode(entry1)
+        }
+
+        let diffs: string[] = []
+        for (let entry2 of list2) {
+            let entry1 = old.get(entry2.Name())
+            if (entry1 == null) {
+                checkMode(entry2)
+                diffs.push("+ " + formatEntry(entry2))
+                continue
+            }
+            if (formatEntry(entry1) != formatEntry(entry2)) {
+                diffs.push("- " + formatEntry(entry1), "+ " + formatEntry(entry2))
+            }
+            old.delete(entry2.Name())
+        }
+        for (let entry1 of old.values()) {
+            diffs.push("- " + formatEntry(entry1))
+        }
+
+        if (diffs.length == 0) {
+            return
+        }
+
+        diffs.sort((a, b) => {
+            let fi = a.split(" ")
+            let fj = b.split(" ")
+            // sort by name (i < j) and then +/- (j < i, because + < -)
+            let ki = fi[1] + " " + fj[0], kj = fj[1] + " " + fi[0]
+            return ki < kj ? -1 : ki > kj ? 1 : 0
+        })
+
+        this.errorf("%s: diff %s:\n\t%s", dir, desc, diffs.join("\n\t"))
+    }
+
+    // checkFile checks that basic file reading works correctly.
+    checkFile(file: string) {
+        this.files.push(file)
+
+        // Read entire file.
+        let [f, err] = this.fsys.Open(file)
+        if (err != null) {
+            this.errorf("%s: Open: %v", file, err)
+            return
+        }
+
+        let data: Uint8Array;
+        [data, err] = ReadAll(f!)
+        if (err != null) {
+            f!.Close()
+            this.errorf("%s: Open+ReadAll: %v", file, err)
+            return
+        }
+
+        err = f!.Close()
+        if (err != null) {
+            this.errorf("%s: Close: %v", file, err)
+        }
+
+        // Check that closing twice doesn't crash.
+        // The return value doesn't matter.
+        f!.Close()
+
+        // Check that ReadFile works if present.
+        if (fs.isReadFileFS(this.fsys)) {
+            let fsys = this.fsys
+            let [data2, err] = fsys.ReadFile(file)
+            if (err != null) {
+                this.errorf("%s: fsys.ReadFile: %v", file, err)
+                return
+            }
+            this.checkFileRead(file, "ReadAll vs fsys.ReadFile", data, data2)
+
+            // Modify the data and check it again. Modifying the
+            // returned Uint8Array should not affect the next call.
+            for (let i = 0; i < data2.length; i++) {
+                data2[i]++
+            }
+            [data2, err] = fsys.ReadFile(file)
+            if (err != null) {
+                this.errorf("%s: second call to fsys.ReadFile: %v", file, err)
+                return
+            }
+            this.checkFileRead(file, "Readall vs second fsys.ReadFile", data, data2)
+
+            this.checkBadPath(file, "ReadFile", (name) => fsys.ReadFile(name)[1])
+        }
+
+        // Check that fs.ReadFile works with t.fsys.
+        let data2: Uint8Array;
+        [data2, err] = fs.ReadFile(this.fsys, file)
+        if (err != null) {
+            this.errorf("%s: fs.ReadFile: %v", file, err)
+            return
+        }
+        this.checkFileRead(file, "ReadAll vs fs.ReadFile", data, data2);
+
+        // Use iotest.TestReader to check small reads, Seek, ReadAt.
+        [f, err] = this.fsys.Open(file)
+        if (err != null) {
+            this.errorf("%s: second Open: %v", file, err)
+            return
+        }
+        try {
+            err = iotest.TestReader(f!, data)
+            if (err != null) {
+                this.errorf("%s: failed TestReader:\n\t%s", file, err.message.replaceAll("\n", "\n\t"))
+            }
+        } finally {
+            f!.Close()
+        }
+    }
+
+    checkFileRead(file: string, desc: string, data1: Uint8Array, data2: Uint8Array) {
+        if (data1.length != data2.length || data1.some((b, i) => b != data2[i])) {
+            this.errorf("%s: %s: different data returned\n\t%q\n\t%q", file, desc, data1, data2)
+            return
+        }
+    }
+
+    // checkOpen checks that various invalid forms of file's name cannot be opened using t.fsys.Open.
+    checkOpen(file: string) {
+        this.checkBadPath(file, "Open", (file) => {
+            let [f, err] = this.fsys.Open(file)
+            if (err == null) {
+                f!.Close()
+            }
+            return err
+        })
+    }
+
+    // checkBadPath checks that various invalid forms of file's name cannot be opened using open.
+    checkBadPath(file: string, desc: string, open: (name: string) => Error | null) {
+        let bad = [
+            "/" + file,
+            file + "/.",
+        ]
+        if (file == ".") {
+            bad.push("/")
+        }
+        let i = file.indexOf("/")
+        if (i >= 0) {
+            bad.push(
+                file.slice(0, i) + "//" + file.slice(i + 1),
+                file.slice(0, i) + "/../" + file,
+            )
+        }
+        i = file.lastIndexOf("/")
+        if (i >= 0) {
+            bad.push(
+                file.slice(0, i) + "//" + file.slice(i + 1),
+                file.slice(0, i) + "/../" + file,
+            )
+        }
+
+        for (let b of bad) {
+            if (open(b) == null) {
+                this.errorf("%s: %s(%s) succeeded, want error", file, desc, b)
+            }
+        }
+    }
+}
+
+// formatEntry formats an fs.DirEntry into a string for error messages and comparison.
+function formatEntry(entry: fs.DirEntry): string {
+    return errors.Errorf("%s IsDir=%t Type=%s", entry.Name(), entry.IsDir(), fs.FileMode.String(entry.Type())).message
+}
+
+// formatInfoEntry formats an fs.FileInfo into a string like the result of formatEntry, for comparison.
+function formatInfoEntry(info: fs.FileInfo): string {
+    return errors.Errorf("%s IsDir=%t Type=%s", info.Name(), info.IsDir(), fs.FileMode.String(fs.FileMode.Type(info.Mode()))).message
+}
+
+// formatInfo formats an fs.FileInfo into a string for error messages and comparison.
+function formatInfo(info: fs.FileInfo): string {
+    return errors.Errorf("%s IsDir=%t Mode=%s Size=%d ModTime=%v", info.Name(), info.IsDir(), fs.FileMode.String(info.Mode()), info.Size(), info.ModTime().getTime()).message
+}
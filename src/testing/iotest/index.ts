@@ -0,0 +1,452 @@
+// Package iotest implements Readers and Writers useful mainly for testing.
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/iotest/
+import * as errors from "../../errors"
+import { EOF, isByteReader, isByteScanner, isReaderAt, isSeeker, ReadAll, Reader, SeekCurrent, SeekEnd, SeekStart, Writer } from "../../io"
+
+/**
+ * OneByteReader returns a Reader that implements
+ * each non-empty Read by reading one byte from r.
+ */
+export function OneByteReader(r: Reader): Reader {
+    return new oneByteReader(r)
+}
+
+class oneByteReader implements Reader {
+    private r: Reader
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (p.length == 0) {
+            return [0, null]
+        }
+        return this.r.Read(p.subarray(0, 1))
+    }
+}
+
+/**
+ * HalfReader returns a Reader that implements Read
+ * by reading half as many requested bytes from r.
+ */
+export function HalfReader(r: Reader): Reader {
+    return new halfReader(r)
+}
+
+class halfReader implements Reader {
+    private r: Reader
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        return this.r.Read(p.subarray(0, (p.length + 1) >> 1))
+    }
+}
+
+/**
+ * DataErrReader changes the way errors are handled by a Reader. Normally, a
+ * Reader returns an error (typically EOF) from the first Read call after the
+ * last piece of data is read. DataErrReader wraps a Reader and changes its
+ * behavior so the final error is returned along with the final data, instead
+ * of in the first call after the final data.
+ */
+export function DataErrReader(r: Reader): Reader {
+    return new dataErrReader(r)
+}
+
+class dataErrReader implements Reader {
+    private r: Reader
+    private unread = new Uint8Array(0)
+    private data = new Uint8Array(1024)
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        let n = 0
+        let err: Error | null = null
+        // loop because first call needs two reads:
+        // one to get data and a second to look for an error.
+        while (true) { // for {}
+            if (this.unread.length == 0) {
+                let [n1, err1] = this.r.Read(this.data)
+                this.unread = this.data.subarray(0, n1)
+                err = err1
+            }
+            if (n > 0 || err != null) {
+                break
+            }
+            n = Math.min(p.length, this.unread.length)
+            p.set(this.unread.subarray(0, n))
+            this.unread = this.unread.subarray(n)
+        }
+        return [n, err]
+    }
+}
+
+/**
+ * ErrTimeout is a fake timeout error.
+ */
+export const ErrTimeout = errors.New("timeout")
+
+/**
+ * TimeoutReader returns ErrTimeout on the second read
+ * with no data. Subsequent calls to read succeed.
+ */
+export function TimeoutReader(r: Reader): Reader {
+    return new timeoutReader(r)
+}
+
+class timeoutReader implements Reader {
+    private r: Reader
+    private count = 0
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        this.count++
+        if (this.count == 2) {
+            return [0, ErrTimeout]
+        }
+        return this.r.Read(p)
+    }
+}
+
+/**
+ * ErrReader returns a Reader that returns 0, err from all Read calls.
+ */
+export function ErrReader(err: Error | null): Reader {
+    return new errReader(err)
+}
+
+class errReader implements Reader {
+    private err: Error | null
+
+    constructor(err: Error | null) {
+        this.err = err
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        return [0, this.err]
+    }
+}
+
+class smallByteReader implements Reader {
+    private r: Reader
+    private off = 0
+    private n = 0
+
+    constructor(r: Reader) {
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        if (p.length == 0) {
+            return [0, null]
+        }
+        this.n = this.n % 3 + 1
+        let n = this.n
+        if (n > p.length) {
+            n = p.length
+        }
+        let err: Error | null;
+        [n, err] = this.r.Read(p.subarray(0, n))
+        if (err != null && err != EOF) {
+            err = errors.Errorf("Read(%d bytes at offset %d): %v", n, this.off, err)
+        }
+        this.off += n
+        return [n, err]
+    }
+}
+
+// equal reports whether a and b hold the same bytes, like bytes.Equal
+function equal(a: Uint8Array, b: Uint8Array): boolean {
+    if (a.length != b.length) {
+        return false
+    }
+    for (let i = 0; i < a.length; i++) {
+        if (a[i] != b[i]) {
+            return false
+        }
+    }
+    return true
+}
+
+/**
+ * TestReader tests that reading from r returns the expected file content.
+ * It does reads of different sizes, until EOF.
+ * If r implements ReaderAt or Seeker, TestReader also checks
+ * that those operations behave as they should.
+ *
+ * If TestReader finds any misbehaviors, it returns an error reporting them.
+ * The error text may span multiple lines.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * If r implements ByteReader or ByteScanner, TestReader also checks that ReadByte
+ * and UnreadByte agree with Read
+ */
+export function TestReader(r: Reader, content: Uint8Array): Error | null {
+    if (content.length > 0) {
+        let [n, err] = r.Read(new Uint8Array(0))
+        if (n != 0 || err != null) {
+            return errors.Errorf("Read(0) = %d, %v, want 0, nil", n, err)
+        }
+    }
+
+    // Not present in the Go code
+    if (isByteScanner(r) && content.length > 0) {
+        // Reading and unreading the first byte leaves the content intact
+        let [c, err] = r.ReadByte()
+        if (c != content[0] || err != null) {
+            return errors.Errorf("ReadByte() = %d, %v, want %d, nil", c, err, content[0])
+        }
+        err = r.UnreadByte()
+        if (err != null) {
+            return errors.Errorf("UnreadByte() = %v, want nil", err)
+        }
+    }
+
+    let [data, err] = ReadAll(new smallByteReader(r))
+    if (err != null) {
+        return err
+    }
+    if (!equal(data, content)) {
+        return errors.Errorf("ReadAll(small amounts) = %q\n\twant %q", data, content)
+    }
+    let n: number;
+    [n, err] = r.Read(new Uint8Array(10))
+    if (n != 0 || err != EOF) {
+        return errors.Errorf("Read(10) at EOF = %v, %v, want 0, EOF", n, err)
+    }
+
+    // Not present in the Go code
+    if (isByteReader(r)) {
+        let c: number;
+        [c, err] = r.ReadByte()
+        if (err != EOF) {
+            return errors.Errorf("ReadByte() at EOF = %v, %v, want EOF", c, err)
+        }
+    }
+
+    if (isSeeker(r)) {
+        let off: number
+        // Seek(0, 1) should report the current file position (EOF).
+        [off, err] = r.Seek(0, SeekCurrent)
+        if (off != content.length || err != null) {
+            return errors.Errorf("Seek(0, 1) from EOF = %d, %v, want %d, nil", off, err, content.length)
+        }
+
+        // Seek backward partway through file, in two steps.
+        // If middle == 0, len(content) == 0, can't use the -1 and +1 seeks.
+        let third = Math.trunc(content.length / 3)
+        let middle = content.length - third
+        if (middle > 0) {
+            [off, err] = r.Seek(-1, SeekCurrent)
+            if (off != content.length - 1 || err != null) {
+                return errors.Errorf("Seek(-1, 1) from EOF = %d, %v, want %d, nil", -off, err, content.length - 1)
+            }
+            [off, err] = r.Seek(-third, SeekCurrent)
+            if (off != middle - 1 || err != null) {
+                return errors.Errorf("Seek(%d, 1) from %d = %d, %v, want %d, nil", -third, content.length - 1, off, err, middle - 1)
+            }
+            [off, err] = r.Seek(+1, SeekCurrent)
+            if (off != middle || err != null) {
+                return errors.Errorf("Seek(+1, 1) from %d = %d, %v, want %d, nil", middle - 1, off, err, middle)
+            }
+        }
+
+        // Seek(0, 1) should report the current file position (middle).
+        [off, err] = r.Seek(0, SeekCurrent)
+        if (off != middle || err != null) {
+            return errors.Errorf("Seek(0, 1) from %d = %d, %v, want %d, nil", middle, off, err, middle)
+        }
+
+        // Reading forward should return the last part of the file.
+        [data, err] = ReadAll(new smallByteReader(r))
+        if (err != null) {
+            return errors.Errorf("ReadAll from offset %d: %v", middle, err)
+        }
+        if (!equal(data, content.subarray(middle))) {
+            return errors.Errorf("ReadAll from offset %d = %q\n\twant %q", middle, data, content.subarray(middle))
+        }
+
+        // Seek relative to end of file, but start elsewhere.
+        let half = middle >> 1;
+        [off, err] = r.Seek(half, SeekStart)
+        if (off != half || err != null) {
+            return errors.Errorf("Seek(%d, 0) from EOF = %d, %v, want %d, nil", half, off, err, half)
+        }
+        [off, err] = r.Seek(-third, SeekEnd)
+        if (off != middle || err != null) {
+            return errors.Errorf("Seek(%d, 2) from %d = %d, %v, want %d, nil", -third, half, off, err, middle)
+        }
+
+        // Reading forward should return the last part of the file (again).
+        [data, err] = ReadAll(new smallByteReader(r))
+        if (err != null) {
+            return errors.Errorf("ReadAll from offset %d: %v", middle, err)
+        }
+        if (!equal(data, content.subarray(middle))) {
+            return errors.Errorf("ReadAll from offset %d = %q\n\twant %q", middle, data, content.subarray(middle))
+        }
+
+        // Absolute seek & read forward.
+        [off, err] = r.Seek(half, SeekStart)
+        if (off != half || err != null) {
+            return errors.Errorf("Seek(%d, 0) from EOF = %d, %v, want %d, nil", half, off, err, half)
+        }
+        [data, err] = ReadAll(r)
+        if (err != null) {
+            return errors.Errorf("ReadAll from offset %d: %v", half, err)
+        }
+        if (!equal(data, content.subarray(half))) {
+            return errors.Errorf("ReadAll from offset %d = %q\n\twant %q", half, data, content.subarray(half))
+        }
+    }
+
+    if (isReaderAt(r)) {
+        // data has one byte of spare capacity, like make([]byte, len(content), len(content)+1)
+        let buf = new Uint8Array(content.length + 1)
+        let data = buf.subarray(0, content.length)
+        data.fill(0xfe);
+        [n, err] = r.ReadAt(data, 0)
+        if (n != data.length || err != null && err != EOF) {
+            return errors.Errorf("ReadAt(%d, 0) = %v, %v, want %d, nil or EOF", data.length, n, err, data.length)
+        }
+        if (!equal(data, content)) {
+            return errors.Errorf("ReadAt(%d, 0) = %q\n\twant %q", data.length, data, content)
+        }
+
+        [n, err] = r.ReadAt(buf.subarray(0, 1), data.length)
+        if (n != 0 || err != EOF) {
+            return errors.Errorf("ReadAt(1, %d) = %v, %v, want 0, EOF", data.length, n, err)
+        }
+
+        data.fill(0xfe);
+        [n, err] = r.ReadAt(buf, 0)
+        if (n != data.length || err != EOF) {
+            return errors.Errorf("ReadAt(%d, 0) = %v, %v, want %d, EOF", buf.length, n, err, data.length)
+        }
+        if (!equal(data, content)) {
+            return errors.Errorf("ReadAt(%d, 0) = %q\n\twant %q", data.length, data, content)
+        }
+
+        data.fill(0xfe)
+        for (let i = 0; i < data.length; i++) {
+            [n, err] = r.ReadAt(data.subarray(i, i + 1), i)
+            if (n != 1 || err != null && (i != data.length - 1 || err != EOF)) {
+                let want = "nil"
+                if (i == data.length - 1) {
+                    want = "nil or EOF"
+                }
+                return errors.Errorf("ReadAt(1, %d) = %v, %v, want 1, %s", i, n, err, want)
+            }
+            if (data[i] != content[i]) {
+                return errors.Errorf("ReadAt(1, %d) = %q want %q", i, data.subarray(i, i + 1), content.subarray(i, i + 1))
+            }
+        }
+    }
+    return null
+}
+
+/**
+ * TruncateWriter returns a Writer that writes to w
+ * but stops silently after n bytes.
+ */
+export function TruncateWriter(w: Writer, n: number): Writer {
+    return new truncateWriter(w, n)
+}
+
+class truncateWriter implements Writer {
+    private w: Writer
+    private n: number
+
+    constructor(w: Writer, n: number) {
+        this.w = w
+        this.n = n
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        if (this.n <= 0) {
+            return [p.length, null]
+        }
+        // real write
+        let n = p.length
+        if (n > this.n) {
+            n = this.n
+        }
+        let err: Error | null;
+        [n, err] = this.w.Write(p.subarray(0, n))
+        this.n -= n
+        if (err == null) {
+            n = p.length
+        }
+        return [n, err]
+    }
+}
+
+// log prints a read or write to standard error, like log.Printf("%s %x: %v")
+function log(prefix: string, p: Uint8Array, err: Error | null) {
+    if (err != null) {
+        console.error(errors.Errorf("%s %x: %v", prefix, p, err).message)
+    } else {
+        console.error(errors.Errorf("%s %x", prefix, p).message)
+    }
+}
+
+class writeLogger implements Writer {
+    private prefix: string
+    private w: Writer
+
+    constructor(prefix: string, w: Writer) {
+        this.prefix = prefix
+        this.w = w
+    }
+
+    Write(p: Uint8Array): [number, Error | null] {
+        let [n, err] = this.w.Write(p)
+        log(this.prefix, p.subarray(0, n), err)
+        return [n, err]
+    }
+}
+
+/**
+ * NewWriteLogger returns a writer that behaves like w except
+ * that it logs (using console.error) each write to standard error,
+ * printing the prefix and the hexadecimal data written.
+ */
+export function NewWriteLogger(prefix: string, w: Writer): Writer {
+    return new writeLogger(prefix, w)
+}
+
+class readLogger implements Reader {
+    private prefix: string
+    private r: Reader
+
+    constructor(prefix: string, r: Reader) {
+        this.prefix = prefix
+        this.r = r
+    }
+
+    Read(p: Uint8Array): [number, Error | null] {
+        let [n, err] = this.r.Read(p)
+        log(this.prefix, p.subarray(0, n), err)
+        return [n, err]
+    }
+}
+
+/**
+ * NewReadLogger returns a reader that behaves like r except
+ * that it logs (using console.error) each read to standard error,
+ * printing the prefix and the hexadecimal data read.
+ */
+export function NewReadLogger(prefix: string, r: Reader): Reader {
+    return new readLogger(prefix, r)
+}
@@ -0,0 +1,261 @@
+// Tests for testing/iotest
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/iotest/reader_test.go,
+// https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/iotest/writer_test.go
+// and https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/testing/iotest/logger_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import { Buffer as GoBuffer } from "../../builtins/tshelpers/buffer"
+import { bytes, str } from "../../builtins/tshelpers/strings"
+import * as errors from "../../errors"
+import { EOF, NewSectionReader, ReadAll, Reader, Writer } from "../../io"
+import {
+    DataErrReader, ErrReader, ErrTimeout, HalfReader, NewReadLogger, NewWriteLogger, OneByteReader, TestReader, TimeoutReader,
+    TruncateWriter,
+} from "./index"
+
+// captureStderr runs f, and returns what it printed with console.error
+function captureStderr(f: () => void): string {
+    let lines: string[] = []
+    let orig = console.error
+    console.error = (msg: string) => lines.push(msg)
+    try {
+        f()
+    } finally {
+        console.error = orig
+    }
+    return lines.map((l) => l + "\n").join("")
+}
+
+test("TestOneByteReader_nonEmptyReader", () => {
+    let msg = "Hello, World!"
+    let buf = new GoBuffer(bytes(msg))
+
+    let obr = OneByteReader(buf)
+    let b = new Uint8Array(msg.length);
+    let [n, err] = obr.Read(b)
+    assert.ok(err == null && n == 1, `Read = ${n}, ${err}; want 1, nil`)
+
+    b = new Uint8Array(0);
+    [n, err] = obr.Read(b)
+    assert.ok(err == null && n == 0, `Read = ${n}, ${err}; want 0, nil`)
+
+    let got = ""
+    b = new Uint8Array(3);
+    [n, err] = obr.Read(b)
+    assert.ok(err == null && n == 1, `Read = ${n}, ${err}; want 1, nil`)
+    got += str(b.subarray(0, n))
+    // Read whatever else remains.
+    let rest: Uint8Array;
+    [rest, err] = ReadAll(obr)
+    assert.equal(err, null)
+    got += str(rest)
+    assert.equal(got, msg.slice(1), `got ${JSON.stringify(got)}; want ${JSON.stringify(msg.slice(1))}`)
+})
+
+test("TestOneByteReader_emptyReader", () => {
+    let r = new GoBuffer(new Uint8Array(0))
+
+    let obr = OneByteReader(r)
+    let b = new Uint8Array(0)
+    let [n, err] = obr.Read(b)
+    assert.ok(err == null && n == 0, `Read = ${n}, ${err}; want 0, nil`)
+
+    b = new Uint8Array(5);
+    [n, err] = obr.Read(b)
+    assert.ok(err == EOF && n == 0, `Read = ${n}, ${err}; want 0, EOF`)
+})
+
+test("TestHalfReader_nonEmptyReader", () => {
+    let msg = "Hello, World!"
+    let buf = new GoBuffer(bytes(msg))
+    // empty read buffer
+    let hr = HalfReader(buf)
+    let b = new Uint8Array(0)
+    let [n, err] = hr.Read(b)
+    assert.ok(err == null && n == 0, `Read = ${n}, ${err}; want 0, nil`)
+    // non empty read buffer
+    b = new Uint8Array(2)
+    let got = ""
+    for (let i = 0; i < 2; i++) {
+        [n, err] = hr.Read(b)
+        assert.ok(err == null && n == 1, `Read = ${n}, ${err}; want 1, nil`)
+        got += str(b.subarray(0, n))
+    }
+    // Read whatever else remains.
+    let rest: Uint8Array;
+    [rest, err] = ReadAll(hr)
+    assert.equal(err, null)
+    got += str(rest)
+    assert.equal(got, msg)
+})
+
+test("TestHalfReader_emptyReader", () => {
+    let r = new GoBuffer(new Uint8Array(0))
+
+    let hr = HalfReader(r)
+    let b = new Uint8Array(0)
+    let [n, err] = hr.Read(b)
+    assert.ok(err == null && n == 0, `Read = ${n}, ${err}; want 0, nil`)
+
+    b = new Uint8Array(5);
+    [n, err] = hr.Read(b)
+    assert.ok(err == EOF && n == 0, `Read = ${n}, ${err}; want 0, EOF`)
+})
+
+test("TestTimeOutReader_nonEmptyReader", () => {
+    let msg = "Hello, World!"
+    let buf = new GoBuffer(bytes(msg))
+    // TimeOutReader always returns an error on the second read.
+    let r = TimeoutReader(buf)
+    let b = new Uint8Array(msg.length)
+    let [n, err] = r.Read(b)
+    assert.ok(err == null && n == msg.length, `Read = ${n}, ${err}; want ${msg.length}, nil`);
+    [n, err] = r.Read(b)
+    assert.ok(err == ErrTimeout && n == 0, `Read = ${n}, ${err}; want 0, ErrTimeout`);
+    [n, err] = r.Read(b)
+    assert.ok(err == EOF && n == 0, `Read = ${n}, ${err}; want 0, EOF`)
+})
+
+test("TestDataErrReader_nonEmptyReader", () => {
+    let msg = "Hello, World!"
+    let buf = new GoBuffer(bytes(msg))
+
+    let r = DataErrReader(buf)
+
+    let b = new Uint8Array(msg.length)
+    let got = ""
+    while (true) { // for {}
+        let [n, err] = r.Read(b)
+        got += str(b.subarray(0, n))
+        if (err == EOF) {
+            break
+        }
+        assert.equal(err, null, `Read = ${n}, ${err}; want n, nil`)
+    }
+    assert.equal(got, msg)
+})
+
+test("TestDataErrReader_emptyReader", () => {
+    let r = new GoBuffer(new Uint8Array(0))
+
+    let der = DataErrReader(r)
+    let b = new Uint8Array(5)
+    let [n, err] = der.Read(b)
+    assert.ok(err == EOF && n == 0, `Read = ${n}, ${err}; want 0, EOF`)
+})
+
+test("TestErrReader", () => {
+    let cases: [string, Error | null][] = [
+        ["nil error", null],
+        ["non-nil error", errors.New("io failure")],
+        ["io.EOF", EOF],
+    ]
+    for (let [name, err] of cases) {
+        let [n, gotErr] = ErrReader(err).Read(new Uint8Array(10))
+        assert.equal(gotErr, err, `${name}: Error mismatch`)
+        assert.equal(n, 0, `${name}: Byte count mismatch`)
+    }
+})
+
+test("TestStringsReader", () => {
+    let msg = "Now is the time for all good gophers."
+
+    // Not present in the Go code: SectionReader stands in for strings.Reader
+    let content = bytes(msg)
+    let r = NewSectionReader(new GoBuffer(content), 0, content.length)
+    assert.equal(TestReader(r, content), null)
+})
+
+// Not present in the Go code
+test("TestTestReaderFindsBugs", () => {
+    let content = bytes("Now is the time for all good gophers.")
+
+    // A reader returning the wrong data
+    let r = new GoBuffer(bytes("Now is the time for all good gophers!"))
+    assert.match(TestReader(r, content)?.message ?? "", /^ReadAll\(small amounts\)/)
+
+    // A reader that returns no data at all
+    let zero: Reader = { Read: (p) => [0, p.length == 0 ? null : EOF] }
+    assert.match(TestReader(zero, content)?.message ?? "", /^ReadAll\(small amounts\)/)
+
+    // A reader that fails on empty reads
+    let strict: Reader = { Read: (p) => [0, p.length == 0 ? errors.New("empty read") : EOF] }
+    assert.equal(TestReader(strict, content)?.message, "Read(0) = 0, empty read, want 0, nil")
+
+    // Read errors are reported with their offset
+    let failing = new GoBuffer(content.slice(0, 5))
+    let readErr = errors.New("disk on fire")
+    let broken: Reader = {
+        Read: (p) => {
+            let [n, err] = failing.Read(p)
+            return err == EOF ? [0, readErr] : [n, err]
+        },
+    }
+    let err = TestReader(broken, content)
+    assert.equal(err?.message, "Read(0 bytes at offset 5): disk on fire")
+})
+
+test("TestTruncateWriter", () => {
+    let truncateWriterTests: { in: string, want: string, trunc: number, n: number }[] = [
+        { in: "hello", want: "", trunc: -1, n: 5 },
+        { in: "world", want: "", trunc: 0, n: 5 },
+        { in: "abcde", want: "abc", trunc: 3, n: 5 },
+        { in: "edcba", want: "edcba", trunc: 7, n: 5 },
+    ]
+    for (let tt of truncateWriterTests) {
+        let buf = new GoBuffer(new Uint8Array(0))
+        let tw = TruncateWriter(buf, tt.trunc)
+        let [n, err] = tw.Write(bytes(tt.in))
+        assert.equal(err, null, `Unexpected error ${err} for\n\t${JSON.stringify(tt)}`)
+        assert.equal(str(buf.underlyingArray), tt.want, `got ${JSON.stringify(str(buf.underlyingArray))}, expected ${JSON.stringify(tt.want)}`)
+        assert.equal(n, tt.n, `read ${n} bytes, but expected ${tt.n}`)
+    }
+})
+
+test("TestWriteLogger", () => {
+    let lw = new GoBuffer(new Uint8Array(0))
+    let wl = NewWriteLogger("write:", lw)
+    let data = bytes("Hello, World!")
+    let out = captureStderr(() => {
+        let [n, err] = wl.Write(data)
+        assert.equal(err, null, `Unexpectedly failed to write: ${err}`)
+        assert.equal(n, data.length, `Wrote ${n} bytes, expected ${data.length}`)
+    })
+    assert.equal(out, "write: 48656c6c6f2c20576f726c6421\n")
+})
+
+test("TestWriteLogger_errorOnWrite", () => {
+    let wl = NewWriteLogger("write:", { Write: () => [0, errors.New("Write Error!")] } as Writer)
+    let data = bytes("Hello, World!")
+    let out = captureStderr(() => {
+        let [n, err] = wl.Write(data)
+        assert.ok(err != null, "Unexpectedly succeeded to write")
+        assert.equal(n, 0, `Wrote ${n} bytes, expected 0`)
+    })
+    assert.equal(out, "write: : Write Error!\n")
+})
+
+test("TestReadLogger", () => {
+    let data = bytes("Hello, World!")
+    let p = new Uint8Array(data.length)
+    let rl = NewReadLogger("read:", new GoBuffer(data.slice()))
+    let out = captureStderr(() => {
+        let [n, err] = rl.Read(p)
+        assert.equal(err, null, `Unexpectedly failed to read: ${err}`)
+        assert.equal(n, data.length, `Read ${n} bytes, expected ${data.length}`)
+    })
+    assert.deepEqual(p, data)
+    assert.equal(out, "read: 48656c6c6f2c20576f726c6421\n")
+})
+
+test("TestReadLogger_errorOnRead", () => {
+    let data = bytes("Hello, World!")
+    let p = new Uint8Array(data.length)
+    let rl = NewReadLogger("read", ErrReader(errors.New("io failure")))
+    let out = captureStderr(() => {
+        let [n, err] = rl.Read(p)
+        assert.ok(err != null, "Unexpectedly succeeded to read")
+        assert.equal(n, 0, `Read ${n} bytes, expected 0`)
+    })
+    assert.equal(out, "read : io failure\n")
+})
@@ -0,0 +1,384 @@
+// Package utf8 implements functions and constants to support text encoded in
+// UTF-8. It includes functions to translate between runes and UTF-8 byte sequences.
+// See https://en.wikipedia.org/wiki/UTF-8
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/unicode/utf8/utf8.go
+//
+// *SEMANTIC DIFFERENCES TO GO:*
+//
+// A rune is a number holding a Unicode code point, and byte sequences are Uint8Arrays.
+// JS strings are UTF-16, so the functions that take a string in Go are left out: encode
+// the string with TextEncoder first.
+
+// The conditions RuneError==unicode.ReplacementChar and
+// MaxRune==unicode.MaxRune are verified in the tests.
+// Defining them locally avoids this package depending on package unicode.
+
+// Numbers fundamental to the encoding.
+export const RuneError = 0xFFFD // the "error" Rune or "Unicode replacement character"
+export const RuneSelf = 0x80 // characters below RuneSelf are represented as themselves in a single byte.
+export const MaxRune = 0x0010FFFF // Maximum valid Unicode code point.
+export const UTFMax = 4 // maximum number of bytes of a UTF-8 encoded Unicode character.
+
+// Code points in the surrogate range are not valid for UTF-8.
+const surrogateMin = 0xD800
+const surrogateMax = 0xDFFF
+
+const t1 = 0b00000000
+const tx = 0b10000000
+const t2 = 0b11000000
+const t3 = 0b11100000
+const t4 = 0b11110000
+const t5 = 0b11111000
+
+const maskx = 0b00111111
+const mask2 = 0b00011111
+const mask3 = 0b00001111
+const mask4 = 0b00000111
+
+const rune1Max = (1 << 7) - 1
+const rune2Max = (1 << 11) - 1
+const rune3Max = (1 << 16) - 1
+
+// The default lowest and highest continuation byte.
+const locb = 0b10000000
+const hicb = 0b10111111
+
+// These names of these constants are chosen to give nice alignment in the
+// table below. The first nibble is an index into acceptRanges or F for
+// special one-byte cases. The second nibble is the Rune length or the
+// Status for the special one-byte case.
+const xx = 0xF1 // invalid: size 1
+const as = 0xF0 // ASCII: size 1
+const s1 = 0x02 // accept 0, size 2
+const s2 = 0x13 // accept 1, size 3
+const s3 = 0x03 // accept 0, size 3
+const s4 = 0x23 // accept 2, size 3
+const s5 = 0x34 // accept 3, size 4
+const s6 = 0x04 // accept 0, size 4
+const s7 = 0x44 // accept 4, size 4
+
+// first is information about the first byte in a UTF-8 sequence.
+const first = new Uint8Array([
+    //   1   2   3   4   5   6   7   8   9   A   B   C   D   E   F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x00-0x0F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x10-0x1F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x20-0x2F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x30-0x3F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x40-0x4F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x50-0x5F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x60-0x6F
+    as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, as, // 0x70-0x7F
+    //   1   2   3   4   5   6   7   8   9   A   B   C   D   E   F
+    xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, // 0x80-0x8F
+    xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, // 0x90-0x9F
+    xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, // 0xA0-0xAF
+    xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, // 0xB0-0xBF
+    xx, xx, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, // 0xC0-0xCF
+    s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, s1, // 0xD0-0xDF
+    s2, s3, s3, s3, s3, s3, s3, s3, s3, s3, s3, s3, s3, s4, s3, s3, // 0xE0-0xEF
+    s5, s6, s6, s6, s7, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, xx, // 0xF0-0xFF
+])
+
+// acceptRange gives the range of valid values for the second byte in a UTF-8
+// sequence, as [lo, hi].
+type acceptRange = [number, number]
+
+// acceptRanges has size 16 to avoid bounds checks in the code that uses it.
+const acceptRanges: acceptRange[] = [
+    [locb, hicb],
+    [0xA0, hicb],
+    [locb, 0x9F],
+    [0x90, hicb],
+    [locb, 0x8F],
+]
+
+/**
+ * FullRune reports whether the bytes in p begin with a full UTF-8 encoding of a rune.
+ * An invalid encoding is considered a full Rune since it will convert as a width-1 error rune.
+ */
+export function FullRune(p: Uint8Array): boolean {
+    let n = p.length
+    if (n == 0) {
+        return false
+    }
+    let x = first[p[0]]
+    if (n >= (x & 7)) {
+        return true // ASCII, invalid or valid.
+    }
+    // Must be short or invalid.
+    let accept = acceptRanges[x >> 4]
+    if (n > 1 && (p[1] < accept[0] || accept[1] < p[1])) {
+        return true
+    } else if (n > 2 && (p[2] < locb || hicb < p[2])) {
+        return true
+    }
+    return false
+}
+
+/**
+ * DecodeRune unpacks the first UTF-8 encoding in p and returns the rune and
+ * its width in bytes. If p is empty it returns (RuneError, 0). Otherwise, if
+ * the encoding is invalid, it returns (RuneError, 1). Both are impossible
+ * results for correct, non-empty UTF-8.
+ *
+ * An encoding is invalid if it is incorrect UTF-8, encodes a rune that is
+ * out of range, or is not the shortest possible UTF-8 encoding for the
+ * value. No other validation is performed.
+ */
+export function DecodeRune(p: Uint8Array): [number, number] {
+    let n = p.length
+    if (n < 1) {
+        return [RuneError, 0]
+    }
+    let p0 = p[0]
+    let x = first[p0]
+    if (x >= as) {
+        // The following code simulates an additional check for x == xx and
+        // handling the ASCII and invalid cases accordingly. This mask-and-or
+        // approach prevents an additional branch.
+        let mask = (x << 31) >> 31 // Create 0x0000 or 0xFFFF.
+        return [(p[0] & ~mask) | (RuneError & mask), 1]
+    }
+    let sz = x & 7
+    let accept = acceptRanges[x >> 4]
+    if (n < sz) {
+        return [RuneError, 1]
+    }
+    let b1 = p[1]
+    if (b1 < accept[0] || accept[1] < b1) {
+        return [RuneError, 1]
+    }
+    if (sz <= 2) { // <= instead of == to help the compiler eliminate some bounds checks
+        return [((p0 & mask2) << 6) | (b1 & maskx), 2]
+    }
+    let b2 = p[2]
+    if (b2 < locb || hicb < b2) {
+        return [RuneError, 1]
+    }
+    if (sz <= 3) {
+        return [((p0 & mask3) << 12) | ((b1 & maskx) << 6) | (b2 & maskx), 3]
+    }
+    let b3 = p[3]
+    if (b3 < locb || hicb < b3) {
+        return [RuneError, 1]
+    }
+    return [((p0 & mask4) << 18) | ((b1 & maskx) << 12) | ((b2 & maskx) << 6) | (b3 & maskx), 4]
+}
+
+/**
+ * DecodeLastRune unpacks the last UTF-8 encoding in p and returns the rune and
+ * its width in bytes. If p is empty it returns (RuneError, 0). Otherwise, if
+ * the encoding is invalid, it returns (RuneError, 1). Both are impossible
+ * results for correct, non-empty UTF-8.
+ *
+ * An encoding is invalid if it is incorrect UTF-8, encodes a rune that is
+ * out of range, or is not the shortest possible UTF-8 encoding for the
+ * value. No other validation is performed.
+ */
+export function DecodeLastRune(p: Uint8Array): [number, number] {
+    let end = p.length
+    if (end == 0) {
+        return [RuneError, 0]
+    }
+    let start = end - 1
+    let r = p[start]
+    if (r < RuneSelf) {
+        return [r, 1]
+    }
+    // guard against O(n^2) behavior when traversing
+    // backwards through strings with long sequences of
+    // invalid UTF-8.
+    let lim = end - UTFMax
+    if (lim < 0) {
+        lim = 0
+    }
+    for (start--; start >= lim; start--) {
+        if (RuneStart(p[start])) {
+            break
+        }
+    }
+    if (start < 0) {
+        start = 0
+    }
+    let size: number
+    [r, size] = DecodeRune(p.subarray(start, end))
+    if (start + size != end) {
+        return [RuneError, 1]
+    }
+    return [r, size]
+}
+
+/**
+ * RuneLen returns the number of bytes required to encode the rune.
+ * It returns -1 if the rune is not a valid value to encode in UTF-8.
+ */
+export function RuneLen(r: number): number {
+    if (r < 0) {
+        return -1
+    } else if (r <= rune1Max) {
+        return 1
+    } else if (r <= rune2Max) {
+        return 2
+    } else if (surrogateMin <= r && r <= surrogateMax) {
+        return -1
+    } else if (r <= rune3Max) {
+        return 3
+    } else if (r <= MaxRune) {
+        return 4
+    }
+    return -1
+}
+
+/**
+ * EncodeRune writes into p (which must be large enough) the UTF-8 encoding of the rune.
+ * If the rune is out of range, it writes the encoding of RuneError.
+ * It returns the number of bytes written.
+ */
+export function EncodeRune(p: Uint8Array, r: number): number {
+    // Negative values are erroneous. Making it unsigned addresses the problem.
+    if (r >= 0 && r <= rune1Max) {
+        p[0] = r
+        return 1
+    }
+    if (r >= 0 && r <= rune2Max) {
+        p[0] = t2 | (r >> 6)
+        p[1] = tx | (r & maskx)
+        return 2
+    }
+    if (r < 0 || r > MaxRune || (surrogateMin <= r && r <= surrogateMax)) {
+        r = RuneError
+    }
+    if (r <= rune3Max) {
+        p[0] = t3 | (r >> 12)
+        p[1] = tx | ((r >> 6) & maskx)
+        p[2] = tx | (r & maskx)
+        return 3
+    }
+    p[0] = t4 | (r >> 18)
+    p[1] = tx | ((r >> 12) & maskx)
+    p[2] = tx | ((r >> 6) & maskx)
+    p[3] = tx | (r & maskx)
+    return 4
+}
+
+/**
+ * AppendRune appends the UTF-8 encoding of r to the end of p and
+ * returns the extended buffer. If the rune is out of range,
+ * it appends the encoding of RuneError.
+ *
+ * *SEMANTIC DIFFERENCES TO GO:*
+ *
+ * Uint8Arrays can't grow, so the result is always a new array
+ */
+export function AppendRune(p: Uint8Array, r: number): Uint8Array {
+    let buf = new Uint8Array(UTFMax)
+    let n = EncodeRune(buf, r)
+    let out = new Uint8Array(p.length + n)
+    out.set(p)
+    out.set(buf.subarray(0, n), p.length)
+    return out
+}
+
+/**
+ * RuneCount returns the number of runes in p. Erroneous and short
+ * encodings are treated as single runes of width 1 byte.
+ */
+export function RuneCount(p: Uint8Array): number {
+    let np = p.length
+    let n = 0
+    for (let i = 0; i < np;) {
+        n++
+        let c = p[i]
+        if (c < RuneSelf) {
+            // ASCII fast path
+            i++
+            continue
+        }
+        let x = first[c]
+        if (x == xx) {
+            i++ // invalid.
+            continue
+        }
+        let size = x & 7
+        if (i + size > np) {
+            i++ // Short or invalid.
+            continue
+        }
+        let accept = acceptRanges[x >> 4]
+        let c1 = p[i + 1]
+        if (c1 < accept[0] || accept[1] < c1) {
+            size = 1
+        } else if (size == 2) {
+        } else {
+            let c2 = p[i + 2]
+            if (c2 < locb || hicb < c2) {
+                size = 1
+            } else if (size == 3) {
+            } else {
+                let c3 = p[i + 3]
+                if (c3 < locb || hicb < c3) {
+                    size = 1
+                }
+            }
+        }
+        i += size
+    }
+    return n
+}
+
+/**
+ * RuneStart reports whether the byte could be the first byte of an encoded,
+ * possibly invalid rune. Second and subsequent bytes always have the top two
+ * bits set to 10.
+ */
+export function RuneStart(b: number): boolean {
+    return (b & 0xC0) != 0x80
+}
+
+/**
+ * Valid reports whether p consists entirely of valid UTF-8-encoded runes.
+ */
+export function Valid(p: Uint8Array): boolean {
+    let n = p.length
+    for (let i = 0; i < n;) {
+        let pi = p[i]
+        if (pi < RuneSelf) {
+            i++
+            continue
+        }
+        let x = first[pi]
+        if (x == xx) {
+            return false // Illegal starter byte.
+        }
+        let size = x & 7
+        if (i + size > n) {
+            return false // Short or invalid.
+        }
+        let accept = acceptRanges[x >> 4]
+        let c = p[i + 1]
+        if (c < accept[0] || accept[1] < c) {
+            return false
+        } else if (size == 2) {
+        } else if (p[i + 2] < locb || hicb < p[i + 2]) {
+            return false
+        } else if (size == 3) {
+        } else if (p[i + 3] < locb || hicb < p[i + 3]) {
+            return false
+        }
+        i += size
+    }
+    return true
+}
+
+/**
+ * ValidRune reports whether r can be legally encoded as UTF-8.
+ * Code points that are out of range or a surrogate half are illegal.
+ */
+export function ValidRune(r: number): boolean {
+    if (0 <= r && r < surrogateMin) {
+        return true
+    } else if (surrogateMax < r && r <= MaxRune) {
+        return true
+    }
+    return false
+}
@@ -0,0 +1,279 @@
+// Tests for unicode/utf8
+// Taken from https://cs.opensource.google/go/go/+/refs/tags/go1.21.3:src/unicode/utf8/utf8_test.go
+import * as assert from "node:assert/strict"
+import { test } from "node:test"
+import {
+    AppendRune, DecodeLastRune, DecodeRune, EncodeRune, FullRune, MaxRune, RuneCount, RuneError, RuneLen, RuneStart, UTFMax,
+    Valid, ValidRune,
+} from "./index"
+
+// Validate the constants redefined from unicode.
+test("TestConstants", () => {
+    assert.equal(MaxRune, 0x10FFFF, `utf8.MaxRune is wrong: ${MaxRune} should be 0x10FFFF`)
+    assert.equal(RuneError, 0xFFFD, `utf8.RuneError is wrong: ${RuneError} should be 0xFFFD`)
+})
+
+type Utf8Map = [number, number[]]
+
+const utf8map: Utf8Map[] = [
+    [0x0000, [0x00]],
+    [0x0001, [0x01]],
+    [0x007e, [0x7e]],
+    [0x007f, [0x7f]],
+    [0x0080, [0xc2, 0x80]],
+    [0x0081, [0xc2, 0x81]],
+    [0x00bf, [0xc2, 0xbf]],
+    [0x00c0, [0xc3, 0x80]],
+    [0x00c1, [0xc3, 0x81]],
+    [0x00c8, [0xc3, 0x88]],
+    [0x00d0, [0xc3, 0x90]],
+    [0x00e0, [0xc3, 0xa0]],
+    [0x00f0, [0xc3, 0xb0]],
+    [0x00f8, [0xc3, 0xb8]],
+    [0x00ff, [0xc3, 0xbf]],
+    [0x0100, [0xc4, 0x80]],
+    [0x07ff, [0xdf, 0xbf]],
+    [0x0400, [0xd0, 0x80]],
+    [0x0800, [0xe0, 0xa0, 0x80]],
+    [0x0801, [0xe0, 0xa0, 0x81]],
+    [0x1000, [0xe1, 0x80, 0x80]],
+    [0xd000, [0xed, 0x80, 0x80]],
+    [0xd7ff, [0xed, 0x9f, 0xbf]], // last code point before surrogate half.
+    [0xe000, [0xee, 0x80, 0x80]], // first code point after surrogate half.
+    [0xfffe, [0xef, 0xbf, 0xbe]],
+    [0xffff, [0xef, 0xbf, 0xbf]],
+    [0x10000, [0xf0, 0x90, 0x80, 0x80]],
+    [0x10001, [0xf0, 0x90, 0x80, 0x81]],
+    [0x40000, [0xf1, 0x80, 0x80, 0x80]],
+    [0x10fffe, [0xf4, 0x8f, 0xbf, 0xbe]],
+    [0x10ffff, [0xf4, 0x8f, 0xbf, 0xbf]],
+    [0xFFFD, [0xef, 0xbf, 0xbd]],
+]
+
+const surrogateMap: Utf8Map[] = [
+    [0xd800, [0xed, 0xa0, 0x80]], // surrogate min decodes to (RuneError, 1)
+    [0xdfff, [0xed, 0xbf, 0xbf]], // surrogate max decodes to (RuneError, 1)
+]
+
+// The Go test strings are byte strings, so the invalid one is spelled out as bytes
+const testStrings: Uint8Array[] = [
+    "",
+    "abcd",
+    "☺☻☹",
+    "日a本b語ç日ð本Ê語þ日¥本¼語i日©",
+    "日a本b語ç日ð本Ê語þ日¥本¼語i日©日a本b語ç日ð本Ê語þ日¥本¼語i日©日a本b語ç日ð本Ê語þ日¥本¼語i日©",
+].map((s) => new TextEncoder().encode(s)).concat([new Uint8Array([0x80, 0x80, 0x80, 0x80])])
+
+function hex(b: Uint8Array | number[]): string {
+    return Array.from(b, (c) => c.toString(16).padStart(2, "0")).join(" ")
+}
+
+test("TestFullRune", () => {
+    for (let [r, str] of utf8map) {
+        let b = new Uint8Array(str)
+        assert.ok(FullRune(b), `FullRune(${hex(b)}) (${r.toString(16)}) = false, want true`)
+        let b1 = b.subarray(0, b.length - 1)
+        assert.ok(!FullRune(b1), `FullRune(${hex(b1)}) = true, want false`)
+    }
+    for (let s of [[0xc0], [0xc1]]) {
+        let b = new Uint8Array(s)
+        assert.ok(FullRune(b), `FullRune(${hex(b)}) = false, want true`)
+    }
+})
+
+test("TestEncodeRune", () => {
+    for (let [r, str] of utf8map) {
+        let buf = new Uint8Array(10)
+        let n = EncodeRune(buf, r)
+        let b1 = buf.subarray(0, n)
+        assert.deepEqual(b1, new Uint8Array(str), `EncodeRune(${r.toString(16)}) = ${hex(b1)} want ${hex(str)}`)
+    }
+})
+
+test("TestAppendRune", () => {
+    for (let [r, str] of utf8map) {
+        let buf = AppendRune(new Uint8Array(0), r)
+        assert.deepEqual(buf, new Uint8Array(str), `AppendRune(nil, ${r.toString(16)}) = ${hex(buf)}, want ${hex(str)}`)
+        let init = new TextEncoder().encode("init")
+        buf = AppendRune(init, r)
+        assert.deepEqual(buf.subarray(4), new Uint8Array(str), `AppendRune(init, ${r.toString(16)}) = ${hex(buf)}, want init${hex(str)}`)
+    }
+})
+
+test("TestDecodeRune", () => {
+    for (let [want, str] of utf8map) {
+        let b = new Uint8Array(str)
+        let [r, size] = DecodeRune(b)
+        assert.ok(r == want && size == b.length, `DecodeRune(${hex(b)}) = ${r.toString(16)}, ${size} want ${want.toString(16)}, ${b.length}`)
+
+        // there's an extra byte that bytes left behind - make sure trailing byte works
+        let b0 = new Uint8Array(b.length + 1)
+        b0.set(b);
+        [r, size] = DecodeRune(b0)
+        assert.ok(r == want && size == b.length, `DecodeRune(${hex(b0)}) = ${r.toString(16)}, ${size} want ${want.toString(16)}, ${b.length}`)
+
+        // make sure missing bytes fail
+        let wantsize = 1
+        if (wantsize >= b.length) {
+            wantsize = 0
+        }
+        [r, size] = DecodeRune(b.subarray(0, b.length - 1))
+        assert.ok(r == RuneError && size == wantsize, `DecodeRune(${hex(b.subarray(0, b.length - 1))}) = ${r.toString(16)}, ${size} want ${RuneError.toString(16)}, ${wantsize}`)
+
+        // make sure bad sequences fail
+        let bad = b.slice()
+        if (bad.length == 1) {
+            bad[0] = 0x80
+        } else {
+            bad[bad.length - 1] = 0x7F
+        }
+        [r, size] = DecodeRune(bad)
+        assert.ok(r == RuneError && size == 1, `DecodeRune(${hex(bad)}) = ${r.toString(16)}, ${size} want ${RuneError.toString(16)}, 1`)
+    }
+})
+
+test("TestDecodeSurrogateRune", () => {
+    for (let [, str] of surrogateMap) {
+        let b = new Uint8Array(str)
+        let [r, size] = DecodeRune(b)
+        assert.ok(r == RuneError && size == 1, `DecodeRune(${hex(b)}) = ${r.toString(16)}, ${size} want ${RuneError.toString(16)}, 1`)
+    }
+})
+
+// Check that DecodeRune and DecodeLastRune correspond to
+// the equivalent range loop.
+test("TestSequencing", () => {
+    for (let b of testStrings) {
+        let runes: [number, number][] = []
+        for (let i = 0; i < b.length;) {
+            let [r, size] = DecodeRune(b.subarray(i))
+            runes.push([r, i])
+            i += size
+        }
+        let j = runes.length - 1
+        for (let end = b.length; end > 0;) {
+            let [r, size] = DecodeLastRune(b.subarray(0, end))
+            end -= size
+            assert.ok(j >= 0, `DecodeLastRune(${hex(b)}) returned too many runes`)
+            assert.deepEqual([r, end], runes[j], `DecodeLastRune(${hex(b)})`)
+            j--
+        }
+        assert.equal(j, -1, `DecodeLastRune(${hex(b)}) returned too few runes`)
+    }
+})
+
+// Not present in the Go code: the runes of a valid string are its code points
+test("TestDecodeMatchesTextDecoder", () => {
+    let s = "日a本b語ç日ð本Ê語þ日¥本¼語i日©😀"
+    let b = new TextEncoder().encode(s)
+    let runes: number[] = []
+    for (let i = 0; i < b.length;) {
+        let [r, size] = DecodeRune(b.subarray(i))
+        runes.push(r)
+        i += size
+    }
+    assert.deepEqual(runes, Array.from(s, (c) => c.codePointAt(0)))
+    assert.equal(RuneCount(b), runes.length)
+})
+
+test("TestRuneCount", () => {
+    let runecounttests: [number[], number][] = [
+        [[], 0],
+        [Array.from(new TextEncoder().encode("abcd")), 4],
+        [Array.from(new TextEncoder().encode("☺☻☹")), 3],
+        [Array.from(new TextEncoder().encode("1,2,3,4")), 7],
+        [[0xe2, 0x00], 2],
+        [[0xe2, 0x80], 2],
+        [[0x61, 0xe2, 0x80], 3],
+    ]
+    for (let [s, want] of runecounttests) {
+        let out = RuneCount(new Uint8Array(s))
+        assert.equal(out, want, `RuneCount(${hex(s)}) = ${out}, want ${want}`)
+    }
+})
+
+test("TestRuneLen", () => {
+    let runelentests: [number, number][] = [
+        [0, 1],
+        [0x65, 1],
+        [0xe9, 2],
+        [0x263a, 3],
+        [RuneError, 3],
+        [MaxRune, 4],
+        [0xD800, -1],
+        [0xDFFF, -1],
+        [MaxRune + 1, -1],
+        [-1, -1],
+    ]
+    for (let [r, want] of runelentests) {
+        let size = RuneLen(r)
+        assert.equal(size, want, `RuneLen(${r.toString(16)}) = ${size}, want ${want}`)
+    }
+})
+
+// Not present in the Go code
+test("TestRuneStart", () => {
+    assert.ok(RuneStart(0x41))
+    assert.ok(RuneStart(0xe6))
+    assert.ok(!RuneStart(0x97))
+})
+
+test("TestValid", () => {
+    let validTests: [number[], boolean][] = [
+        [[], true],
+        [[0x61], true],
+        [[0x61, 0x62], true],
+        [Array.from(new TextEncoder().encode("Ж")), true],
+        [Array.from(new TextEncoder().encode("ЖЖ")), true],
+        [Array.from(new TextEncoder().encode("брэд-ЛГТМ")), true],
+        [Array.from(new TextEncoder().encode("☺☻☹")), true],
+        [[0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0x61, 0xe2], false], // incomplete
+        [[0x61, 0xe2], false],
+        [[0x61, 0x66, 0x6f, 0xe2, 0x9a], false],
+        [[0x66], true],
+        [[0x66, 0x80], false],
+        [[0x66, 0xc3, 0xa0], true],
+        [[0x66, 0xc3], false],
+        [[0x66, 0xe2, 0x82, 0xac], true],
+        [[0x66, 0xe2, 0x82], false],
+        [[0x66, 0xf0, 0x9f, 0x98, 0x80], true],
+        [[0x66, 0xf0, 0x9f, 0x98], false],
+        [[0xf5, 0x80, 0x80, 0x80], false], // out of range
+        [[0xf4, 0x90, 0x80, 0x80], false], // out of range
+        [[0xf7, 0xbf, 0xbf, 0xbf], false], // out of range
+        [[0xed, 0xa0, 0x80], false], // U+D800 high surrogate (sic)
+        [[0xed, 0xbf, 0xbf], false], // U+DFFF low surrogate (sic)
+        [[0xc0, 0x80], false], // overlong encoding of U+0000
+    ]
+    for (let [s, want] of validTests) {
+        assert.equal(Valid(new Uint8Array(s)), want, `Valid(${hex(s)}) = ${!want}; want ${want}`)
+    }
+})
+
+test("TestValidRune", () => {
+    let validrunetests: [number, boolean][] = [
+        [0, true],
+        [0x65, true],
+        [0xe9, true],
+        [0x263a, true],
+        [RuneError, true],
+        [MaxRune, true],
+        [0xD7FF, true],
+        [0xD800, false],
+        [0xDFFF, false],
+        [0xE000, true],
+        [MaxRune + 1, false],
+        [-1, false],
+    ]
+    for (let [r, ok] of validrunetests) {
+        assert.equal(ValidRune(r), ok, `ValidRune(${r.toString(16)}) = ${!ok}, want ${ok}`)
+    }
+})
+
+test("TestNegativeRune", () => {
+    let errorbuf = new Uint8Array(UTFMax)
+    errorbuf = errorbuf.subarray(0, EncodeRune(errorbuf, RuneError))
+    let buf = new Uint8Array(UTFMax)
+    buf = buf.subarray(0, EncodeRune(buf, -1))
+    assert.deepEqual(buf, errorbuf, `incorrect encoding [${hex(buf)}] for -1; expected [${hex(errorbuf)}]`)
+})